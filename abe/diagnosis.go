@@ -0,0 +1,197 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package abe
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/fentec-project/gofe/data"
+)
+
+// DecryptFailure describes why a decryption fails.
+type DecryptFailure int
+
+const (
+	// DecryptSucceeds means that the decryption does not fail.
+	DecryptSucceeds DecryptFailure = iota
+	// DecryptMalformed means that the cipher or the key are not
+	// consistent, e.g. the number of their parts does not match
+	// the policy.
+	DecryptMalformed
+	// DecryptPolicyNotSatisfied means that the attributes do not
+	// satisfy the policy, i.e. no combination of the rows of the
+	// policy matrix belonging to the present attributes gives the
+	// target vector.
+	DecryptPolicyNotSatisfied
+	// DecryptSymmetricFailed means that the policy is satisfied, but
+	// the symmetric layer of the cipher could not be decrypted with
+	// the recovered key, hence the cipher was modified or the key does
+	// not belong to the master keys the cipher was encrypted with.
+	DecryptSymmetricFailed
+)
+
+// String returns a description of the failure.
+func (f DecryptFailure) String() string {
+	switch f {
+	case DecryptSucceeds:
+		return "decryption succeeds"
+	case DecryptMalformed:
+		return "the cipher or the key is malformed"
+	case DecryptPolicyNotSatisfied:
+		return "the attributes do not satisfy the policy"
+	case DecryptSymmetricFailed:
+		return "the policy is satisfied, but the symmetric decryption failed; " +
+			"the cipher is corrupted or the key does not match the master keys of the cipher"
+	}
+
+	return fmt.Sprintf("unknown failure %d", int(f))
+}
+
+// DecryptDiagnosis reports why a decryption of an ABE cipher with
+// a given key fails. It lists the attributes referenced by the policy,
+// split into those that are present, i.e. owned by the key in FAME or
+// associated with the cipher in GPSW, and those that are missing.
+type DecryptDiagnosis struct {
+	Failure  DecryptFailure
+	Required []string // attributes referenced by the policy
+	Present  []string // attributes of the policy that are present
+	Missing  []string // attributes of the policy that are missing
+	Err      error    // error returned by the decryption, nil if it succeeds
+}
+
+// String returns a human readable report of the diagnosis.
+func (d *DecryptDiagnosis) String() string {
+	var b strings.Builder
+	b.WriteString(d.Failure.String())
+	if d.Err != nil && d.Failure != DecryptPolicyNotSatisfied {
+		fmt.Fprintf(&b, " (%v)", d.Err)
+	}
+	fmt.Fprintf(&b, "; required attributes: [%s]", strings.Join(d.Required, ", "))
+	fmt.Fprintf(&b, "; present: [%s]", strings.Join(d.Present, ", "))
+	fmt.Fprintf(&b, "; missing: [%s]", strings.Join(d.Missing, ", "))
+
+	return b.String()
+}
+
+// diagnosePolicy fills in the attributes of the diagnosis and checks
+// whether the rows of msp belonging to the present attributes span
+// the target vector, [1, 1,..., 1] if ones is true and [1, 0,..., 0]
+// otherwise.
+func diagnosePolicy(msp *MSP, present map[string]bool, p *big.Int, ones bool) *DecryptDiagnosis {
+	diag := &DecryptDiagnosis{Required: msp.Attributes(), Present: []string{}, Missing: []string{}}
+	for _, attrib := range diag.Required {
+		if present[attrib] {
+			diag.Present = append(diag.Present, attrib)
+		} else {
+			diag.Missing = append(diag.Missing, attrib)
+		}
+	}
+
+	_, mat := msp.ownedRows(present)
+	if len(mat) == 0 {
+		diag.Failure = DecryptPolicyNotSatisfied
+		diag.Err = ErrPolicyNotSatisfied
+		return diag
+	}
+	target := data.NewConstantVector(len(mat[0]), big.NewInt(0))
+	target[0].SetInt64(1)
+	if ones {
+		target = data.NewConstantVector(len(mat[0]), big.NewInt(1))
+	}
+	if _, err := data.GaussianEliminationSolver(mat.Transpose(), target, p); err != nil {
+		diag.Failure = DecryptPolicyNotSatisfied
+		diag.Err = ErrPolicyNotSatisfied
+	}
+
+	return diag
+}
+
+// Diagnose reports why the cipher cannot be decrypted with the key,
+// distinguishing a malformed cipher or key, attributes of the key
+// not satisfying the policy of the cipher, and a failure of the
+// symmetric decryption despite a satisfied policy. It is meant for
+// troubleshooting; it performs the decryption, but does not return
+// the decrypted message. Note that in the CBC mode a wrong symmetric
+// key is detected only with a high probability, as the padding of
+// the decrypted message might still be valid.
+func (a *FAME) Diagnose(cipher *FAMECipher, key *FAMEAttribKeys, pk *FAMEPubKey) *DecryptDiagnosis {
+	if cipher.Msp == nil || len(cipher.Msp.Mat) == 0 || len(cipher.Msp.Mat) != len(cipher.Ct) ||
+		len(cipher.Msp.Mat) != len(cipher.Msp.RowToAttrib) {
+		return &DecryptDiagnosis{Failure: DecryptMalformed, Err: fmt.Errorf("the cipher does not match its policy")}
+	}
+	present := make(map[string]bool)
+	for attrib, i := range key.AttribToI {
+		if i < 0 || i >= len(key.K) {
+			return &DecryptDiagnosis{Failure: DecryptMalformed, Err: fmt.Errorf("the key does not match its attributes")}
+		}
+		present[attrib] = true
+	}
+
+	diag := diagnosePolicy(cipher.Msp, present, a.P, false)
+	if diag.Failure != DecryptSucceeds {
+		return diag
+	}
+	if _, err := a.DecryptBytes(cipher, key, pk); err != nil {
+		diag.Failure = DecryptSymmetricFailed
+		diag.Err = err
+	}
+
+	return diag
+}
+
+// Diagnose reports why the cipher cannot be decrypted with the key,
+// distinguishing a malformed cipher or key, attributes of the cipher
+// not satisfying the policy of the key, and a failure of the symmetric
+// decryption despite a satisfied policy. The attributes are reported
+// by the names used in the policy of the key. It is meant for
+// troubleshooting; it performs the decryption, but does not return
+// the decrypted message.
+func (a *GPSW) Diagnose(cipher *GPSWCipher, key *GPSWKey) *DecryptDiagnosis {
+	if key.Msp == nil || len(key.Msp.Mat) == 0 || len(key.Msp.Mat) != len(key.D) ||
+		len(key.Msp.Mat) != len(key.Msp.RowToAttrib) {
+		return &DecryptDiagnosis{Failure: DecryptMalformed, Err: fmt.Errorf("the key does not match its policy")}
+	}
+	gammaMap := make(map[int]bool)
+	for _, e := range cipher.Gamma {
+		i, ok := cipher.AttribToI[e]
+		if !ok || i < 0 || i >= len(cipher.E) {
+			return &DecryptDiagnosis{Failure: DecryptMalformed, Err: fmt.Errorf("the cipher does not match its attributes")}
+		}
+		gammaMap[e] = true
+	}
+	present := make(map[string]bool)
+	for _, attrib := range key.Msp.RowToAttrib {
+		i, err := a.attribIndex(attrib)
+		if err != nil {
+			return &DecryptDiagnosis{Failure: DecryptMalformed, Err: err}
+		}
+		present[attrib] = gammaMap[i]
+	}
+
+	diag := diagnosePolicy(key.Msp, present, a.Params.P, true)
+	if diag.Failure != DecryptSucceeds {
+		return diag
+	}
+	if _, err := a.DecryptBytes(cipher, key); err != nil {
+		diag.Failure = DecryptSymmetricFailed
+		diag.Err = err
+	}
+
+	return diag
+}
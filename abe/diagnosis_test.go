@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package abe_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fentec-project/gofe/abe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFAME_Diagnose(t *testing.T) {
+	// in the GCM mode a wrong symmetric key is always detected
+	a := abe.NewFAME()
+	a.SymMode = abe.SymGCM
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	_, otherSecKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+
+	msp, err := abe.BooleanToMSP("(a AND b) OR c", false)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	cipher, err := a.Encrypt("Attack at dawn!", msp, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	keys, err := a.GenerateAttribKeys([]string{"a", "b", "d"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	diag := a.Diagnose(cipher, keys, pubKey)
+	assert.Equal(t, abe.DecryptSucceeds, diag.Failure)
+	assert.NoError(t, diag.Err)
+	assert.Equal(t, []string{"a", "b", "c"}, diag.Required)
+	assert.Equal(t, []string{"a", "b"}, diag.Present)
+	assert.Equal(t, []string{"c"}, diag.Missing)
+
+	keys, err = a.GenerateAttribKeys([]string{"a", "d"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	diag = a.Diagnose(cipher, keys, pubKey)
+	assert.Equal(t, abe.DecryptPolicyNotSatisfied, diag.Failure)
+	assert.Equal(t, []string{"b", "c"}, diag.Missing)
+	assert.True(t, strings.Contains(diag.String(), "missing: [b, c]"), diag.String())
+
+	// keys of other master keys satisfy the policy, but give
+	// a wrong symmetric key
+	keys, err = a.GenerateAttribKeys([]string{"c"}, otherSecKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	diag = a.Diagnose(cipher, keys, pubKey)
+	assert.Equal(t, abe.DecryptSymmetricFailed, diag.Failure)
+	assert.Error(t, diag.Err)
+
+	cipher.Ct = cipher.Ct[1:]
+	diag = a.Diagnose(cipher, keys, pubKey)
+	assert.Equal(t, abe.DecryptMalformed, diag.Failure)
+}
+
+func TestGPSW_Diagnose(t *testing.T) {
+	a := abe.NewGPSW(5)
+	a.SymMode = abe.SymGCM
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+
+	msp, err := abe.BooleanToMSP("0 AND (1 OR 2)", true)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	key, err := a.GeneratePolicyKey(msp, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate the key: %v", err)
+	}
+
+	cipher, err := a.Encrypt("Attack at dawn!", []int{0, 2, 4}, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	diag := a.Diagnose(cipher, key)
+	assert.Equal(t, abe.DecryptSucceeds, diag.Failure)
+	assert.Equal(t, []string{"0", "2"}, diag.Present)
+	assert.Equal(t, []string{"1"}, diag.Missing)
+
+	// a key of other master keys satisfies the policy, but gives
+	// a wrong symmetric key
+	_, otherSecKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	otherKey, err := a.GeneratePolicyKey(msp, otherSecKey)
+	if err != nil {
+		t.Fatalf("Failed to generate the key: %v", err)
+	}
+	diag = a.Diagnose(cipher, otherKey)
+	assert.Equal(t, abe.DecryptSymmetricFailed, diag.Failure)
+
+	cipher, err = a.Encrypt("Attack at dawn!", []int{1, 2}, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	diag = a.Diagnose(cipher, key)
+	assert.Equal(t, abe.DecryptPolicyNotSatisfied, diag.Failure)
+	assert.Equal(t, []string{"0"}, diag.Missing)
+
+	key.D = key.D[1:]
+	diag = a.Diagnose(cipher, key)
+	assert.Equal(t, abe.DecryptMalformed, diag.Failure)
+}
@@ -17,16 +17,13 @@
 package abe
 
 import (
-	"crypto/aes"
-	cbc "crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"math/big"
 	"strconv"
 
-	"io"
-
 	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/data"
 	"github.com/fentec-project/gofe/sample"
@@ -37,10 +34,12 @@ import (
 // "Decentralized Policy-Hiding Attribute-Based Encryption with Receiver Privacy"
 // https://eprint.iacr.org/2018/753.pdf
 type DIPPE struct {
-	secLevel int
-	G1ToA    data.MatrixG1
-	G1ToUA   data.MatrixG1
-	P        *big.Int // order of the elliptic curve
+	secLevel   int
+	G1ToA      data.MatrixG1
+	G1ToUA     data.MatrixG1
+	P          *big.Int // order of the elliptic curve
+	SymMode    SymMode  // symmetric encryption of the messages, SymCBC by default
+	SymKeySize int      // size of the AES key in bytes (16, 24 or 32), DefaultSymKeySize by default
 }
 
 // DIPPEPubKey represents a public key of an authority in DIPPE scheme.
@@ -66,12 +65,21 @@ type DIPPEAuth struct {
 
 // DIPPECipher represents a ciphertext in DIPPE scheme
 type DIPPECipher struct {
-	C0     data.VectorG1
-	C      data.MatrixG1
-	CPrime *bn256.GT
-	X      data.Vector // policy vector
-	SymEnc []byte      // symmetric encryption of the message
-	Iv     []byte      // initialization vector for symmetric encryption
+	C0      data.VectorG1
+	C       data.MatrixG1
+	CPrime  *bn256.GT
+	X       data.Vector // policy vector
+	SymEnc  []byte      // symmetric encryption of the message
+	Iv      []byte      // initialization vector for symmetric encryption in the CBC mode
+	Nonce   []byte      // nonce for symmetric encryption in the GCM mode
+	Tag     []byte      // authentication tag of symmetric encryption in the GCM mode
+	Mode    SymMode     // mode of symmetric encryption
+	KeySize int         // size of the AES key in bytes
+}
+
+// sym returns the symmetric part of the cipher.
+func (c *DIPPECipher) sym() *SymEnvelope {
+	return &SymEnvelope{Mode: c.Mode, KeySize: c.KeySize, SymEnc: c.SymEnc, Iv: c.Iv, Nonce: c.Nonce, Tag: c.Tag}
 }
 
 // NewDIPPE configures a new instance of the scheme. The input parameter
@@ -84,12 +92,46 @@ func NewDIPPE(secLevel int) (*DIPPE, error) {
 	if err != nil {
 		return nil, err
 	}
-	g1ToA := A.MulG1()
 
 	U, err := data.NewRandomMatrix(secLevel+1, secLevel+1, sampler)
 	if err != nil {
 		return nil, err
 	}
+
+	return newDIPPEFromMatrices(secLevel, A, U)
+}
+
+// NewDIPPEFromSeed configures a new instance of the scheme as NewDIPPE,
+// but derives the matrices A and U deterministically from the given
+// seed. Parties that use the same seed obtain the same shared public
+// parameters G1ToA and G1ToUA, while the secrets of the authorities
+// are still sampled randomly by NewDIPPEAuth. The seed should be
+// chosen with enough entropy and agreed upon by all the parties.
+func NewDIPPEFromSeed(secLevel int, seed []byte) (*DIPPE, error) {
+	if len(seed) == 0 {
+		return nil, fmt.Errorf("seed should not be empty")
+	}
+
+	keyA := sha256.Sum256(append([]byte("DIPPE A"), seed...))
+	A, err := data.NewRandomDetMatrix(secLevel+1, secLevel, bn256.Order, &keyA)
+	if err != nil {
+		return nil, err
+	}
+
+	keyU := sha256.Sum256(append([]byte("DIPPE U"), seed...))
+	U, err := data.NewRandomDetMatrix(secLevel+1, secLevel+1, bn256.Order, &keyU)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDIPPEFromMatrices(secLevel, A, U)
+}
+
+// newDIPPEFromMatrices configures a new instance of the scheme
+// from the matrices A and U.
+func newDIPPEFromMatrices(secLevel int, A, U data.Matrix) (*DIPPE, error) {
+	g1ToA := A.MulG1()
+
 	UA, err := U.Mul(A)
 	if err != nil {
 		return nil, err
@@ -125,13 +167,31 @@ func (d *DIPPE) NewDIPPEAuth(id int) (*DIPPEAuth, error) {
 	}
 
 	sk := DIPPESecKey{W: W, Alpha: alpha, Sigma: sigma}
+	pk, err := d.PubKeyFromSecKey(&sk)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DIPPEAuth{ID: id, Sk: sk, Pk: *pk}, nil
+}
+
+// PubKeyFromSecKey computes the public key of an authority from its
+// secret key sk and the public parameters of the scheme, so that an
+// authority only needs to store its secret key. The public key is
+// uniquely determined by them, hence it equals the one generated
+// together with sk. An error is returned if the dimensions of sk do
+// not match the scheme.
+func (d *DIPPE) PubKeyFromSecKey(sk *DIPPESecKey) (*DIPPEPubKey, error) {
+	if sk.Sigma == nil || !sk.W.CheckDims(d.secLevel+1, d.secLevel+1) || len(sk.Alpha) != d.secLevel+1 {
+		return nil, fmt.Errorf("the secret key does not match the scheme")
+	}
 
-	g1ToWtA, err := W.Transpose().MatMulMatG1(d.G1ToA)
+	g1ToWtA, err := sk.W.Transpose().MatMulMatG1(d.G1ToA)
 	if err != nil {
 		return nil, err
 	}
 
-	alphaAsMatrix := data.Matrix([]data.Vector{alpha})
+	alphaAsMatrix := data.Matrix([]data.Vector{sk.Alpha})
 	g1ToAlphaA, err := alphaAsMatrix.MatMulMatG1(d.G1ToA)
 	if err != nil {
 		return nil, err
@@ -143,11 +203,9 @@ func (d *DIPPE) NewDIPPEAuth(id int) (*DIPPEAuth, error) {
 		gtToAlphaA[i] = bn256.Pair(g1ToAlphaA[0][i], g2)
 	}
 
-	g2ToSigma := new(bn256.G2).ScalarMult(g2, sigma)
-
-	pk := DIPPEPubKey{G1ToWtA: g1ToWtA, GToAlphaA: gtToAlphaA, G2ToSigma: g2ToSigma}
+	g2ToSigma := new(bn256.G2).ScalarMult(g2, sk.Sigma)
 
-	return &DIPPEAuth{ID: id, Sk: sk, Pk: pk}, nil
+	return &DIPPEPubKey{G1ToWtA: g1ToWtA, GToAlphaA: gtToAlphaA, G2ToSigma: g2ToSigma}, nil
 }
 
 // Encrypt takes as an input a string message msg, a vector x representing a
@@ -156,38 +214,24 @@ func (d *DIPPE) NewDIPPEAuth(id int) (*DIPPEAuth, error) {
 // id i. It returns an encryption of msg. In case of a failed procedure an
 // error is returned.
 func (d *DIPPE) Encrypt(msg string, x data.Vector, pubKeys []*DIPPEPubKey) (*DIPPECipher, error) {
-	// msg is encrypted using CBC, with a random key that is encapsulated
+	return d.EncryptBytes([]byte(msg), x, pubKeys)
+}
+
+// EncryptBytes works as Encrypt, but accepts the message as a byte
+// slice, hence arbitrary binary data can be encrypted without
+// converting it to a string.
+func (d *DIPPE) EncryptBytes(msg []byte, x data.Vector, pubKeys []*DIPPEPubKey) (*DIPPECipher, error) {
+	// msg is encrypted using AES, with a random key that is encapsulated
 	// with DIPPE
 	_, keyGt, err := bn256.RandomGT(rand.Reader)
 	if err != nil {
 		return nil, err
 	}
-	keyCBC := sha256.Sum256([]byte(keyGt.String()))
-
-	a, err := aes.NewCipher(keyCBC[:])
-	if err != nil {
+	env := &SymEnvelope{Mode: d.SymMode, KeySize: d.SymKeySize}
+	if err := env.Seal(keyGt, msg); err != nil {
 		return nil, err
 	}
 
-	iv := make([]byte, a.BlockSize())
-	_, err = io.ReadFull(rand.Reader, iv)
-	if err != nil {
-		return nil, err
-	}
-	encrypterCBC := cbc.NewCBCEncrypter(a, iv)
-
-	msgByte := []byte(msg)
-	// message is padded according to pkcs7 standard
-	padLen := a.BlockSize() - (len(msgByte) % a.BlockSize())
-	msgPad := make([]byte, len(msgByte)+padLen)
-	copy(msgPad, msgByte)
-	for i := len(msgByte); i < len(msgPad); i++ {
-		msgPad[i] = byte(padLen)
-	}
-
-	symEnc := make([]byte, len(msgPad))
-	encrypterCBC.CryptBlocks(symEnc, msgPad)
-
 	// encapsulate the key with DIPPE
 	sampler := sample.NewUniform(bn256.Order)
 	s, err := data.NewRandomVector(d.secLevel, sampler)
@@ -214,7 +258,8 @@ func (d *DIPPE) Encrypt(msg string, x data.Vector, pubKeys []*DIPPEPubKey) (*DIP
 	}
 	cPrime.Add(keyGt, cPrime)
 
-	return &DIPPECipher{C0: c0, C: c, CPrime: cPrime, X: x.Copy(), SymEnc: symEnc, Iv: iv}, nil
+	return &DIPPECipher{C0: c0, C: c, CPrime: cPrime, X: x.Copy(),
+		SymEnc: env.SymEnc, Iv: env.Iv, Nonce: env.Nonce, Tag: env.Tag, Mode: d.SymMode, KeySize: env.KeySize}, nil
 }
 
 // DeriveKeyShare allows an authority to give a partial decryption key. Collecting all
@@ -223,6 +268,19 @@ func (d *DIPPE) Encrypt(msg string, x data.Vector, pubKeys []*DIPPEPubKey) (*DIP
 // v times x = 0 for the policy x. GID is a global identifier of the user and a slice of
 // public keys of the authorities should be given.
 func (a *DIPPEAuth) DeriveKeyShare(v data.Vector, pubKeys []*DIPPEPubKey, gid string) (data.VectorG2, error) {
+	if len(v) != len(pubKeys) {
+		return nil, fmt.Errorf("length of the vector %d does not match the number of public keys %d",
+			len(v), len(pubKeys))
+	}
+	if a.ID < 0 || a.ID >= len(pubKeys) {
+		return nil, fmt.Errorf("id of the authority %d is not in the range of the public keys", a.ID)
+	}
+	for j, pk := range pubKeys {
+		if pk == nil || pk.G2ToSigma == nil {
+			return nil, fmt.Errorf("public key %d is missing", j)
+		}
+	}
+
 	g2ToMu := make(data.VectorG2, a.Sk.W.Rows())
 	for i := 0; i < a.Sk.W.Rows(); i++ {
 		g2ToMu[i] = new(bn256.G2).ScalarBaseMult(big.NewInt(0))
@@ -250,13 +308,9 @@ func (a *DIPPEAuth) DeriveKeyShare(v data.Vector, pubKeys []*DIPPEPubKey, gid st
 		}
 	}
 
-	g2ToH := make(data.VectorG2, a.Sk.W.Rows())
-	for j := range g2ToH {
-		g2ToH[j], err = bn256.HashG2(strconv.Itoa(j) + gid + v.String())
-
-		if err != nil {
-			return nil, err
-		}
+	g2ToH, err := hashUser(v, gid, a.Sk.W.Rows())
+	if err != nil {
+		return nil, err
 	}
 	g2ToWH, err := a.Sk.W.MatMulVecG2(g2ToH)
 	if err != nil {
@@ -267,29 +321,226 @@ func (a *DIPPEAuth) DeriveKeyShare(v data.Vector, pubKeys []*DIPPEPubKey, gid st
 	return g2ToAlpha.Add(g2ToViWH).Add(g2ToMu), nil
 }
 
+// VerifyKeyShare checks a key share obtained from an authority with
+// DeriveKeyShare for the user with vector v and global identifier gid.
+// It returns an error if the share or the public key of the authority
+// is malformed. The share is masked with hashes of g2^(sigma_i*sigma_j)
+// shared only between pairs of authorities, so a pairing check against
+// the public key over H(gid, v) does not hold for a single share and no
+// one but the authorities can remove the mask. Hence for a well-formed
+// share ErrKeyShareUnverifiable is returned rather than nil; use
+// VerifyKeys once the shares of all the authorities are collected, as
+// the masks cancel out in their sum.
+func (d *DIPPE) VerifyKeyShare(share data.VectorG2, pubKey *DIPPEPubKey, v data.Vector, gid string) error {
+	if len(v) == 0 {
+		return fmt.Errorf("user vector should not be empty")
+	}
+	for _, e := range v {
+		if e == nil {
+			return fmt.Errorf("user vector contains a missing element")
+		}
+	}
+	if pubKey == nil || pubKey.G2ToSigma == nil ||
+		len(pubKey.G1ToWtA) != d.secLevel+1 || len(pubKey.GToAlphaA) != d.secLevel {
+		return fmt.Errorf("malformed public key")
+	}
+	for _, row := range pubKey.G1ToWtA {
+		if len(row) != d.secLevel {
+			return fmt.Errorf("malformed public key")
+		}
+	}
+	if len(share) != d.secLevel+1 {
+		return fmt.Errorf("key share has length %d, expected %d", len(share), d.secLevel+1)
+	}
+	for _, e := range share {
+		if e == nil {
+			return fmt.Errorf("key share contains a missing element")
+		}
+	}
+
+	return ErrKeyShareUnverifiable
+}
+
+// VerifyKeys checks that the key shares collected from all the
+// authorities, given in the order of their public keys, are
+// consistent with the public keys for the user with vector v and
+// global identifier gid. Since the masks of the shares cancel out
+// only in the sum, a failed check reveals that at least one share
+// is bad, but not which one. This allows detecting bad shares before
+// decryption, when a failure could also be due to the policy.
+func (d *DIPPE) VerifyKeys(keys []data.VectorG2, pubKeys []*DIPPEPubKey, v data.Vector, gid string) error {
+	if len(keys) != len(pubKeys) || len(v) != len(pubKeys) {
+		return fmt.Errorf("the number of keys %d, public keys %d and the length of the vector %d should match",
+			len(keys), len(pubKeys), len(v))
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no keys given")
+	}
+	for i := range keys {
+		if err := d.VerifyKeyShare(keys[i], pubKeys[i], v, gid); !errors.Is(err, ErrKeyShareUnverifiable) {
+			return fmt.Errorf("key share %d: %v", i, err)
+		}
+	}
+
+	hashed, err := hashUser(v, gid, d.secLevel+1)
+	if err != nil {
+		return err
+	}
+	sum := keys[0].Copy()
+	for _, k := range keys[1:] {
+		sum = sum.Add(k)
+	}
+
+	// the sum of the keys is [alpha - sum_i v_i W_i h]_2, hence for each
+	// column c of A it holds e([A_c]_1, sum) = prod_i e(g1, g2)^(alpha_i A_c) /
+	// e([W_i^T A_c]_1, [h]_2)^v_i
+	for c := 0; c < d.secLevel; c++ {
+		lhs := new(bn256.GT).ScalarBaseMult(big.NewInt(0))
+		for r := range sum {
+			lhs.Add(lhs, bn256.Pair(d.G1ToA[r][c], sum[r]))
+		}
+
+		rhs := new(bn256.GT).ScalarBaseMult(big.NewInt(0))
+		for i, pk := range pubKeys {
+			rhs.Add(rhs, pk.GToAlphaA[c])
+			if v[i].Sign() == 0 {
+				continue
+			}
+			wh := new(bn256.GT).ScalarBaseMult(big.NewInt(0))
+			for r := range hashed {
+				wh.Add(wh, bn256.Pair(pk.G1ToWtA[r][c], hashed[r]))
+			}
+			vi := new(big.Int).Mod(v[i], d.P)
+			rhs.Add(rhs, new(bn256.GT).Neg(new(bn256.GT).ScalarMult(wh, vi)))
+		}
+
+		if lhs.String() != rhs.String() {
+			return fmt.Errorf("key shares are not consistent with the public keys")
+		}
+	}
+
+	return nil
+}
+
 // Decrypt accepts the ciphertext, a slice of keys obtained from the authorities,
 // a vector v representing the users decryption allowance, and a global identifier.
 // If the provided keys are correct and the inner product v times x = 0 for the policy
 // x, the message is decrypted, otherwise an error is returned.
 func (d *DIPPE) Decrypt(cipher *DIPPECipher, keys []data.VectorG2, v data.Vector, gid string) (string, error) {
+	msgByte, err := d.DecryptBytes(cipher, keys, v, gid)
+	if err != nil {
+		return "", err
+	}
+
+	return string(msgByte), nil
+}
+
+// DecryptBytes works as Decrypt, but returns the decrypted message
+// as a byte slice.
+func (d *DIPPE) DecryptBytes(cipher *DIPPECipher, keys []data.VectorG2, v data.Vector, gid string) ([]byte, error) {
+	hashed, err := hashUser(v, gid, len(cipher.C0))
+	if err != nil {
+		return nil, err
+	}
+
+	return d.decrypt(cipher, keys, v, hashed)
+}
+
+// hashUser returns the elements H(j || gid || v) of G2 for j in [0, n)
+// that bind the decryption keys to the user.
+func hashUser(v data.Vector, gid string, n int) (data.VectorG2, error) {
+	hashed := make(data.VectorG2, n)
+	for j := range hashed {
+		var err error
+		hashed[j], err = bn256.HashG2(strconv.Itoa(j) + gid + v.String())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return hashed, nil
+}
+
+// DIPPEUserHashes holds the elements of G2 obtained by hashing the
+// vector v and the global identifier gid of a user. They are needed
+// in every decryption by the user and can be computed once with
+// PrecomputeUserHashes and reused across many decryptions.
+type DIPPEUserHashes struct {
+	V   data.Vector
+	Gid string
+	H   data.VectorG2
+}
+
+// PrecomputeUserHashes accepts a vector v representing the users decryption
+// allowance and a global identifier gid, and computes the hashed values
+// needed for the decryption of ciphertexts of the scheme d.
+func (d *DIPPE) PrecomputeUserHashes(v data.Vector, gid string) (*DIPPEUserHashes, error) {
+	hashed, err := hashUser(v, gid, d.secLevel+1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DIPPEUserHashes{V: v, Gid: gid, H: hashed}, nil
+}
+
+// DecryptWithUserHashes works as Decrypt, but instead of hashing
+// the vector and the global identifier of the user in each call, it uses
+// the values precomputed by PrecomputeUserHashes. This is useful when a
+// user decrypts many ciphertexts. The result is the same as the result
+// of Decrypt called with h.V and h.Gid.
+func (d *DIPPE) DecryptWithUserHashes(cipher *DIPPECipher, keys []data.VectorG2, h *DIPPEUserHashes) (string, error) {
+	msgByte, err := d.decrypt(cipher, keys, h.V, h.H)
+	if err != nil {
+		return "", err
+	}
+
+	return string(msgByte), nil
+}
+
+// decrypt implements Decrypt given the hashed values of the user.
+func (d *DIPPE) decrypt(cipher *DIPPECipher, keys []data.VectorG2, v data.Vector, hashed data.VectorG2) ([]byte, error) {
+	// check if the dimensions of the inputs match
+	if len(cipher.C) != len(cipher.X) {
+		return nil, fmt.Errorf("the provided cipher is faulty")
+	}
+	if len(v) != len(cipher.X) {
+		return nil, fmt.Errorf("length of the vector %d does not match the length of the policy %d",
+			len(v), len(cipher.X))
+	}
+	if len(keys) != len(cipher.C) {
+		return nil, fmt.Errorf("number of keys %d does not match the number of authorities %d",
+			len(keys), len(cipher.C))
+	}
+	if len(hashed) != len(cipher.C0) {
+		return nil, fmt.Errorf("the precomputed values do not match the cipher")
+	}
+	for i, k := range keys {
+		if len(k) != len(cipher.C0) {
+			return nil, fmt.Errorf("key %d has length %d, expected %d", i, len(k), len(cipher.C0))
+		}
+		if len(cipher.C[i]) != len(cipher.C0) {
+			return nil, fmt.Errorf("the provided cipher is faulty")
+		}
+	}
+
 	// check if the decryption is possible
 	prod, err := v.Dot(cipher.X)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if prod.Sign() != 0 {
-		return "", fmt.Errorf("insufficient keys")
+		return nil, ErrPolicyNotSatisfied
 	}
 
-	// use DIPPE decryption procedure to get a CBC key
+	// use DIPPE decryption procedure to get a symmetric key
 	// needed for the decryption of the message
 	gTToAlphaAS := new(bn256.GT).ScalarBaseMult(big.NewInt(0))
 
 	ones := data.NewConstantMatrix(1, len(keys), big.NewInt(1))
 	sum, err := ones.MatMulMatG2(data.MatrixG2(keys))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	for i, e := range cipher.C0 {
@@ -301,41 +552,18 @@ func (d *DIPPE) Decrypt(cipher *DIPPECipher, keys []data.VectorG2, v data.Vector
 	vMat[0] = v
 	cSum, err := vMat.MatMulMatG1(cipher.C)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	for j := range cSum[0] {
-		hashed, err := bn256.HashG2(strconv.Itoa(j) + gid + v.String())
-		if err != nil {
-			return "", err
-		}
-
-		tmpGT := bn256.Pair(cSum[0][j], hashed)
+		tmpGT := bn256.Pair(cSum[0][j], hashed[j])
 		gTToAlphaAS.Add(gTToAlphaAS, tmpGT)
 	}
 	gTToAlphaAS.Neg(gTToAlphaAS)
 
 	keyGt := new(bn256.GT).Add(cipher.CPrime, gTToAlphaAS)
 
-	keyCBC := sha256.Sum256([]byte(keyGt.String()))
-
-	a, err := aes.NewCipher(keyCBC[:])
-	if err != nil {
-		return "", err
-	}
-
-	msgPad := make([]byte, len(cipher.SymEnc))
-	decrypter := cbc.NewCBCDecrypter(a, cipher.Iv)
-	decrypter.CryptBlocks(msgPad, cipher.SymEnc)
-
-	// unpad the message
-	padLen := int(msgPad[len(msgPad)-1])
-	if (len(msgPad) - padLen) < 0 {
-		return "", fmt.Errorf("failed to decrypt")
-	}
-	msgByte := msgPad[0:(len(msgPad) - padLen)]
-
-	return string(msgByte), nil
+	return cipher.sym().Open(keyGt)
 }
 
 // ExactThresholdPolicyVecInit is used for the transformation of the DIPPE
@@ -17,9 +17,11 @@
 package abe_test
 
 import (
+	"errors"
 	"testing"
 
 	"math/big"
+	"strconv"
 
 	"github.com/fentec-project/gofe/abe"
 	"github.com/fentec-project/gofe/data"
@@ -81,6 +83,81 @@ func TestDIPPE(t *testing.T) {
 	assert.Equal(t, msg, dec)
 }
 
+func TestDIPPE_DecryptWithUserHashes(t *testing.T) {
+	d, err := abe.NewDIPPE(3)
+	if err != nil {
+		t.Fatalf("Failed to generate a new scheme: %v", err)
+	}
+	vecLen := 3
+
+	auth := make([]*abe.DIPPEAuth, vecLen)
+	pubKeys := make([]*abe.DIPPEPubKey, vecLen)
+	for i := range auth {
+		auth[i], err = d.NewDIPPEAuth(i)
+		if err != nil {
+			t.Fatalf("Failed to generate a new authority: %v", err)
+		}
+		pubKeys[i] = &auth[i].Pk
+	}
+
+	userGID := "someGID"
+	userVec := data.Vector([]*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(0)})
+	userKeys := make([]data.VectorG2, vecLen)
+	for i := range auth {
+		userKeys[i], err = auth[i].DeriveKeyShare(userVec, pubKeys, userGID)
+		if err != nil {
+			t.Fatalf("Failed to generate a user key: %v", err)
+		}
+	}
+
+	// the user hashes its identity once and decrypts many ciphertexts
+	hashes, err := d.PrecomputeUserHashes(userVec, userGID)
+	if err != nil {
+		t.Fatalf("Failed to precompute user hashes: %v", err)
+	}
+
+	policyVec := data.Vector([]*big.Int{big.NewInt(1), big.NewInt(-1), big.NewInt(5)})
+	for i := 0; i < 3; i++ {
+		msg := "message " + strconv.Itoa(i)
+		cipher, err := d.Encrypt(msg, policyVec, pubKeys)
+		if err != nil {
+			t.Fatalf("Failed to encrypt: %v", err)
+		}
+		dec, err := d.DecryptWithUserHashes(cipher, userKeys, hashes)
+		if err != nil {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		assert.Equal(t, msg, dec)
+		decCheck, err := d.Decrypt(cipher, userKeys, userVec, userGID)
+		if err != nil {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		assert.Equal(t, decCheck, dec)
+	}
+
+	// a policy not satisfied by the user still fails
+	cipher, err := d.Encrypt("secret", data.Vector([]*big.Int{big.NewInt(1), big.NewInt(0), big.NewInt(0)}), pubKeys)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	_, err = d.DecryptWithUserHashes(cipher, userKeys, hashes)
+	assert.Error(t, err)
+
+	// hashes of another identity do not decrypt
+	otherHashes, err := d.PrecomputeUserHashes(userVec, "otherGID")
+	if err != nil {
+		t.Fatalf("Failed to precompute user hashes: %v", err)
+	}
+	cipher, err = d.Encrypt("secret", policyVec, pubKeys)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	dec, err := d.DecryptWithUserHashes(cipher, userKeys, otherHashes)
+	if err == nil {
+		assert.NotEqual(t, "secret", dec)
+	}
+}
+
 func TestDIPPE_ABE_threshold(t *testing.T) {
 	// this test transforms DIPPE scheme into an ABE scheme
 	// with the exact threshold policy; in threshold policy each user has
@@ -224,3 +301,254 @@ func TestDIPPE_ABE_conjugation(t *testing.T) {
 	}
 	assert.Equal(t, msg, dec)
 }
+
+func TestDIPPE_LengthValidation(t *testing.T) {
+	d, err := abe.NewDIPPE(2)
+	if err != nil {
+		t.Fatalf("Failed to generate a new scheme: %v", err)
+	}
+	vecLen := 3
+
+	auth := make([]*abe.DIPPEAuth, vecLen)
+	pubKeys := make([]*abe.DIPPEPubKey, vecLen)
+	for i := range auth {
+		auth[i], err = d.NewDIPPEAuth(i)
+		if err != nil {
+			t.Fatalf("Failed to generate a new authority: %v", err)
+		}
+		pubKeys[i] = &auth[i].Pk
+	}
+
+	policyVec := data.Vector{big.NewInt(1), big.NewInt(-1), big.NewInt(0)}
+	cipher, err := d.Encrypt("some message", policyVec, pubKeys)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	userGID := "someGID"
+	userVec := data.Vector{big.NewInt(1), big.NewInt(1), big.NewInt(5)}
+	userKeys := make([]data.VectorG2, vecLen)
+	for i := range auth {
+		userKeys[i], err = auth[i].DeriveKeyShare(userVec, pubKeys, userGID)
+		if err != nil {
+			t.Fatalf("Failed to generate a user key: %v", err)
+		}
+	}
+
+	// key shares cannot be derived for vectors of a wrong length
+	// or with public keys missing
+	_, err = auth[0].DeriveKeyShare(userVec[:2], pubKeys, userGID)
+	assert.Error(t, err)
+	_, err = auth[2].DeriveKeyShare(userVec[:2], pubKeys[:2], userGID)
+	assert.Error(t, err)
+	_, err = auth[0].DeriveKeyShare(userVec, []*abe.DIPPEPubKey{pubKeys[0], nil, pubKeys[2]}, userGID)
+	assert.Error(t, err)
+
+	// mismatched key counts and dimensions return errors instead of panicking
+	_, err = d.Decrypt(cipher, userKeys[:2], userVec, userGID)
+	assert.Error(t, err)
+	_, err = d.Decrypt(cipher, append(userKeys, userKeys[0]), userVec, userGID)
+	assert.Error(t, err)
+	_, err = d.Decrypt(cipher, []data.VectorG2{userKeys[0], userKeys[1][:1], userKeys[2]}, userVec, userGID)
+	assert.Error(t, err)
+	_, err = d.Decrypt(cipher, userKeys, userVec[:2], userGID)
+	assert.Error(t, err)
+
+	dec, err := d.Decrypt(cipher, userKeys, userVec, userGID)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, "some message", dec)
+}
+
+func TestNewDIPPEFromSeed(t *testing.T) {
+	seed := []byte("federation parameters 2021")
+	d1, err := abe.NewDIPPEFromSeed(2, seed)
+	if err != nil {
+		t.Fatalf("Failed to generate a new scheme: %v", err)
+	}
+	d2, err := abe.NewDIPPEFromSeed(2, seed)
+	if err != nil {
+		t.Fatalf("Failed to generate a new scheme: %v", err)
+	}
+	d3, err := abe.NewDIPPEFromSeed(2, []byte("other parameters"))
+	if err != nil {
+		t.Fatalf("Failed to generate a new scheme: %v", err)
+	}
+
+	// the same seed gives the same shared parameters
+	assert.Equal(t, d1.G1ToA, d2.G1ToA)
+	assert.Equal(t, d1.G1ToUA, d2.G1ToUA)
+	assert.NotEqual(t, d1.G1ToA, d3.G1ToA)
+
+	// authorities of the two parties can be used together
+	vecLen := 2
+	pubKeys := make([]*abe.DIPPEPubKey, vecLen)
+	auth0, err := d1.NewDIPPEAuth(0)
+	if err != nil {
+		t.Fatalf("Failed to generate a new authority: %v", err)
+	}
+	auth1, err := d2.NewDIPPEAuth(1)
+	if err != nil {
+		t.Fatalf("Failed to generate a new authority: %v", err)
+	}
+	pubKeys[0], pubKeys[1] = &auth0.Pk, &auth1.Pk
+
+	msg := "some message"
+	policyVec := data.Vector{big.NewInt(1), big.NewInt(-1)}
+	cipher, err := d1.Encrypt(msg, policyVec, pubKeys)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	userVec := data.Vector{big.NewInt(1), big.NewInt(1)}
+	userKeys := make([]data.VectorG2, vecLen)
+	for i, a := range []*abe.DIPPEAuth{auth0, auth1} {
+		userKeys[i], err = a.DeriveKeyShare(userVec, pubKeys, "someGID")
+		if err != nil {
+			t.Fatalf("Failed to generate a user key: %v", err)
+		}
+	}
+	dec, err := d2.Decrypt(cipher, userKeys, userVec, "someGID")
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, dec)
+
+	_, err = abe.NewDIPPEFromSeed(2, nil)
+	assert.Error(t, err)
+}
+
+func TestDIPPE_GCM(t *testing.T) {
+	d, err := abe.NewDIPPE(2)
+	if err != nil {
+		t.Fatalf("Failed to generate a new scheme: %v", err)
+	}
+	d.SymMode = abe.SymGCM
+	vecLen := 3
+	auth := make([]*abe.DIPPEAuth, vecLen)
+	pubKeys := make([]*abe.DIPPEPubKey, vecLen)
+	for i := range auth {
+		auth[i], err = d.NewDIPPEAuth(i)
+		if err != nil {
+			t.Fatalf("Failed to generate a new authority: %v", err)
+		}
+		pubKeys[i] = &auth[i].Pk
+	}
+
+	msg := "some message"
+	policyVec := data.Vector([]*big.Int{big.NewInt(1), big.NewInt(-1), big.NewInt(0)})
+	cipher, err := d.Encrypt(msg, policyVec, pubKeys)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	userGID := "someGID"
+	userVec := data.Vector([]*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(5)})
+	userKeys := make([]data.VectorG2, vecLen)
+	for i := range auth {
+		userKeys[i], err = auth[i].DeriveKeyShare(userVec, pubKeys, userGID)
+		if err != nil {
+			t.Fatalf("Failed to generate a user key: %v", err)
+		}
+	}
+	dec, err := d.Decrypt(cipher, userKeys, userVec, userGID)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, dec)
+
+	// a tampered ciphertext fails to decrypt
+	cipher.Nonce[0] ^= 1
+	_, err = d.Decrypt(cipher, userKeys, userVec, userGID)
+	assert.Error(t, err)
+}
+
+func TestDIPPE_VerifyKeys(t *testing.T) {
+	d, err := abe.NewDIPPE(2)
+	if err != nil {
+		t.Fatalf("Failed to generate a new scheme: %v", err)
+	}
+	vecLen := 3
+
+	auth := make([]*abe.DIPPEAuth, vecLen)
+	pubKeys := make([]*abe.DIPPEPubKey, vecLen)
+	for i := range auth {
+		auth[i], err = d.NewDIPPEAuth(i)
+		if err != nil {
+			t.Fatalf("Failed to generate a new authority: %v", err)
+		}
+		pubKeys[i] = &auth[i].Pk
+	}
+
+	userGID := "someGID"
+	userVec := data.Vector([]*big.Int{big.NewInt(2), big.NewInt(-1), big.NewInt(0)})
+	userKeys := make([]data.VectorG2, vecLen)
+	for i := range auth {
+		userKeys[i], err = auth[i].DeriveKeyShare(userVec, pubKeys, userGID)
+		if err != nil {
+			t.Fatalf("Failed to generate a user key: %v", err)
+		}
+		err = d.VerifyKeyShare(userKeys[i], pubKeys[i], userVec, userGID)
+		assert.True(t, errors.Is(err, abe.ErrKeyShareUnverifiable))
+	}
+	assert.NoError(t, d.VerifyKeys(userKeys, pubKeys, userVec, userGID))
+
+	// keys of a different user or for a different vector are rejected
+	assert.Error(t, d.VerifyKeys(userKeys, pubKeys, userVec, "otherGID"))
+	otherVec := data.Vector([]*big.Int{big.NewInt(2), big.NewInt(-1), big.NewInt(1)})
+	assert.Error(t, d.VerifyKeys(userKeys, pubKeys, otherVec, userGID))
+
+	// a share derived by an authority for a different user is rejected
+	badKeys := append([]data.VectorG2(nil), userKeys...)
+	badKeys[1], err = auth[1].DeriveKeyShare(userVec, pubKeys, "otherGID")
+	if err != nil {
+		t.Fatalf("Failed to generate a user key: %v", err)
+	}
+	// a single share cannot tell it apart, only the sum of all the shares
+	err = d.VerifyKeyShare(badKeys[1], pubKeys[1], userVec, userGID)
+	assert.True(t, errors.Is(err, abe.ErrKeyShareUnverifiable))
+	assert.Error(t, d.VerifyKeys(badKeys, pubKeys, userVec, userGID))
+
+	// malformed shares are rejected early
+	for _, err := range []error{
+		d.VerifyKeyShare(userKeys[0][:2], pubKeys[0], userVec, userGID),
+		d.VerifyKeyShare(data.VectorG2{userKeys[0][0], nil, userKeys[0][2]}, pubKeys[0], userVec, userGID),
+		d.VerifyKeyShare(userKeys[0], nil, userVec, userGID),
+		d.VerifyKeyShare(userKeys[0], pubKeys[0], nil, userGID),
+	} {
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, abe.ErrKeyShareUnverifiable))
+	}
+	assert.Error(t, d.VerifyKeys(userKeys[:2], pubKeys, userVec, userGID))
+}
+
+func TestDIPPE_PubKeyFromSecKey(t *testing.T) {
+	d, err := abe.NewDIPPE(2)
+	if err != nil {
+		t.Fatalf("Failed to generate a new scheme: %v", err)
+	}
+	auth, err := d.NewDIPPEAuth(0)
+	if err != nil {
+		t.Fatalf("Failed to generate a new authority: %v", err)
+	}
+
+	pk, err := d.PubKeyFromSecKey(&auth.Sk)
+	if err != nil {
+		t.Fatalf("Failed to compute the public key: %v", err)
+	}
+	assert.Equal(t, auth.Pk.G2ToSigma.String(), pk.G2ToSigma.String())
+	assert.Equal(t, len(auth.Pk.GToAlphaA), len(pk.GToAlphaA))
+	for i := range pk.GToAlphaA {
+		assert.Equal(t, auth.Pk.GToAlphaA[i].String(), pk.GToAlphaA[i].String())
+	}
+	assert.Equal(t, len(auth.Pk.G1ToWtA), len(pk.G1ToWtA))
+	for i := range pk.G1ToWtA {
+		for j := range pk.G1ToWtA[i] {
+			assert.Equal(t, auth.Pk.G1ToWtA[i][j].String(), pk.G1ToWtA[i][j].String())
+		}
+	}
+
+	badKey := abe.DIPPESecKey{Sigma: auth.Sk.Sigma, W: auth.Sk.W, Alpha: auth.Sk.Alpha[1:]}
+	_, err = d.PubKeyFromSecKey(&badKey)
+	assert.Error(t, err)
+}
@@ -0,0 +1,222 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package abe_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/fentec-project/gofe/abe"
+	"github.com/fentec-project/gofe/data"
+	"github.com/stretchr/testify/assert"
+)
+
+// binaryMsg returns a message that is not valid UTF-8 and
+// includes zero bytes.
+func binaryMsg() []byte {
+	msg := make([]byte, 300)
+	for i := range msg {
+		msg[i] = byte(255 - i)
+	}
+
+	return msg
+}
+
+// malformedSymEncs returns truncated and empty variants of the
+// symmetric part of a ciphertext.
+func malformedSymEncs(symEnc []byte) [][]byte {
+	return [][]byte{symEnc[:len(symEnc)-1], symEnc[:0], nil}
+}
+
+func TestEncryptBytes(t *testing.T) {
+	msg := binaryMsg()
+
+	t.Run("FAME", func(t *testing.T) {
+		a := abe.NewFAME()
+		pubKey, secKey, err := a.GenerateMasterKeys()
+		if err != nil {
+			t.Fatalf("Failed to generate master keys: %v", err)
+		}
+		msp, err := abe.BooleanToMSP("0 AND 1", false)
+		if err != nil {
+			t.Fatalf("Failed to generate the policy: %v", err)
+		}
+		cipher, err := a.EncryptBytes(msg, msp, pubKey)
+		if err != nil {
+			t.Fatalf("Failed to encrypt: %v", err)
+		}
+		keys, err := a.GenerateAttribKeys([]string{"0", "1"}, secKey)
+		if err != nil {
+			t.Fatalf("Failed to generate keys: %v", err)
+		}
+		dec, err := a.DecryptBytes(cipher, keys, pubKey)
+		if err != nil {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		assert.Equal(t, msg, dec)
+
+		// malformed symmetric parts are rejected without a panic
+		for _, symEnc := range malformedSymEncs(cipher.SymEnc) {
+			malformed := *cipher
+			malformed.SymEnc = symEnc
+			_, err = a.DecryptBytes(&malformed, keys, pubKey)
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("GPSW", func(t *testing.T) {
+		a := abe.NewGPSW(3)
+		pubKey, secKey, err := a.GenerateMasterKeys()
+		if err != nil {
+			t.Fatalf("Failed to generate master keys: %v", err)
+		}
+		msp, err := abe.BooleanToMSP("0 AND 1", true)
+		if err != nil {
+			t.Fatalf("Failed to generate the policy: %v", err)
+		}
+		cipher, err := a.EncryptBytes(msg, []int{0, 1}, pubKey)
+		if err != nil {
+			t.Fatalf("Failed to encrypt: %v", err)
+		}
+		key, err := a.GeneratePolicyKey(msp, secKey)
+		if err != nil {
+			t.Fatalf("Failed to generate keys: %v", err)
+		}
+		dec, err := a.DecryptBytes(cipher, key)
+		if err != nil {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		assert.Equal(t, msg, dec)
+
+		// malformed symmetric parts are rejected without a panic
+		for _, symEnc := range malformedSymEncs(cipher.SymEnc) {
+			malformed := *cipher
+			malformed.SymEnc = symEnc
+			_, err = a.DecryptBytes(&malformed, key)
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("GPSWLU", func(t *testing.T) {
+		a := abe.NewGPSWLU()
+		pubKey, secKey, err := a.GenerateMasterKeys()
+		if err != nil {
+			t.Fatalf("Failed to generate master keys: %v", err)
+		}
+		msp, err := abe.BooleanToMSP("a AND b", true)
+		if err != nil {
+			t.Fatalf("Failed to generate the policy: %v", err)
+		}
+		cipher, err := a.EncryptBytes(msg, []string{"a", "b"}, pubKey)
+		if err != nil {
+			t.Fatalf("Failed to encrypt: %v", err)
+		}
+		key, err := a.GeneratePolicyKey(msp, secKey)
+		if err != nil {
+			t.Fatalf("Failed to generate keys: %v", err)
+		}
+		dec, err := a.DecryptBytes(cipher, key)
+		if err != nil {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		assert.Equal(t, msg, dec)
+
+		// malformed symmetric parts are rejected without a panic
+		for _, symEnc := range malformedSymEncs(cipher.SymEnc) {
+			malformed := *cipher
+			malformed.SymEnc = symEnc
+			_, err = a.DecryptBytes(&malformed, key)
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("MAABE", func(t *testing.T) {
+		maabe := abe.NewMAABE()
+		attribs := []string{"auth1:at1", "auth1:at2"}
+		auth, err := maabe.NewMAABEAuth("auth1", attribs)
+		if err != nil {
+			t.Fatalf("Failed to generate the authority: %v", err)
+		}
+		msp, err := abe.BooleanToMSP("auth1:at1 AND auth1:at2", false)
+		if err != nil {
+			t.Fatalf("Failed to generate the policy: %v", err)
+		}
+		cipher, err := maabe.EncryptBytes(msg, msp, []*abe.MAABEPubKey{auth.PubKeys()})
+		if err != nil {
+			t.Fatalf("Failed to encrypt: %v", err)
+		}
+		keys, err := auth.GenerateAttribKeys("gid", attribs)
+		if err != nil {
+			t.Fatalf("Failed to generate keys: %v", err)
+		}
+		dec, err := maabe.DecryptBytes(cipher, keys)
+		if err != nil {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		assert.Equal(t, msg, dec)
+
+		// malformed symmetric parts are rejected without a panic
+		for _, symEnc := range malformedSymEncs(cipher.SymEnc) {
+			malformed := *cipher
+			malformed.SymEnc = symEnc
+			_, err = maabe.DecryptBytes(&malformed, keys)
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("DIPPE", func(t *testing.T) {
+		d, err := abe.NewDIPPE(2)
+		if err != nil {
+			t.Fatalf("Failed to generate a new scheme: %v", err)
+		}
+		auth := make([]*abe.DIPPEAuth, 2)
+		pubKeys := make([]*abe.DIPPEPubKey, 2)
+		for i := range auth {
+			auth[i], err = d.NewDIPPEAuth(i)
+			if err != nil {
+				t.Fatalf("Failed to generate a new authority: %v", err)
+			}
+			pubKeys[i] = &auth[i].Pk
+		}
+		policyVec := data.Vector([]*big.Int{big.NewInt(1), big.NewInt(-1)})
+		cipher, err := d.EncryptBytes(msg, policyVec, pubKeys)
+		if err != nil {
+			t.Fatalf("Failed to encrypt: %v", err)
+		}
+		userVec := data.Vector([]*big.Int{big.NewInt(1), big.NewInt(1)})
+		keys := make([]data.VectorG2, 2)
+		for i := range auth {
+			keys[i], err = auth[i].DeriveKeyShare(userVec, pubKeys, "gid")
+			if err != nil {
+				t.Fatalf("Failed to generate a user key: %v", err)
+			}
+		}
+		dec, err := d.DecryptBytes(cipher, keys, userVec, "gid")
+		if err != nil {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		assert.Equal(t, msg, dec)
+
+		// malformed symmetric parts are rejected without a panic
+		for _, symEnc := range malformedSymEncs(cipher.SymEnc) {
+			malformed := *cipher
+			malformed.SymEnc = symEnc
+			_, err = d.DecryptBytes(&malformed, keys, userVec, "gid")
+			assert.Error(t, err)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package abe
+
+import (
+	"fmt"
+)
+
+// ErrPolicyNotSatisfied is returned when the keys provided for the
+// decryption do not satisfy the policy of the ciphertext.
+var ErrPolicyNotSatisfied = fmt.Errorf("provided key is not sufficient for decryption")
+
+// ErrKeyShareUnverifiable is returned by DIPPE.VerifyKeyShare for a
+// well-formed key share. A single share is masked with values known
+// only to pairs of authorities, hence its consistency with the public
+// key of the authority cannot be checked on its own.
+var ErrKeyShareUnverifiable = fmt.Errorf("key share cannot be verified on its own")
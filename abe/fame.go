@@ -22,15 +22,14 @@ import (
 	"fmt"
 	"strconv"
 
-	"crypto/aes"
-	cbc "crypto/cipher"
 	"crypto/rand"
-	"crypto/sha256"
 
-	"io"
+	"runtime"
+	"sync"
 
 	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/internal"
 	"github.com/fentec-project/gofe/sample"
 )
 
@@ -49,7 +48,9 @@ import (
 
 // FAME represents a FAME scheme.
 type FAME struct {
-	P *big.Int // order of the elliptic curve
+	P          *big.Int // order of the elliptic curve
+	SymMode    SymMode  // symmetric encryption of the messages, SymCBC by default
+	SymKeySize int      // size of the AES key in bytes (16, 24 or 32), DefaultSymKeySize by default
 }
 
 // NewFAME configures a new instance of the scheme.
@@ -73,7 +74,7 @@ type FAMEPubKey struct {
 // for encrypting data, and master secret keys needed for generating
 // keys for decrypting.
 func (a *FAME) GenerateMasterKeys() (*FAMEPubKey, *FAMESecKey, error) {
-	sampler := sample.NewUniformRange(big.NewInt(1), a.P)
+	sampler := sample.NewUniformInvertible(a.P)
 	val, err := data.NewRandomVector(7, sampler)
 	if err != nil {
 		return nil, nil, err
@@ -100,8 +101,24 @@ type FAMECipher struct {
 	Ct      [][3]*bn256.G1
 	CtPrime *bn256.GT
 	Msp     *MSP
-	SymEnc  []byte // symmetric encryption of the message
-	Iv      []byte // initialization vector for symmetric encryption
+	SymEnc  []byte  // symmetric encryption of the message
+	Iv      []byte  // initialization vector for symmetric encryption in the CBC mode
+	Nonce   []byte  // nonce for symmetric encryption in the GCM mode
+	Tag     []byte  // authentication tag of symmetric encryption in the GCM mode
+	Mode    SymMode // mode of symmetric encryption
+	KeySize int     // size of the AES key in bytes
+}
+
+// sym returns the symmetric part of the cipher.
+func (c *FAMECipher) sym() *SymEnvelope {
+	return &SymEnvelope{Mode: c.Mode, KeySize: c.KeySize, SymEnc: c.SymEnc, Iv: c.Iv, Nonce: c.Nonce, Tag: c.Tag}
+}
+
+// Attributes returns the distinct attributes referenced by the policy
+// of the cipher, so that a decryptor can tell which attribute keys
+// might be needed for decryption.
+func (c *FAMECipher) Attributes() []string {
+	return c.Msp.Attributes()
 }
 
 // Encrypt takes as an input a message msg represented as an element of an elliptic
@@ -110,58 +127,292 @@ type FAMECipher struct {
 // is returned. Note that safety of the encryption is only proved if the mapping
 // msp.RowToAttrib from the rows of msp.Mat to attributes is injective.
 func (a *FAME) Encrypt(msg string, msp *MSP, pk *FAMEPubKey) (*FAMECipher, error) {
+	return a.EncryptBytes([]byte(msg), msp, pk)
+}
+
+// EncryptBytes works as Encrypt, but accepts the message as a byte
+// slice, hence arbitrary binary data can be encrypted without
+// converting it to a string.
+func (a *FAME) EncryptBytes(msg []byte, msp *MSP, pk *FAMEPubKey) (*FAMECipher, error) {
+	if err := checkFAMEPolicy(msp); err != nil {
+		return nil, err
+	}
+
+	// msg is encrypted using AES, with a random key that is encapsulated
+	// with FAME
+	_, keyGt, err := bn256.RandomGT(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	env := &SymEnvelope{Mode: a.SymMode, KeySize: a.SymKeySize}
+	if err := env.Seal(keyGt, msg); err != nil {
+		return nil, err
+	}
+
+	// encapsulate the key with FAME
+	ct0, ct, ctPrime, err := a.encapsulate(keyGt, msp, pk)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FAMECipher{Ct0: ct0, Ct: ct, CtPrime: ctPrime, Msp: msp,
+		SymEnc: env.SymEnc, Iv: env.Iv, Nonce: env.Nonce, Tag: env.Tag, Mode: a.SymMode, KeySize: env.KeySize}, nil
+}
+
+// EncryptGT encrypts an element msg of GT directly with FAME, without
+// the symmetric encryption used by Encrypt. This is useful when the
+// element of GT is itself a key of another scheme. The symmetric part
+// of the returned cipher is empty; it should be decrypted with
+// DecryptGT.
+func (a *FAME) EncryptGT(msg *bn256.GT, msp *MSP, pk *FAMEPubKey) (*FAMECipher, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("the message should not be nil")
+	}
+	if err := checkFAMEPolicy(msp); err != nil {
+		return nil, err
+	}
+
+	ct0, ct, ctPrime, err := a.encapsulate(msg, msp, pk)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FAMECipher{Ct0: ct0, Ct: ct, CtPrime: ctPrime, Msp: msp}, nil
+}
+
+// checkFAMEPolicy checks that msp can be used as a policy of
+// an encryption, i.e. it is nonempty and its rows correspond to
+// different attributes.
+func checkFAMEPolicy(msp *MSP) error {
 	if len(msp.Mat) == 0 || len(msp.Mat[0]) == 0 {
-		return nil, fmt.Errorf("empty msp matrix")
+		return fmt.Errorf("empty msp matrix")
 	}
 
 	attrib := make(map[string]bool)
 	for _, i := range msp.RowToAttrib {
 		if attrib[i] {
-			return nil, fmt.Errorf("some attributes correspond to" +
+			return fmt.Errorf("some attributes correspond to" +
 				"multiple rows of the MSP struct, the scheme is not secure")
 		}
 		attrib[i] = true
 	}
 
-	// msg is encrypted using CBC, with a random key that is encapsulated
-	// with FAME
+	return nil
+}
+
+// BatchEncrypt works as Encrypt, but it encrypts several messages under
+// the same policy at the cost of a single FAME encapsulation. A single
+// random symmetric key is encapsulated under msp, and each message is
+// encrypted with it using a fresh initialization vector (or nonce in
+// the GCM mode), reusing the AES block cipher. The returned ciphers
+// share Ct0, Ct and CtPrime, hence they should not be modified.
+//
+// This is secure since the messages share the policy: a key satisfying
+// it can decrypt all of them anyway, and the symmetric encryption is
+// secure for many messages under one key as long as the initialization
+// vectors or nonces are fresh. However, the ciphers of a batch are
+// linkable, i.e. anyone can see that they were encrypted together, and
+// whoever learns the symmetric key of one message can decrypt all of
+// them. Nothing can be reused across batches or different policies,
+// and in the GCM mode a batch should stay far below 2^32 messages, the
+// limit for random nonces under a single key. In case of a failed
+// procedure an error is returned.
+func (a *FAME) BatchEncrypt(msgs []string, msp *MSP, pk *FAMEPubKey) ([]*FAMECipher, error) {
+	if len(msgs) == 0 {
+		return nil, fmt.Errorf("at least one message should be given")
+	}
+	if err := checkFAMEPolicy(msp); err != nil {
+		return nil, err
+	}
+
 	_, keyGt, err := bn256.RandomGT(rand.Reader)
 	if err != nil {
 		return nil, err
 	}
-	keyCBC := sha256.Sum256([]byte(keyGt.String()))
+	keySize := a.SymKeySize
+	if keySize == 0 {
+		keySize = DefaultSymKeySize
+	}
+	c, err := newSymBlock(keyGt, keySize)
+	if err != nil {
+		return nil, err
+	}
 
-	c, err := aes.NewCipher(keyCBC[:])
+	ct0, ct, ctPrime, err := a.encapsulate(keyGt, msp, pk)
 	if err != nil {
 		return nil, err
 	}
 
-	iv := make([]byte, c.BlockSize())
-	_, err = io.ReadFull(rand.Reader, iv)
+	ciphers := make([]*FAMECipher, len(msgs))
+	for i, msg := range msgs {
+		env := &SymEnvelope{Mode: a.SymMode, KeySize: keySize}
+		if err := env.sealWithBlock(c, []byte(msg)); err != nil {
+			return nil, err
+		}
+		ciphers[i] = &FAMECipher{Ct0: ct0, Ct: ct, CtPrime: ctPrime, Msp: msp,
+			SymEnc: env.SymEnc, Iv: env.Iv, Nonce: env.Nonce, Tag: env.Tag, Mode: a.SymMode, KeySize: keySize}
+	}
+
+	return ciphers, nil
+}
+
+// FAMEMultiCipher represents a ciphertext of a FAME scheme encrypted
+// under several alternative policies. The message is encrypted only
+// once with a symmetric key, while the i-th elements of Ct0, Ct and
+// CtPrime form an independent FAME encapsulation of the key under
+// the policy Msp[i].
+type FAMEMultiCipher struct {
+	Ct0     [][3]*bn256.G2
+	Ct      [][][3]*bn256.G1
+	CtPrime []*bn256.GT
+	Msp     []*MSP
+	SymEnc  []byte  // symmetric encryption of the message
+	Iv      []byte  // initialization vector for symmetric encryption in the CBC mode
+	Nonce   []byte  // nonce for symmetric encryption in the GCM mode
+	Tag     []byte  // authentication tag of symmetric encryption in the GCM mode
+	Mode    SymMode // mode of symmetric encryption
+	KeySize int     // size of the AES key in bytes
+}
+
+// EncryptMulti works as Encrypt, but it encrypts the message under
+// several policies, so that it can be decrypted by keys satisfying any
+// of them. The message is encrypted only once and the symmetric key
+// is encapsulated under each of the policies. In case of a failed
+// procedure an error is returned.
+func (a *FAME) EncryptMulti(msg string, policies []*MSP, pk *FAMEPubKey) (*FAMEMultiCipher, error) {
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("at least one policy should be given")
+	}
+	for i, msp := range policies {
+		if err := checkFAMEPolicy(msp); err != nil {
+			return nil, fmt.Errorf("policy %d: %w", i, err)
+		}
+	}
+
+	_, keyGt, err := bn256.RandomGT(rand.Reader)
 	if err != nil {
 		return nil, err
 	}
-	encrypterCBC := cbc.NewCBCEncrypter(c, iv)
+	env := &SymEnvelope{Mode: a.SymMode, KeySize: a.SymKeySize}
+	if err := env.Seal(keyGt, []byte(msg)); err != nil {
+		return nil, err
+	}
+
+	cipher := &FAMEMultiCipher{
+		Ct0:     make([][3]*bn256.G2, len(policies)),
+		Ct:      make([][][3]*bn256.G1, len(policies)),
+		CtPrime: make([]*bn256.GT, len(policies)),
+		Msp:     policies,
+		SymEnc:  env.SymEnc,
+		Iv:      env.Iv,
+		Nonce:   env.Nonce,
+		Tag:     env.Tag,
+		Mode:    a.SymMode,
+		KeySize: env.KeySize,
+	}
+	for i, msp := range policies {
+		cipher.Ct0[i], cipher.Ct[i], cipher.CtPrime[i], err = a.encapsulate(keyGt, msp, pk)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	msgByte := []byte(msg)
+	return cipher, nil
+}
 
-	// message is padded according to pkcs7 standard
-	padLen := c.BlockSize() - (len(msgByte) % c.BlockSize())
-	msgPad := make([]byte, len(msgByte)+padLen)
-	copy(msgPad, msgByte)
-	for i := len(msgByte); i < len(msgPad); i++ {
-		msgPad[i] = byte(padLen)
+// Cipher returns the cipher of the message under the i-th policy,
+// which can be decrypted with Decrypt. The returned cipher shares the
+// symmetric encryption with the multi cipher. An error is returned
+// if there is no i-th policy.
+func (c *FAMEMultiCipher) Cipher(i int) (*FAMECipher, error) {
+	if i < 0 || i >= len(c.Msp) || i >= len(c.Ct0) || i >= len(c.Ct) || i >= len(c.CtPrime) {
+		return nil, fmt.Errorf("the cipher has no policy %d", i)
 	}
 
-	symEnc := make([]byte, len(msgPad))
-	encrypterCBC.CryptBlocks(symEnc, msgPad)
+	return &FAMECipher{Ct0: c.Ct0[i], Ct: c.Ct[i], CtPrime: c.CtPrime[i], Msp: c.Msp[i],
+		SymEnc: c.SymEnc, Iv: c.Iv, Nonce: c.Nonce, Tag: c.Tag, Mode: c.Mode, KeySize: c.KeySize}, nil
+}
 
-	// encapsulate the key with FAME
-	sampler := sample.NewUniform(a.P)
-	s, err := data.NewRandomVector(2, sampler)
+// DecryptMulti takes as an input a cipher encrypted under several
+// policies and an FAMEAttribKeys and tries to decrypt the cipher. This
+// is possible if the set of possessed attributes satisfies any of the
+// policies of the cipher. If this is not possible, an error is returned.
+func (a *FAME) DecryptMulti(cipher *FAMEMultiCipher, key *FAMEAttribKeys, pk *FAMEPubKey) (string, error) {
+	for i := range cipher.Msp {
+		c, err := cipher.Cipher(i)
+		if err != nil {
+			return "", err
+		}
+		if len(c.Ct) != len(c.Msp.Mat) {
+			return "", fmt.Errorf("the provided cipher is faulty")
+		}
+		keyGt, err := a.decryptKeyGT(c, key)
+		if err != nil {
+			continue
+		}
+		msgByte, err := c.sym().Open(keyGt)
+		if err != nil {
+			return "", err
+		}
+
+		return string(msgByte), nil
+	}
+
+	return "", ErrPolicyNotSatisfied
+}
+
+// ReRandomize takes as an input a cipher and the public key pk used
+// for its encryption, and returns a new cipher of the same message under
+// the same policy. The part of the cipher encapsulating the symmetric key
+// is multiplied by a fresh encapsulation of the neutral element, so the
+// new cipher decrypts with the same keys as the original one. Only the
+// encapsulation is re-randomized: the symmetric encryption of the message
+// (SymEnc, Iv, Nonce and Tag) is copied unchanged, hence anyone can link
+// the new cipher to the original one by comparing these fields. In case
+// of a failed procedure an error is returned.
+func (a *FAME) ReRandomize(cipher *FAMECipher, pk *FAMEPubKey) (*FAMECipher, error) {
+	if len(cipher.Ct) != len(cipher.Msp.Mat) {
+		return nil, fmt.Errorf("the provided cipher is faulty")
+	}
+
+	zero := new(bn256.GT).ScalarBaseMult(big.NewInt(0))
+	ct0Rand, ctRand, ctPrimeRand, err := a.encapsulate(zero, cipher.Msp, pk)
 	if err != nil {
 		return nil, err
 	}
+
+	var ct0 [3]*bn256.G2
+	for l := 0; l < 3; l++ {
+		ct0[l] = new(bn256.G2).Add(cipher.Ct0[l], ct0Rand[l])
+	}
+	ct := make([][3]*bn256.G1, len(cipher.Ct))
+	for i := range cipher.Ct {
+		for l := 0; l < 3; l++ {
+			ct[i][l] = new(bn256.G1).Add(cipher.Ct[i][l], ctRand[i][l])
+		}
+	}
+	ctPrime := new(bn256.GT).Add(cipher.CtPrime, ctPrimeRand)
+
+	return &FAMECipher{Ct0: ct0,
+		Ct:      ct,
+		CtPrime: ctPrime,
+		Msp:     cipher.Msp,
+		SymEnc:  append([]byte(nil), cipher.SymEnc...),
+		Iv:      append([]byte(nil), cipher.Iv...),
+		Nonce:   append([]byte(nil), cipher.Nonce...),
+		Tag:     append([]byte(nil), cipher.Tag...),
+		Mode:    cipher.Mode,
+		KeySize: cipher.KeySize}, nil
+}
+
+// encapsulate encapsulates keyGt with FAME under the policy given
+// by msp, using fresh randomness.
+func (a *FAME) encapsulate(keyGt *bn256.GT, msp *MSP, pk *FAMEPubKey) ([3]*bn256.G2, [][3]*bn256.G1, *bn256.GT, error) {
+	sampler := sample.NewUniformNonZero(a.P)
+	s, err := data.NewRandomVector(2, sampler)
+	if err != nil {
+		return [3]*bn256.G2{}, nil, nil, err
+	}
 	ct0 := [3]*bn256.G2{new(bn256.G2).ScalarMult(pk.PartG2[0], s[0]),
 		new(bn256.G2).ScalarMult(pk.PartG2[1], s[1]),
 		new(bn256.G2).ScalarBaseMult(new(big.Int).Add(s[0], s[1]))}
@@ -171,13 +422,13 @@ func (a *FAME) Encrypt(msg string, msp *MSP, pk *FAMEPubKey) (*FAMECipher, error
 		for l := 0; l < 3; l++ {
 			hs1, err := bn256.HashG1(msp.RowToAttrib[i] + " " + strconv.Itoa(l) + " 0")
 			if err != nil {
-				return nil, err
+				return [3]*bn256.G2{}, nil, nil, err
 			}
 			hs1.ScalarMult(hs1, s[0])
 
 			hs2, err := bn256.HashG1(msp.RowToAttrib[i] + " " + strconv.Itoa(l) + " 1")
 			if err != nil {
-				return nil, err
+				return [3]*bn256.G2{}, nil, nil, err
 			}
 			hs2.ScalarMult(hs2, s[1])
 
@@ -185,25 +436,18 @@ func (a *FAME) Encrypt(msg string, msp *MSP, pk *FAMEPubKey) (*FAMECipher, error
 			for j := 0; j < len(msp.Mat[0]); j++ {
 				hs1, err = bn256.HashG1("0 " + strconv.Itoa(j) + " " + strconv.Itoa(l) + " 0")
 				if err != nil {
-					return nil, err
+					return [3]*bn256.G2{}, nil, nil, err
 				}
 				hs1.ScalarMult(hs1, s[0])
 
 				hs2, err = bn256.HashG1("0 " + strconv.Itoa(j) + " " + strconv.Itoa(l) + " 1")
 				if err != nil {
-					return nil, err
+					return [3]*bn256.G2{}, nil, nil, err
 				}
 				hs2.ScalarMult(hs2, s[1])
 
 				hsToM := new(bn256.G1).Add(hs1, hs2)
-				pow := new(big.Int).Set(msp.Mat[i][j])
-				if pow.Sign() == -1 {
-					pow.Neg(pow)
-					hsToM.ScalarMult(hsToM, pow)
-					hsToM.Neg(hsToM)
-				} else {
-					hsToM.ScalarMult(hsToM, pow)
-				}
+				hsToM.ScalarMult(hsToM, internal.ModPos(msp.Mat[i][j], a.P))
 				ct[i][l].Add(ct[i][l], hsToM)
 			}
 		}
@@ -213,7 +457,7 @@ func (a *FAME) Encrypt(msg string, msp *MSP, pk *FAMEPubKey) (*FAMECipher, error
 	ctPrime.Add(ctPrime, new(bn256.GT).ScalarMult(pk.PartGT[1], s[1]))
 	ctPrime.Add(ctPrime, keyGt)
 
-	return &FAMECipher{Ct0: ct0, Ct: ct, CtPrime: ctPrime, Msp: msp, SymEnc: symEnc, Iv: iv}, nil
+	return ct0, ct, ctPrime, nil
 }
 
 // FAMEAttribKeys represents keys corresponding to attributes possessed by
@@ -225,6 +469,36 @@ type FAMEAttribKeys struct {
 	AttribToI map[string]int
 }
 
+// Fingerprint returns a stable sha256 based fingerprint of the keys,
+// computed from the marshaled group elements and the attributes they
+// are connected to. Since the keys are randomized at generation, keys
+// issued to different entities for the same attributes have different
+// fingerprints, so it can be used for auditing the issuance of keys.
+// The fingerprint is a one-way function of the keys and can be logged
+// without revealing them.
+func (key *FAMEAttribKeys) Fingerprint() string {
+	iToAttrib := make([]string, len(key.K))
+	for at, i := range key.AttribToI {
+		iToAttrib[i] = at
+	}
+
+	parts := make([][]byte, 0, 3+4*len(key.K)+3)
+	for _, e := range key.K0 {
+		parts = append(parts, e.Marshal())
+	}
+	for i, k := range key.K {
+		parts = append(parts, []byte(iToAttrib[i]))
+		for _, e := range k {
+			parts = append(parts, e.Marshal())
+		}
+	}
+	for _, e := range key.KPrime {
+		parts = append(parts, e.Marshal())
+	}
+
+	return fingerprint(parts...)
+}
+
 // GenerateAttribKeys given a set of attributes gamma and the master secret key
 // generates keys that can be used for the decryption of any ciphertext encoded
 // with a policy for which attributes gamma are sufficient.
@@ -257,37 +531,43 @@ func (a *FAME) GenerateAttribKeys(gamma []string, sk *FAMESecKey) (*FAMEAttribKe
 	k := make([][3]*bn256.G1, len(gamma))
 	attribToI := make(map[string]int)
 	for i, y := range gamma {
-		k[i] = [3]*bn256.G1{new(bn256.G1), new(bn256.G1), new(bn256.G1)}
-		gSigma := new(bn256.G1).ScalarBaseMult(sigma[i])
-		for t := 0; t < 2; t++ {
-			hs0, err := bn256.HashG1(y + " 0 " + strconv.Itoa(t))
-			if err != nil {
-				return nil, err
-			}
-			hs0.ScalarMult(hs0, pow0)
-			hs1, err := bn256.HashG1(y + " 1 " + strconv.Itoa(t))
-			if err != nil {
-				return nil, err
-			}
-			hs1.ScalarMult(hs1, pow1)
-			hs2, err := bn256.HashG1(y + " 2 " + strconv.Itoa(t))
-			if err != nil {
-				return nil, err
-			}
-			hs2.ScalarMult(hs2, pow2)
-
-			k[i][t].Add(hs0, hs1)
-			k[i][t].Add(k[i][t], hs2)
-			k[i][t].Add(k[i][t], gSigma)
-			k[i][t].ScalarMult(k[i][t], aInv[t])
-		}
-
-		k[i][2].ScalarBaseMult(sigma[i])
-		k[i][2].Neg(k[i][2])
-
 		attribToI[y] = i
 	}
 
+	// keys for the attributes are computed independently of each other,
+	// hence the work is split among a pool of workers; all the randomness
+	// was sampled above, so the result does not depend on the scheduling
+	jobs := make(chan int, len(gamma))
+	errChan := make(chan error, len(gamma))
+	var wg sync.WaitGroup
+	workers := runtime.NumCPU()
+	if workers > len(gamma) {
+		workers = len(gamma)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				ki, err := a.attribKey(gamma[i], sigma[i], [3]*big.Int{pow0, pow1, pow2}, aInv)
+				if err != nil {
+					errChan <- err
+					continue
+				}
+				k[i] = ki
+			}
+		}()
+	}
+	for i := range gamma {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	close(errChan)
+	if err, ok := <-errChan; ok {
+		return nil, err
+	}
+
 	sigmaPrime, err := sampler.Sample()
 	if err != nil {
 		return nil, err
@@ -326,97 +606,209 @@ func (a *FAME) GenerateAttribKeys(gamma []string, sk *FAMESecKey) (*FAMEAttribKe
 	return &FAMEAttribKeys{K0: k0, K: k, KPrime: k2, AttribToI: attribToI}, nil
 }
 
+// attribKey computes the part of the FAME attribute keys corresponding
+// to attribute y, given its randomness sigma, the exponents pow used in
+// K0 and the inverses aInv of the first two parts of the master secret
+// key.
+func (a *FAME) attribKey(y string, sigma *big.Int, pow [3]*big.Int, aInv [2]*big.Int) ([3]*bn256.G1, error) {
+	k := [3]*bn256.G1{new(bn256.G1), new(bn256.G1), new(bn256.G1)}
+	gSigma := new(bn256.G1).ScalarBaseMult(sigma)
+	for t := 0; t < 2; t++ {
+		hs0, err := bn256.HashG1(y + " 0 " + strconv.Itoa(t))
+		if err != nil {
+			return k, err
+		}
+		hs0.ScalarMult(hs0, pow[0])
+		hs1, err := bn256.HashG1(y + " 1 " + strconv.Itoa(t))
+		if err != nil {
+			return k, err
+		}
+		hs1.ScalarMult(hs1, pow[1])
+		hs2, err := bn256.HashG1(y + " 2 " + strconv.Itoa(t))
+		if err != nil {
+			return k, err
+		}
+		hs2.ScalarMult(hs2, pow[2])
+
+		k[t].Add(hs0, hs1)
+		k[t].Add(k[t], hs2)
+		k[t].Add(k[t], gSigma)
+		k[t].ScalarMult(k[t], aInv[t])
+	}
+
+	k[2].ScalarBaseMult(sigma)
+	k[2].Neg(k[2])
+
+	return k, nil
+}
+
+// DelegateKeys derives from the keys key the keys restricted to the
+// attributes in subset, so that an entity holding the keys can hand out
+// narrowed keys without access to the master secret key. The delegated
+// keys decrypt any ciphertext whose policy is satisfied by subset.
+// Note that the parts of the keys cannot be re-randomized without
+// the master secret key: fresh r1, r2 terms need h^b1 and h^b2 in K0
+// and the hashes raised to b1/a_t, b2/a_t in K and KPrime, and fresh
+// sigma terms need g^(1/a_t), none of which is derivable from the
+// public key. Hence the delegated keys share their randomness with key;
+// they do not give more than key itself, but they can be linked to it.
+// If independent keys are needed, they should be generated with
+// GenerateAttribKeys. An error is returned if subset includes an
+// attribute that key does not possess.
+func (a *FAME) DelegateKeys(key *FAMEAttribKeys, subset []string) (*FAMEAttribKeys, error) {
+	k := make([][3]*bn256.G1, len(subset))
+	attribToI := make(map[string]int, len(subset))
+	for i, y := range subset {
+		if _, ok := attribToI[y]; ok {
+			return nil, fmt.Errorf("attribute %s appears more than once", y)
+		}
+		j, ok := key.AttribToI[y]
+		if !ok || j < 0 || j >= len(key.K) {
+			return nil, fmt.Errorf("the key does not possess attribute %s", y)
+		}
+		for l := 0; l < 3; l++ {
+			k[i][l] = new(bn256.G1).Set(key.K[j][l])
+		}
+		attribToI[y] = i
+	}
+
+	var k0 [3]*bn256.G2
+	var kPrime [3]*bn256.G1
+	for l := 0; l < 3; l++ {
+		k0[l] = new(bn256.G2).Set(key.K0[l])
+		kPrime[l] = new(bn256.G1).Set(key.KPrime[l])
+	}
+
+	return &FAMEAttribKeys{K0: k0, K: k, KPrime: kPrime, AttribToI: attribToI}, nil
+}
+
 // Decrypt takes as an input a cipher and an FAMEAttribKeys and tries to decrypt
 // the cipher. This is possible only if the set of possessed attributes (and
 // corresponding keys FAMEAttribKeys) suffices the encryption policy of the
-// cipher. If this is not possible, an error is returned.
+// cipher. If this is not possible, an error is returned. The symmetric
+// encryption is decrypted in the mode recorded in the cipher; in the GCM
+// mode an error is returned also if the cipher was modified.
 func (a *FAME) Decrypt(cipher *FAMECipher, key *FAMEAttribKeys, pk *FAMEPubKey) (string, error) {
+	msgByte, err := a.DecryptBytes(cipher, key, pk)
+	if err != nil {
+		return "", err
+	}
+
+	return string(msgByte), nil
+}
+
+// DecryptBytes works as Decrypt, but returns the decrypted message
+// as a byte slice.
+func (a *FAME) DecryptBytes(cipher *FAMECipher, key *FAMEAttribKeys, pk *FAMEPubKey) ([]byte, error) {
+	keyGt, err := a.decryptKeyGT(cipher, key)
+	if err != nil {
+		return nil, err
+	}
+
+	msgByte, err := cipher.sym().Open(keyGt)
+	if err != nil {
+		return nil, err
+	}
+
+	return msgByte, nil
+}
+
+// DecryptExact works as Decrypt, but instead of reading the padding
+// of the decrypted message it returns its first plaintextLen bytes. It
+// is meant for the applications that know the length of the message
+// from an authenticated source. An error is returned if plaintextLen
+// does not match the length of the padded message.
+func (a *FAME) DecryptExact(cipher *FAMECipher, key *FAMEAttribKeys, pk *FAMEPubKey, plaintextLen int) ([]byte, error) {
+	keyGt, err := a.decryptKeyGT(cipher, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.sym().openExact(keyGt, plaintextLen)
+}
+
+// DecryptGT decrypts a cipher produced by EncryptGT and returns the
+// encrypted element of GT. As with Decrypt, this is possible only if
+// the attributes of key satisfy the policy of the cipher, otherwise
+// an error is returned. Note that there is no symmetric encryption to
+// authenticate the result, hence a modified cipher decrypts to a
+// different element of GT rather than failing.
+func (a *FAME) DecryptGT(cipher *FAMECipher, key *FAMEAttribKeys, pk *FAMEPubKey) (*bn256.GT, error) {
+	return a.decryptKeyGT(cipher, key)
+}
+
+// decryptKeyGT computes the element of GT from which the symmetric
+// key of the cipher is derived.
+func (a *FAME) decryptKeyGT(cipher *FAMECipher, key *FAMEAttribKeys) (*bn256.GT, error) {
 	// find out which attributes are owned
 	attribMap := make(map[string]bool)
 	for k := range key.AttribToI {
 		attribMap[k] = true
 	}
 
-	countAttrib := 0
-	for i := 0; i < len(cipher.Msp.Mat); i++ {
-		if attribMap[cipher.Msp.RowToAttrib[i]] {
-			countAttrib++
-		}
-	}
-
 	// create a matrix of needed keys
-	preMatForKey := make([]data.Vector, countAttrib)
-	ctForKey := make([][3]*bn256.G1, countAttrib)
-	rowToAttrib := make([]string, countAttrib)
-	countAttrib = 0
-	for i := 0; i < len(cipher.Msp.Mat); i++ {
-		if attribMap[cipher.Msp.RowToAttrib[i]] {
-			preMatForKey[countAttrib] = cipher.Msp.Mat[i]
-			ctForKey[countAttrib] = cipher.Ct[i]
-			rowToAttrib[countAttrib] = cipher.Msp.RowToAttrib[i]
-			countAttrib++
-		}
+	rows, preMatForKey := cipher.Msp.ownedRows(attribMap)
+	if len(cipher.Ct) < len(cipher.Msp.Mat) {
+		return nil, fmt.Errorf("the provided cipher is faulty")
+	}
+	ctForKey := make([][3]*bn256.G1, len(rows))
+	rowToAttrib := make([]string, len(rows))
+	for j, i := range rows {
+		ctForKey[j] = cipher.Ct[i]
+		rowToAttrib[j] = cipher.Msp.RowToAttrib[i]
 	}
 
 	matForKey, err := data.NewMatrix(preMatForKey)
 	if err != nil {
-		return "", fmt.Errorf("the provided cipher is faulty")
+		return nil, fmt.Errorf("the provided cipher is faulty")
 	}
 
 	// matForKey may have a len of 0 if there is a single condition
 	if len(matForKey) == 0 {
-		return "", fmt.Errorf("provided key is not sufficient for decryption")
+		return nil, ErrPolicyNotSatisfied
 	}
 
 	// get a combination alpha of keys needed to decrypt
-	// matForKey may have a len of 0 if there is a single condition
-	if len(matForKey) == 0 {
-		return "", fmt.Errorf("provided key is not sufficient for decryption")
-	}
 	oneVec := data.NewConstantVector(len(matForKey[0]), big.NewInt(0))
 	oneVec[0].SetInt64(1)
 	alpha, err := data.GaussianEliminationSolver(matForKey.Transpose(), oneVec, a.P)
 	if err != nil {
-		return "", fmt.Errorf("provided key is not sufficient for decryption")
+		return nil, ErrPolicyNotSatisfied
 	}
 
-	// get a CBC key needed for the decryption of msg
+	// get a symmetric key needed for the decryption of msg
 	keyGt := new(bn256.GT).Set(cipher.CtPrime)
 
-	ctProd := new([3]*bn256.G1)
-	keyProd := new([3]*bn256.G1)
+	// the three components are independent of each other, hence their
+	// pairings are computed concurrently; the products over the rows are
+	// computed as multi-scalar multiplications
+	var parts [3]*bn256.GT
+	var wg sync.WaitGroup
 	for j := 0; j < 3; j++ {
-		ctProd[j] = new(bn256.G1).ScalarBaseMult(big.NewInt(0))
-		keyProd[j] = new(bn256.G1).ScalarBaseMult(big.NewInt(0))
+		ctJ := make(data.VectorG1, len(rowToAttrib))
+		keyJ := make(data.VectorG1, len(rowToAttrib))
 		for i, e := range rowToAttrib {
-			ctProd[j].Add(ctProd[j], new(bn256.G1).ScalarMult(ctForKey[i][j], alpha[i]))
-			keyProd[j].Add(keyProd[j], new(bn256.G1).ScalarMult(key.K[key.AttribToI[e]][j], alpha[i]))
+			ctJ[i] = ctForKey[i][j]
+			keyJ[i] = key.K[key.AttribToI[e]][j]
 		}
-		keyProd[j].Add(keyProd[j], key.KPrime[j])
-		ctPairing := bn256.Pair(ctProd[j], key.K0[j])
-		keyPairing := bn256.Pair(keyProd[j], cipher.Ct0[j])
-		keyPairing.Neg(keyPairing)
-		keyGt.Add(keyGt, ctPairing)
-		keyGt.Add(keyGt, keyPairing)
-	}
-
-	keyCBC := sha256.Sum256([]byte(keyGt.String()))
 
-	c, err := aes.NewCipher(keyCBC[:])
-	if err != nil {
-		return "", err
+		wg.Add(1)
+		go func(j int, ctJ, keyJ data.VectorG1) {
+			defer wg.Done()
+			ctProd, _ := data.CommitG1(alpha, ctJ)
+			keyProd, _ := data.CommitG1(alpha, keyJ)
+			keyProd = new(bn256.G1).Add(keyProd, key.KPrime[j])
+			ctPairing := bn256.Pair(ctProd, key.K0[j])
+			keyPairing := bn256.Pair(keyProd, cipher.Ct0[j])
+			keyPairing.Neg(keyPairing)
+			parts[j] = new(bn256.GT).Add(ctPairing, keyPairing)
+		}(j, ctJ, keyJ)
 	}
+	wg.Wait()
 
-	msgPad := make([]byte, len(cipher.SymEnc))
-	decrypter := cbc.NewCBCDecrypter(c, cipher.Iv)
-	decrypter.CryptBlocks(msgPad, cipher.SymEnc)
-
-	// unpad the message
-	padLen := int(msgPad[len(msgPad)-1])
-	if (len(msgPad) - padLen) < 0 {
-		return "", fmt.Errorf("failed to decrypt")
+	for j := 0; j < 3; j++ {
+		keyGt.Add(keyGt, parts[j])
 	}
-	msgByte := msgPad[0:(len(msgPad) - padLen)]
 
-	return string(msgByte), nil
+	return keyGt, nil
 }
@@ -17,8 +17,13 @@
 package abe_test
 
 import (
+	"crypto/rand"
+	"errors"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/abe"
 	"github.com/stretchr/testify/assert"
 )
@@ -59,6 +64,7 @@ func TestFAME(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to encrypt: %v", err)
 	}
+	assert.Equal(t, []string{"0", "1", "2", "3", "5"}, cipher.Attributes())
 
 	// define a set of attributes (a subset of the universe of attributes)
 	// that an entity possesses
@@ -188,3 +194,495 @@ func TestFAME(t *testing.T) {
 	_, err = a.Decrypt(cipherMultiUUID, keysInsuffUUID, pubKey)
 	assert.Error(t, err)
 }
+
+func TestFAME_ManyAttribKeys(t *testing.T) {
+	a := abe.NewFAME()
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+
+	// generate keys for an entity with many attributes, so that
+	// the work is spread among several workers
+	gamma := make([]string, 64)
+	for i := range gamma {
+		gamma[i] = "attrib" + strconv.Itoa(i)
+	}
+	keys, err := a.GenerateAttribKeys(gamma, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	assert.Equal(t, len(gamma), len(keys.K))
+	for i, y := range gamma {
+		assert.Equal(t, i, keys.AttribToI[y])
+	}
+
+	// keys of attributes from different parts of gamma must be
+	// usable together
+	msg := "Attack at dawn!"
+	msp, err := abe.BooleanToMSP("attrib0 AND (attrib31 OR attrib100) AND attrib63", false)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	cipher, err := a.Encrypt(msg, msp, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	msgCheck, err := a.Decrypt(cipher, keys, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, msgCheck)
+
+	// a policy requiring many attributes, so that the products over
+	// the rows in the decryption are large
+	msp, err = abe.BooleanToMSP(strings.Join(gamma[:20], " AND "), false)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	cipher, err = a.Encrypt(msg, msp, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	msgCheck, err = a.Decrypt(cipher, keys, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, msgCheck)
+}
+
+func TestFAMEAttribKeys_Fingerprint(t *testing.T) {
+	a := abe.NewFAME()
+	_, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+
+	keys1, err := a.GenerateAttribKeys([]string{"0", "1"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	keys2, err := a.GenerateAttribKeys([]string{"0", "2"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+
+	// the same key should always give the same fingerprint, while
+	// different keys should give different fingerprints
+	assert.Equal(t, keys1.Fingerprint(), keys1.Fingerprint())
+	assert.NotEqual(t, keys1.Fingerprint(), keys2.Fingerprint())
+
+	// keys issued independently for the same attributes are told apart
+	keys1Again, err := a.GenerateAttribKeys([]string{"1", "0"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	assert.NotEqual(t, keys1.Fingerprint(), keys1Again.Fingerprint())
+}
+
+func TestFAME_DecryptExact(t *testing.T) {
+	a := abe.NewFAME()
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	msp, err := abe.BooleanToMSP("0 AND (1 OR 2)", false)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	keys, err := a.GenerateAttribKeys([]string{"0", "2"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+
+	// the length of the payload is known to the caller, so
+	// the padding is not read
+	for _, payload := range [][]byte{[]byte("Attack!"), make([]byte, 32), {}} {
+		cipher, err := a.Encrypt(string(payload), msp, pubKey)
+		if err != nil {
+			t.Fatalf("Failed to encrypt: %v", err)
+		}
+		payloadCheck, err := a.DecryptExact(cipher, keys, pubKey, len(payload))
+		if err != nil {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		assert.Equal(t, payload, payloadCheck)
+
+		// lengths that do not match the padded ciphertext are rejected
+		_, err = a.DecryptExact(cipher, keys, pubKey, len(cipher.SymEnc))
+		assert.Error(t, err)
+		_, err = a.DecryptExact(cipher, keys, pubKey, -1)
+		assert.Error(t, err)
+	}
+}
+
+func TestFAME_ReRandomize(t *testing.T) {
+	a := abe.NewFAME()
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	msp, err := abe.BooleanToMSP("0 AND (1 OR 2)", false)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	msg := "Attack at dawn!"
+	cipher, err := a.Encrypt(msg, msp, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	cipher1, err := a.ReRandomize(cipher, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to re-randomize: %v", err)
+	}
+	cipher2, err := a.ReRandomize(cipher, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to re-randomize: %v", err)
+	}
+
+	// the encapsulation parts of the ciphers should differ
+	assert.NotEqual(t, cipher.CtPrime.String(), cipher1.CtPrime.String())
+	assert.NotEqual(t, cipher1.CtPrime.String(), cipher2.CtPrime.String())
+	assert.NotEqual(t, cipher1.Ct0[0].String(), cipher2.Ct0[0].String())
+	assert.NotEqual(t, cipher1.Ct[0][0].String(), cipher2.Ct[0][0].String())
+	// while the symmetric part is shared and links the ciphers
+	assert.Equal(t, cipher.SymEnc, cipher1.SymEnc)
+	assert.Equal(t, cipher.Iv, cipher1.Iv)
+
+	// while the re-randomized ciphers decrypt with the same keys
+	keys, err := a.GenerateAttribKeys([]string{"0", "2"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	for _, c := range []*abe.FAMECipher{cipher1, cipher2} {
+		msgCheck, err := a.Decrypt(c, keys, pubKey)
+		if err != nil {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		assert.Equal(t, msg, msgCheck)
+	}
+
+	// and still cannot be decrypted with insufficient keys
+	keysInsuff, err := a.GenerateAttribKeys([]string{"1", "2"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	_, err = a.Decrypt(cipher1, keysInsuff, pubKey)
+	assert.Error(t, err)
+}
+
+func TestFAME_GCM(t *testing.T) {
+	a := abe.NewFAME()
+	a.SymMode = abe.SymGCM
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	msp, err := abe.BooleanToMSP("0 AND (1 OR 2)", false)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	keys, err := a.GenerateAttribKeys([]string{"0", "2"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+
+	msg := "Attack at dawn!"
+	cipher, err := a.Encrypt(msg, msp, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	assert.Equal(t, abe.SymGCM, cipher.Mode)
+	assert.Empty(t, cipher.Iv)
+	assert.Equal(t, len(msg), len(cipher.SymEnc))
+
+	msgCheck, err := a.Decrypt(cipher, keys, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, msgCheck)
+	payloadCheck, err := a.DecryptExact(cipher, keys, pubKey, len(msg))
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, []byte(msg), payloadCheck)
+
+	// any modification of the ciphertext is detected
+	cipher.SymEnc[0] ^= 1
+	_, err = a.Decrypt(cipher, keys, pubKey)
+	assert.Error(t, err)
+	cipher.SymEnc[0] ^= 1
+	cipher.Tag[0] ^= 1
+	_, err = a.Decrypt(cipher, keys, pubKey)
+	assert.Error(t, err)
+}
+
+func TestFAME_SymKeySize(t *testing.T) {
+	a := abe.NewFAME()
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	msp, err := abe.BooleanToMSP("0 AND 1", false)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	keys, err := a.GenerateAttribKeys([]string{"0", "1"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+
+	msg := "Attack at dawn!"
+	for _, mode := range []abe.SymMode{abe.SymCBC, abe.SymGCM} {
+		for _, size := range []int{16, 24, 32} {
+			a.SymMode = mode
+			a.SymKeySize = size
+			cipher, err := a.Encrypt(msg, msp, pubKey)
+			if err != nil {
+				t.Fatalf("Failed to encrypt: %v", err)
+			}
+			assert.Equal(t, size, cipher.KeySize)
+
+			// the decryption uses the key size recorded in the cipher
+			a.SymKeySize = 0
+			msgCheck, err := a.Decrypt(cipher, keys, pubKey)
+			if err != nil {
+				t.Fatalf("Failed to decrypt: %v", err)
+			}
+			assert.Equal(t, msg, msgCheck)
+		}
+	}
+
+	// ciphers without a recorded key size use the default size
+	a.SymMode = abe.SymGCM
+	cipher, err := a.Encrypt(msg, msp, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	assert.Equal(t, abe.DefaultSymKeySize, cipher.KeySize)
+	cipher.KeySize = 0
+	msgCheck, err := a.Decrypt(cipher, keys, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, msgCheck)
+
+	// a wrong key size is detected in the GCM mode
+	cipher.KeySize = 16
+	_, err = a.Decrypt(cipher, keys, pubKey)
+	assert.Error(t, err)
+
+	cipher.KeySize = 20
+	_, err = a.Decrypt(cipher, keys, pubKey)
+	assert.Error(t, err)
+	a.SymKeySize = 20
+	_, err = a.Encrypt(msg, msp, pubKey)
+	assert.Error(t, err)
+}
+
+func TestFAME_EncryptMulti(t *testing.T) {
+	a := abe.NewFAME()
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	msp1, err := abe.BooleanToMSP("0 AND 1", false)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	msp2, err := abe.BooleanToMSP("2 AND (3 OR 4)", false)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+
+	msg := "Attack at dawn!"
+	cipher, err := a.EncryptMulti(msg, []*abe.MSP{msp1, msp2}, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	// keys satisfying any of the policies decrypt the message
+	for _, gamma := range [][]string{{"0", "1"}, {"2", "4"}, {"0", "2", "3"}} {
+		keys, err := a.GenerateAttribKeys(gamma, secKey)
+		if err != nil {
+			t.Fatalf("Failed to generate keys: %v", err)
+		}
+		msgCheck, err := a.DecryptMulti(cipher, keys, pubKey)
+		if err != nil {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		assert.Equal(t, msg, msgCheck)
+	}
+
+	// the cipher under a single policy decrypts with Decrypt
+	keys, err := a.GenerateAttribKeys([]string{"2", "3"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	cipher2, err := cipher.Cipher(1)
+	if err != nil {
+		t.Fatalf("Failed to get the cipher: %v", err)
+	}
+	msgCheck, err := a.Decrypt(cipher2, keys, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, msgCheck)
+	_, err = cipher.Cipher(2)
+	assert.Error(t, err)
+
+	// keys satisfying none of the policies do not
+	keysInsuff, err := a.GenerateAttribKeys([]string{"0", "2"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	_, err = a.DecryptMulti(cipher, keysInsuff, pubKey)
+	assert.True(t, errors.Is(err, abe.ErrPolicyNotSatisfied))
+
+	_, err = a.EncryptMulti(msg, nil, pubKey)
+	assert.Error(t, err)
+}
+
+func TestFAME_DelegateKeys(t *testing.T) {
+	a := abe.NewFAME()
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	keys, err := a.GenerateAttribKeys([]string{"0", "1", "2", "3"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+
+	// narrow the keys to a subset of the attributes
+	delegated, err := a.DelegateKeys(keys, []string{"3", "1"})
+	if err != nil {
+		t.Fatalf("Failed to delegate keys: %v", err)
+	}
+	assert.Len(t, delegated.K, 2)
+
+	msp, err := abe.BooleanToMSP("1 AND (3 OR 4)", false)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	msg := "Attack at dawn!"
+	cipher, err := a.Encrypt(msg, msp, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	msgCheck, err := a.Decrypt(cipher, delegated, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, msgCheck)
+
+	// the delegated keys cannot decrypt with the dropped attributes
+	msp2, err := abe.BooleanToMSP("0 AND 1", false)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	cipher2, err := a.Encrypt(msg, msp2, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	_, err = a.Decrypt(cipher2, delegated, pubKey)
+	assert.Error(t, err)
+
+	// the delegated keys are not re-randomized, so their components
+	// are those of the parent keys
+	assert.Equal(t, keys.K[keys.AttribToI["3"]][0].String(), delegated.K[delegated.AttribToI["3"]][0].String())
+	assert.Equal(t, keys.K0[0].String(), delegated.K0[0].String())
+	assert.Equal(t, keys.KPrime[0].String(), delegated.KPrime[0].String())
+
+	_, err = a.DelegateKeys(keys, []string{"1", "5"})
+	assert.Error(t, err)
+}
+
+func TestFAME_EncryptGT(t *testing.T) {
+	a := abe.NewFAME()
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	msp, err := abe.BooleanToMSP("(0 AND 1) OR 2", false)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+
+	_, msg, err := bn256.RandomGT(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate a message: %v", err)
+	}
+	cipher, err := a.EncryptGT(msg, msp, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	assert.Nil(t, cipher.SymEnc)
+
+	keys, err := a.GenerateAttribKeys([]string{"0", "1"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	msgCheck, err := a.DecryptGT(cipher, keys, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg.String(), msgCheck.String())
+
+	keysInsuff, err := a.GenerateAttribKeys([]string{"1"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	_, err = a.DecryptGT(cipher, keysInsuff, pubKey)
+	assert.True(t, errors.Is(err, abe.ErrPolicyNotSatisfied))
+
+	_, err = a.EncryptGT(nil, msp, pubKey)
+	assert.Error(t, err)
+}
+
+func TestFAME_BatchEncrypt(t *testing.T) {
+	for _, mode := range []abe.SymMode{abe.SymCBC, abe.SymGCM} {
+		a := abe.NewFAME()
+		a.SymMode = mode
+		pubKey, secKey, err := a.GenerateMasterKeys()
+		if err != nil {
+			t.Fatalf("Failed to generate master keys: %v", err)
+		}
+		msp, err := abe.BooleanToMSP("(0 AND 1) OR 2", false)
+		if err != nil {
+			t.Fatalf("Failed to generate the policy: %v", err)
+		}
+
+		msgs := []string{"Attack at dawn!", "Retreat at noon!", "", "Attack at dawn!"}
+		ciphers, err := a.BatchEncrypt(msgs, msp, pubKey)
+		if err != nil {
+			t.Fatalf("Failed to encrypt: %v", err)
+		}
+		assert.Equal(t, len(msgs), len(ciphers))
+		// equal messages are encrypted differently
+		assert.NotEqual(t, ciphers[0].SymEnc, ciphers[3].SymEnc)
+
+		keys, err := a.GenerateAttribKeys([]string{"2"}, secKey)
+		if err != nil {
+			t.Fatalf("Failed to generate keys: %v", err)
+		}
+		for i, cipher := range ciphers {
+			msgCheck, err := a.Decrypt(cipher, keys, pubKey)
+			if err != nil {
+				t.Fatalf("Failed to decrypt: %v", err)
+			}
+			assert.Equal(t, msgs[i], msgCheck)
+		}
+
+		keysInsuff, err := a.GenerateAttribKeys([]string{"1"}, secKey)
+		if err != nil {
+			t.Fatalf("Failed to generate keys: %v", err)
+		}
+		_, err = a.Decrypt(ciphers[1], keysInsuff, pubKey)
+		assert.Error(t, err)
+	}
+
+	_, err := abe.NewFAME().BatchEncrypt(nil, nil, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package abe
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// fingerprint hashes the given parts with sha256 and returns the
+// hex encoded digest. Each part is prefixed with its length so that
+// different sequences of parts cannot produce the same input to the
+// hash function.
+func fingerprint(parts ...[]byte) string {
+	h := sha256.New()
+	lenBytes := make([]byte, 8)
+	for _, p := range parts {
+		binary.BigEndian.PutUint64(lenBytes, uint64(len(p)))
+		h.Write(lenBytes)
+		h.Write(p)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,275 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package abe
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/sample"
+)
+
+// This is a large universe variant of the key policy (KP) attribute
+// based (ABE) GPSW scheme. Instead of fixing the universe of
+// attributes in the public key, the attributes are hashed to the
+// elements of G2 (in the random oracle model, as in the FAME scheme),
+// hence the public key is of constant size and any string can be used
+// as an attribute without declaring it in advance.
+// This scheme is a PUBLIC-KEY scheme - no master secret key is needed
+// to encrypt the messages.
+
+// GPSWLU represents a large universe GPSW ABE-scheme.
+type GPSWLU struct {
+	P          *big.Int // order of the elliptic curve
+	SymMode    SymMode  // symmetric encryption of the messages, SymCBC by default
+	SymKeySize int      // size of the AES key in bytes (16, 24 or 32), DefaultSymKeySize by default
+}
+
+// NewGPSWLU configures a new instance of the large universe scheme.
+func NewGPSWLU() *GPSWLU {
+	return &GPSWLU{P: bn256.Order}
+}
+
+// GPSWLUPubKey represents a public key of the large universe
+// GPSW ABE-scheme.
+type GPSWLUPubKey struct {
+	Y *bn256.GT
+}
+
+// GenerateMasterKeys generates a public key, needed for encrypting
+// data, and a secret key needed for generating keys for decryption.
+func (a *GPSWLU) GenerateMasterKeys() (*GPSWLUPubKey, *big.Int, error) {
+	sampler := sample.NewUniformInvertible(a.P)
+	sk, err := sampler.Sample()
+	if err != nil {
+		return nil, nil, err
+	}
+	y := new(bn256.GT).ScalarBaseMult(sk)
+
+	return &GPSWLUPubKey{Y: y}, sk, nil
+}
+
+// GPSWLUCipher represents a ciphertext of the large universe
+// GPSW ABE-scheme.
+type GPSWLUCipher struct {
+	Gamma     []string       // the set of attributes that can be used for policy of decryption
+	AttribToI map[string]int // a map that connects the attributes in gamma with elements of e
+	E0        *bn256.GT      // the first part of the encryption
+	EPrime    *bn256.G1      // the randomness of the encryption in G1
+	E         data.VectorG2  // the parts of the encryption bound to the attributes
+	SymEnc    []byte         // symmetric encryption of the message
+	Iv        []byte         // initialization vector for symmetric encryption in the CBC mode
+	Nonce     []byte         // nonce for symmetric encryption in the GCM mode
+	Tag       []byte         // authentication tag of symmetric encryption in the GCM mode
+	Mode      SymMode        // mode of symmetric encryption
+	KeySize   int            // size of the AES key in bytes
+}
+
+// sym returns the symmetric part of the cipher.
+func (c *GPSWLUCipher) sym() *SymEnvelope {
+	return &SymEnvelope{Mode: c.Mode, KeySize: c.KeySize, SymEnc: c.SymEnc, Iv: c.Iv, Nonce: c.Nonce, Tag: c.Tag}
+}
+
+// hashAttrib maps an attribute to an element of G2.
+func (a *GPSWLU) hashAttrib(attrib string) (*bn256.G2, error) {
+	return bn256.HashG2("gpsw " + attrib)
+}
+
+// Encrypt takes as an input a message msg given as a string, gamma a set (slice)
+// of attributes that will be associated with the encryption and a public
+// key pk. It returns an encryption of msg. The attributes can be given
+// as []string or as []int, in which case they are converted to their
+// decimal representation. In case of a failed procedure an error is returned.
+func (a *GPSWLU) Encrypt(msg string, gamma interface{}, pk *GPSWLUPubKey) (*GPSWLUCipher, error) {
+	return a.EncryptBytes([]byte(msg), gamma, pk)
+}
+
+// EncryptBytes works as Encrypt, but accepts the message as a byte
+// slice, hence arbitrary binary data can be encrypted without
+// converting it to a string.
+func (a *GPSWLU) EncryptBytes(msg []byte, gamma interface{}, pk *GPSWLUPubKey) (*GPSWLUCipher, error) {
+	var gammaS []string
+	switch gamma.(type) {
+	default:
+		return nil, fmt.Errorf("attributes should be of type []int or []string")
+	case []string:
+		gammaS = append([]string(nil), gamma.([]string)...)
+	case []int:
+		gammaS = make([]string, len(gamma.([]int)))
+		for i, e := range gamma.([]int) {
+			gammaS[i] = strconv.Itoa(e)
+		}
+	}
+	if len(gammaS) == 0 {
+		return nil, fmt.Errorf("the set of attributes should not be empty")
+	}
+
+	// msg is encrypted using AES, with a random key that is encapsulated
+	// with GPSW
+	_, keyGt, err := bn256.RandomGT(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	env := &SymEnvelope{Mode: a.SymMode, KeySize: a.SymKeySize}
+	if err := env.Seal(keyGt, msg); err != nil {
+		return nil, err
+	}
+
+	// encapsulate the key with GPSW
+	sampler := sample.NewUniformNonZero(a.P)
+	s, err := sampler.Sample()
+	if err != nil {
+		return nil, err
+	}
+
+	e0 := new(bn256.GT).Add(keyGt, new(bn256.GT).ScalarMult(pk.Y, s))
+	ePrime := new(bn256.G1).ScalarBaseMult(s)
+	e := make(data.VectorG2, len(gammaS))
+	attribToI := make(map[string]int)
+	for i, el := range gammaS {
+		if _, ok := attribToI[el]; ok {
+			return nil, fmt.Errorf("attribute %s appears more than once", el)
+		}
+		h, err := a.hashAttrib(el)
+		if err != nil {
+			return nil, err
+		}
+		e[i] = new(bn256.G2).ScalarMult(h, s)
+		attribToI[el] = i
+	}
+
+	return &GPSWLUCipher{Gamma: gammaS,
+		AttribToI: attribToI,
+		E0:        e0,
+		EPrime:    ePrime,
+		E:         e,
+		SymEnc:    env.SymEnc,
+		Iv:        env.Iv,
+		Nonce:     env.Nonce,
+		Tag:       env.Tag,
+		Mode:      a.SymMode,
+		KeySize:   env.KeySize}, nil
+}
+
+// GPSWLUKey represents a key structure for decrypting a ciphertext of
+// the large universe GPSW scheme. It includes a msp structure (policy)
+// associated with the key, a vector D representing the main part of
+// the key and a vector R of its randomness.
+type GPSWLUKey struct {
+	Msp *MSP
+	D   data.VectorG2
+	R   data.VectorG1
+}
+
+// GeneratePolicyKey given a monotone span program (MSP) msp and the secret
+// key produces an ABE key associated with the policy given by MSP. In particular,
+// this key can be used to decrypt any cipertext associated with attributes that
+// satisfy given policy.
+func (a *GPSWLU) GeneratePolicyKey(msp *MSP, sk *big.Int) (*GPSWLUKey, error) {
+	if len(msp.Mat) == 0 || len(msp.Mat[0]) == 0 {
+		return nil, fmt.Errorf("empty msp matrix")
+	}
+	if len(msp.Mat) != len(msp.RowToAttrib) {
+		return nil, fmt.Errorf("the msp matrix does not match its attributes")
+	}
+
+	u, err := getSum(sk, a.P, len(msp.Mat[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	sampler := sample.NewUniform(a.P)
+	r, err := data.NewRandomVector(len(msp.Mat), sampler)
+	if err != nil {
+		return nil, err
+	}
+
+	d := make(data.VectorG2, len(msp.Mat))
+	for i := 0; i < len(msp.Mat); i++ {
+		h, err := a.hashAttrib(msp.RowToAttrib[i])
+		if err != nil {
+			return nil, err
+		}
+		lambda, err := msp.Mat[i].DotMod(u, a.P)
+		if err != nil {
+			return nil, err
+		}
+		d[i] = new(bn256.G2).ScalarBaseMult(lambda)
+		d[i].Add(d[i], new(bn256.G2).ScalarMult(h, r[i]))
+	}
+
+	return &GPSWLUKey{Msp: msp, D: d, R: r.MulG1()}, nil
+}
+
+// Decrypt takes as an input a cipher and a GPSWLUKey key and tries to decrypt
+// the cipher. This is possible if and only if the set of attributes associated
+// with the ciphertext satisfies the policy (boolean expression) of the key. If
+// this is not possible, an error is returned.
+func (a *GPSWLU) Decrypt(cipher *GPSWLUCipher, key *GPSWLUKey) (string, error) {
+	msgByte, err := a.DecryptBytes(cipher, key)
+	if err != nil {
+		return "", err
+	}
+
+	return string(msgByte), nil
+}
+
+// DecryptBytes works as Decrypt, but returns the decrypted message
+// as a byte slice.
+func (a *GPSWLU) DecryptBytes(cipher *GPSWLUCipher, key *GPSWLUKey) ([]byte, error) {
+	if len(key.Msp.Mat) != len(key.D) || len(key.D) != len(key.R) {
+		return nil, fmt.Errorf("the key does not match its policy")
+	}
+
+	// get the rows of the key whose attributes are in the cipher
+	rows, mat := key.Msp.SatisfyingRows(cipher.Gamma)
+	if len(mat) == 0 {
+		return nil, ErrPolicyNotSatisfied
+	}
+
+	// get a combination alpha of keys needed to decrypt
+	ones := data.NewConstantVector(len(mat[0]), big.NewInt(1))
+	alpha, err := data.GaussianEliminationSolver(mat.Transpose(), ones, a.P)
+	if err != nil {
+		return nil, ErrPolicyNotSatisfied
+	}
+
+	// each row gives e(g1, g2)^(s * lambda_i) = e(E', D_i) / e(R_i, E_i)
+	sum := new(bn256.GT).ScalarBaseMult(big.NewInt(0))
+	for j, i := range rows {
+		if alpha[j].Sign() == 0 {
+			continue
+		}
+		pair := bn256.Pair(cipher.EPrime, key.D[i])
+		eI := cipher.E[cipher.AttribToI[key.Msp.RowToAttrib[i]]]
+		pair.Add(pair, new(bn256.GT).Neg(bn256.Pair(key.R[i], eI)))
+		pair.ScalarMult(pair, alpha[j])
+		sum.Add(sum, pair)
+	}
+	keyGt := new(bn256.GT).Add(cipher.E0, new(bn256.GT).Neg(sum))
+
+	msgByte, err := cipher.sym().Open(keyGt)
+	if err != nil {
+		return nil, err
+	}
+
+	return msgByte, nil
+}
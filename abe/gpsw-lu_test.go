@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package abe_test
+
+import (
+	"testing"
+
+	"github.com/fentec-project/gofe/abe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGPSWLU(t *testing.T) {
+	// create a new large universe GPSW struct, no universe of
+	// attributes needs to be declared
+	a := abe.NewGPSWLU()
+
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+
+	msg := "Attack at dawn!"
+
+	// arbitrary strings can be used as attributes
+	gamma1 := []string{"doctor", "cardiology", "ljubljana"}
+	gamma2 := []string{"nurse", "cardiology"}
+
+	cipher1, err := a.Encrypt(msg, gamma1, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	cipher2, err := a.Encrypt(msg, gamma2, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	msp, err := abe.BooleanToMSP("(doctor OR surgeon) AND (cardiology OR (ljubljana AND oncology))", true)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+
+	abeKey, err := a.GeneratePolicyKey(msp, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+
+	msgCheck, err := a.Decrypt(cipher1, abeKey)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, msgCheck)
+
+	_, err = a.Decrypt(cipher2, abeKey)
+	assert.Error(t, err)
+
+	// integer attributes are converted to strings
+	cipher3, err := a.Encrypt(msg, []int{3, 14}, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	mspInt, err := abe.BooleanToMSP("14 AND (3 OR 15)", true)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	keyInt, err := a.GeneratePolicyKey(mspInt, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	msgCheck, err = a.Decrypt(cipher3, keyInt)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, msgCheck)
+
+	_, err = a.Encrypt(msg, []string{"doctor", "doctor"}, pubKey)
+	assert.Error(t, err)
+}
@@ -17,15 +17,10 @@
 package abe
 
 import (
-	"crypto/aes"
-	cbc "crypto/cipher"
 	"crypto/rand"
-	"crypto/sha256"
 	"fmt"
 	"math/big"
 
-	"io"
-
 	"strconv"
 
 	"github.com/fentec-project/bn256"
@@ -49,13 +44,19 @@ import (
 
 // GPSWParams represents configuration parameters for the GPSW ABE-scheme instance.
 type GPSWParams struct {
-	L int      // number of attributes
-	P *big.Int // order of the elliptic curve
+	L        int      // number of attributes
+	P        *big.Int // order of the elliptic curve
+	Universe []string // names of the attributes, if not given they are {0, 1,..., l-1}
 }
 
-// GPSW represents an GPSW ABE-scheme.
+// GPSW represents an GPSW ABE-scheme. SymMode selects the symmetric
+// encryption of the messages, SymCBC by default, and SymKeySize the
+// size of the AES key in bytes, DefaultSymKeySize by default.
 type GPSW struct {
-	Params *GPSWParams
+	Params        *GPSWParams
+	SymMode       SymMode
+	SymKeySize    int
+	attribToIndex map[string]int
 }
 
 // NewGPSW configures a new instance of the scheme.
@@ -69,6 +70,63 @@ func NewGPSW(l int) *GPSW {
 	}}
 }
 
+// NewGPSWWithUniverse configures a new instance of the scheme
+// where the attributes are given by their names in the universe.
+// Internally the i-th name is represented by the index i. The
+// names used in boolean expressions describing the policies should
+// not include AND or OR in any case as a separate word, "&&" or "||",
+// and '(' or ')' as a character. An error is returned if the universe is empty or
+// a name appears more than once.
+func NewGPSWWithUniverse(universe []string) (*GPSW, error) {
+	if len(universe) == 0 {
+		return nil, fmt.Errorf("the universe of attributes should not be empty")
+	}
+	attribToIndex := make(map[string]int, len(universe))
+	for i, name := range universe {
+		if _, ok := attribToIndex[name]; ok {
+			return nil, fmt.Errorf("attribute %s appears in the universe more than once", name)
+		}
+		attribToIndex[name] = i
+	}
+
+	return &GPSW{
+		Params: &GPSWParams{
+			L:        len(universe),
+			P:        bn256.Order,
+			Universe: append([]string(nil), universe...),
+		},
+		attribToIndex: attribToIndex,
+	}, nil
+}
+
+// attribIndex returns the index of the attribute with the given name.
+// If the scheme has no universe of names, the name should be an integer
+// from {0, 1,..., l-1}. An error is returned if the attribute is not in
+// the universe of the scheme.
+func (a *GPSW) attribIndex(name string) (int, error) {
+	if len(a.Params.Universe) == 0 {
+		i, err := strconv.Atoi(name)
+		if err != nil || i < 0 || i >= a.Params.L {
+			return 0, fmt.Errorf("attribute %s is not in the universe {0,..., %d}", name, a.Params.L-1)
+		}
+		return i, nil
+	}
+
+	if a.attribToIndex != nil {
+		if i, ok := a.attribToIndex[name]; ok {
+			return i, nil
+		}
+	} else {
+		for i, e := range a.Params.Universe {
+			if e == name {
+				return i, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("attribute %s is not in the universe", name)
+}
+
 // GPSWPubKey represents a public key of the GPSW ABE-scheme.
 type GPSWPubKey struct {
 	T data.VectorG2
@@ -79,7 +137,7 @@ type GPSWPubKey struct {
 // for encrypting data, and secret keys needed for generating keys
 // for decryption.
 func (a *GPSW) GenerateMasterKeys() (*GPSWPubKey, data.Vector, error) {
-	sampler := sample.NewUniform(a.Params.P)
+	sampler := sample.NewUniformInvertible(a.Params.P)
 	sk, err := data.NewRandomVector(a.Params.L+1, sampler)
 	if err != nil {
 		return nil, nil, err
@@ -97,24 +155,57 @@ type GPSWCipher struct {
 	E0        *bn256.GT     // the first part of the encryption
 	E         data.VectorG2 // the second part of the encryption
 	SymEnc    []byte        // symmetric encryption of the message
-	Iv        []byte        // initialization vector for symmetric encryption
+	Iv        []byte        // initialization vector for symmetric encryption in the CBC mode
+	Nonce     []byte        // nonce for symmetric encryption in the GCM mode
+	Tag       []byte        // authentication tag of symmetric encryption in the GCM mode
+	Mode      SymMode       // mode of symmetric encryption
+	KeySize   int           // size of the AES key in bytes
+}
+
+// sym returns the symmetric part of the cipher.
+func (c *GPSWCipher) sym() *SymEnvelope {
+	return &SymEnvelope{Mode: c.Mode, KeySize: c.KeySize, SymEnc: c.SymEnc, Iv: c.Iv, Nonce: c.Nonce, Tag: c.Tag}
+}
+
+// Attributes returns the distinct attributes the cipher is associated
+// with, in the order of their first appearance. A key can decrypt the
+// cipher iff these attributes satisfy its policy.
+func (c *GPSWCipher) Attributes() []int {
+	seen := make(map[int]bool)
+	attribs := make([]int, 0, len(c.Gamma))
+	for _, attrib := range c.Gamma {
+		if !seen[attrib] {
+			seen[attrib] = true
+			attribs = append(attribs, attrib)
+		}
+	}
+
+	return attribs
 }
 
 // Encrypt takes as an input a message msg given as a string, gamma a set (slice)
 // of attributes that will be associated with the encryption and a public
-// key pk. It returns an encryption of msg. In case of a failed procedure an
-// error is returned.
+// key pk. It returns an encryption of msg. The attributes can be given
+// as []int of their indices or as []string of their names. In case of
+// a failed procedure an error is returned.
 func (a *GPSW) Encrypt(msg string, gamma interface{}, pk *GPSWPubKey) (*GPSWCipher, error) {
+	return a.EncryptBytes([]byte(msg), gamma, pk)
+}
+
+// EncryptBytes works as Encrypt, but accepts the message as a byte
+// slice, hence arbitrary binary data can be encrypted without
+// converting it to a string.
+func (a *GPSW) EncryptBytes(msg []byte, gamma interface{}, pk *GPSWPubKey) (*GPSWCipher, error) {
 	var gammaI []int
 	switch gamma.(type) {
 	default:
-		return nil, fmt.Errorf("attributes should be of type []int or []string of integers")
+		return nil, fmt.Errorf("attributes should be of type []int or []string")
 	case []int:
 		gammaI = gamma.([]int)
 	case []string:
 		gammaI = make([]int, len(gamma.([]string)))
 		for i, e := range gamma.([]string) {
-			att, err := strconv.Atoi(e)
+			att, err := a.attribIndex(e)
 			if err != nil {
 				return nil, err
 			}
@@ -122,40 +213,19 @@ func (a *GPSW) Encrypt(msg string, gamma interface{}, pk *GPSWPubKey) (*GPSWCiph
 		}
 	}
 
-	// msg is encrypted using CBC, with a random key that is encapsulated
+	// msg is encrypted using AES, with a random key that is encapsulated
 	// with GPSW
 	_, keyGt, err := bn256.RandomGT(rand.Reader)
 	if err != nil {
 		return nil, err
 	}
-	keyCBC := sha256.Sum256([]byte(keyGt.String()))
-
-	c, err := aes.NewCipher(keyCBC[:])
-	if err != nil {
+	env := &SymEnvelope{Mode: a.SymMode, KeySize: a.SymKeySize}
+	if err := env.Seal(keyGt, msg); err != nil {
 		return nil, err
 	}
 
-	iv := make([]byte, c.BlockSize())
-	_, err = io.ReadFull(rand.Reader, iv)
-	if err != nil {
-		return nil, err
-	}
-	encrypterCBC := cbc.NewCBCEncrypter(c, iv)
-
-	msgByte := []byte(msg)
-	// message is padded according to pkcs7 standard
-	padLen := c.BlockSize() - (len(msgByte) % c.BlockSize())
-	msgPad := make([]byte, len(msgByte)+padLen)
-	copy(msgPad, msgByte)
-	for i := len(msgByte); i < len(msgPad); i++ {
-		msgPad[i] = byte(padLen)
-	}
-
-	symEnc := make([]byte, len(msgPad))
-	encrypterCBC.CryptBlocks(symEnc, msgPad)
-
 	// encapsulate the key with GPSW
-	sampler := sample.NewUniform(a.Params.P)
+	sampler := sample.NewUniformNonZero(a.Params.P)
 	s, err := sampler.Sample()
 	if err != nil {
 		return nil, err
@@ -173,8 +243,12 @@ func (a *GPSW) Encrypt(msg string, gamma interface{}, pk *GPSWPubKey) (*GPSWCiph
 		AttribToI: attribToI,
 		E0:        e0,
 		E:         e,
-		SymEnc:    symEnc,
-		Iv:        iv}, nil
+		SymEnc:    env.SymEnc,
+		Iv:        env.Iv,
+		Nonce:     env.Nonce,
+		Tag:       env.Tag,
+		Mode:      a.SymMode,
+		KeySize:   env.KeySize}, nil
 }
 
 // GPSWKey represents a key structure for decrypting a ciphertext. It includes
@@ -185,6 +259,23 @@ type GPSWKey struct {
 	D           data.VectorG1
 }
 
+// Fingerprint returns a stable sha256 based fingerprint of the key,
+// computed from its policy and the marshaled group elements. Since the
+// key is randomized at generation, keys issued to different entities
+// for the same policy have different fingerprints, so it can be used
+// for auditing the issuance of keys. The fingerprint is a one-way
+// function of the key and can be logged without revealing it.
+func (key *GPSWKey) Fingerprint() string {
+	parts := make([][]byte, 0, 3*len(key.Msp.Mat))
+	for i, row := range key.Msp.Mat {
+		parts = append(parts, []byte(key.Msp.RowToAttrib[i]))
+		parts = append(parts, row.CanonicalBytes())
+		parts = append(parts, key.D[i].Marshal())
+	}
+
+	return fingerprint(parts...)
+}
+
 // GeneratePolicyKey given a monotone span program (MSP) msp and the vector of secret
 // keys produces an ABE key associated with the policy given by MSP. In particular,
 // this key can be used to decrypt any cipertext associated with attributes that
@@ -205,16 +296,13 @@ func (a *GPSW) GeneratePolicyKey(msp *MSP, sk data.Vector) (*GPSWKey, error) {
 	key := make(data.VectorG1, len(msp.Mat))
 
 	for i := 0; i < len(msp.Mat); i++ {
-		attrib, err := strconv.Atoi(msp.RowToAttrib[i])
+		attrib, err := a.attribIndex(msp.RowToAttrib[i])
 		if err != nil {
 			return nil, err
 		}
-		if 0 > attrib || a.Params.L <= attrib {
-			return nil, fmt.Errorf("attributes of msp not in the universe of a")
-		}
 
 		tMapIInv := new(big.Int).ModInverse(sk[attrib], a.Params.P)
-		matTimesU, err := msp.Mat[i].Dot(u)
+		matTimesU, err := msp.Mat[i].DotMod(u, a.Params.P)
 		if err != nil {
 			return nil, err
 		}
@@ -234,11 +322,7 @@ func getSum(y *big.Int, p *big.Int, d int) (data.Vector, error) {
 	if err != nil {
 		return nil, err
 	}
-	sum := big.NewInt(0)
-	for i := 0; i < d-1; i++ {
-		sum.Add(sum, ret[i])
-		sum.Mod(sum, p)
-	}
+	sum := ret[:d-1].SumMod(p)
 	ret[d-1] = new(big.Int).Sub(y, sum)
 	ret[d-1].Mod(ret[d-1], p)
 
@@ -253,6 +337,69 @@ func getSum(y *big.Int, p *big.Int, d int) (data.Vector, error) {
 // ciphertext span the vector [1, 1,..., 1]. If this is not possible, an
 //error is returned.
 func (a *GPSW) Decrypt(cipher *GPSWCipher, key *GPSWKey) (string, error) {
+	msgByte, err := a.DecryptBytes(cipher, key)
+	if err != nil {
+		return "", err
+	}
+
+	return string(msgByte), nil
+}
+
+// DecryptBytes works as Decrypt, but returns the decrypted message
+// as a byte slice.
+func (a *GPSW) DecryptBytes(cipher *GPSWCipher, key *GPSWKey) ([]byte, error) {
+	keyGt, err := a.decryptKeyGT(cipher, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.sym().Open(keyGt)
+}
+
+// decryptWithKeyGT decrypts the symmetric part of the cipher with
+// the key derived from keyGt.
+func decryptWithKeyGT(cipher *GPSWCipher, keyGt *bn256.GT) (string, error) {
+	msgByte, err := cipher.sym().Open(keyGt)
+	if err != nil {
+		return "", err
+	}
+
+	return string(msgByte), nil
+}
+
+// DecryptExact works as Decrypt, but instead of reading the padding
+// of the decrypted message it returns its first plaintextLen bytes. It
+// is meant for the applications that know the length of the message
+// from an authenticated source. An error is returned if plaintextLen
+// does not match the length of the padded message.
+func (a *GPSW) DecryptExact(cipher *GPSWCipher, key *GPSWKey, plaintextLen int) ([]byte, error) {
+	keyGt, err := a.decryptKeyGT(cipher, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.sym().openExact(keyGt, plaintextLen)
+}
+
+// decryptKeyGT computes the element of GT from which the symmetric
+// key of the cipher is derived.
+func (a *GPSW) decryptKeyGT(cipher *GPSWCipher, key *GPSWKey) (*bn256.GT, error) {
+	pairings, err := a.pairKey(cipher, key.Msp, key.D)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(bn256.GT).Add(cipher.E0, new(bn256.GT).Neg(pairings)), nil
+}
+
+// pairKey combines the pairings of the parts of the key d, given
+// together with its policy msp, with the ciphertext. For a key
+// generated by GeneratePolicyKey the result is the value that
+// masks the symmetric key in cipher.E0.
+func (a *GPSW) pairKey(cipher *GPSWCipher, msp *MSP, d data.VectorG1) (*bn256.GT, error) {
+	if len(msp.Mat) != len(d) {
+		return nil, fmt.Errorf("the key does not match its policy")
+	}
 	// get intersection of gamma and attributes used in the key policy
 	gammaMap := make(map[int]bool)
 	for _, e := range cipher.Gamma {
@@ -260,52 +407,92 @@ func (a *GPSW) Decrypt(cipher *GPSWCipher, key *GPSWKey) (string, error) {
 	}
 	intersection := make([]int, 0)
 	mat := make(data.Matrix, 0)
-	d := make(data.VectorG1, 0)
-	for i := 0; i < len(key.Msp.Mat); i++ {
-		attrib, err := strconv.Atoi(key.Msp.RowToAttrib[i])
+	dInter := make(data.VectorG1, 0)
+	for i := 0; i < len(msp.Mat); i++ {
+		attrib, err := a.attribIndex(msp.RowToAttrib[i])
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		if gammaMap[attrib] {
 			intersection = append(intersection, attrib)
-			mat = append(mat, key.Msp.Mat[i])
-			d = append(d, key.D[i])
+			mat = append(mat, msp.Mat[i])
+			dInter = append(dInter, d[i])
 		}
 	}
+	if len(mat) == 0 {
+		return nil, ErrPolicyNotSatisfied
+	}
 
 	// get a combination alpha of keys needed to decrypt
 	ones := data.NewConstantVector(len(mat[0]), big.NewInt(1))
 	alpha, err := data.GaussianEliminationSolver(mat.Transpose(), ones, a.Params.P)
 	if err != nil {
-		return "", fmt.Errorf("the provided key is not sufficient for the decryption")
+		return nil, ErrPolicyNotSatisfied
 	}
 
-	// get a CBC key needed for the decryption of msg
-	keyGt := new(bn256.GT).Set(cipher.E0)
+	// combine the pairings of the keys with the ciphertext
+	sum := new(bn256.GT).ScalarBaseMult(big.NewInt(0))
 	for i := 0; i < len(alpha); i++ {
-		pair := bn256.Pair(d[i], cipher.E[cipher.AttribToI[intersection[i]]])
+		pair := bn256.Pair(dInter[i], cipher.E[cipher.AttribToI[intersection[i]]])
 		pair.ScalarMult(pair, alpha[i])
-		pair.Neg(pair)
-		keyGt.Add(keyGt, pair)
+		sum.Add(sum, pair)
 	}
 
-	keyCBC := sha256.Sum256([]byte(keyGt.String()))
+	return sum, nil
+}
+
+// GPSWTransformKey represents a blinded key that can be given to an
+// untrusted proxy to perform the expensive part of the decryption,
+// following the outsourcing technique of Green, Hohenberger, Waters:
+// "Outsourcing the Decryption of ABE Ciphertexts". It includes the
+// policy of the original key and its parts raised to 1/z, where z is
+// the retrieval key kept by the owner of the key.
+type GPSWTransformKey struct {
+	Msp *MSP
+	D   data.VectorG1
+}
 
-	c, err := aes.NewCipher(keyCBC[:])
+// TransformKey blinds the key with a random z and returns the
+// transformation key together with the retrieval key z. The
+// transformation key can be published to a proxy, since neither the
+// key nor the decrypted messages can be obtained from it without z.
+// In case of a failed procedure an error is returned.
+func (a *GPSW) TransformKey(key *GPSWKey) (*GPSWTransformKey, *big.Int, error) {
+	if len(key.Msp.Mat) != len(key.D) {
+		return nil, nil, fmt.Errorf("the key does not match its policy")
+	}
+	sampler := sample.NewUniformInvertible(a.Params.P)
+	z, err := sampler.Sample()
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
+	zInv := new(big.Int).ModInverse(z, a.Params.P)
 
-	msgPad := make([]byte, len(cipher.SymEnc))
-	decrypter := cbc.NewCBCDecrypter(c, cipher.Iv)
-	decrypter.CryptBlocks(msgPad, cipher.SymEnc)
-
-	// unpad the message
-	padLen := int(msgPad[len(msgPad)-1])
-	if (len(msgPad) - padLen) < 0 {
-		return "", fmt.Errorf("failed to decrypt")
+	d := make(data.VectorG1, len(key.D))
+	for i, di := range key.D {
+		d[i] = new(bn256.G1).ScalarMult(di, zInv)
 	}
-	msgByte := msgPad[0:(len(msgPad) - padLen)]
 
-	return string(msgByte), nil
+	return &GPSWTransformKey{Msp: key.Msp, D: d}, z, nil
+}
+
+// ProxyDecrypt is run by the proxy holding the transformation key. It
+// computes all the pairings needed for the decryption and returns
+// a token from which the owner of the retrieval key can finish the
+// decryption with DecryptToken. An error is returned if the attributes
+// of the cipher do not satisfy the policy of the key.
+func (a *GPSW) ProxyDecrypt(cipher *GPSWCipher, transformKey *GPSWTransformKey) (*bn256.GT, error) {
+	return a.pairKey(cipher, transformKey.Msp, transformKey.D)
+}
+
+// DecryptToken finishes the decryption of the cipher from the token
+// returned by ProxyDecrypt and the retrieval key returned by
+// TransformKey. It only needs a single exponentiation in GT and
+// the symmetric decryption. An error is returned if the decryption
+// failed.
+func (a *GPSW) DecryptToken(cipher *GPSWCipher, token *bn256.GT, retrievalKey *big.Int) (string, error) {
+	mask := new(bn256.GT).ScalarMult(token, retrievalKey)
+	keyGt := new(bn256.GT).Add(cipher.E0, new(bn256.GT).Neg(mask))
+
+	return decryptWithKeyGT(cipher, keyGt)
 }
@@ -56,6 +56,8 @@ func TestGPSW(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to encrypt: %v", err)
 	}
+	assert.Equal(t, gamma1, cipher1.Attributes())
+	assert.Equal(t, gamma2, cipher2.Attributes())
 
 	// create a msp struct out of a boolean expression representing the
 	// policy specifying which attributes are needed to decrypt the ciphertext;
@@ -96,3 +98,252 @@ func TestGPSW(t *testing.T) {
 	_, err = a.Decrypt(cipher2, abeKey)
 	assert.Error(t, err)
 }
+
+func TestGPSW_Threshold(t *testing.T) {
+	a := abe.NewGPSW(5)
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+
+	// the key allows decryption if at least two of the attributes
+	// 1, 2, 3 are present, together with the attribute 0
+	msp, err := abe.BooleanToMSP("0 AND THRESHOLD(2, 1, 2, 3)", true)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	abeKey, err := a.GeneratePolicyKey(msp, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+
+	msg := "Attack at dawn!"
+	cipher1, err := a.Encrypt(msg, []int{0, 1, 3}, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	cipher2, err := a.Encrypt(msg, []int{0, 2, 4}, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	msgCheck, err := a.Decrypt(cipher1, abeKey)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, msgCheck)
+
+	_, err = a.Decrypt(cipher2, abeKey)
+	assert.Error(t, err)
+}
+
+func TestGPSWKey_Fingerprint(t *testing.T) {
+	a := abe.NewGPSW(5)
+	_, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+
+	msp1, err := abe.BooleanToMSP("0 AND (1 OR 2)", true)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	msp2, err := abe.BooleanToMSP("3 OR 4", true)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	key1, err := a.GeneratePolicyKey(msp1, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	key2, err := a.GeneratePolicyKey(msp2, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+
+	// the same key should always give the same fingerprint, while
+	// different keys should give different fingerprints
+	assert.Equal(t, key1.Fingerprint(), key1.Fingerprint())
+	assert.NotEqual(t, key1.Fingerprint(), key2.Fingerprint())
+
+	// keys issued independently for the same policy are told apart
+	key1Again, err := a.GeneratePolicyKey(msp1, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	assert.NotEqual(t, key1.Fingerprint(), key1Again.Fingerprint())
+}
+
+func TestGPSW_DecryptExact(t *testing.T) {
+	a := abe.NewGPSW(5)
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	msp, err := abe.BooleanToMSP("0 AND (1 OR 2)", true)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	key, err := a.GeneratePolicyKey(msp, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+
+	// the length of the payload is known to the caller, so
+	// the padding is not read
+	payload := []byte("Attack at dawn, bring snacks!")
+	cipher, err := a.Encrypt(string(payload), []int{0, 1, 3}, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	payloadCheck, err := a.DecryptExact(cipher, key, len(payload))
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, payload, payloadCheck)
+
+	// lengths that do not match the padded ciphertext are rejected
+	_, err = a.DecryptExact(cipher, key, 15)
+	assert.Error(t, err)
+	_, err = a.DecryptExact(cipher, key, 32)
+	assert.Error(t, err)
+}
+
+func TestGPSW_Universe(t *testing.T) {
+	universe := []string{"department:cardiology", "role:doctor", "role:nurse", "site:ljubljana"}
+	a, err := abe.NewGPSWWithUniverse(universe)
+	if err != nil {
+		t.Fatalf("Failed to create the scheme: %v", err)
+	}
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+
+	msg := "Attack at dawn!"
+	cipher, err := a.Encrypt(msg, []string{"department:cardiology", "role:nurse"}, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	msp, err := abe.BooleanToMSP("department:cardiology AND (role:doctor OR role:nurse)", true)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	key, err := a.GeneratePolicyKey(msp, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	msgCheck, err := a.Decrypt(cipher, key)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, msgCheck)
+
+	// a key whose policy is not satisfied should not decrypt
+	mspSite, err := abe.BooleanToMSP("site:ljubljana AND role:nurse", true)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	keySite, err := a.GeneratePolicyKey(mspSite, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	_, err = a.Decrypt(cipher, keySite)
+	assert.Error(t, err)
+
+	// unknown attributes and repeated names are rejected
+	_, err = a.Encrypt(msg, []string{"role:janitor"}, pubKey)
+	assert.Error(t, err)
+	mspUnknown, err := abe.BooleanToMSP("role:doctor OR role:janitor", true)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	_, err = a.GeneratePolicyKey(mspUnknown, secKey)
+	assert.Error(t, err)
+	_, err = abe.NewGPSWWithUniverse([]string{"role:doctor", "role:doctor"})
+	assert.Error(t, err)
+}
+
+func TestGPSW_OutsourcedDecryption(t *testing.T) {
+	a := abe.NewGPSW(5)
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	msp, err := abe.BooleanToMSP("0 AND (1 OR 2)", true)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	key, err := a.GeneratePolicyKey(msp, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+
+	msg := "Attack at dawn!"
+	cipher, err := a.Encrypt(msg, []int{0, 2, 3}, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	// the owner of the key blinds it and gives the transformation
+	// key to a proxy, keeping the retrieval key
+	transformKey, retrievalKey, err := a.TransformKey(key)
+	if err != nil {
+		t.Fatalf("Failed to transform the key: %v", err)
+	}
+	token, err := a.ProxyDecrypt(cipher, transformKey)
+	if err != nil {
+		t.Fatalf("Failed to partially decrypt: %v", err)
+	}
+	msgCheck, err := a.DecryptToken(cipher, token, retrievalKey)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, msgCheck)
+
+	// the transformation key on its own is not a valid key
+	msgWrong, err := a.Decrypt(cipher, &abe.GPSWKey{Msp: transformKey.Msp, D: transformKey.D})
+	assert.False(t, err == nil && msgWrong == msg)
+
+	// the proxy cannot transform ciphertexts not satisfying the policy
+	cipher2, err := a.Encrypt(msg, []int{1, 2}, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	_, err = a.ProxyDecrypt(cipher2, transformKey)
+	assert.Error(t, err)
+}
+
+func TestGPSW_GCM(t *testing.T) {
+	a := abe.NewGPSW(5)
+	a.SymMode = abe.SymGCM
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	msp, err := abe.BooleanToMSP("0 AND (1 OR 2)", true)
+	if err != nil {
+		t.Fatalf("Failed to generate the policy: %v", err)
+	}
+	key, err := a.GeneratePolicyKey(msp, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+
+	msg := "Attack at dawn!"
+	cipher, err := a.Encrypt(msg, []int{0, 1}, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	msgCheck, err := a.Decrypt(cipher, key)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, msgCheck)
+
+	// a tampered ciphertext fails to decrypt
+	cipher.SymEnc[len(cipher.SymEnc)-1] ^= 0x80
+	_, err = a.Decrypt(cipher, key)
+	assert.Error(t, err)
+}
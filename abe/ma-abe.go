@@ -17,15 +17,14 @@
 package abe
 
 import (
-    "crypto/aes"
-    cbc "crypto/cipher"
     "crypto/rand"
-    "crypto/sha256"
     "fmt"
     "math/big"
-    "io"
+    "strconv"
+    "strings"
     "github.com/fentec-project/bn256"
     "github.com/fentec-project/gofe/data"
+    "github.com/fentec-project/gofe/internal"
     "github.com/fentec-project/gofe/sample"
 )
 
@@ -34,6 +33,19 @@ import (
 // Encryption" by Allison Lewko and Brent Waters, accessible at
 // (https://eprint.iacr.org/2010/351.pdf).
 //
+// Keys can be revoked through attribute versioning. Every attribute of an
+// authority has a version, starting with 0, which is published in the public
+// key and embedded in the issued keys and in the ciphertexts. Calling
+// RegenerateKey replaces the keys of the attribute with fresh ones and
+// increases its version, so that ciphertexts created with the new public key
+// can only be decrypted with keys of the matching version. Note that the
+// version itself is only a label - the revocation is enforced by the fresh
+// key material, while the versions allow detecting stale keys. Revocation
+// is not retroactive: ciphertexts created before the version was increased
+// can still be decrypted with the old keys and have to be reencrypted, and
+// all entities that are still entitled to the attribute have to obtain new
+// keys from the authority.
+//
 // This scheme enables encryption based on a boolean expression determining
 // which attributes are needed for an entity to be able to decrypt, where the
 // attributes can be spread across many different authorities, eliminating the
@@ -42,12 +54,16 @@ import (
 // of keys whose attributes are sufficient according to the boolean formula can
 // decrypt the message.
 
-// MAABE represents a MAABE scheme.
+// MAABE represents a MAABE scheme. SymMode selects the symmetric
+// encryption of the messages, SymCBC by default, and SymKeySize the
+// size of the AES key in bytes, DefaultSymKeySize by default.
 type MAABE struct {
     P *big.Int
     G1 *bn256.G1
     G2 *bn256.G2
     Gt *bn256.GT
+    SymMode SymMode
+    SymKeySize int
 }
 
 // NewMAABE configures a new instance of the scheme.
@@ -58,15 +74,52 @@ func NewMAABE() *MAABE {
             P: bn256.Order,
             G1: gen1,
             G2: gen2,
-            Gt: bn256.Pair(gen1, gen2),
+            Gt: new(bn256.GT).ScalarBaseMult(big.NewInt(1)), // equals e(gen1, gen2)
+    }
+}
+
+// MAABEAttribSep separates the authority ID from the attribute name in
+// authority-qualified attribute names, e.g. "authX:admin".
+const MAABEAttribSep = ":"
+
+// QualifyMAABEAttrib returns the authority-qualified name of the attribute
+// attrib of the authority with the given ID. If attrib is already qualified
+// with the same ID it is returned unchanged. It returns an error if attrib
+// is qualified with a different authority ID.
+func QualifyMAABEAttrib(id, attrib string) (string, error) {
+    if len(attrib) == 0 {
+        return "", fmt.Errorf("attribute cannot be an empty string")
     }
+    authID, name, ok := SplitMAABEAttrib(attrib)
+    if !ok {
+        return id + MAABEAttribSep + attrib, nil
+    }
+    if authID != id || len(name) == 0 {
+        return "", fmt.Errorf("attribute %s does not belong to authority %s", attrib, id)
+    }
+    return attrib, nil
+}
+
+// SplitMAABEAttrib splits an authority-qualified attribute name into the
+// authority ID and the bare attribute name. The last return value is false
+// if the attribute is not qualified.
+func SplitMAABEAttrib(attrib string) (string, string, bool) {
+    parts := strings.SplitN(attrib, MAABEAttribSep, 2)
+    if len(parts) != 2 || len(parts[0]) == 0 {
+        return "", attrib, false
+    }
+    return parts[0], parts[1], true
 }
 
-// MAABEPubKey represents a public key for an authority.
+// MAABEPubKey represents a public key for an authority. ID is the ID of
+// the authority and all the attributes are qualified with it. Versions
+// holds the current versions of the attributes.
 type MAABEPubKey struct {
+    ID string
     Attribs []string
     EggToAlpha map[string]*bn256.GT
     GToY map[string]*bn256.G2
+    Versions map[string]int
 }
 
 // MAABESecKey represents a secret key for an authority.
@@ -85,8 +138,11 @@ type MAABEAuth struct {
 }
 
 // NewMAABEAuth configures a new instance of an authority and generates its
-// public and secret keys for the given set of attributes. In case of a failed
-// procedure an error is returned.
+// public and secret keys for the given set of attributes. Attributes are
+// namespaced by the authority ID, i.e. a bare attribute "admin" of the
+// authority "authX" is stored as "authX:admin", so that attributes of
+// different authorities never collide. In case of a failed procedure an
+// error is returned.
 func (a *MAABE) NewMAABEAuth(id string, attribs []string) (*MAABEAuth, error) {
     numattrib := len(attribs)
     // sanity checks
@@ -96,6 +152,23 @@ func (a *MAABE) NewMAABEAuth(id string, attribs []string) (*MAABEAuth, error) {
     if len(id) == 0 {
         return nil, fmt.Errorf("empty id string")
     }
+    if strings.Contains(id, MAABEAttribSep) {
+        return nil, fmt.Errorf("id string cannot contain %q", MAABEAttribSep)
+    }
+    qualified := make([]string, numattrib)
+    seen := make(map[string]bool)
+    for i, at := range attribs {
+        q, err := QualifyMAABEAttrib(id, at)
+        if err != nil {
+            return nil, err
+        }
+        if seen[q] {
+            return nil, fmt.Errorf("attribute %s given more than once", q)
+        }
+        seen[q] = true
+        qualified[i] = q
+    }
+    attribs = qualified
     // rand generator
     sampler := sample.NewUniform(a.P)
     // generate seckey
@@ -120,8 +193,14 @@ func (a *MAABE) NewMAABEAuth(id string, attribs []string) (*MAABEAuth, error) {
         eggToAlpha[at] = new(bn256.GT).ScalarMult(a.Gt, alpha[at])
         gToY[at] = new(bn256.G2).ScalarMult(a.G2, y[at])
     }
-    sk := &MAABESecKey{Attribs: attribs, Alpha: alpha, Y: y}
-    pk := &MAABEPubKey{Attribs: attribs, EggToAlpha: eggToAlpha, GToY: gToY}
+    versions := make(map[string]int)
+    for _, at := range attribs {
+        versions[at] = 0
+    }
+    skAttribs := make([]string, numattrib)
+    copy(skAttribs, attribs)
+    sk := &MAABESecKey{Attribs: skAttribs, Alpha: alpha, Y: y}
+    pk := &MAABEPubKey{ID: id, Attribs: attribs, EggToAlpha: eggToAlpha, GToY: gToY, Versions: versions}
     return &MAABEAuth{
         ID: id,
         Maabe: a,
@@ -143,20 +222,41 @@ func (auth *MAABEAuth) PubKeys() *MAABEPubKey {
     for at, g2 := range auth.Pk.GToY {
         newGToY[at] = new(bn256.G2).Set(g2)
     }
+    newVersions := make(map[string]int)
+    for at, v := range auth.Pk.Versions {
+        newVersions[at] = v
+    }
     return &MAABEPubKey{
+        ID: auth.ID,
         Attribs: newAttribs,
         EggToAlpha: newEggToAlpha,
         GToY: newGToY,
+        Versions: newVersions,
     }
 }
 
+// Version returns the current version of the attribute. It returns an
+// error if the authority does not manage the attribute.
+func (auth *MAABEAuth) Version(attrib string) (int, error) {
+    attrib, err := QualifyMAABEAttrib(auth.ID, attrib)
+    if err != nil {
+        return 0, err
+    }
+    if auth.Pk.EggToAlpha[attrib] == nil {
+        return 0, fmt.Errorf("attribute %s does not exist", attrib)
+    }
+    return auth.Pk.Versions[attrib], nil
+}
+
 // AddAttribute generates public and secret keys for a new attribute that is
-// given as input. In case of a failed procedure an error is returned, and nil
+// given as input. The attribute is namespaced by the authority ID as in
+// NewMAABEAuth. In case of a failed procedure an error is returned, and nil
 // otherwise.
 func (auth *MAABEAuth) AddAttribute(attrib string) error {
     // sanity checks
-    if len(attrib) == 0 {
-        return fmt.Errorf("attribute cannot be an empty string")
+    attrib, err := QualifyMAABEAttrib(auth.ID, attrib)
+    if err != nil {
+        return err
     }
     if auth.Maabe == nil {
         return fmt.Errorf("MAABE struct cannot be nil")
@@ -181,21 +281,27 @@ func (auth *MAABEAuth) AddAttribute(attrib string) error {
     auth.Sk.Y[attrib] = y
     auth.Pk.EggToAlpha[attrib] = eggToAlpha
     auth.Pk.GToY[attrib] = gToY
+    if auth.Pk.Versions == nil {
+        auth.Pk.Versions = make(map[string]int)
+    }
+    auth.Pk.Versions[attrib] = 0
     auth.Sk.Attribs = append(auth.Sk.Attribs, attrib)
     auth.Pk.Attribs = append(auth.Pk.Attribs, attrib)
     return nil
 }
 
 // RegenerateKey generates public and secret keys for an already existing
-// attribute that is given as input. In case of a failed procedure an error is
-// returned. It is meant to be used in case only a part of the authority's
-// secret keys get compromised. Note that the new public keys have to be
-// distributed and messages that were encrypted with a policy that contains
-// this attribute have to also be reencrypted.
+// attribute that is given as input and increases the version of the
+// attribute. In case of a failed procedure an error is returned. It is meant
+// to be used in case only a part of the authority's secret keys get
+// compromised or when keys for the attribute have to be revoked. Note that
+// the new public keys have to be distributed and messages that were encrypted
+// with a policy that contains this attribute have to also be reencrypted.
 func (auth *MAABEAuth) RegenerateKey(attrib string) error {
     // sanity checks
-    if len(attrib) == 0 {
-        return fmt.Errorf("attribute cannot be an empty string")
+    attrib, err := QualifyMAABEAttrib(auth.ID, attrib)
+    if err != nil {
+        return err
     }
     if auth.Maabe == nil {
         return fmt.Errorf("MAABE struct cannot be nil")
@@ -220,6 +326,10 @@ func (auth *MAABEAuth) RegenerateKey(attrib string) error {
     auth.Sk.Y[attrib] = y
     auth.Pk.EggToAlpha[attrib] = eggToAlpha
     auth.Pk.GToY[attrib] = gToY
+    if auth.Pk.Versions == nil {
+        auth.Pk.Versions = make(map[string]int)
+    }
+    auth.Pk.Versions[attrib]++
     return nil
 }
 
@@ -230,16 +340,49 @@ type MAABECipher struct {
     C2x map[string]*bn256.G2
     C3x map[string]*bn256.G2
     Msp *MSP
+    Versions map[string]int // versions of the attributes used in the encryption
     SymEnc []byte // symmetric encryption of the string message
-    Iv []byte // initialization vector for symmetric encryption
+    Iv []byte // initialization vector for symmetric encryption in the CBC mode
+    Nonce []byte // nonce for symmetric encryption in the GCM mode
+    Tag []byte // authentication tag of symmetric encryption in the GCM mode
+    Mode SymMode // mode of symmetric encryption
+    KeySize int // size of the AES key in bytes
+}
+
+// sym returns the symmetric part of the cipher.
+func (ct *MAABECipher) sym() *SymEnvelope {
+    return &SymEnvelope{Mode: ct.Mode, KeySize: ct.KeySize, SymEnc: ct.SymEnc, Iv: ct.Iv, Nonce: ct.Nonce, Tag: ct.Tag}
 }
 
 // Encrypt takes an input message in string form, a MSP struct representing the
 // decryption policy and a list of public keys of the relevant authorities. It
 // returns a ciphertext consisting of an AES encrypted message with the secret
-// key encrypted according to the MAABE scheme. In case of a failed procedure
-// an error is returned.
+// key encrypted according to the MAABE scheme. Every attribute in the policy
+// has to be qualified with the ID of the authority that manages it, e.g.
+// "authX:admin", and the public key of that authority has to be given. In
+// case of a failed procedure an error is returned.
 func (a *MAABE) Encrypt(msg string, msp *MSP, pks []*MAABEPubKey) (*MAABECipher, error) {
+    return a.EncryptBytes([]byte(msg), msp, pks)
+}
+
+// EncryptPolicy works as Encrypt, but accepts the decryption policy
+// as a boolean expression of AND, OR and THRESHOLD gates, e.g.
+// "auth1:doctor AND auth2:admin", which is converted into a MSP struct
+// with BooleanToMSP. The attributes of the expression are strings,
+// hence they can be qualified with the IDs of the authorities directly.
+// The MSP is kept in the Msp field of the returned ciphertext.
+func (a *MAABE) EncryptPolicy(msg string, policy string, pks []*MAABEPubKey) (*MAABECipher, error) {
+    msp, err := BooleanToMSP(policy, false)
+    if err != nil {
+        return nil, err
+    }
+    return a.EncryptBytes([]byte(msg), msp, pks)
+}
+
+// EncryptBytes works as Encrypt, but accepts the message as a byte
+// slice, hence arbitrary binary data can be encrypted without
+// converting it to a string.
+func (a *MAABE) EncryptBytes(msg []byte, msp *MSP, pks []*MAABEPubKey) (*MAABECipher, error) {
     // sanity checks
     if len(msp.Mat) == 0 || len(msp.Mat[0]) == 0 {
         return nil, fmt.Errorf("empty msp matrix")
@@ -257,49 +400,36 @@ func (a *MAABE) Encrypt(msg string, msp *MSP, pks []*MAABEPubKey) (*MAABECipher,
     if len(msg) == 0 {
         return nil, fmt.Errorf("message cannot be empty")
     }
-    // msg is encrypted with AES-CBC with a random key that is encrypted with
-    // MA-ABE
-    // generate secret key
-    _, symKey, err := bn256.RandomGT(rand.Reader)
+    atToPk, err := policyPubKeys(msp, pks)
     if err != nil {
         return nil, err
     }
-    // generate new AES-CBC params
-    keyCBC := sha256.Sum256([]byte(symKey.String()))
-    cipherAES, err := aes.NewCipher(keyCBC[:])
+    // msg is encrypted with AES with a random key that is encrypted with
+    // MA-ABE
+    // generate secret key
+    _, symKey, err := bn256.RandomGT(rand.Reader)
     if err != nil {
         return nil, err
     }
-    iv := make([]byte, cipherAES.BlockSize())
-    _, err = io.ReadFull(rand.Reader, iv)
-    if err != nil {
+    // encrypt data
+    env := &SymEnvelope{Mode: a.SymMode, KeySize: a.SymKeySize}
+    if err := env.Seal(symKey, msg); err != nil {
         return nil, err
     }
-    encrypterCBC := cbc.NewCBCEncrypter(cipherAES, iv)
-    // interpret msg as a byte array and pad it according to PKCS7 standard
-    msgByte := []byte(msg)
-    padLen := cipherAES.BlockSize() - (len(msgByte) % cipherAES.BlockSize())
-    msgPad := make([]byte, len(msgByte) + padLen)
-    copy(msgPad, msgByte)
-    for i := len(msgByte); i < len(msgPad); i++ {
-        msgPad[i] = byte(padLen)
-    }
-    // encrypt data
-    symEnc := make([]byte, len(msgPad))
-    encrypterCBC.CryptBlocks(symEnc, msgPad)
 
     // now encrypt symKey with MA-ABE
     // rand generator
     sampler := sample.NewUniform(a.P)
-    // pick random vector v with random s as first element
+    // pick random vector v with random nonzero s as first element
     v, err := data.NewRandomVector(mspCols, sampler)
     if err != nil {
         return nil, err
     }
-    s := v[0]
+    s, err := sample.NewUniformNonZero(a.P).Sample()
     if err != nil {
         return nil, err
     }
+    v[0] = s
     lambdaI, err := msp.Mat.MulVec(v)
     if err != nil {
         return nil, err
@@ -333,6 +463,7 @@ func (a *MAABE) Encrypt(msg string, msp *MSP, pks []*MAABEPubKey) (*MAABECipher,
     c1 := make(map[string]*bn256.GT)
     c2 := make(map[string]*bn256.G2)
     c3 := make(map[string]*bn256.G2)
+    versions := make(map[string]int)
     // get randomness
     rI, err := data.NewRandomVector(mspRows, sampler)
     r := make(map[string]*big.Int)
@@ -343,35 +474,15 @@ func (a *MAABE) Encrypt(msg string, msp *MSP, pks []*MAABEPubKey) (*MAABECipher,
         return nil, err
     }
     for _, at := range msp.RowToAttrib {
-        // find the correct pubkey
-        foundPK := false
-        for _, pk := range pks {
-            if pk.EggToAlpha[at] != nil {
-                // CAREFUL: negative numbers do not play well with ScalarMult
-                signLambda := lambda[at].Cmp(big.NewInt(0))
-                signOmega := omega[at].Cmp(big.NewInt(0))
-                var tmpLambda *bn256.GT
-                var tmpOmega *bn256.G2
-                if signLambda >= 0 {
-                    tmpLambda = new(bn256.GT).ScalarMult(a.Gt, lambda[at])
-                } else {
-                    tmpLambda = new(bn256.GT).ScalarMult(new(bn256.GT).Neg(a.Gt), new(big.Int).Abs(lambda[at]))
-                }
-                if signOmega >= 0 {
-                    tmpOmega = new(bn256.G2).ScalarMult(a.G2, omega[at])
-                } else {
-                    tmpOmega = new(bn256.G2).ScalarMult(new(bn256.G2).Neg(a.G2), new(big.Int).Abs(omega[at]))
-                }
-                c1[at] = new(bn256.GT).Add(tmpLambda, new(bn256.GT).ScalarMult(pk.EggToAlpha[at], r[at]))
-                c2[at] = new(bn256.G2).ScalarMult(a.G2, r[at])
-                c3[at] = new(bn256.G2).Add(new(bn256.G2).ScalarMult(pk.GToY[at], r[at]), tmpOmega)
-                foundPK = true
-                break
-            }
-        }
-        if !foundPK {
-            return nil, fmt.Errorf("attribute not found in any pubkey")
-        }
+        pk := atToPk[at]
+        // negative numbers do not play well with ScalarMult, hence the
+        // exponents are mapped to Z_P
+        tmpLambda := new(bn256.GT).ScalarMult(a.Gt, internal.ModPos(lambda[at], a.P))
+        tmpOmega := new(bn256.G2).ScalarMult(a.G2, internal.ModPos(omega[at], a.P))
+        c1[at] = new(bn256.GT).Add(tmpLambda, new(bn256.GT).ScalarMult(pk.EggToAlpha[at], r[at]))
+        c2[at] = new(bn256.G2).ScalarMult(a.G2, r[at])
+        c3[at] = new(bn256.G2).Add(new(bn256.G2).ScalarMult(pk.GToY[at], r[at]), tmpOmega)
+        versions[at] = pk.Versions[at]
     }
     return &MAABECipher{
         C0: c0,
@@ -379,23 +490,91 @@ func (a *MAABE) Encrypt(msg string, msp *MSP, pks []*MAABEPubKey) (*MAABECipher,
         C2x: c2,
         C3x: c3,
         Msp: msp,
-        SymEnc: symEnc,
-        Iv: iv,
+        Versions: versions,
+        SymEnc: env.SymEnc,
+        Iv: env.Iv,
+        Nonce: env.Nonce,
+        Tag: env.Tag,
+        Mode: a.SymMode,
+        KeySize: env.KeySize,
     }, nil
 }
 
+// policyPubKeys maps every attribute of the policy to the public key of the
+// authority that the attribute is qualified with. It returns an error if
+// some attribute is not qualified, if the public key of its authority is
+// not given or does not contain the attribute, if several public keys
+// claim the same authority ID, or if an attribute is claimed by several
+// authorities or by an authority it is not qualified with. Note that all
+// the authorities work in the same bn256 groups, so their public keys
+// cannot differ in the group order.
+func policyPubKeys(msp *MSP, pks []*MAABEPubKey) (map[string]*MAABEPubKey, error) {
+    idToPk := make(map[string]*MAABEPubKey)
+    owner := make(map[string]string)
+    for _, pk := range pks {
+        if pk == nil || len(pk.ID) == 0 {
+            return nil, fmt.Errorf("public key is missing the authority ID")
+        }
+        if idToPk[pk.ID] != nil {
+            return nil, fmt.Errorf("multiple public keys for authority %s", pk.ID)
+        }
+        idToPk[pk.ID] = pk
+        for at := range pk.EggToAlpha {
+            if id, ok := owner[at]; ok {
+                return nil, fmt.Errorf("attribute %s is claimed by authorities %s and %s", at, id, pk.ID)
+            }
+            owner[at] = pk.ID
+        }
+    }
+    for at, ownerID := range owner {
+        if id, _, ok := SplitMAABEAttrib(at); !ok || id != ownerID {
+            return nil, fmt.Errorf("attribute %s is claimed by authority %s it does not belong to", at, ownerID)
+        }
+    }
+    atToPk := make(map[string]*MAABEPubKey)
+    for _, at := range msp.RowToAttrib {
+        id, _, ok := SplitMAABEAttrib(at)
+        if !ok {
+            return nil, fmt.Errorf("attribute %s is not qualified with an authority ID", at)
+        }
+        pk := idToPk[id]
+        if pk == nil {
+            return nil, fmt.Errorf("public key of authority %s not given", id)
+        }
+        if pk.EggToAlpha[at] == nil || pk.GToY[at] == nil {
+            return nil, fmt.Errorf("attribute %s not found in the public key of authority %s", at, id)
+        }
+        atToPk[at] = pk
+    }
+    return atToPk, nil
+}
+
 // MAABEKey represents a key corresponding to an attribute possessed by an
 // entity. They are issued by the relevant authorities and are used for
-// decryption in a MAABE scheme.
+// decryption in a MAABE scheme. Version is the version of the attribute
+// at the time the key was issued.
 type MAABEKey struct {
     Gid string
     Attrib string
     Key *bn256.G1
+    Version int
+}
+
+// Fingerprint returns a stable sha256 based fingerprint of the key,
+// computed from the GID, the attribute, its version and the marshaled
+// group element. Keys issued to different GIDs or for different
+// versions of an attribute have different fingerprints, so it can be
+// used for auditing the issuance of keys. The fingerprint is a one-way
+// function of the key and can be logged without revealing it.
+func (key *MAABEKey) Fingerprint() string {
+    return fingerprint([]byte(key.Gid), []byte(key.Attrib), []byte(strconv.Itoa(key.Version)), key.Key.Marshal())
 }
 
 // GenerateAttribKeys generates a list of attribute keys for the given user
 // (represented by its Global ID) that possesses the given list of attributes.
-// In case of a failed procedure an error is returned. The relevant authority
+// Attributes may be given bare or qualified with the authority ID, the
+// returned keys always hold the qualified names. In case of a failed
+// procedure an error is returned. The relevant authority
 // has to check that the entity actually possesses the attributes via some
 // other channel.
 func (auth *MAABEAuth) GenerateAttribKeys(gid string, attribs []string) ([]*MAABEKey, error) {
@@ -415,6 +594,10 @@ func (auth *MAABEAuth) GenerateAttribKeys(gid string, attribs []string) ([]*MAAB
     }
     ks := make([]*MAABEKey, len(attribs))
     for i, at := range attribs {
+        at, err := QualifyMAABEAttrib(auth.ID, at)
+        if err != nil {
+            return nil, err
+        }
         var k *bn256.G1
         if auth.Sk.Alpha[at] != nil && auth.Sk.Y[at] != nil {
             k = new(bn256.G1).Add(new(bn256.G1).ScalarMult(auth.Maabe.G1, auth.Sk.Alpha[at]), new(bn256.G1).ScalarMult(hash, auth.Sk.Y[at]))
@@ -422,6 +605,7 @@ func (auth *MAABEAuth) GenerateAttribKeys(gid string, attribs []string) ([]*MAAB
                 Gid: gid,
                 Attrib: at,
                 Key: k,
+                Version: auth.Pk.Versions[at],
             }
         } else {
             return nil, fmt.Errorf("attribute not found in secret key")
@@ -433,29 +617,46 @@ func (auth *MAABEAuth) GenerateAttribKeys(gid string, attribs []string) ([]*MAAB
 // Decrypt takes a ciphertext in a MAABE scheme and a set of attribute keys
 // belonging to the same entity, and attempts to decrypt the cipher. This is
 // possible only if the set of possessed attributes/keys suffices the
-// decryption policy of the ciphertext. In case this is not possible or
-// something goes wrong an error is returned.
+// decryption policy of the ciphertext. Keys whose version does not match
+// the version of the attribute in the ciphertext are ignored. In case this
+// is not possible or something goes wrong an error is returned.
 func (a * MAABE) Decrypt(ct *MAABECipher, ks []*MAABEKey) (string, error) {
+    msgByte, err := a.DecryptBytes(ct, ks)
+    if err != nil {
+        return "", err
+    }
+
+    return string(msgByte), nil
+}
+
+// DecryptBytes works as Decrypt, but returns the decrypted message
+// as a byte slice.
+func (a *MAABE) DecryptBytes(ct *MAABECipher, ks []*MAABEKey) ([]byte, error) {
     // sanity checks
     if len(ks) == 0 {
-        return "", fmt.Errorf("empty set of attribute keys")
+        return nil, fmt.Errorf("empty set of attribute keys")
     }
     gid := ks[0].Gid
     for _, k := range ks {
         if k.Gid != gid {
-            return "", fmt.Errorf("not all GIDs are the same")
+            return nil, fmt.Errorf("not all GIDs are the same")
         }
     }
     // get hashed GID
     hash, err := bn256.HashG1(gid)
     if err != nil {
-        return "", err
+        return nil, err
     }
     // find out which attributes are valid and extract them
     goodMatRows := make([]data.Vector, 0)
     goodAttribs := make([]string, 0)
     aToK := make(map[string]*MAABEKey)
+    staleKeys := 0
     for _, k := range ks {
+        if k.Version != ct.Versions[k.Attrib] {
+            staleKeys++
+            continue
+        }
         aToK[k.Attrib] = k
     }
     for i, at := range ct.Msp.RowToAttrib {
@@ -466,19 +667,22 @@ func (a * MAABE) Decrypt(ct *MAABECipher, ks []*MAABEKey) (string, error) {
     }
     goodMat, err := data.NewMatrix(goodMatRows)
     if err != nil {
-        return "", err
+        return nil, err
     }
     //choose consts c_x, such that \sum c_x A_x = (1,0,...,0)
     // if they don't exist, keys are not ok
     goodCols := goodMat.Cols()
     if goodCols == 0 {
-        return "", fmt.Errorf("no good matrix columns, most likely the keys contain no valid attribute")
+        if staleKeys > 0 {
+            return nil, fmt.Errorf("%w: %d keys have a version not matching the ciphertext", ErrPolicyNotSatisfied, staleKeys)
+        }
+        return nil, fmt.Errorf("%w: the keys contain no valid attribute", ErrPolicyNotSatisfied)
     }
     one := data.NewConstantVector(goodCols, big.NewInt(0))
     one[0] = big.NewInt(1)
     c, err := data.GaussianEliminationSolver(goodMat.Transpose(), one, a.P)
     if err != nil {
-        return "", err
+        return nil, ErrPolicyNotSatisfied
     }
     cx := make(map[string]*big.Int)
     for i, at := range goodAttribs {
@@ -492,7 +696,7 @@ func (a * MAABE) Decrypt(ct *MAABECipher, ks []*MAABEKey) (string, error) {
             den := new(bn256.GT).Neg(bn256.Pair(aToK[at].Key, ct.C2x[at]))
             eggLambda[at] = new(bn256.GT).Add(num, den)
         } else {
-            return "", fmt.Errorf("attribute %s not in ciphertext dicts", at)
+            return nil, fmt.Errorf("attribute %s not in ciphertext dicts", at)
         }
     }
     eggs := new(bn256.GT).ScalarBaseMult(big.NewInt(0))
@@ -505,25 +709,15 @@ func (a * MAABE) Decrypt(ct *MAABECipher, ks []*MAABEKey) (string, error) {
                 eggs.Add(eggs, new(bn256.GT).ScalarMult(new(bn256.GT).Neg(eggLambda[at]), new(big.Int).Abs(cx[at])))
             }
         } else {
-            return "", fmt.Errorf("missing intermediate result")
+            return nil, fmt.Errorf("missing intermediate result")
         }
     }
     // calculate key for symmetric encryption
     symKey := new(bn256.GT).Add(ct.C0, new(bn256.GT).Neg(eggs))
     // now decrypt message with it
-    keyCBC := sha256.Sum256([]byte(symKey.String()))
-    cipherAES, err := aes.NewCipher(keyCBC[:])
+    msgByte, err := ct.sym().Open(symKey)
     if err != nil {
-        return "", err
-    }
-    msgPad := make([]byte, len(ct.SymEnc))
-    decrypter := cbc.NewCBCDecrypter(cipherAES, ct.Iv)
-    decrypter.CryptBlocks(msgPad, ct.SymEnc)
-    // unpad the message
-    padLen := int(msgPad[len(msgPad)-1])
-    if (len(msgPad) - padLen) < 0 {
-        return "", fmt.Errorf("failed to decrypt")
+        return nil, err
     }
-    msgByte := msgPad[0:(len(msgPad) - padLen)]
-    return string(msgByte), nil
+    return msgByte, nil
 }
@@ -2,6 +2,7 @@ package abe_test
 
 import (
     "testing"
+    "github.com/fentec-project/bn256"
     "github.com/fentec-project/gofe/abe"
     "github.com/stretchr/testify/assert"
 )
@@ -9,6 +10,7 @@ import (
 func TestMAABE(t *testing.T) {
     // create new MAABE struct with Global Parameters
     maabe := abe.NewMAABE()
+    assert.Equal(t, bn256.Pair(maabe.G1, maabe.G2).String(), maabe.Gt.String())
 
     // create three authorities, each with two attributes
     attribs1 := []string{"auth1:at1", "auth1:at2"}
@@ -166,3 +168,253 @@ func TestMAABE(t *testing.T) {
     assert.Equal(t, msg, msg7)
 }
 
+
+func TestMAABEKey_Fingerprint(t *testing.T) {
+    maabe := abe.NewMAABE()
+    auth, err := maabe.NewMAABEAuth("auth1", []string{"auth1:at1", "auth1:at2"})
+    if err != nil {
+        t.Fatalf("Failed generation authority %s: %v\n", "auth1", err)
+    }
+
+    keys, err := auth.GenerateAttribKeys("gid1", []string{"auth1:at1", "auth1:at2"})
+    if err != nil {
+        t.Fatalf("Failed to generate attribute keys: %v\n", err)
+    }
+
+    // the same key should always give the same fingerprint, while
+    // different keys should give different fingerprints
+    assert.Equal(t, keys[0].Fingerprint(), keys[0].Fingerprint())
+    assert.NotEqual(t, keys[0].Fingerprint(), keys[1].Fingerprint())
+
+    // MA-ABE keys are deterministic in the GID, the attribute and its
+    // version, and so is the fingerprint
+    keysAgain, err := auth.GenerateAttribKeys("gid1", []string{"auth1:at1"})
+    if err != nil {
+        t.Fatalf("Failed to generate attribute keys: %v\n", err)
+    }
+    assert.Equal(t, keys[0].Fingerprint(), keysAgain[0].Fingerprint())
+    if err := auth.RegenerateKey("auth1:at1"); err != nil {
+        t.Fatalf("Failed to regenerate the key: %v\n", err)
+    }
+    keysNew, err := auth.GenerateAttribKeys("gid1", []string{"auth1:at1"})
+    if err != nil {
+        t.Fatalf("Failed to generate attribute keys: %v\n", err)
+    }
+    assert.NotEqual(t, keys[0].Fingerprint(), keysNew[0].Fingerprint())
+}
+
+func TestMAABE_NamespacedAttribs(t *testing.T) {
+    maabe := abe.NewMAABE()
+    // both authorities define a bare attribute named admin
+    authX, err := maabe.NewMAABEAuth("authX", []string{"admin", "user"})
+    if err != nil {
+        t.Fatalf("Failed generation authority %s: %v\n", "authX", err)
+    }
+    authY, err := maabe.NewMAABEAuth("authY", []string{"admin"})
+    if err != nil {
+        t.Fatalf("Failed generation authority %s: %v\n", "authY", err)
+    }
+    assert.Equal(t, []string{"authX:admin", "authX:user"}, authX.Pk.Attribs)
+    assert.Equal(t, []string{"authY:admin"}, authY.Pk.Attribs)
+    pks := []*abe.MAABEPubKey{authX.PubKeys(), authY.PubKeys()}
+
+    // the encryptor explicitly chooses the admin of authority Y
+    msp, err := abe.BooleanToMSP("authY:admin AND authX:user", false)
+    if err != nil {
+        t.Fatalf("Failed to generate the policy: %v\n", err)
+    }
+    msg := "Attack at dawn!"
+    ct, err := maabe.Encrypt(msg, msp, pks)
+    if err != nil {
+        t.Fatalf("Failed to encrypt: %v\n", err)
+    }
+
+    gid := "gid1"
+    keysX, err := authX.GenerateAttribKeys(gid, []string{"admin", "authX:user"})
+    if err != nil {
+        t.Fatalf("Failed to generate attribute keys: %v\n", err)
+    }
+    assert.Equal(t, "authX:admin", keysX[0].Attrib)
+    keysY, err := authY.GenerateAttribKeys(gid, []string{"admin"})
+    if err != nil {
+        t.Fatalf("Failed to generate attribute keys: %v\n", err)
+    }
+
+    // the admin key of authority X does not substitute the one of Y
+    _, err = maabe.Decrypt(ct, keysX)
+    assert.Error(t, err)
+    msgCheck, err := maabe.Decrypt(ct, []*abe.MAABEKey{keysY[0], keysX[1]})
+    if err != nil {
+        t.Fatalf("Failed to decrypt: %v\n", err)
+    }
+    assert.Equal(t, msg, msgCheck)
+
+    // policies must reference existing authority-qualified attributes
+    for _, policy := range []string{"admin AND authX:user", "authZ:admin", "authY:user"} {
+        mspBad, err := abe.BooleanToMSP(policy, false)
+        if err != nil {
+            t.Fatalf("Failed to generate the policy: %v\n", err)
+        }
+        _, err = maabe.Encrypt(msg, mspBad, pks)
+        assert.Error(t, err)
+    }
+    // authorities cannot issue keys for attributes of other authorities
+    _, err = authX.GenerateAttribKeys(gid, []string{"authY:admin"})
+    assert.Error(t, err)
+    _, err = maabe.NewMAABEAuth("authX", []string{"authY:admin"})
+    assert.Error(t, err)
+
+    // a public key claiming an attribute of another authority is rejected
+    pkY := authY.PubKeys()
+    forged := &abe.MAABEPubKey{ID: pkY.ID,
+        Attribs: append([]string{"authX:user"}, pkY.Attribs...),
+        EggToAlpha: map[string]*bn256.GT{"authX:user": authX.Pk.EggToAlpha["authX:user"]},
+        GToY: map[string]*bn256.G2{"authX:user": authX.Pk.GToY["authX:user"]}}
+    for at := range pkY.EggToAlpha {
+        forged.EggToAlpha[at] = pkY.EggToAlpha[at]
+        forged.GToY[at] = pkY.GToY[at]
+    }
+    _, err = maabe.Encrypt(msg, msp, []*abe.MAABEPubKey{authX.PubKeys(), forged})
+    assert.Error(t, err)
+    _, err = maabe.Encrypt(msg, msp, []*abe.MAABEPubKey{forged})
+    assert.Error(t, err)
+}
+
+func TestMAABE_Versioning(t *testing.T) {
+    maabe := abe.NewMAABE()
+    auth, err := maabe.NewMAABEAuth("auth1", []string{"at1", "at2"})
+    if err != nil {
+        t.Fatalf("Failed generation authority %s: %v\n", "auth1", err)
+    }
+    msp, err := abe.BooleanToMSP("auth1:at1 AND auth1:at2", false)
+    if err != nil {
+        t.Fatalf("Failed to generate the policy: %v\n", err)
+    }
+    msg := "Attack at dawn!"
+    gid := "gid1"
+
+    keysOld, err := auth.GenerateAttribKeys(gid, []string{"at1", "at2"})
+    if err != nil {
+        t.Fatalf("Failed to generate attribute keys: %v\n", err)
+    }
+    assert.Equal(t, 0, keysOld[0].Version)
+    ctOld, err := maabe.Encrypt(msg, msp, []*abe.MAABEPubKey{auth.PubKeys()})
+    if err != nil {
+        t.Fatalf("Failed to encrypt: %v\n", err)
+    }
+
+    // revoke the keys for at1 by bumping its version
+    err = auth.RegenerateKey("at1")
+    if err != nil {
+        t.Fatalf("Error regenerating key: %v\n", err)
+    }
+    version, err := auth.Version("auth1:at1")
+    if err != nil {
+        t.Fatalf("Failed to get the version: %v\n", err)
+    }
+    assert.Equal(t, 1, version)
+    ctNew, err := maabe.Encrypt(msg, msp, []*abe.MAABEPubKey{auth.PubKeys()})
+    if err != nil {
+        t.Fatalf("Failed to encrypt: %v\n", err)
+    }
+    assert.Equal(t, 1, ctNew.Versions["auth1:at1"])
+    assert.Equal(t, 0, ctNew.Versions["auth1:at2"])
+
+    // previously issued keys do not decrypt the new ciphertext
+    _, err = maabe.Decrypt(ctNew, keysOld)
+    assert.Error(t, err)
+    // but revocation is not retroactive
+    msgCheck, err := maabe.Decrypt(ctOld, keysOld)
+    if err != nil {
+        t.Fatalf("Failed to decrypt: %v\n", err)
+    }
+    assert.Equal(t, msg, msgCheck)
+
+    // keys of the new version decrypt the new ciphertext
+    keysNew, err := auth.GenerateAttribKeys(gid, []string{"at1"})
+    if err != nil {
+        t.Fatalf("Failed to generate attribute keys: %v\n", err)
+    }
+    assert.Equal(t, 1, keysNew[0].Version)
+    msgCheck, err = maabe.Decrypt(ctNew, []*abe.MAABEKey{keysNew[0], keysOld[1]})
+    if err != nil {
+        t.Fatalf("Failed to decrypt: %v\n", err)
+    }
+    assert.Equal(t, msg, msgCheck)
+
+    _, err = auth.Version("auth1:at3")
+    assert.Error(t, err)
+}
+
+func TestMAABE_GCM(t *testing.T) {
+    maabe := abe.NewMAABE()
+    maabe.SymMode = abe.SymGCM
+    auth, err := maabe.NewMAABEAuth("auth1", []string{"at1", "at2"})
+    if err != nil {
+        t.Fatalf("Failed generation authority %s: %v\n", "auth1", err)
+    }
+    msp, err := abe.BooleanToMSP("auth1:at1 OR auth1:at2", false)
+    if err != nil {
+        t.Fatalf("Failed to generate the policy: %v\n", err)
+    }
+    msg := "Attack at dawn!"
+    ct, err := maabe.Encrypt(msg, msp, []*abe.MAABEPubKey{auth.PubKeys()})
+    if err != nil {
+        t.Fatalf("Failed to encrypt: %v\n", err)
+    }
+    keys, err := auth.GenerateAttribKeys("gid1", []string{"at2"})
+    if err != nil {
+        t.Fatalf("Failed to generate attribute keys: %v\n", err)
+    }
+    msgCheck, err := maabe.Decrypt(ct, keys)
+    if err != nil {
+        t.Fatalf("Failed to decrypt: %v\n", err)
+    }
+    assert.Equal(t, msg, msgCheck)
+
+    // a tampered ciphertext fails to decrypt
+    ct.SymEnc = append(ct.SymEnc, 0)
+    _, err = maabe.Decrypt(ct, keys)
+    assert.Error(t, err)
+}
+
+func TestMAABE_EncryptPolicy(t *testing.T) {
+    maabe := abe.NewMAABE()
+    auth1, err := maabe.NewMAABEAuth("auth1", []string{"doctor", "nurse"})
+    if err != nil {
+        t.Fatalf("Failed generation authority %s: %v\n", "auth1", err)
+    }
+    auth2, err := maabe.NewMAABEAuth("auth2", []string{"admin"})
+    if err != nil {
+        t.Fatalf("Failed generation authority %s: %v\n", "auth2", err)
+    }
+    pks := []*abe.MAABEPubKey{auth1.PubKeys(), auth2.PubKeys()}
+    msg := "Attack at dawn!"
+    ct, err := maabe.EncryptPolicy(msg, "auth1:doctor AND auth2:admin", pks)
+    if err != nil {
+        t.Fatalf("Failed to encrypt: %v\n", err)
+    }
+    assert.Equal(t, "auth1:doctor AND auth2:admin", ct.Msp.Expr)
+
+    keys1, err := auth1.GenerateAttribKeys("gid1", []string{"doctor"})
+    if err != nil {
+        t.Fatalf("Failed to generate attribute keys: %v\n", err)
+    }
+    keys2, err := auth2.GenerateAttribKeys("gid1", []string{"admin"})
+    if err != nil {
+        t.Fatalf("Failed to generate attribute keys: %v\n", err)
+    }
+    msgCheck, err := maabe.Decrypt(ct, append(keys1, keys2...))
+    if err != nil {
+        t.Fatalf("Failed to decrypt: %v\n", err)
+    }
+    assert.Equal(t, msg, msgCheck)
+
+    // the policy is not satisfied without the admin attribute
+    _, err = maabe.Decrypt(ct, keys1)
+    assert.Error(t, err)
+
+    // a malformed policy is reported
+    _, err = maabe.EncryptPolicy(msg, "auth1:doctor AND (auth2:admin", pks)
+    assert.Error(t, err)
+}
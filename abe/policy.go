@@ -17,11 +17,15 @@
 package abe
 
 import (
+	"encoding/json"
+	"io"
 	"math/big"
+	"strconv"
 	"strings"
 
 	"fmt"
 
+	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/data"
 )
 
@@ -30,11 +34,13 @@ import (
 // mat and a mapping from the rows of the mat to attributes. A MSP policy
 // allows decryption of an entity with a set of attributes A if an only if all the
 // rows of the matrix mapped to an element of A span the vector [1, 0,..., 0] (or
-// vector [1, 1,..., 1] depending on the use case).
+// vector [1, 1,..., 1] depending on the use case). If the MSP was obtained
+// from a boolean expression by BooleanToMSP, the expression is kept in Expr.
 type MSP struct {
 	P           *big.Int
 	Mat         data.Matrix
 	RowToAttrib []string
+	Expr        string
 }
 
 // BooleanToMSP takes as an input a boolean expression (without a NOT gate) as
@@ -47,9 +53,20 @@ type MSP struct {
 // vector is produced whose i-th entry indicates to which attribute the i-th row
 // corresponds.
 // Example: BooleanToMSP("attrib1 AND (attrib2 OR attrib3)", true)
-// The names of the attributes should not include "AND" or "OR" as
-// a substring and '(' or ')' as a character, otherwise the function
-// will not work properly.
+// Besides AND and OR gates, threshold gates requiring at least k of
+// the given sub-expressions can be used, e.g.
+// BooleanToMSP("attrib1 AND THRESHOLD(2, attrib2, attrib3, attrib4)", true).
+// A threshold gate with n sub-expressions adds only n rows and k-1
+// columns to the matrix.
+// The gates can also be written in lowercase or as the symbols && and
+// ||, e.g. "attrib1 && (attrib2 or threshold(1, attrib3, attrib4))".
+// The words AND and OR are recognized as gates only when they are
+// separated from the attributes by spaces or brackets, and THRESHOLD
+// only when it is followed by a space or its bracketed arguments, so
+// attributes like "brand" or "thresholdLevel" are parsed correctly. The names of
+// the attributes should not include AND or OR in any case as a separate
+// word, should not include "&&", "||" or ',', and should not include
+// '(' or ')', for which an error is returned.
 func BooleanToMSP(boolExp string, convertToOnes bool) (*MSP, error) {
 	// by the Lewko-Waters algorithm we obtain a MSP struct with the property
 	// that is the the boolean expression is satisfied if and only if the corresponding
@@ -65,101 +82,116 @@ func BooleanToMSP(boolExp string, convertToOnes bool) (*MSP, error) {
 	// struct so that the boolean expression is satisfied iff the
 	// corresponding rows span the vector [1, 1,..., 1]
 	if convertToOnes {
-		// create an invertible matrix that maps [1, 0,..., 0] to [1,1,...,1]
-		invMat := make(data.Matrix, len(msp.Mat[0]))
-		for i := 0; i < len(msp.Mat[0]); i++ {
-			invMat[i] = make(data.Vector, len(msp.Mat[0]))
-			for j := 0; j < len(msp.Mat[0]); j++ {
-				if i == 0 || j == i {
-					invMat[i][j] = big.NewInt(1)
-				} else {
-					invMat[i][j] = big.NewInt(0)
-				}
-			}
-		}
-		//change the msp matrix by multiplying with it the matrix invMat
-		msp.Mat, err = msp.Mat.Mul(invMat)
+		// change the msp matrix by multiplying with it an invertible
+		// matrix that maps [1, 0,..., 0] to [1,1,...,1]; the matrix
+		// is invertible by construction, so no check is needed
+		msp.Mat, err = msp.Mat.Mul(OnesTransform(len(msp.Mat[0])))
 		if err != nil {
 			return nil, err
 		}
 	}
+	msp.Expr = strings.TrimSpace(boolExp)
 
 	return msp, nil
 }
 
+// OnesTransform returns the n x n invertible matrix used by BooleanToMSP
+// with convertToOnes set to true. Its first row consists of ones and the
+// rest of it is the identity matrix, hence it maps [1, 0,..., 0] to
+// [1, 1,..., 1]. Multiplying the matrix of a MSP from the right with it
+// converts a MSP whose authorized rows span [1, 0,..., 0] to one whose
+// authorized rows span [1, 1,..., 1].
+func OnesTransform(n int) data.Matrix {
+	t := make(data.Matrix, n)
+	for i := 0; i < n; i++ {
+		t[i] = make(data.Vector, n)
+		for j := 0; j < n; j++ {
+			if i == 0 || j == i {
+				t[i][j] = big.NewInt(1)
+			} else {
+				t[i][j] = big.NewInt(0)
+			}
+		}
+	}
+
+	return t
+}
+
+// ApplyLinearTransform multiplies the matrix of the MSP from the right
+// with a square matrix t, e.g. to randomize the matrix. Since t should
+// be invertible modulo msp.P (or modulo the order of the bn256 groups
+// if msp.P is not set), a set of rows spans a vector v after the
+// transformation iff it spanned v * t^-1 before it, so the policy is
+// preserved up to this change of the target vector; with the target
+// [1, 0,..., 0], it becomes the first row of t. An error is returned
+// if t is not of the right dimensions or is not invertible, in which
+// case msp is left unchanged.
+func (msp *MSP) ApplyLinearTransform(t data.Matrix) error {
+	if len(msp.Mat) == 0 || len(msp.Mat[0]) == 0 {
+		return fmt.Errorf("empty msp matrix")
+	}
+	n := len(msp.Mat[0])
+	if _, err := data.NewMatrix(t); err != nil || !t.CheckDims(n, n) {
+		return fmt.Errorf("the transformation should be a %d x %d matrix", n, n)
+	}
+	p := msp.P
+	if p == nil {
+		p = bn256.Order
+	}
+	if !t.IsFullRank(p) {
+		return fmt.Errorf("the transformation is not invertible")
+	}
+
+	mat, err := msp.Mat.Mul(t)
+	if err != nil {
+		return err
+	}
+	msp.Mat = mat
+
+	return nil
+}
+
 // booleanToMspIterative iteratively builds a msp structure by splitting the expression
-// into two parts separated by an AND or OR gate, generating a msp structure on each of
-// them, and joining both structures together. The structure is such the the boolean expression
+// into parts separated by AND or OR gates, generating a msp structure on each of
+// them, and joining the structures together. The structure is such the the boolean expression
 // assigning 1 to some attributes is satisfied iff the corresponding rows span a vector
 // [1, 0,..., 0]. The algorithm is known as Lewko-Waters algorithm, see Appendix G in
 // https://eprint.iacr.org/2010/351.pdf.
+//
+// A chain of the same gate on one level, e.g. "1 OR 2 OR 3 OR 4", is split into
+// all of its parts at once instead of being split one gate at a time. The
+// resulting msp structure is the same as the one of the nested expression
+// "1 OR (2 OR (3 OR 4))", but the expression is scanned only once and the depth
+// of the recursion does not grow with the length of the chain. The number of
+// columns of the matrix is not reduced: the Lewko-Waters construction adds a
+// column only for an AND gate and already needs exactly one column per AND
+// gate of a chain, which is minimal for it.
 func booleanToMSPIterative(boolExp string, vec data.Vector, c int) (*MSP, int, error) {
 	boolExp = strings.TrimSpace(boolExp)
-	numBrc := 0
-	var boolExp1 string
-	var boolExp2 string
-	var c1 int
-	var cOut int
-	var msp1 *MSP
-	var msp2 *MSP
-	var err error
-	found := false
 
-	// find the main AND or OR gate and iteratively call the function on
-	// both the sub-expressions
-	for i, e := range boolExp {
-		if e == '(' {
-			numBrc++
-			continue
-		}
-		if e == ')' {
-			numBrc--
-			continue
-		}
-		if numBrc == 0 && i < len(boolExp)-3 && boolExp[i:i+3] == "AND" {
-			boolExp1 = boolExp[:i]
-			boolExp2 = boolExp[i+3:]
-			vec1, vec2 := makeAndVecs(vec, c)
-			msp1, c1, err = booleanToMSPIterative(boolExp1, vec1, c+1)
-			if err != nil {
-				return nil, 0, err
-			}
-			msp2, cOut, err = booleanToMSPIterative(boolExp2, vec2, c1)
-			if err != nil {
-				return nil, 0, err
-			}
-			found = true
-			break
-		}
-		if numBrc == 0 && i < len(boolExp)-2 && boolExp[i:i+2] == "OR" {
-			boolExp1 = boolExp[:i]
-			boolExp2 = boolExp[i+2:]
-			msp1, c1, err = booleanToMSPIterative(boolExp1, vec, c)
-			if err != nil {
-				return nil, 0, err
-			}
-			msp2, cOut, err = booleanToMSPIterative(boolExp2, vec, c1)
-			if err != nil {
-				return nil, 0, err
-			}
-			found = true
-			break
-		}
-	}
+	// find the main AND or OR gates and iteratively call the function on
+	// all the sub-expressions
+	gate, subExps := splitGateChain(boolExp)
 
 	// If the AND or OR gate is not found then there are two options,
 	// either the whole expression is in brackets, or the the expression
 	// is only one attribute. It neither of both is true, then
 	// an error is returned while converting the expression into an
 	// attribute
-	if !found {
+	if gate == "" {
+		if len(boolExp) == 0 {
+			return nil, 0, fmt.Errorf("bad boolean expression, empty sub-expression")
+		}
+		if isThresholdGate(boolExp) {
+			return thresholdToMSP(boolExp, vec, c)
+		}
 		if boolExp[0] == '(' && boolExp[len(boolExp)-1] == ')' {
 			boolExp = boolExp[1:(len(boolExp) - 1)]
 			return booleanToMSPIterative(boolExp, vec, c)
 		}
 
-		if strings.Contains(boolExp, "(") || strings.Contains(boolExp, ")") {
-			return nil, 0, fmt.Errorf("bad boolean expression or attributes contain ( or )")
+		if strings.ContainsAny(boolExp, "()") {
+			return nil, 0, fmt.Errorf("bad boolean expression or attribute %q contains ( or )", boolExp)
 		}
 
 		mat := make(data.Matrix, 1)
@@ -175,25 +207,233 @@ func booleanToMSPIterative(boolExp string, vec data.Vector, c int) (*MSP, int, e
 		rowToAttribS := make([]string, 1)
 		rowToAttribS[0] = boolExp
 		return &MSP{Mat: mat, RowToAttrib: rowToAttribS}, c, nil
+	}
 
+	msps := make([]*MSP, len(subExps))
+	cOut := c
+	var err error
+	if gate == "OR" {
+		// all the sub-expressions share the same vector
+		for i, e := range subExps {
+			msps[i], cOut, err = booleanToMSPIterative(e, vec, cOut)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+	} else {
+		// e_1 AND e_2 AND ... AND e_k is treated as
+		// e_1 AND (e_2 AND (... AND e_k))
+		vecRest := vec
+		for i, e := range subExps[:len(subExps)-1] {
+			vec1, vec2 := makeAndVecs(vecRest, cOut)
+			msps[i], cOut, err = booleanToMSPIterative(e, vec1, cOut+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			vecRest = vec2
+		}
+		msps[len(msps)-1], cOut, err = booleanToMSPIterative(subExps[len(subExps)-1], vecRest, cOut)
+		if err != nil {
+			return nil, 0, err
+		}
 	}
-	// otherwise we join the two msp structures into one
-	mat := make(data.Matrix, len(msp1.Mat)+len(msp2.Mat))
-	for i := 0; i < len(msp1.Mat); i++ {
-		mat[i] = make(data.Vector, cOut)
-		for j := 0; j < len(msp1.Mat[0]); j++ {
-			mat[i][j] = msp1.Mat[i][j]
+
+	// otherwise we join the msp structures into one
+	return joinMSPs(msps, cOut), cOut, nil
+}
+
+// joinMSPs stacks the matrices of the given msp structures, padding
+// their rows with zeros to the length c.
+func joinMSPs(msps []*MSP, c int) *MSP {
+	mat := make(data.Matrix, 0)
+	rowToAttribS := make([]string, 0)
+	for _, m := range msps {
+		for _, row := range m.Mat {
+			// rows are never longer than c
+			row, _ = row.Pad(c)
+			mat = append(mat, row)
 		}
-		for j := len(msp1.Mat[0]); j < cOut; j++ {
-			mat[i][j] = big.NewInt(0)
+		rowToAttribS = append(rowToAttribS, m.RowToAttrib...)
+	}
+
+	return &MSP{Mat: mat, RowToAttrib: rowToAttribS}
+}
+
+// thresholdToMSP builds a msp structure for a threshold gate of the form
+// THRESHOLD(k, e_1, ..., e_n), which is satisfied iff at least k of the
+// sub-expressions e_1, ..., e_n are satisfied. Instead of expanding the
+// gate into AND and OR gates, the vector is shared with Shamir's scheme:
+// k-1 new columns are added and the i-th sub-expression gets the vector
+// extended by (i, i^2,..., i^(k-1)), so that any k of the vectors combine
+// into the original one, while fewer than k do not.
+func thresholdToMSP(boolExp string, vec data.Vector, c int) (*MSP, int, error) {
+	args := strings.TrimSpace(boolExp[len(thresholdWord):])
+	if len(args) < 2 || args[0] != '(' || args[len(args)-1] != ')' {
+		return nil, 0, fmt.Errorf("bad threshold gate, expected THRESHOLD(k, e_1, ..., e_n)")
+	}
+	subExps, err := splitArgs(args[1 : len(args)-1])
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(subExps) < 2 {
+		return nil, 0, fmt.Errorf("bad threshold gate, no sub-expressions given")
+	}
+	k, err := strconv.Atoi(strings.TrimSpace(subExps[0]))
+	if err != nil {
+		return nil, 0, fmt.Errorf("bad threshold gate, threshold is not an integer")
+	}
+	subExps = subExps[1:]
+	if k < 1 || k > len(subExps) {
+		return nil, 0, fmt.Errorf("bad threshold gate, threshold should be between 1 and %d", len(subExps))
+	}
+
+	msps := make([]*MSP, len(subExps))
+	cOut := c + k - 1
+	for i, e := range subExps {
+		// vec is never longer than c
+		vecI, _ := vec.Pad(c + k - 1)
+		x := big.NewInt(int64(i + 1))
+		pow := big.NewInt(1)
+		for j := c; j < c+k-1; j++ {
+			pow = new(big.Int).Mul(pow, x)
+			vecI[j] = pow
+		}
+		msps[i], cOut, err = booleanToMSPIterative(e, vecI, cOut)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return joinMSPs(msps, cOut), cOut, nil
+}
+
+// splitArgs splits the arguments of a gate at the commas that are
+// not in brackets. An error is returned if the brackets do not match.
+func splitArgs(s string) ([]string, error) {
+	numBrc := 0
+	start := 0
+	args := make([]string, 0)
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			numBrc++
+		case ')':
+			numBrc--
+			if numBrc < 0 {
+				return nil, fmt.Errorf("bad boolean expression, brackets do not match")
+			}
+		case ',':
+			if numBrc == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if numBrc != 0 {
+		return nil, fmt.Errorf("bad boolean expression, brackets do not match")
+	}
+
+	return append(args, s[start:]), nil
+}
+
+// splitGateChain finds the first AND or OR gate of the expression that is
+// not in brackets, and splits the expression at it and at all the following
+// gates of the same type that are not in brackets. Splitting stops at the
+// first gate of the other type, i.e. "1 OR 2 OR 3 AND 4" is split into
+// "1", "2" and "3 AND 4". If no gate is found an empty string is returned.
+func splitGateChain(boolExp string) (string, []string) {
+	numBrc := 0
+	gate := ""
+	start := 0
+	subExps := make([]string, 0)
+	for i := 0; i < len(boolExp); i++ {
+		e := boolExp[i]
+		if e == '(' {
+			numBrc++
+			continue
+		}
+		if e == ')' {
+			numBrc--
+			continue
+		}
+		if numBrc != 0 {
+			continue
+		}
+
+		found, length := gateAt(boolExp, i)
+		if found == "" {
+			continue
+		}
+
+		if gate == "" {
+			gate = found
+		} else if gate != found {
+			break
+		}
+		subExps = append(subExps, boolExp[start:i])
+		i += length - 1
+		start = i + 1
+	}
+	if gate == "" {
+		return "", nil
+	}
+
+	return gate, append(subExps, boolExp[start:])
+}
+
+// gateAt checks whether an AND or OR gate starts at position i of the
+// expression. The gates are written as the words AND and OR in any
+// case, or as the symbols && and ||. A word is recognized as a gate
+// only if it is not a part of a longer attribute, i.e. if it is
+// surrounded by spaces or brackets, so that e.g. "brand" does not
+// include an AND gate. It returns the type of the gate, "AND" or "OR",
+// and its length in the expression, or an empty string if no gate is
+// found.
+func gateAt(boolExp string, i int) (string, int) {
+	switch {
+	case strings.HasPrefix(boolExp[i:], "&&"):
+		return "AND", 2
+	case strings.HasPrefix(boolExp[i:], "||"):
+		return "OR", 2
+	}
+
+	for _, word := range []string{"AND", "OR"} {
+		end := i + len(word)
+		if end > len(boolExp) || !strings.EqualFold(boolExp[i:end], word) {
+			continue
 		}
+		if i > 0 && !isGateBoundary(boolExp[i-1], ')') {
+			continue
+		}
+		if end < len(boolExp) && !isGateBoundary(boolExp[end], '(') {
+			continue
+		}
+		return word, len(word)
 	}
-	for i := 0; i < len(msp2.Mat); i++ {
-		mat[i+len(msp1.Mat)] = msp2.Mat[i]
+
+	return "", 0
+}
+
+// thresholdWord is the word starting a threshold gate.
+const thresholdWord = "THRESHOLD"
+
+// isThresholdGate reports whether the expression is a threshold gate,
+// i.e. whether it starts with the word THRESHOLD in any case, followed
+// by a space or an opening bracket. The arguments of the gate are
+// checked by thresholdToMSP.
+func isThresholdGate(boolExp string) bool {
+	n := len(thresholdWord)
+	if len(boolExp) <= n || !strings.EqualFold(boolExp[:n], thresholdWord) {
+		return false
 	}
-	rowToAttribS := append(msp1.RowToAttrib, msp2.RowToAttrib...)
 
-	return &MSP{Mat: mat, RowToAttrib: rowToAttribS}, cOut, nil
+	return isGateBoundary(boolExp[n], '(')
+}
+
+// isGateBoundary reports whether character e can surround a gate given
+// as a word, i.e. if it is a space or the given bracket.
+func isGateBoundary(e, bracket byte) bool {
+	return e == bracket || e == ' ' || e == '\t' || e == '\n' || e == '\r'
 }
 
 // makeAndVecs is a helping structure that given a vector and and counter
@@ -201,12 +441,561 @@ func booleanToMSPIterative(boolExp string, vec data.Vector, c int) (*MSP, int, e
 // step of BooleanToMsp
 func makeAndVecs(vec data.Vector, c int) (data.Vector, data.Vector) {
 	vec1 := data.NewConstantVector(c+1, big.NewInt(0))
-	vec2 := data.NewConstantVector(c+1, big.NewInt(0))
-	for i := 0; i < len(vec); i++ {
-		vec2[i].Set(vec[i])
-	}
+	// vec is never longer than c
+	vec2, _ := vec.Pad(c + 1)
 	vec1[c] = big.NewInt(-1)
 	vec2[c] = big.NewInt(1)
 
 	return vec1, vec2
 }
+
+// mspStreamHeader is the first value written by EncodeStream. It
+// carries everything needed to allocate the MSP before its rows
+// are read.
+type mspStreamHeader struct {
+	P    *big.Int `json:"p"`
+	Rows int      `json:"rows"`
+	Cols int      `json:"cols"`
+}
+
+// mspStreamRow is a single row of the MSP matrix together with the
+// attribute it is mapped to, as written by EncodeStream.
+type mspStreamRow struct {
+	Attrib string      `json:"attrib"`
+	Row    data.Vector `json:"row"`
+}
+
+// EncodeStream writes msp to w as a sequence of JSON values: a header
+// holding the modulus and dimensions of the matrix, followed by one
+// value for each row of the matrix and its attribute. Rows are written
+// one at a time so that encoding a MSP with a large number of rows does
+// not require building the whole JSON document in memory.
+func (msp *MSP) EncodeStream(w io.Writer) error {
+	if len(msp.Mat) != len(msp.RowToAttrib) {
+		return fmt.Errorf("the number of rows of the msp matrix does not match the number of attributes")
+	}
+
+	enc := json.NewEncoder(w)
+	err := enc.Encode(mspStreamHeader{P: msp.P, Rows: msp.Mat.Rows(), Cols: msp.Mat.Cols()})
+	if err != nil {
+		return err
+	}
+
+	for i, row := range msp.Mat {
+		if len(row) != msp.Mat.Cols() {
+			return fmt.Errorf("all rows of the msp matrix should be of the same length")
+		}
+		err = enc.Encode(mspStreamRow{Attrib: msp.RowToAttrib[i], Row: row})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DecodeMSPStream reads a MSP written by EncodeStream from r. Rows are
+// decoded one at a time. An error is returned if the stream is
+// malformed or does not match the dimensions announced in its header.
+func DecodeMSPStream(r io.Reader) (*MSP, error) {
+	dec := json.NewDecoder(r)
+
+	var header mspStreamHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, err
+	}
+	if header.Rows < 0 || header.Cols < 0 {
+		return nil, fmt.Errorf("msp stream header is not of the proper form")
+	}
+
+	mat := make(data.Matrix, 0, header.Rows)
+	rowToAttrib := make([]string, 0, header.Rows)
+	for i := 0; i < header.Rows; i++ {
+		var row mspStreamRow
+		if err := dec.Decode(&row); err != nil {
+			return nil, err
+		}
+		if len(row.Row) != header.Cols {
+			return nil, fmt.Errorf("row %d of the msp stream has length %d, expected %d",
+				i, len(row.Row), header.Cols)
+		}
+		for _, e := range row.Row {
+			if e == nil {
+				return nil, fmt.Errorf("row %d of the msp stream contains an empty entry", i)
+			}
+		}
+		mat = append(mat, row.Row)
+		rowToAttrib = append(rowToAttrib, row.Attrib)
+	}
+
+	return &MSP{P: header.P, Mat: mat, RowToAttrib: rowToAttrib}, nil
+}
+
+// Simplify returns a new MSP in which the redundant rows of msp are
+// removed. A row is redundant if, modulo p, it is a linear combination
+// of the rows that are kept and mapped to the same attribute. Since an
+// entity owning the attribute of a removed row owns also the rows that
+// span it, simplification does not change which attribute sets satisfy
+// the policy, and every attribute of msp keeps at least one row. Using
+// a simplified MSP reduces the size of FAME and GPSW ciphertexts and keys.
+// If p is nil, msp.P is used, or the order of the bn256 groups if msp.P
+// is not set either.
+func (msp *MSP) Simplify(p *big.Int) *MSP {
+	if p == nil {
+		p = msp.P
+	}
+	if p == nil {
+		p = bn256.Order
+	}
+
+	mat := make(data.Matrix, 0, len(msp.Mat))
+	rowToAttrib := make([]string, 0, len(msp.RowToAttrib))
+	attribRows := make(map[string]data.Matrix)
+	for i, row := range msp.Mat {
+		attrib := msp.RowToAttrib[i]
+		kept := attribRows[attrib]
+		if len(kept) > 0 {
+			_, err := data.GaussianEliminationSolver(kept.Transpose(), row.Mod(p), p)
+			if err == nil {
+				continue
+			}
+		}
+
+		attribRows[attrib] = append(kept, row.Mod(p))
+		mat = append(mat, row.Copy())
+		rowToAttrib = append(rowToAttrib, attrib)
+	}
+
+	return &MSP{P: msp.P, Mat: mat, RowToAttrib: rowToAttrib, Expr: msp.Expr}
+}
+
+// Compact returns a new MSP in which the redundant columns of msp are
+// removed. Parameter ones tells whether the policy is satisfied when
+// the rows span the vector [1, 1,..., 1] (as for BooleanToMSP with
+// convertToOnes set to true) or [1, 0,..., 0]. A column is redundant
+// if, modulo p, it is a linear combination of the columns that are
+// kept, with the same combination of the entries of the target vector
+// giving its entry. Such a column, in particular a zero column that
+// is not the first one when the target is [1, 0,..., 0], adds a
+// constraint that every combination of rows spanning the target
+// satisfies anyway, hence compaction does not change which attribute
+// sets satisfy the policy. The first column is always kept, so the
+// target of the compacted MSP is of the same form. Using a compacted
+// MSP reduces the work of Gaussian elimination in decryption.
+func (msp *MSP) Compact(p *big.Int, ones bool) *MSP {
+	if len(msp.Mat) == 0 || len(msp.Mat[0]) == 0 {
+		return &MSP{P: msp.P, Mat: msp.Mat.Copy(), RowToAttrib: append([]string(nil), msp.RowToAttrib...), Expr: msp.Expr}
+	}
+
+	// a column is checked together with the entry of the target vector
+	target := big.NewInt(0)
+	if ones {
+		target = big.NewInt(1)
+	}
+	column := func(j int) data.Vector {
+		col := make(data.Vector, len(msp.Mat)+1)
+		col[0] = big.NewInt(1)
+		if j > 0 {
+			col[0] = new(big.Int).Set(target)
+		}
+		for i, row := range msp.Mat {
+			col[i+1] = new(big.Int).Mod(row[j], p)
+		}
+		return col
+	}
+
+	keep := []int{0}
+	keptCols := data.Matrix{column(0)}
+	for j := 1; j < len(msp.Mat[0]); j++ {
+		col := column(j)
+		if _, err := data.GaussianEliminationSolver(keptCols.Transpose(), col, p); err == nil {
+			continue
+		}
+		keep = append(keep, j)
+		keptCols = append(keptCols, col)
+	}
+
+	mat := make(data.Matrix, len(msp.Mat))
+	for i, row := range msp.Mat {
+		mat[i] = make(data.Vector, len(keep))
+		for k, j := range keep {
+			mat[i][k] = new(big.Int).Set(row[j])
+		}
+	}
+
+	return &MSP{P: msp.P, Mat: mat, RowToAttrib: append([]string(nil), msp.RowToAttrib...), Expr: msp.Expr}
+}
+
+// Attributes returns the distinct attributes of msp, i.e. the
+// attributes its rows are mapped to, in the order of their first
+// appearance.
+func (msp *MSP) Attributes() []string {
+	seen := make(map[string]bool)
+	attribs := make([]string, 0)
+	for _, attrib := range msp.RowToAttrib {
+		if !seen[attrib] {
+			seen[attrib] = true
+			attribs = append(attribs, attrib)
+		}
+	}
+
+	return attribs
+}
+
+// IsInjective reports whether every attribute of msp corresponds to at
+// most one row of the matrix, i.e. whether the mapping RowToAttrib is
+// injective. FAME and MA-ABE encryption is only secure (and allowed) for
+// injective MSPs.
+func (msp *MSP) IsInjective() bool {
+	seen := make(map[string]bool)
+	for _, attrib := range msp.RowToAttrib {
+		if seen[attrib] {
+			return false
+		}
+		seen[attrib] = true
+	}
+
+	return true
+}
+
+// MakeInjective returns a new MSP with an injective mapping RowToAttrib.
+// The first row of every attribute keeps its name, while every further
+// row of the attribute is relabeled with a fresh attribute of the form
+// attrib_k, where k is the smallest positive integer giving a name not
+// used elsewhere in the policy. It also returns a map from the fresh
+// attributes to the original ones, so that an entity owning an original
+// attribute can be given keys also for its duplicates.
+func (msp *MSP) MakeInjective() (*MSP, map[string]string) {
+	used := make(map[string]bool)
+	for _, attrib := range msp.RowToAttrib {
+		used[attrib] = true
+	}
+
+	seen := make(map[string]bool)
+	duplicates := make(map[string]string)
+	mat := make(data.Matrix, len(msp.Mat))
+	rowToAttrib := make([]string, len(msp.RowToAttrib))
+	for i, attrib := range msp.RowToAttrib {
+		mat[i] = msp.Mat[i].Copy()
+		if !seen[attrib] {
+			seen[attrib] = true
+			rowToAttrib[i] = attrib
+			continue
+		}
+
+		label := attrib
+		for k := 1; used[label]; k++ {
+			label = fmt.Sprintf("%s_%d", attrib, k)
+		}
+		used[label] = true
+		duplicates[label] = attrib
+		rowToAttrib[i] = label
+	}
+
+	return &MSP{P: msp.P, Mat: mat, RowToAttrib: rowToAttrib}, duplicates
+}
+
+// AuthorizedSets returns all the minimal sets of attributes that
+// satisfy the policy given by msp, as enumerated by
+// EnumerateAuthorizedSets. A set with a single attribute reveals that
+// this attribute alone suffices for decryption.
+func (msp *MSP) AuthorizedSets(p *big.Int, ones bool) ([][]string, error) {
+	sets := make([][]string, 0)
+	err := msp.EnumerateAuthorizedSets(p, ones, func(set []string) bool {
+		sets = append(sets, set)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sets, nil
+}
+
+// EnumerateAuthorizedSets calls f with every minimal set of attributes
+// whose rows of the msp matrix span, modulo p, the vector [1, 1,..., 1]
+// if ones is set to true, or the vector [1, 0,..., 0] otherwise (see
+// BooleanToMSP). A set is minimal if none of its proper subsets spans
+// the vector. The sets are enumerated by increasing size, and the
+// attributes of each set are ordered as they first appear in
+// RowToAttrib. The enumeration stops when f returns false.
+// Since the number of examined sets grows exponentially with the
+// number of distinct attributes, the function is meant for auditing
+// policies with a moderate number of attributes.
+func (msp *MSP) EnumerateAuthorizedSets(p *big.Int, ones bool, f func([]string) bool) error {
+	if len(msp.Mat) == 0 || len(msp.Mat[0]) == 0 {
+		return fmt.Errorf("empty msp matrix")
+	}
+	if len(msp.Mat) != len(msp.RowToAttrib) {
+		return fmt.Errorf("the msp matrix does not match its attributes")
+	}
+
+	// group the rows by attributes
+	attribs := make([]string, 0)
+	attribRows := make(map[string]data.Matrix)
+	for i, attrib := range msp.RowToAttrib {
+		if _, ok := attribRows[attrib]; !ok {
+			attribs = append(attribs, attrib)
+		}
+		attribRows[attrib] = append(attribRows[attrib], msp.Mat[i].Mod(p))
+	}
+
+	target := mspTarget(len(msp.Mat[0]), ones)
+
+	// a set containing an already found minimal set is authorized,
+	// but not minimal, hence only the others need to be checked
+	found := make([][]bool, 0)
+	containsFound := func(set []int) bool {
+		in := make([]bool, len(attribs))
+		for _, j := range set {
+			in[j] = true
+		}
+		for _, m := range found {
+			contained := true
+			for j := range m {
+				if m[j] && !in[j] {
+					contained = false
+					break
+				}
+			}
+			if contained {
+				return true
+			}
+		}
+		return false
+	}
+
+	for size := 1; size <= len(attribs); size++ {
+		set := make([]int, size)
+		for j := range set {
+			set[j] = j
+		}
+		for {
+			if !containsFound(set) {
+				mat := make(data.Matrix, 0)
+				for _, j := range set {
+					mat = append(mat, attribRows[attribs[j]]...)
+				}
+				if _, err := data.GaussianEliminationSolver(mat.Transpose(), target, p); err == nil {
+					m := make([]bool, len(attribs))
+					names := make([]string, size)
+					for k, j := range set {
+						m[j] = true
+						names[k] = attribs[j]
+					}
+					found = append(found, m)
+					if !f(names) {
+						return nil
+					}
+				}
+			}
+
+			// move to the next set of the same size
+			k := size - 1
+			for k >= 0 && set[k] == len(attribs)-size+k {
+				k--
+			}
+			if k < 0 {
+				break
+			}
+			set[k]++
+			for j := k + 1; j < size; j++ {
+				set[j] = set[j-1] + 1
+			}
+		}
+	}
+
+	return nil
+}
+
+// mspTarget returns the vector of length n that the rows of an
+// authorized set span, i.e. [1, 1,..., 1] if ones is true and
+// [1, 0,..., 0] otherwise.
+func mspTarget(n int, ones bool) data.Vector {
+	if ones {
+		return data.NewConstantVector(n, big.NewInt(1))
+	}
+	target := data.NewConstantVector(n, big.NewInt(0))
+	target[0].SetInt64(1)
+
+	return target
+}
+
+// SatisfyingRows returns the indices of the rows of msp whose attributes
+// are in attribs, together with the submatrix of msp.Mat formed by these
+// rows. An entity owning the attributes attribs can use exactly these
+// rows for decryption, and the policy is satisfied iff they span the
+// target vector (see IsSatisfiable). The rows of the submatrix are
+// shared with msp.Mat.
+func (msp *MSP) SatisfyingRows(attribs []string) ([]int, data.Matrix) {
+	owned := make(map[string]bool, len(attribs))
+	for _, attrib := range attribs {
+		owned[attrib] = true
+	}
+
+	return msp.ownedRows(owned)
+}
+
+// ownedRows works as SatisfyingRows, but takes the owned attributes
+// as a set.
+func (msp *MSP) ownedRows(owned map[string]bool) ([]int, data.Matrix) {
+	rows := make([]int, 0)
+	mat := make(data.Matrix, 0)
+	for i, attrib := range msp.RowToAttrib {
+		if owned[attrib] && i < len(msp.Mat) {
+			rows = append(rows, i)
+			mat = append(mat, msp.Mat[i])
+		}
+	}
+
+	return rows, mat
+}
+
+// spans reports whether the rows of msp whose attributes are in
+// attribs span the target vector modulo p (see mspTarget).
+func (msp *MSP) spans(attribs map[string]bool, p *big.Int, ones bool) bool {
+	if len(msp.Mat) == 0 || len(msp.Mat[0]) == 0 || len(msp.Mat) != len(msp.RowToAttrib) {
+		return false
+	}
+	_, mat := msp.ownedRows(attribs)
+	if len(mat) == 0 {
+		return false
+	}
+	for i := range mat {
+		mat[i] = mat[i].Mod(p)
+	}
+	_, err := data.GaussianEliminationSolver(mat.Transpose(), mspTarget(len(msp.Mat[0]), ones), p)
+
+	return err == nil
+}
+
+// IsSatisfiable reports whether some set of attributes satisfies the
+// policy given by msp, i.e. whether all the rows of the matrix span
+// the vector [1, 1,..., 1] (if ones is true) or [1, 0,..., 0] modulo p.
+// Since the policy is monotone, it is satisfiable iff the set of all
+// its attributes satisfies it. A policy that is not satisfiable can
+// never be used for decryption, which usually indicates a mistake in
+// its construction. An empty or malformed msp is not satisfiable.
+func (msp *MSP) IsSatisfiable(p *big.Int, ones bool) bool {
+	attribs := make(map[string]bool)
+	for _, attrib := range msp.RowToAttrib {
+		attribs[attrib] = true
+	}
+
+	return msp.spans(attribs, p, ones)
+}
+
+// IsTautology reports whether the policy given by msp is satisfied by
+// any nonempty set of attributes from universe. The empty set never
+// satisfies a MSP policy, so this is the closest a policy can get to
+// being always true. Since the policy is monotone, this holds iff each
+// attribute from universe satisfies it on its own. If universe is nil,
+// the attributes of msp are used. Such a policy does not restrict
+// decryption, which usually indicates a mistake in its construction.
+func (msp *MSP) IsTautology(p *big.Int, ones bool, universe []string) bool {
+	if universe == nil {
+		universe = msp.Attributes()
+	}
+	if len(universe) == 0 {
+		return false
+	}
+	for _, attrib := range universe {
+		if !msp.spans(map[string]bool{attrib: true}, p, ones) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// EncodeText encodes msp in a human readable text form. The first
+// lines hold the boolean expression of the policy (if known) and the
+// modulus (if set), prefixed by "expr" and "p", respectively. They are
+// followed by a line for each row of the matrix, holding "row", the
+// quoted attribute of the row and its entries in decimal form, e.g.
+//
+//	expr "A AND (B OR C)"
+//	row "A" 1 1
+//	row "B" 0 -1
+//	row "C" 0 -1
+//
+// The method is deliberately not named MarshalText, since that would
+// change the JSON and gob encoding of the MSP and of all the ciphers and
+// keys that include it.
+func (msp *MSP) EncodeText() ([]byte, error) {
+	if len(msp.Mat) != len(msp.RowToAttrib) {
+		return nil, fmt.Errorf("the number of rows of the msp matrix does not match the number of attributes")
+	}
+
+	var b strings.Builder
+	if msp.Expr != "" {
+		b.WriteString("expr " + strconv.Quote(msp.Expr) + "\n")
+	}
+	if msp.P != nil {
+		b.WriteString("p " + msp.P.String() + "\n")
+	}
+	for i, row := range msp.Mat {
+		b.WriteString("row " + strconv.Quote(msp.RowToAttrib[i]) + row.String() + "\n")
+	}
+
+	return []byte(b.String()), nil
+}
+
+// DecodeText decodes msp from the text form produced by EncodeText.
+// It returns an error if the text is malformed or the rows of the
+// matrix are not of the same length.
+func (msp *MSP) DecodeText(text []byte) error {
+	res := MSP{}
+	rows := make([]data.Vector, 0)
+	for i, line := range strings.Split(string(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, value := line, ""
+		if j := strings.IndexByte(line, ' '); j >= 0 {
+			key, value = line[:j], strings.TrimSpace(line[j+1:])
+		}
+
+		switch key {
+		case "expr":
+			expr, err := strconv.Unquote(value)
+			if err != nil {
+				return fmt.Errorf("line %d: malformed expression", i)
+			}
+			res.Expr = expr
+		case "p":
+			p, ok := new(big.Int).SetString(value, 10)
+			if !ok {
+				return fmt.Errorf("line %d: malformed modulus", i)
+			}
+			res.P = p
+		case "row":
+			quoted, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return fmt.Errorf("line %d: malformed attribute", i)
+			}
+			attrib, _ := strconv.Unquote(quoted) // error is impossible to happen
+			row, err := data.ParseVector(value[len(quoted):])
+			if err != nil {
+				return fmt.Errorf("line %d: %w", i, err)
+			}
+			rows = append(rows, row)
+			res.RowToAttrib = append(res.RowToAttrib, attrib)
+		default:
+			return fmt.Errorf("line %d: unknown field %s", i, key)
+		}
+	}
+
+	if len(rows) == 0 {
+		return fmt.Errorf("the msp has no rows")
+	}
+	mat, err := data.NewMatrix(rows)
+	if err != nil {
+		return err
+	}
+	res.Mat = mat
+	*msp = res
+
+	return nil
+}
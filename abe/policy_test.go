@@ -17,10 +17,17 @@
 package abe
 
 import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
 	"math/big"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/sample"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -53,3 +60,608 @@ func TestBooleanToMsp(t *testing.T) {
 	_, err = BooleanToMSP("1 AND ((6 OR 7) AND (8 OR 9)) OR ((2 AND 3) OR (4 AND 5)))", true)
 	assert.Error(t, err)
 }
+
+func TestBooleanToMsp_Operators(t *testing.T) {
+	p := bn256.Order
+	reference, err := BooleanToMSP("grandadmin AND (brand OR orchid)", false)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+	assert.Equal(t, []string{"grandadmin", "brand", "orchid"}, reference.RowToAttrib)
+	refSets, err := reference.AuthorizedSets(p, false)
+	if err != nil {
+		t.Fatalf("Error while enumerating authorized sets: %v", err)
+	}
+	assert.Equal(t, [][]string{{"grandadmin", "brand"}, {"grandadmin", "orchid"}}, refSets)
+
+	for _, expr := range []string{
+		"grandadmin and (brand or orchid)",
+		"grandadmin And (brand oR orchid)",
+		"grandadmin && (brand || orchid)",
+		"grandadmin&&(brand||orchid)",
+		"grandadmin AND(brand OR orchid)",
+		"(grandadmin)AND (brand\tOR\norchid)",
+	} {
+		msp, err := BooleanToMSP(expr, false)
+		if err != nil {
+			t.Fatalf("Error while processing a boolean expression %s: %v", expr, err)
+		}
+		assert.Equal(t, reference.Mat, msp.Mat, expr)
+		assert.Equal(t, reference.RowToAttrib, msp.RowToAttrib, expr)
+	}
+
+	// AND and OR inside the names of attributes are not gates
+	msp, err := BooleanToMSP("ANDROID OR CORE", false)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+	assert.Equal(t, []string{"ANDROID", "CORE"}, msp.RowToAttrib)
+}
+
+func TestBooleanToMsp_Flattening(t *testing.T) {
+	p := big.NewInt(7)
+	attribs := []string{"1", "2", "3", "4", "5", "6", "7", "8"}
+	flat := strings.Join(attribs, " OR ")
+	nested := attribs[len(attribs)-1]
+	for i := len(attribs) - 2; i >= 0; i-- {
+		nested = "(" + attribs[i] + " OR " + nested + ")"
+	}
+
+	mspFlat, err := BooleanToMSP(flat, true)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+	mspNested, err := BooleanToMSP(nested, true)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+	// an OR gate adds no columns, so the chain needs a single one, which
+	// is already the minimum; splitting the chain at once does not change
+	// the matrix
+	assert.Equal(t, len(attribs), mspFlat.Mat.Rows())
+	assert.Equal(t, 1, mspFlat.Mat.Cols())
+	assert.Equal(t, mspNested.Mat, mspFlat.Mat)
+	assert.Equal(t, mspNested.RowToAttrib, mspFlat.RowToAttrib)
+
+	// every single attribute should be enough to span the vector [1, 1,..., 1]
+	v := data.NewConstantVector(mspFlat.Mat.Cols(), big.NewInt(1))
+	for i := range attribs {
+		_, err = data.GaussianEliminationSolver(data.Matrix{mspFlat.Mat[i]}.Transpose(), v, p)
+		assert.NoError(t, err)
+	}
+
+	// chains of AND gates and chains of mixed gates should give the same
+	// structure as the nested expressions, with one column per AND gate
+	// on top of the first one
+	pairs := [][2]string{
+		{"1 AND 2 AND 3 AND 4", "1 AND (2 AND (3 AND 4))"},
+		{"1 OR 2 AND 3 OR 4 OR 5", "1 OR (2 AND (3 OR (4 OR 5)))"},
+		{"(1 AND 2 AND 3) OR 4 OR (5 AND 6)", "(1 AND (2 AND 3)) OR (4 OR (5 AND 6))"},
+	}
+	cols := []int{4, 2, 4}
+	for i, pair := range pairs {
+		m1, err := BooleanToMSP(pair[0], false)
+		if err != nil {
+			t.Fatalf("Error while processing a boolean expression: %v", err)
+		}
+		m2, err := BooleanToMSP(pair[1], false)
+		if err != nil {
+			t.Fatalf("Error while processing a boolean expression: %v", err)
+		}
+		assert.Equal(t, m2.Mat, m1.Mat)
+		assert.Equal(t, m2.RowToAttrib, m1.RowToAttrib)
+		assert.Equal(t, cols[i], m1.Mat.Cols(), pair[0])
+	}
+
+	_, err = BooleanToMSP("1 OR OR 2", false)
+	assert.Error(t, err)
+}
+
+func TestBooleanToMsp_Threshold(t *testing.T) {
+	p := bn256.Order
+	msp, err := BooleanToMSP("THRESHOLD(2, 1, 2, 3)", true)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+	// a threshold gate adds one row per sub-expression and k-1 columns
+	assert.Equal(t, 3, msp.Mat.Rows())
+	assert.Equal(t, 2, msp.Mat.Cols())
+
+	spans := func(msp *MSP, rows []int) bool {
+		m := make(data.Matrix, len(rows))
+		for i, r := range rows {
+			m[i] = msp.Mat[r]
+		}
+		v := data.NewConstantVector(msp.Mat.Cols(), big.NewInt(1))
+		_, err := data.GaussianEliminationSolver(m.Transpose(), v, p)
+		return err == nil
+	}
+	for _, rows := range [][]int{{0, 1}, {0, 2}, {1, 2}, {0, 1, 2}} {
+		assert.True(t, spans(msp, rows), "rows %v should satisfy the policy", rows)
+	}
+	for _, rows := range [][]int{{0}, {1}, {2}} {
+		assert.False(t, spans(msp, rows), "rows %v should not satisfy the policy", rows)
+	}
+
+	// threshold gates can be nested and combined with AND and OR gates
+	msp, err = BooleanToMSP("0 AND THRESHOLD(2, 1 OR 2, (3 AND 4), THRESHOLD(1, 5, 6))", false)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+	assert.Equal(t, []string{"0", "1", "2", "3", "4", "5", "6"}, msp.RowToAttrib)
+	rowsOf := func(attribs ...string) []int {
+		rows := make([]int, 0)
+		for i, a := range msp.RowToAttrib {
+			for _, b := range attribs {
+				if a == b {
+					rows = append(rows, i)
+				}
+			}
+		}
+		return rows
+	}
+	spansFirst := func(rows []int) bool {
+		m := make(data.Matrix, len(rows))
+		for i, r := range rows {
+			m[i] = msp.Mat[r]
+		}
+		v := data.NewConstantVector(msp.Mat.Cols(), big.NewInt(0))
+		v[0].SetInt64(1)
+		_, err := data.GaussianEliminationSolver(m.Transpose(), v, p)
+		return err == nil
+	}
+	assert.True(t, spansFirst(rowsOf("0", "2", "6")))
+	assert.True(t, spansFirst(rowsOf("0", "3", "4", "5")))
+	assert.False(t, spansFirst(rowsOf("0", "3", "5")))
+	assert.False(t, spansFirst(rowsOf("1", "2", "5", "6")))
+	assert.False(t, spansFirst(rowsOf("0", "1", "2")))
+
+	for _, exp := range []string{
+		"THRESHOLD(4, 1, 2, 3)",
+		"THRESHOLD(0, 1, 2)",
+		"THRESHOLD(a, 1, 2)",
+		"THRESHOLD(2)",
+		"THRESHOLD(2, 1, , 3)",
+		"THRESHOLD(2, 1, (2, 3)",
+		"THRESHOLD 2, 1, 2",
+		"Threshold(2, 1, (2, 3)",
+		"0 AND a(b)",
+		"0 OR (1 AND c)d",
+	} {
+		_, err = BooleanToMSP(exp, true)
+		assert.Error(t, err, exp)
+	}
+
+	// the gate is recognized in any case and only as a separate word
+	for _, exp := range []string{"threshold(2, 1, 2, 3)", "Threshold (2, 1, 2, 3)"} {
+		msp, err = BooleanToMSP(exp, true)
+		if err != nil {
+			t.Fatalf("Error while processing a boolean expression %s: %v", exp, err)
+		}
+		assert.Equal(t, []string{"1", "2", "3"}, msp.RowToAttrib)
+		assert.Equal(t, 2, msp.Mat.Cols())
+	}
+	msp, err = BooleanToMSP("thresholdLevel AND threshold", true)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+	assert.Equal(t, []string{"thresholdLevel", "threshold"}, msp.RowToAttrib)
+}
+
+func TestMSP_EncodeStream(t *testing.T) {
+	// create a large msp struct directly, since parsing a boolean
+	// expression with thousands of attributes would be slow
+	rows, cols := 5000, 8
+	sampler := sample.NewUniformRange(big.NewInt(-100), big.NewInt(100))
+	mat, err := data.NewRandomMatrix(rows, cols, sampler)
+	if err != nil {
+		t.Fatalf("Error during matrix generation: %v", err)
+	}
+	rowToAttrib := make([]string, rows)
+	for i := range rowToAttrib {
+		rowToAttrib[i] = "attrib" + strconv.Itoa(i)
+	}
+	msp := &MSP{P: big.NewInt(7919), Mat: mat, RowToAttrib: rowToAttrib}
+
+	var buf bytes.Buffer
+	err = msp.EncodeStream(&buf)
+	if err != nil {
+		t.Fatalf("Error during msp encoding: %v", err)
+	}
+
+	mspCheck, err := DecodeMSPStream(&buf)
+	if err != nil {
+		t.Fatalf("Error during msp decoding: %v", err)
+	}
+	assert.Equal(t, msp.P.Cmp(mspCheck.P), 0)
+	assert.Equal(t, msp.RowToAttrib, mspCheck.RowToAttrib)
+	assert.True(t, msp.Mat.DimsMatch(mspCheck.Mat))
+	for i := range msp.Mat {
+		for j := range msp.Mat[i] {
+			assert.Equal(t, msp.Mat[i][j].Cmp(mspCheck.Mat[i][j]), 0)
+		}
+	}
+
+	// a truncated stream should produce an error
+	err = msp.EncodeStream(&buf)
+	if err != nil {
+		t.Fatalf("Error during msp encoding: %v", err)
+	}
+	_, err = DecodeMSPStream(bytes.NewReader(buf.Bytes()[:buf.Len()/2]))
+	assert.Error(t, err)
+}
+
+// mspSatisfied checks if the rows of msp mapped to attributes in
+// attribs span the vector [1, 0,..., 0].
+func mspSatisfied(msp *MSP, attribs map[string]bool, p *big.Int) bool {
+	mat := make(data.Matrix, 0)
+	for i, row := range msp.Mat {
+		if attribs[msp.RowToAttrib[i]] {
+			mat = append(mat, row)
+		}
+	}
+	if len(mat) == 0 {
+		return false
+	}
+	v := data.NewConstantVector(msp.Mat.Cols(), big.NewInt(0))
+	v[0].SetInt64(1)
+	_, err := data.GaussianEliminationSolver(mat.Transpose(), v, p)
+
+	return err == nil
+}
+
+func TestMSP_Simplify(t *testing.T) {
+	p := big.NewInt(17)
+	msp, err := BooleanToMSP("(1 OR 1) AND (2 OR 3 OR 2)", false)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+
+	// add rows that are linear combinations of rows of the same attribute
+	msp.Mat = append(msp.Mat, msp.Mat[2].MulScalar(big.NewInt(3)), data.NewConstantVector(msp.Mat.Cols(), big.NewInt(0)))
+	msp.RowToAttrib = append(msp.RowToAttrib, "2", "3")
+
+	assert.Equal(t, []string{"1", "2", "3"}, msp.Attributes())
+
+	simple := msp.Simplify(p)
+	assert.Equal(t, 3, simple.Mat.Rows())
+	assert.Equal(t, []string{"1", "2", "3"}, simple.RowToAttrib)
+	assert.Equal(t, 7, msp.Mat.Rows(), "original msp should not change")
+
+	// every set of attributes should satisfy the simplified policy
+	// iff it satisfies the original one
+	attribs := []string{"1", "2", "3"}
+	for mask := 0; mask < 1<<len(attribs); mask++ {
+		owned := make(map[string]bool)
+		for i, a := range attribs {
+			if mask&(1<<i) != 0 {
+				owned[a] = true
+			}
+		}
+		assert.Equal(t, mspSatisfied(msp, owned, p), mspSatisfied(simple, owned, p))
+	}
+
+	// without a modulus the one of msp or the order of bn256 is used
+	simple = msp.Simplify(nil)
+	assert.Equal(t, 3, simple.Mat.Rows())
+	assert.Equal(t, []string{"1", "2", "3"}, simple.RowToAttrib)
+	msp.P = p
+	simple = msp.Simplify(nil)
+	assert.Equal(t, 3, simple.Mat.Rows())
+	assert.Equal(t, p, simple.P)
+}
+
+func TestMSP_Compact(t *testing.T) {
+	p := bn256.Order
+	for _, ones := range []bool{false, true} {
+		msp, err := BooleanToMSP("a AND (b OR (c AND d)) AND THRESHOLD(2, e, f, g)", ones)
+		if err != nil {
+			t.Fatalf("Error while processing a boolean expression: %v", err)
+		}
+
+		// add a copy of the second column and a zero column
+		cols := msp.Mat.Cols()
+		padded := &MSP{P: msp.P, RowToAttrib: msp.RowToAttrib}
+		for _, row := range msp.Mat {
+			padded.Mat = append(padded.Mat, append(row.Copy(), new(big.Int).Set(row[1]), big.NewInt(0)))
+		}
+
+		compact := padded.Compact(p, ones)
+		if ones {
+			// a zero column makes [1, 1,..., 1] unreachable and is kept
+			assert.Equal(t, cols+1, compact.Mat.Cols())
+		} else {
+			assert.Equal(t, cols, compact.Mat.Cols())
+		}
+		assert.Equal(t, cols+2, padded.Mat.Cols(), "original msp should not change")
+		assert.Equal(t, padded.RowToAttrib, compact.RowToAttrib)
+
+		setsPadded, err := padded.AuthorizedSets(p, ones)
+		if err != nil {
+			t.Fatalf("Error while enumerating authorized sets: %v", err)
+		}
+		setsCompact, err := compact.AuthorizedSets(p, ones)
+		if err != nil {
+			t.Fatalf("Error while enumerating authorized sets: %v", err)
+		}
+		assert.Equal(t, setsPadded, setsCompact)
+
+		// a matrix produced by BooleanToMSP has no redundant columns
+		assert.Equal(t, cols, msp.Compact(p, ones).Mat.Cols())
+	}
+}
+
+func TestMSP_AuthorizedSets(t *testing.T) {
+	p := bn256.Order
+	tests := []struct {
+		expr string
+		sets [][]string
+	}{
+		{"a AND (b OR c)", [][]string{{"a", "b"}, {"a", "c"}}},
+		{"a OR (b AND c) OR (a AND d)", [][]string{{"a"}, {"b", "c"}}},
+		{"THRESHOLD(2, a, b, c)", [][]string{{"a", "b"}, {"a", "c"}, {"b", "c"}}},
+		{"(a OR b) AND (a OR c)", [][]string{{"a"}, {"b", "c"}}},
+	}
+	for _, test := range tests {
+		for _, ones := range []bool{false, true} {
+			msp, err := BooleanToMSP(test.expr, ones)
+			if err != nil {
+				t.Fatalf("Error while processing a boolean expression: %v", err)
+			}
+			sets, err := msp.AuthorizedSets(p, ones)
+			if err != nil {
+				t.Fatalf("Error while enumerating authorized sets: %v", err)
+			}
+			assert.Equal(t, test.sets, sets, test.expr)
+		}
+	}
+
+	// the enumeration can be stopped early
+	msp, err := BooleanToMSP("a OR b OR c", false)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+	count := 0
+	err = msp.EnumerateAuthorizedSets(p, false, func(set []string) bool {
+		count++
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	_, err = (&MSP{}).AuthorizedSets(p, false)
+	assert.Error(t, err)
+}
+
+func TestMSP_MakeInjective(t *testing.T) {
+	msp, err := BooleanToMSP("(A AND B) OR (A AND C) OR (A_1 AND A)", false)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+	assert.False(t, msp.IsInjective())
+
+	mspInj, duplicates := msp.MakeInjective()
+	assert.True(t, mspInj.IsInjective())
+	assert.Equal(t, map[string]string{"A_2": "A", "A_3": "A"}, duplicates)
+	assert.Equal(t, msp.Mat, mspInj.Mat)
+	assert.Equal(t, []string{"A", "B", "A_2", "C", "A_1", "A_3"}, mspInj.RowToAttrib)
+
+	// FAME refuses the original policy, but encrypts with the injective one
+	a := NewFAME()
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	msg := "Attack at dawn!"
+	_, err = a.Encrypt(msg, msp, pubKey)
+	assert.Error(t, err)
+	cipher, err := a.Encrypt(msg, mspInj, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	// an entity owning A and C gets keys also for the duplicates of A
+	gamma := []string{"A", "C"}
+	for dup, orig := range duplicates {
+		if orig == "A" {
+			gamma = append(gamma, dup)
+		}
+	}
+	keys, err := a.GenerateAttribKeys(gamma, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	msgCheck, err := a.Decrypt(cipher, keys, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, msg, msgCheck)
+}
+
+func TestMSP_EncodeText(t *testing.T) {
+	msp, err := BooleanToMSP("(doctor AND \"ljubljana hospital\") OR (auth:nurse AND NOT_A_GATE)", false)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+	msp.P = bn256.Order
+
+	text, err := msp.EncodeText()
+	if err != nil {
+		t.Fatalf("Error while marshaling the msp: %v", err)
+	}
+
+	var decoded MSP
+	if err := decoded.DecodeText(text); err != nil {
+		t.Fatalf("Error while unmarshaling the msp: %v", err)
+	}
+	assert.Equal(t, msp, &decoded)
+
+	// the decoded msp can be used for encryption right away
+	a := NewFAME()
+	pubKey, secKey, err := a.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Failed to generate master keys: %v", err)
+	}
+	cipher, err := a.Encrypt("Attack at dawn!", &decoded, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	keys, err := a.GenerateAttribKeys([]string{"auth:nurse", "NOT_A_GATE"}, secKey)
+	if err != nil {
+		t.Fatalf("Failed to generate keys: %v", err)
+	}
+	msg, err := a.Decrypt(cipher, keys, pubKey)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	assert.Equal(t, "Attack at dawn!", msg)
+
+	for _, malformed := range []string{
+		"",
+		"expr A AND B\nrow \"A\" 1",
+		"row A 1 1",
+		"row \"A\" 1 1\nrow \"B\" 1",
+		"row \"A\" 1 x",
+		"col \"A\" 1",
+	} {
+		assert.Error(t, decoded.DecodeText([]byte(malformed)), malformed)
+	}
+}
+
+func TestMSP_JSONFormat(t *testing.T) {
+	// ciphers encoded before the text form was added still decode
+	old := `{"Msp":{"P":7,"Mat":[[1,0],[1,-1]],"RowToAttrib":["A","B"]},"SymEnc":"AQI=","Mode":1,"KeySize":16}`
+	var cipher FAMECipher
+	if err := json.Unmarshal([]byte(old), &cipher); err != nil {
+		t.Fatalf("Error while decoding the cipher: %v", err)
+	}
+	assert.Equal(t, big.NewInt(7), cipher.Msp.P)
+	assert.Equal(t, []string{"A", "B"}, cipher.Msp.RowToAttrib)
+	assert.Equal(t, data.Matrix{
+		data.Vector{big.NewInt(1), big.NewInt(0)},
+		data.Vector{big.NewInt(1), big.NewInt(-1)},
+	}, cipher.Msp.Mat)
+	assert.Equal(t, []byte{1, 2}, cipher.SymEnc)
+
+	// the msp is encoded as an object, which round-trips
+	msp, err := BooleanToMSP("A AND (B OR C)", false)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+	enc, err := json.Marshal(msp)
+	if err != nil {
+		t.Fatalf("Error while encoding the msp: %v", err)
+	}
+	assert.True(t, strings.HasPrefix(string(enc), "{"))
+	var decoded MSP
+	if err := json.Unmarshal(enc, &decoded); err != nil {
+		t.Fatalf("Error while decoding the msp: %v", err)
+	}
+	assert.Equal(t, msp, &decoded)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(msp); err != nil {
+		t.Fatalf("Error while encoding the msp: %v", err)
+	}
+	var gobDecoded MSP
+	if err := gob.NewDecoder(&buf).Decode(&gobDecoded); err != nil {
+		t.Fatalf("Error while decoding the msp: %v", err)
+	}
+	assert.Equal(t, msp, &gobDecoded)
+}
+
+func TestMSP_ApplyLinearTransform(t *testing.T) {
+	p := bn256.Order
+	exp := "a AND (b OR (c AND d)) AND THRESHOLD(2, e, f, g)"
+	msp, err := BooleanToMSP(exp, false)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+	mspOnes, err := BooleanToMSP(exp, true)
+	if err != nil {
+		t.Fatalf("Error while processing a boolean expression: %v", err)
+	}
+	sets, err := msp.AuthorizedSets(p, false)
+	if err != nil {
+		t.Fatalf("Error while enumerating authorized sets: %v", err)
+	}
+	n := msp.Mat.Cols()
+
+	// the conversion of BooleanToMSP
+	converted := &MSP{P: msp.P, Mat: msp.Mat.Copy(), RowToAttrib: msp.RowToAttrib}
+	if err := converted.ApplyLinearTransform(OnesTransform(n)); err != nil {
+		t.Fatalf("Error while transforming the msp: %v", err)
+	}
+	assert.True(t, converted.Mat.Equal(mspOnes.Mat))
+
+	// a random transformation keeping [1, 0,..., 0] in place
+	// preserves the policy
+	tr, err := data.NewRandomMatrix(n, n, sample.NewUniform(p))
+	if err != nil {
+		t.Fatalf("Error during random matrix generation: %v", err)
+	}
+	tr[0] = data.NewConstantVector(n, big.NewInt(0))
+	tr[0][0].SetInt64(1)
+	randomized := &MSP{P: p, Mat: msp.Mat.Copy(), RowToAttrib: msp.RowToAttrib}
+	if err := randomized.ApplyLinearTransform(tr); err != nil {
+		t.Fatalf("Error while transforming the msp: %v", err)
+	}
+	setsRandomized, err := randomized.AuthorizedSets(p, false)
+	if err != nil {
+		t.Fatalf("Error while enumerating authorized sets: %v", err)
+	}
+	assert.Equal(t, sets, setsRandomized)
+
+	// a transformation that is not invertible or of wrong dimensions
+	// is rejected and the msp is not changed
+	mat := msp.Mat.Copy()
+	assert.Error(t, msp.ApplyLinearTransform(data.NewConstantMatrix(n, n, big.NewInt(1))))
+	assert.Error(t, msp.ApplyLinearTransform(OnesTransform(n+1)))
+	assert.True(t, msp.Mat.Equal(mat))
+}
+
+func TestMSP_IsSatisfiable(t *testing.T) {
+	p := bn256.Order
+	for _, ones := range []bool{false, true} {
+		msp, err := BooleanToMSP("a AND (b OR c)", ones)
+		if err != nil {
+			t.Fatalf("Error while processing a boolean expression: %v", err)
+		}
+		assert.True(t, msp.IsSatisfiable(p, ones))
+		assert.False(t, msp.IsTautology(p, ones, nil))
+
+		msp, err = BooleanToMSP("a OR b OR c", ones)
+		if err != nil {
+			t.Fatalf("Error while processing a boolean expression: %v", err)
+		}
+		assert.True(t, msp.IsSatisfiable(p, ones))
+		assert.True(t, msp.IsTautology(p, ones, nil))
+		assert.True(t, msp.IsTautology(p, ones, []string{"a", "c"}))
+		assert.False(t, msp.IsTautology(p, ones, []string{"a", "d"}))
+	}
+
+	// the rows can never span [1, 0]
+	msp := &MSP{Mat: data.Matrix{
+		data.Vector{big.NewInt(0), big.NewInt(1)},
+		data.Vector{big.NewInt(0), big.NewInt(2)},
+	}, RowToAttrib: []string{"a", "b"}}
+	assert.False(t, msp.IsSatisfiable(p, false))
+	assert.False(t, msp.IsTautology(p, false, nil))
+	assert.False(t, (&MSP{}).IsSatisfiable(p, false))
+}
+
+func TestMSP_SatisfyingRows(t *testing.T) {
+	msp := &MSP{Mat: data.Matrix{
+		data.Vector{big.NewInt(1), big.NewInt(1)},
+		data.Vector{big.NewInt(0), big.NewInt(2)},
+		data.Vector{big.NewInt(3), big.NewInt(0)},
+	}, RowToAttrib: []string{"a", "b", "a"}}
+
+	rows, mat := msp.SatisfyingRows([]string{"a", "c"})
+	assert.Equal(t, []int{0, 2}, rows)
+	assert.Equal(t, data.Matrix{msp.Mat[0], msp.Mat[2]}, mat)
+
+	rows, mat = msp.SatisfyingRows([]string{"c"})
+	assert.Empty(t, rows)
+	assert.Empty(t, mat)
+}
@@ -0,0 +1,267 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package abe
+
+import (
+	"crypto/aes"
+	cbc "crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/fentec-project/bn256"
+)
+
+// SymMode selects the symmetric encryption of the messages in the ABE
+// schemes. In all the modes the message is encrypted with AES using
+// a key derived from a random element of GT, which is encapsulated with
+// the ABE scheme. The size of the AES key is selected separately, see
+// DefaultSymKeySize.
+type SymMode int
+
+const (
+	// SymCBC is AES in the CBC mode with PKCS7 padding. It provides
+	// no integrity of the ciphertext and is kept as the default for
+	// backward compatibility.
+	SymCBC SymMode = iota
+	// SymGCM is AES in the GCM mode, an authenticated encryption
+	// that detects any modification of the ciphertext.
+	SymGCM
+)
+
+// DefaultSymKeySize is the size in bytes of the AES key used when the
+// key size of a scheme is not set, i.e. AES-256 is used by default. The
+// key size can be set to 16, 24 or 32 bytes, selecting AES-128, AES-192
+// or AES-256. The key is obtained by truncating the SHA-256 hash of the
+// encapsulated element of GT. The size is recorded in the ciphertext,
+// where 0 also denotes the default size.
+const DefaultSymKeySize = 32
+
+// SymEnvelope holds a message encrypted with AES under a key derived
+// from an element of GT. It is the symmetric part of the ciphertexts of
+// the ABE schemes, which encapsulate the element of GT, but it can also
+// be used on its own with any element of GT as the key. In the CBC mode
+// Iv is set, while in the GCM mode Nonce and Tag are set.
+type SymEnvelope struct {
+	Mode    SymMode // mode of symmetric encryption
+	KeySize int     // size of the AES key in bytes, DefaultSymKeySize if 0
+	SymEnc  []byte  // symmetric encryption of the message
+	Iv      []byte  // initialization vector for symmetric encryption in the CBC mode
+	Nonce   []byte  // nonce for symmetric encryption in the GCM mode
+	Tag     []byte  // authentication tag of symmetric encryption in the GCM mode
+}
+
+// newSymBlock derives an AES key of keySize bytes from keyGt and
+// returns the block cipher with this key. If keySize is 0, the
+// default key size is used.
+func newSymBlock(keyGt *bn256.GT, keySize int) (cbc.Block, error) {
+	if keySize == 0 {
+		keySize = DefaultSymKeySize
+	}
+	if keySize != 16 && keySize != 24 && keySize != 32 {
+		return nil, fmt.Errorf("AES key size should be 16, 24 or 32 bytes")
+	}
+	key := sha256.Sum256([]byte(keyGt.String()))
+
+	return aes.NewCipher(key[:keySize])
+}
+
+// Seal encrypts plaintext in the mode e.Mode with the AES key of
+// e.KeySize bytes derived from key, and stores the encryption in e.
+// If e.KeySize is 0, it is set to DefaultSymKeySize.
+func (e *SymEnvelope) Seal(key *bn256.GT, plaintext []byte) error {
+	if e.KeySize == 0 {
+		e.KeySize = DefaultSymKeySize
+	}
+	c, err := newSymBlock(key, e.KeySize)
+	if err != nil {
+		return err
+	}
+
+	return e.sealWithBlock(c, plaintext)
+}
+
+// sealWithBlock works as Seal, but with the block cipher c already
+// derived from the key, so that it can be reused for several messages.
+// A fresh initialization vector or nonce is sampled for each message.
+func (e *SymEnvelope) sealWithBlock(c cbc.Block, plaintext []byte) error {
+	switch e.Mode {
+	case SymCBC:
+		iv := make([]byte, c.BlockSize())
+		_, err := io.ReadFull(rand.Reader, iv)
+		if err != nil {
+			return err
+		}
+		encrypterCBC := cbc.NewCBCEncrypter(c, iv)
+
+		// message is padded according to pkcs7 standard
+		padLen := c.BlockSize() - (len(plaintext) % c.BlockSize())
+		msgPad := make([]byte, len(plaintext)+padLen)
+		copy(msgPad, plaintext)
+		for i := len(plaintext); i < len(msgPad); i++ {
+			msgPad[i] = byte(padLen)
+		}
+
+		symEnc := make([]byte, len(msgPad))
+		encrypterCBC.CryptBlocks(symEnc, msgPad)
+
+		e.SymEnc, e.Iv, e.Nonce, e.Tag = symEnc, iv, nil, nil
+		return nil
+	case SymGCM:
+		gcm, err := cbc.NewGCM(c)
+		if err != nil {
+			return err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		_, err = io.ReadFull(rand.Reader, nonce)
+		if err != nil {
+			return err
+		}
+
+		sealed := gcm.Seal(nil, nonce, plaintext, nil)
+		tagStart := len(sealed) - gcm.Overhead()
+
+		e.SymEnc, e.Iv, e.Nonce, e.Tag = sealed[:tagStart], nil, nonce, sealed[tagStart:]
+		return nil
+	default:
+		return fmt.Errorf("unknown symmetric encryption mode")
+	}
+}
+
+// Open decrypts the message in e with the AES key derived from key.
+// The padding is removed from the message. An error is returned if
+// the decryption failed, in the GCM mode also if the envelope was
+// modified.
+func (e *SymEnvelope) Open(key *bn256.GT) ([]byte, error) {
+	switch e.Mode {
+	case SymCBC:
+		msgPad, err := e.decryptCBC(key)
+		if err != nil {
+			return nil, err
+		}
+
+		return unpadPKCS7(msgPad, aes.BlockSize)
+	case SymGCM:
+		return e.decryptGCM(key)
+	default:
+		return nil, fmt.Errorf("unknown symmetric encryption mode")
+	}
+}
+
+// unpadPKCS7 removes the pkcs7 padding from msgPad. An error is
+// returned if the padding is not of the proper form, i.e. if the
+// message is empty, or if the padding length is not between 1 and the
+// block size or its bytes are not all equal to it.
+func unpadPKCS7(msgPad []byte, blockSize int) ([]byte, error) {
+	if len(msgPad) == 0 {
+		return nil, fmt.Errorf("failed to decrypt")
+	}
+	padLen := int(msgPad[len(msgPad)-1])
+	if padLen == 0 || padLen > blockSize || padLen > len(msgPad) {
+		return nil, fmt.Errorf("failed to decrypt")
+	}
+	for _, b := range msgPad[len(msgPad)-padLen:] {
+		if int(b) != padLen {
+			return nil, fmt.Errorf("failed to decrypt")
+		}
+	}
+
+	return msgPad[:len(msgPad)-padLen], nil
+}
+
+// openExact works as Open, but returns the first plaintextLen bytes of
+// the message instead of reading the padding. An error is returned if
+// plaintextLen does not match the length of the ciphertext.
+func (e *SymEnvelope) openExact(key *bn256.GT, plaintextLen int) ([]byte, error) {
+	switch e.Mode {
+	case SymCBC:
+		return e.decryptCBCExact(key, plaintextLen)
+	case SymGCM:
+		if plaintextLen != len(e.SymEnc) {
+			return nil, fmt.Errorf("plaintext length does not match the length of the ciphertext")
+		}
+		return e.decryptGCM(key)
+	default:
+		return nil, fmt.Errorf("unknown symmetric encryption mode")
+	}
+}
+
+// decryptGCM decrypts and authenticates the message in the GCM mode.
+func (e *SymEnvelope) decryptGCM(key *bn256.GT) ([]byte, error) {
+	c, err := newSymBlock(key, e.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cbc.NewGCM(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != gcm.NonceSize() || len(e.Tag) != gcm.Overhead() {
+		return nil, fmt.Errorf("nonce or tag is not of a valid length")
+	}
+
+	sealed := make([]byte, 0, len(e.SymEnc)+len(e.Tag))
+	sealed = append(sealed, e.SymEnc...)
+	sealed = append(sealed, e.Tag...)
+	msg, err := gcm.Open(nil, e.Nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt")
+	}
+
+	return msg, nil
+}
+
+// decryptCBC derives an AES key from key and decrypts the message in
+// the CBC mode. The returned message still includes the padding. An
+// error is returned if the ciphertext or the initialization vector are
+// not of a valid length.
+func (e *SymEnvelope) decryptCBC(key *bn256.GT) ([]byte, error) {
+	c, err := newSymBlock(key, e.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Iv) != c.BlockSize() {
+		return nil, fmt.Errorf("initialization vector is not of a valid length")
+	}
+	if len(e.SymEnc) == 0 || len(e.SymEnc)%c.BlockSize() != 0 {
+		return nil, fmt.Errorf("symmetric ciphertext is not of a valid length")
+	}
+
+	msgPad := make([]byte, len(e.SymEnc))
+	decrypter := cbc.NewCBCDecrypter(c, e.Iv)
+	decrypter.CryptBlocks(msgPad, e.SymEnc)
+
+	return msgPad, nil
+}
+
+// decryptCBCExact decrypts the message as decryptCBC and returns the
+// first plaintextLen bytes of it, ignoring the padding. Since pkcs7
+// padding adds between 1 and a block size of bytes, an error is
+// returned if plaintextLen is not within these limits.
+func (e *SymEnvelope) decryptCBCExact(key *bn256.GT, plaintextLen int) ([]byte, error) {
+	if plaintextLen < 0 || plaintextLen >= len(e.SymEnc) || len(e.SymEnc)-plaintextLen > aes.BlockSize {
+		return nil, fmt.Errorf("plaintext length does not match the length of the ciphertext")
+	}
+
+	msgPad, err := e.decryptCBC(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return msgPad[:plaintextLen], nil
+}
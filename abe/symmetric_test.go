@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package abe_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/abe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSymEnvelope(t *testing.T) {
+	_, key, err := bn256.RandomGT(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate a random key: %v", err)
+	}
+	_, otherKey, err := bn256.RandomGT(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate a random key: %v", err)
+	}
+	msg := []byte("Attack at dawn!\x00\x10")
+
+	for _, mode := range []abe.SymMode{abe.SymCBC, abe.SymGCM} {
+		for _, keySize := range []int{0, 16, 24, 32} {
+			env := &abe.SymEnvelope{Mode: mode, KeySize: keySize}
+			if err := env.Seal(key, msg); err != nil {
+				t.Fatalf("Failed to seal: %v", err)
+			}
+			if keySize == 0 {
+				assert.Equal(t, abe.DefaultSymKeySize, env.KeySize)
+			}
+			dec, err := env.Open(key)
+			if err != nil {
+				t.Fatalf("Failed to open: %v", err)
+			}
+			assert.Equal(t, msg, dec)
+
+			// a different key does not reveal the message
+			dec, err = env.Open(otherKey)
+			if err == nil {
+				assert.NotEqual(t, msg, dec)
+			}
+		}
+	}
+
+	// sealing again in another mode replaces the previous encryption
+	env := &abe.SymEnvelope{Mode: abe.SymGCM}
+	if err := env.Seal(key, msg); err != nil {
+		t.Fatalf("Failed to seal: %v", err)
+	}
+	env.Mode = abe.SymCBC
+	if err := env.Seal(key, msg); err != nil {
+		t.Fatalf("Failed to seal: %v", err)
+	}
+	assert.Nil(t, env.Nonce)
+	assert.Nil(t, env.Tag)
+
+	assert.Error(t, (&abe.SymEnvelope{KeySize: 20}).Seal(key, msg))
+	assert.Error(t, (&abe.SymEnvelope{Mode: abe.SymMode(7)}).Seal(key, msg))
+}
+
+func TestSymEnvelope_Padding(t *testing.T) {
+	_, key, err := bn256.RandomGT(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate a random key: %v", err)
+	}
+	aesKey := sha256.Sum256([]byte(key.String()))
+	block, err := aes.NewCipher(aesKey[:])
+	if err != nil {
+		t.Fatalf("Failed to create the block cipher: %v", err)
+	}
+
+	// seal encrypts a message that is already padded
+	seal := func(msgPad []byte) *abe.SymEnvelope {
+		iv := make([]byte, aes.BlockSize)
+		symEnc := make([]byte, len(msgPad))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(symEnc, msgPad)
+		return &abe.SymEnvelope{Mode: abe.SymCBC, SymEnc: symEnc, Iv: iv}
+	}
+	padded := func(last ...byte) []byte {
+		msgPad := make([]byte, 2*aes.BlockSize)
+		copy(msgPad[len(msgPad)-len(last):], last)
+		return msgPad
+	}
+
+	dec, err := seal(padded(3, 3, 3)).Open(key)
+	if err != nil {
+		t.Fatalf("Failed to open: %v", err)
+	}
+	assert.Equal(t, make([]byte, 2*aes.BlockSize-3), dec)
+	dec, err = seal(bytes16(16)).Open(key)
+	if err != nil {
+		t.Fatalf("Failed to open: %v", err)
+	}
+	assert.Empty(t, dec)
+
+	// zero, too long and inconsistent padding is rejected
+	for _, msgPad := range [][]byte{padded(0), padded(17), padded(33), padded(1, 3, 3), padded(16)} {
+		_, err = seal(msgPad).Open(key)
+		assert.Error(t, err)
+	}
+
+	// so are truncated and empty ciphertexts
+	env := seal(padded(3, 3, 3))
+	for _, symEnc := range [][]byte{env.SymEnc[:aes.BlockSize+1], env.SymEnc[:0], nil} {
+		_, err = (&abe.SymEnvelope{Mode: abe.SymCBC, SymEnc: symEnc, Iv: env.Iv}).Open(key)
+		assert.Error(t, err)
+	}
+}
+
+// bytes16 returns a block with all the bytes equal to b.
+func bytes16(b byte) []byte {
+	block := make([]byte, aes.BlockSize)
+	for i := range block {
+		block[i] = b
+	}
+
+	return block
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import (
+	"fmt"
+
+	"github.com/fentec-project/bn256"
+)
+
+// CommitG1 returns a Pedersen-style commitment to vector v with
+// the given bases, i.e. v_1 * bases_1 + ... + v_n * bases_n (in
+// additive notation). Negative entries of v are interpreted modulo
+// the order of the group. The sum is computed as a single
+// multi-scalar multiplication. It returns an error if v and bases are
+// not of the same length.
+//
+// The commitment is binding only if the discrete logarithms between
+// the bases are unknown, e.g. if the bases are obtained by hashing
+// to the group; to make it hiding, one of the entries of v should be
+// uniformly random.
+func CommitG1(v Vector, bases VectorG1) (*bn256.G1, error) {
+	if len(v) != len(bases) {
+		return nil, fmt.Errorf("vector and bases should be of the same length")
+	}
+
+	return multiExpG1(bases, v), nil
+}
+
+// CommitG2 works as CommitG1, but with the bases in the BN256.G2 group.
+func CommitG2(v Vector, bases VectorG2) (*bn256.G2, error) {
+	if len(v) != len(bases) {
+		return nil, fmt.Errorf("vector and bases should be of the same length")
+	}
+
+	return multiExpG2(bases, v), nil
+}
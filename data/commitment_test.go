@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/sample"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitG(t *testing.T) {
+	// with 3 and 6 elements both the separate scalar multiplications
+	// and the multi-scalar multiplication are used
+	for _, n := range []int{3, 6} {
+		v, err := NewRandomVector(n, sample.NewUniformRange(big.NewInt(-1000), big.NewInt(1000)))
+		if err != nil {
+			t.Fatalf("Error during random vector generation: %v", err)
+		}
+		basesG1 := make(VectorG1, n)
+		basesG2 := make(VectorG2, n)
+		for i := 0; i < n; i++ {
+			basesG1[i], err = bn256.HashG1("commit " + strconv.Itoa(i))
+			if err != nil {
+				t.Fatalf("Error during hashing: %v", err)
+			}
+			basesG2[i], err = bn256.HashG2("commit " + strconv.Itoa(i))
+			if err != nil {
+				t.Fatalf("Error during hashing: %v", err)
+			}
+		}
+
+		commitG1, err := CommitG1(v, basesG1)
+		if err != nil {
+			t.Fatalf("Error during commitment: %v", err)
+		}
+		commitG2, err := CommitG2(v, basesG2)
+		if err != nil {
+			t.Fatalf("Error during commitment: %v", err)
+		}
+
+		sumG1 := new(bn256.G1).ScalarBaseMult(big.NewInt(0))
+		for _, e := range v.MulVecG1(basesG1) {
+			sumG1 = new(bn256.G1).Add(sumG1, e)
+		}
+		sumG2 := new(bn256.G2).ScalarBaseMult(big.NewInt(0))
+		for _, e := range v.MulVecG2(basesG2) {
+			sumG2 = new(bn256.G2).Add(sumG2, e)
+		}
+		assert.Equal(t, sumG1.String(), commitG1.String())
+		assert.Equal(t, sumG2.String(), commitG2.String())
+
+		// the commitment is the same for the entries reduced modulo the order
+		commitMod, err := CommitG1(v.Mod(bn256.Order), basesG1)
+		if err != nil {
+			t.Fatalf("Error during commitment: %v", err)
+		}
+		assert.Equal(t, commitG1.String(), commitMod.String())
+
+		_, err = CommitG1(v[1:], basesG1)
+		assert.Error(t, err)
+		_, err = CommitG2(v, basesG2[1:])
+		assert.Error(t, err)
+	}
+}
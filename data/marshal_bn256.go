@@ -0,0 +1,237 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/fentec-project/bn256"
+)
+
+// The binary encoding of a vector of group elements starts with the
+// number of its elements given as an 8 byte big-endian integer,
+// followed by the elements marshaled with bn256. The encoding of a
+// matrix starts with the number of its rows, followed by the encodings
+// of the rows.
+
+// minMarshaledG1 and minMarshaledG2 are the minimal lengths of the
+// marshaled elements of G1 and G2, used to reject too large lengths
+// before allocating.
+const (
+	minMarshaledG1 = 64
+	minMarshaledG2 = 1
+)
+
+// MarshalBinary encodes vector v into a binary form. It returns
+// an error if an element of v is nil.
+func (v VectorG1) MarshalBinary() ([]byte, error) {
+	res := appendLen(nil, len(v))
+	for _, e := range v {
+		if e == nil {
+			return nil, fmt.Errorf("vector includes a nil element")
+		}
+		res = append(res, e.Marshal()...)
+	}
+
+	return res, nil
+}
+
+// UnmarshalBinary decodes a vector encoded by MarshalBinary into v.
+// It returns an error if data is truncated, has trailing bytes, or
+// includes an element that is not a point of the curve.
+func (v *VectorG1) UnmarshalBinary(data []byte) error {
+	vec, rest, err := decodeVectorG1(data)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected trailing data")
+	}
+	*v = vec
+
+	return nil
+}
+
+// MarshalBinary encodes vector v into a binary form. It returns
+// an error if an element of v is nil.
+func (v VectorG2) MarshalBinary() ([]byte, error) {
+	res := appendLen(nil, len(v))
+	for _, e := range v {
+		if e == nil {
+			return nil, fmt.Errorf("vector includes a nil element")
+		}
+		res = append(res, e.Marshal()...)
+	}
+
+	return res, nil
+}
+
+// UnmarshalBinary decodes a vector encoded by MarshalBinary into v.
+// It returns an error if data is truncated, has trailing bytes, or
+// includes an element that is not a point of the curve.
+func (v *VectorG2) UnmarshalBinary(data []byte) error {
+	vec, rest, err := decodeVectorG2(data)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected trailing data")
+	}
+	*v = vec
+
+	return nil
+}
+
+// MarshalBinary encodes matrix m into a binary form. It returns
+// an error if an element of m is nil.
+func (m MatrixG1) MarshalBinary() ([]byte, error) {
+	res := appendLen(nil, len(m))
+	for _, row := range m {
+		rowBytes, err := row.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, rowBytes...)
+	}
+
+	return res, nil
+}
+
+// UnmarshalBinary decodes a matrix encoded by MarshalBinary into m.
+// It returns an error if data is truncated, has trailing bytes, or
+// includes an element that is not a point of the curve.
+func (m *MatrixG1) UnmarshalBinary(data []byte) error {
+	n, rest, err := readLen(data, 8)
+	if err != nil {
+		return err
+	}
+	mat := make(MatrixG1, n)
+	for i := range mat {
+		mat[i], rest, err = decodeVectorG1(rest)
+		if err != nil {
+			return err
+		}
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected trailing data")
+	}
+	*m = mat
+
+	return nil
+}
+
+// MarshalBinary encodes matrix m into a binary form. It returns
+// an error if an element of m is nil.
+func (m MatrixG2) MarshalBinary() ([]byte, error) {
+	res := appendLen(nil, len(m))
+	for _, row := range m {
+		rowBytes, err := row.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, rowBytes...)
+	}
+
+	return res, nil
+}
+
+// UnmarshalBinary decodes a matrix encoded by MarshalBinary into m.
+// It returns an error if data is truncated, has trailing bytes, or
+// includes an element that is not a point of the curve.
+func (m *MatrixG2) UnmarshalBinary(data []byte) error {
+	n, rest, err := readLen(data, 8)
+	if err != nil {
+		return err
+	}
+	mat := make(MatrixG2, n)
+	for i := range mat {
+		mat[i], rest, err = decodeVectorG2(rest)
+		if err != nil {
+			return err
+		}
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected trailing data")
+	}
+	*m = mat
+
+	return nil
+}
+
+// decodeVectorG1 decodes a vector from the beginning of data and
+// returns it together with the remaining data.
+func decodeVectorG1(data []byte) (VectorG1, []byte, error) {
+	n, rest, err := readLen(data, minMarshaledG1)
+	if err != nil {
+		return nil, nil, err
+	}
+	vec := make(VectorG1, n)
+	for i := range vec {
+		vec[i] = new(bn256.G1)
+		rest, err = vec[i].Unmarshal(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return vec, rest, nil
+}
+
+// decodeVectorG2 decodes a vector from the beginning of data and
+// returns it together with the remaining data.
+func decodeVectorG2(data []byte) (VectorG2, []byte, error) {
+	n, rest, err := readLen(data, minMarshaledG2)
+	if err != nil {
+		return nil, nil, err
+	}
+	vec := make(VectorG2, n)
+	for i := range vec {
+		vec[i] = new(bn256.G2)
+		rest, err = vec[i].Unmarshal(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return vec, rest, nil
+}
+
+// appendLen appends n to b as an 8 byte big-endian integer.
+func appendLen(b []byte, n int) []byte {
+	lenBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(lenBytes, uint64(n))
+
+	return append(b, lenBytes...)
+}
+
+// readLen reads a length from the beginning of data and returns it
+// together with the remaining data. Since each of the counted items
+// takes at least minItemLen bytes, a length that does not fit into
+// the remaining data is rejected.
+func readLen(data []byte, minItemLen int) (int, []byte, error) {
+	if len(data) < 8 {
+		return 0, nil, fmt.Errorf("not enough data")
+	}
+	n := binary.BigEndian.Uint64(data[:8])
+	rest := data[8:]
+	if n > uint64(len(rest)/minItemLen) {
+		return 0, nil, fmt.Errorf("not enough data")
+	}
+
+	return int(n), rest, nil
+}
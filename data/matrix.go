@@ -19,6 +19,7 @@ package data
 import (
 	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/sample"
@@ -129,6 +130,36 @@ func (m Matrix) DimsMatch(other Matrix) bool {
 	return m.Rows() == other.Rows() && m.Cols() == other.Cols()
 }
 
+// Equal checks whether matrices m and other are of the same
+// dimensions and have equal entries.
+func (m Matrix) Equal(other Matrix) bool {
+	if len(m) != len(other) {
+		return false
+	}
+	for i, row := range m {
+		if !row.Equal(other[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// EqualMod checks whether matrices m and other are of the same
+// dimensions and have entries that are equal modulo p.
+func (m Matrix) EqualMod(other Matrix, p *big.Int) bool {
+	if len(m) != len(other) {
+		return false
+	}
+	for i, row := range m {
+		if !row.EqualMod(other[i], p) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // GetCol returns i-th column of matrix m as a vector.
 // It returns error if i >= the number of m's columns.
 func (m Matrix) GetCol(i int) (Vector, error) {
@@ -289,6 +320,54 @@ func (m Matrix) MulScalar(x *big.Int) Matrix {
 	})
 }
 
+// SumRows returns a vector whose i-th element is the sum of
+// the elements of the i-th row of matrix m.
+func (m Matrix) SumRows() Vector {
+	res := make(Vector, m.Rows())
+	for i, row := range m {
+		res[i] = row.Sum()
+	}
+
+	return res
+}
+
+// ScaleRows multiplies the i-th row of matrix m by scalars[i], i.e. it
+// computes diag(scalars) * m. The result is returned in a new Matrix.
+// Error is returned if the number of rows of m differs from the number
+// of elements of scalars.
+func (m Matrix) ScaleRows(scalars Vector) (Matrix, error) {
+	if m.Rows() != len(scalars) {
+		return nil, fmt.Errorf("number of scalars should match the number of rows")
+	}
+
+	res := make(Matrix, m.Rows())
+	for i, row := range m {
+		res[i] = row.MulScalar(scalars[i])
+	}
+
+	return res, nil
+}
+
+// ScaleCols multiplies the j-th column of matrix m by scalars[j], i.e. it
+// computes m * diag(scalars). The result is returned in a new Matrix.
+// Error is returned if the number of columns of m differs from the number
+// of elements of scalars.
+func (m Matrix) ScaleCols(scalars Vector) (Matrix, error) {
+	if m.Cols() != len(scalars) {
+		return nil, fmt.Errorf("number of scalars should match the number of columns")
+	}
+
+	res := make(Matrix, m.Rows())
+	for i, row := range m {
+		res[i] = make(Vector, len(row))
+		for j, e := range row {
+			res[i][j] = new(big.Int).Mul(e, scalars[j])
+		}
+	}
+
+	return res, nil
+}
+
 // MulVec multiplies matrix m and vector v.
 // It returns the resulting vector.
 // Error is returned if the number of columns of m differs from the number
@@ -306,6 +385,31 @@ func (m Matrix) MulVec(v Vector) (Vector, error) {
 	return res, nil
 }
 
+// MulVecMod multiplies matrix m and vector v modulo p. In contrast
+// to MulVec, the products are accumulated in place and each element of
+// the result is reduced modulo p, which avoids allocating a new big.Int
+// for every product. The resulting vector has elements in [0, p).
+// Error is returned if the number of columns of m differs from the number
+// of elements of v.
+func (m Matrix) MulVecMod(v Vector, p *big.Int) (Vector, error) {
+	if m.Cols() != len(v) {
+		return nil, fmt.Errorf("cannot multiply matrix by a vector")
+	}
+
+	prod := new(big.Int)
+	res := make(Vector, m.Rows())
+	for i, row := range m {
+		res[i] = new(big.Int)
+		for j, c := range row {
+			prod.Mul(c, v[j])
+			res[i].Add(res[i], prod)
+		}
+		res[i].Mod(res[i], p)
+	}
+
+	return res, nil
+}
+
 // MulXMatY calculates the function x^T * m * y, where x and y are
 // vectors.
 func (m Matrix) MulXMatY(x, y Vector) (*big.Int, error) {
@@ -447,27 +551,26 @@ func (m Matrix) MulG2() MatrixG2 {
 // MatMulMatG1 multiplies m and other in the sense that
 // if other is t * [bn256.G1] for some matrix t, then the
 // function returns m * t * [bn256.G1] where m * t is a
-// matrix multiplication.
+// matrix multiplication. Each element of the product is computed
+// as a multi-scalar multiplication with the bucket (Pippenger)
+// method, which is considerably faster than separate scalar
+// multiplications unless m has only a few columns.
 func (m Matrix) MatMulMatG1(other MatrixG1) (MatrixG1, error) {
 	if m.Cols() != other.Rows() {
 		return nil, fmt.Errorf("cannot multiply matrices")
 	}
 
 	prod := make(MatrixG1, m.Rows())
-	for i := 0; i < m.Rows(); i++ {
+	for i := range prod {
 		prod[i] = make([]*bn256.G1, other.Cols())
-		for j := 0; j < other.Cols(); j++ {
-			prod[i][j] = new(bn256.G1).ScalarBaseMult(big.NewInt(0))
-			for k := 0; k < m.Cols(); k++ {
-				mik := new(big.Int).Set(m[i][k])
-				okj := new(bn256.G1).Set(other[k][j])
-				if m[i][k].Sign() == -1 {
-					okj.Neg(okj)
-					mik.Neg(mik)
-				}
-				tmp := new(bn256.G1).ScalarMult(okj, mik)
-				prod[i][j].Add(tmp, prod[i][j])
-			}
+	}
+	col := make([]*bn256.G1, other.Rows())
+	for j := 0; j < other.Cols(); j++ {
+		for k := range col {
+			col[k] = other[k][j]
+		}
+		for i := 0; i < m.Rows(); i++ {
+			prod[i][j] = multiExpG1(col, m[i])
 		}
 	}
 
@@ -477,27 +580,23 @@ func (m Matrix) MatMulMatG1(other MatrixG1) (MatrixG1, error) {
 // MatMulMatG2 multiplies m and other in the sense that
 // if other is t * [bn256.G2] for some matrix t, then the
 // function returns m * t * [bn256.G2] where m * t is a
-// matrix multiplication.
+// matrix multiplication. It is computed as in MatMulMatG1.
 func (m Matrix) MatMulMatG2(other MatrixG2) (MatrixG2, error) {
 	if m.Cols() != other.Rows() {
 		return nil, fmt.Errorf("cannot multiply matrices")
 	}
 
 	prod := make(MatrixG2, m.Rows())
-	for i := 0; i < m.Rows(); i++ {
+	for i := range prod {
 		prod[i] = make([]*bn256.G2, other.Cols())
-		for j := 0; j < other.Cols(); j++ {
-			prod[i][j] = new(bn256.G2).ScalarBaseMult(big.NewInt(0))
-			for k := 0; k < m.Cols(); k++ {
-				mik := new(big.Int).Set(m[i][k])
-				okj := new(bn256.G2).Set(other[k][j])
-				if m[i][k].Sign() == -1 {
-					okj.Neg(okj)
-					mik.Neg(mik)
-				}
-				tmp := new(bn256.G2).ScalarMult(okj, mik)
-				prod[i][j].Add(tmp, prod[i][j])
-			}
+	}
+	col := make([]*bn256.G2, other.Rows())
+	for j := 0; j < other.Cols(); j++ {
+		for k := range col {
+			col[k] = other[k][j]
+		}
+		for i := 0; i < m.Rows(); i++ {
+			prod[i][j] = multiExpG2(col, m[i])
 		}
 	}
 
@@ -515,17 +614,7 @@ func (m Matrix) MatMulVecG2(other VectorG2) (VectorG2, error) {
 
 	prod := make(VectorG2, m.Rows())
 	for j := 0; j < m.Rows(); j++ {
-		prod[j] = new(bn256.G2).ScalarBaseMult(big.NewInt(0))
-		for k := 0; k < m.Cols(); k++ {
-			mjk := new(big.Int).Set(m[j][k])
-			ok := new(bn256.G2).Set(other[k])
-			if m[j][k].Sign() == -1 {
-				ok.Neg(ok)
-				mjk.Neg(mjk)
-			}
-			tmp := new(bn256.G2).ScalarMult(ok, mjk)
-			prod[j].Add(tmp, prod[j])
-		}
+		prod[j] = multiExpG2(other, m[j])
 	}
 
 	return prod, nil
@@ -662,6 +751,163 @@ func (m Matrix) DeterminantGauss(p *big.Int) (*big.Int, error) {
 	return ret, nil
 }
 
+// RankMod returns the rank of matrix m over Z_p, where p should be
+// a prime number. The rank of an empty matrix is 0.
+func (m Matrix) RankMod(p *big.Int) int {
+	if m.Rows() == 0 || m.Cols() == 0 {
+		return 0
+	}
+	triang, err := m.Mod(p).GaussianElimination(p)
+	if err != nil {
+		return 0
+	}
+
+	rank := 0
+	for _, row := range triang {
+		for _, e := range row {
+			if e.Sign() != 0 {
+				rank++
+				break
+			}
+		}
+	}
+
+	return rank
+}
+
+// IsFullRank reports whether matrix m over Z_p has the largest possible
+// rank, i.e. the minimum of the number of its rows and columns. A square
+// matrix is of full rank iff it is invertible modulo p. An empty matrix
+// is not considered to be of full rank.
+func (m Matrix) IsFullRank(p *big.Int) bool {
+	if m.Rows() == 0 || m.Cols() == 0 {
+		return false
+	}
+	n := m.Rows()
+	if m.Cols() < n {
+		n = m.Cols()
+	}
+
+	return m.RankMod(p) == n
+}
+
+// MatrixLU represents an LU decomposition of a square matrix m over Z_p
+// with partial pivoting, i.e. P * m = L * U for a permutation matrix P,
+// a lower triangular matrix L with ones on the diagonal and an upper
+// triangular matrix U. Once computed, it can be reused to solve systems
+// with many right-hand sides, or to obtain the determinant and the
+// inverse of m without repeating the elimination.
+type MatrixLU struct {
+	lu   Matrix   // L below the diagonal and U on and above the diagonal
+	perm []int    // the i-th row of P * m is the perm[i]-th row of m
+	sign int      // the sign of the permutation P
+	p    *big.Int // the modulus
+}
+
+// LUMod computes the LU decomposition of matrix m over Z_p, where p
+// should be a prime number. It returns an error if m is empty, not
+// square or not invertible modulo p.
+func (m Matrix) LUMod(p *big.Int) (*MatrixLU, error) {
+	if m.Rows() == 0 || m.Cols() == 0 {
+		return nil, fmt.Errorf("the matrix should not be empty")
+	}
+	if m.Rows() != m.Cols() {
+		return nil, fmt.Errorf("the number of rows must equal the number of columns")
+	}
+
+	n := m.Rows()
+	lu := m.Mod(p)
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	sign := 1
+	for k := 0; k < n; k++ {
+		pivot := -1
+		for i := k; i < n; i++ {
+			if lu[i][k].Sign() != 0 {
+				pivot = i
+				break
+			}
+		}
+		if pivot == -1 {
+			return nil, fmt.Errorf("matrix non-invertable")
+		}
+		if pivot != k {
+			lu[k], lu[pivot] = lu[pivot], lu[k]
+			perm[k], perm[pivot] = perm[pivot], perm[k]
+			sign = -sign
+		}
+		inv := new(big.Int).ModInverse(lu[k][k], p)
+		for i := k + 1; i < n; i++ {
+			f := lu[i][k].Mul(lu[i][k], inv)
+			f.Mod(f, p)
+			for j := k + 1; j < n; j++ {
+				lu[i][j].Sub(lu[i][j], new(big.Int).Mul(f, lu[k][j]))
+				lu[i][j].Mod(lu[i][j], p)
+			}
+		}
+	}
+
+	return &MatrixLU{lu: lu, perm: perm, sign: sign, p: new(big.Int).Set(p)}, nil
+}
+
+// Solve solves the equation m * x = v over Z_p and returns x. It
+// returns an error if the length of v does not match the matrix.
+func (lu *MatrixLU) Solve(v Vector) (Vector, error) {
+	n := lu.lu.Rows()
+	if len(v) != n {
+		return nil, fmt.Errorf("dimensions should match: "+
+			"rows of the matrix %d, length of the vector %d", n, len(v))
+	}
+
+	// solve L * y = P * v
+	x := make(Vector, n)
+	for i := 0; i < n; i++ {
+		x[i] = new(big.Int).Set(v[lu.perm[i]])
+		for j := 0; j < i; j++ {
+			x[i].Sub(x[i], new(big.Int).Mul(lu.lu[i][j], x[j]))
+		}
+		x[i].Mod(x[i], lu.p)
+	}
+	// solve U * x = y
+	for i := n - 1; i >= 0; i-- {
+		for j := i + 1; j < n; j++ {
+			x[i].Sub(x[i], new(big.Int).Mul(lu.lu[i][j], x[j]))
+		}
+		x[i].Mul(x[i], new(big.Int).ModInverse(lu.lu[i][i], lu.p))
+		x[i].Mod(x[i], lu.p)
+	}
+
+	return x, nil
+}
+
+// Det returns the determinant of the decomposed matrix over Z_p.
+// Note that unlike DeterminantGauss, it takes into account the row
+// swaps, hence the results of the two might differ in the sign.
+func (lu *MatrixLU) Det() *big.Int {
+	det := big.NewInt(int64(lu.sign))
+	for i := 0; i < lu.lu.Rows(); i++ {
+		det.Mul(det, lu.lu[i][i])
+		det.Mod(det, lu.p)
+	}
+
+	return det
+}
+
+// Inverse returns the inverse of the decomposed matrix over Z_p.
+func (lu *MatrixLU) Inverse() Matrix {
+	n := lu.lu.Rows()
+	cols := make(Matrix, n)
+	for j := 0; j < n; j++ {
+		e := NewConstantVector(n, big.NewInt(0))
+		e[j].SetInt64(1)
+		cols[j], _ = lu.Solve(e) // error is impossible to happen
+	}
+
+	return cols.Transpose()
+}
+
 // GaussianEliminationSolver solves a vector equation mat * x = v and finds vector x,
 // using Gaussian elimination. Arithmetic operations are considered to be over
 // Z_p, where p should be a prime number. If such x does not exist, then the
@@ -747,8 +993,105 @@ func GaussianEliminationSolver(mat Matrix, v Vector, p *big.Int) (Vector, error)
 	return ret, nil
 }
 
-// Tensor creates a tensor product of matrices m and other.
-// The result is returned in a new Matrix.
+// SolveAndKernel solves a vector equation mat * x = v over Z_p, where p
+// should be a prime number. It returns a particular solution, in which
+// all the free variables are set to 0 as in GaussianEliminationSolver,
+// together with a basis of the kernel of mat, i.e. of the solutions of
+// mat * x = 0. Every solution of mat * x = v is the particular solution
+// plus a linear combination of the kernel vectors. The kernel basis is
+// empty if the solution is unique. If no solution exists, the function
+// returns an error.
+func SolveAndKernel(mat Matrix, v Vector, p *big.Int) (Vector, []Vector, error) {
+	if mat.Rows() == 0 || mat.Cols() == 0 {
+		return nil, nil, fmt.Errorf("the matrix should not be empty")
+	}
+	if mat.Rows() != len(v) {
+		return nil, nil, fmt.Errorf("dimensions should match: "+
+			"rows of the matrix %d, length of the vector %d", mat.Rows(), len(v))
+	}
+
+	// transform the extended matrix [mat | v] into the reduced
+	// row echelon form
+	rows, cols := mat.Rows(), mat.Cols()
+	m := make(Matrix, rows)
+	for i := 0; i < rows; i++ {
+		m[i] = make(Vector, cols+1)
+		for j := 0; j < cols; j++ {
+			m[i][j] = new(big.Int).Mod(mat[i][j], p)
+		}
+		m[i][cols] = new(big.Int).Mod(v[i], p)
+	}
+
+	pivotCols := make([]int, 0, rows)
+	isPivot := make([]bool, cols)
+	tmp := new(big.Int)
+	h := 0
+	for k := 0; k < cols && h < rows; k++ {
+		pivot := -1
+		for i := h; i < rows; i++ {
+			if m[i][k].Sign() != 0 {
+				pivot = i
+				break
+			}
+		}
+		if pivot == -1 {
+			continue
+		}
+		m[h], m[pivot] = m[pivot], m[h]
+
+		inv := new(big.Int).ModInverse(m[h][k], p)
+		for j := k; j <= cols; j++ {
+			m[h][j].Mul(m[h][j], inv)
+			m[h][j].Mod(m[h][j], p)
+		}
+		for i := 0; i < rows; i++ {
+			if i == h || m[i][k].Sign() == 0 {
+				continue
+			}
+			f := new(big.Int).Set(m[i][k])
+			for j := k; j <= cols; j++ {
+				m[i][j].Sub(m[i][j], tmp.Mul(f, m[h][j]))
+				m[i][j].Mod(m[i][j], p)
+			}
+		}
+		pivotCols = append(pivotCols, k)
+		isPivot[k] = true
+		h++
+	}
+
+	for i := h; i < rows; i++ {
+		if m[i][cols].Sign() != 0 {
+			return nil, nil, fmt.Errorf("no solution")
+		}
+	}
+
+	particular := NewConstantVector(cols, big.NewInt(0))
+	for i, k := range pivotCols {
+		particular[k].Set(m[i][cols])
+	}
+
+	kernel := make([]Vector, 0, cols-len(pivotCols))
+	for f := 0; f < cols; f++ {
+		if isPivot[f] {
+			continue
+		}
+		vec := NewConstantVector(cols, big.NewInt(0))
+		vec[f].SetInt64(1)
+		for i, k := range pivotCols {
+			vec[k].Neg(m[i][f])
+			vec[k].Mod(vec[k], p)
+		}
+		kernel = append(kernel, vec)
+	}
+
+	return particular, kernel, nil
+}
+
+// Tensor creates a tensor (Kronecker) product of matrices m and other.
+// If m is a k x l matrix and other is a r x s matrix, the result is
+// a kr x ls matrix consisting of k x l blocks, where the block at
+// position (i, j) equals m[i][j] * other. The result is returned in
+// a new Matrix.
 func (m Matrix) Tensor(other Matrix) Matrix {
 	prod := make(Matrix, m.Rows()*other.Rows())
 	for i := 0; i < prod.Rows(); i++ {
@@ -762,7 +1105,8 @@ func (m Matrix) Tensor(other Matrix) Matrix {
 }
 
 // ToVec creates a vector whose entries are entries of m
-// ordered as m_11, m_12,..., m_21, m_22,..., m_kl.
+// ordered as m_11, m_12,..., m_21, m_22,..., m_kl, i.e. the rows
+// of a k x l matrix m are concatenated into a vector of length kl.
 func (m Matrix) ToVec() Vector {
 	res := make(Vector, m.Rows()*m.Cols())
 	for i := 0; i < m.Rows(); i++ {
@@ -775,7 +1119,9 @@ func (m Matrix) ToVec() Vector {
 }
 
 // JoinCols joins matrices m and other in a new Matrix
-// with columns from both matrices.
+// with columns from both matrices, i.e. a k x l matrix m and
+// a k x s matrix other give a k x (l+s) matrix [m | other].
+// It returns an error if m and other have different numbers of rows.
 func (m Matrix) JoinCols(other Matrix) (Matrix, error) {
 	if m.Rows() != other.Rows() {
 		return nil, fmt.Errorf("dimensions do not fit")
@@ -797,7 +1143,10 @@ func (m Matrix) JoinCols(other Matrix) (Matrix, error) {
 }
 
 // JoinRows joins matrices m and other in a new Matrix
-// with rows from both matrices.
+// with rows from both matrices, i.e. a k x l matrix m and
+// a r x l matrix other give a (k+r) x l matrix with the rows
+// of m followed by the rows of other.
+// It returns an error if m and other have different numbers of columns.
 func (m Matrix) JoinRows(other Matrix) (Matrix, error) {
 	if m.Cols() != other.Cols() {
 		return nil, fmt.Errorf("dimensions do not fit")
@@ -819,6 +1168,8 @@ func (m Matrix) JoinRows(other Matrix) (Matrix, error) {
 }
 
 // Identity returns the identity matrix with given dimensions.
+// If rows and cols differ, the result is a rows x cols matrix
+// with ones on the main diagonal and zeros elsewhere.
 func Identity(rows, cols int) Matrix {
 	res := NewConstantMatrix(rows, cols, big.NewInt(0))
 	for i := 0; i < rows && i < cols; i++ {
@@ -827,3 +1178,36 @@ func Identity(rows, cols int) Matrix {
 
 	return res
 }
+
+// String produces a string representation of a matrix, where
+// each row is given by the string representation of a vector and
+// the rows are separated by new lines.
+func (m Matrix) String() string {
+	rows := make([]string, len(m))
+	for i, v := range m {
+		rows[i] = v.String()
+	}
+
+	return strings.Join(rows, "\n")
+}
+
+// ParseMatrix parses a matrix from its string representation given
+// by String, i.e. from rows separated by new lines, each row given
+// by decimal integers separated by white space. Empty lines are
+// ignored. It returns an error if some element is not a valid integer
+// or the rows are not of the same length.
+func ParseMatrix(s string) (Matrix, error) {
+	rows := make([]Vector, 0)
+	for i, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		v, err := ParseVector(line)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		rows = append(rows, v)
+	}
+
+	return NewMatrix(rows)
+}
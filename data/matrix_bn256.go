@@ -4,6 +4,7 @@ import (
 	"math/big"
 
 	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/internal"
 )
 
 // MatrixG1 wraps a slice of VectorG1 elements. It represents a row-major.
@@ -27,8 +28,9 @@ func (m MatrixG1) Cols() int {
 	return 0
 }
 
-// Add sums matrices m and other componentwise.
-// It returns the result in a new MatrixG1 instance.
+// Add sums matrices m and other componentwise (also m * other in
+// multiplicative notation). Both matrices are expected to have the
+// same dimensions. It returns the result in a new MatrixG1 instance.
 func (m MatrixG1) Add(other MatrixG1) MatrixG1 {
 	sum := make(MatrixG1, len(m))
 	for i := range sum {
@@ -38,7 +40,9 @@ func (m MatrixG1) Add(other MatrixG1) MatrixG1 {
 	return sum
 }
 
-// MulScalar multiplies matrix m by a scalar s.
+// MulScalar multiplies matrix m by a scalar s (in additive notation).
+// A negative s is handled by negating the elements of m and multiplying
+// them by |s|, hence the result is the same as for s mod bn256.Order.
 // It returns the result in a new MatrixG1 instance.
 func (m MatrixG1) MulScalar(s *big.Int) MatrixG1 {
 	out := make(MatrixG1, m.Rows())
@@ -51,19 +55,15 @@ func (m MatrixG1) MulScalar(s *big.Int) MatrixG1 {
 
 // MulVector multiplies matrix m by a vector v, i.e if
 // m is t * [bn256.G1] for some matrix t, then the result
-// is (t * v) [bn256.G1]
+// is (t * v) [bn256.G1]. The vector v should have m.Cols()
+// elements. Negative entries of v are handled the same way
+// as in MulScalar. It returns the result in a new VectorG1 instance.
 func (m MatrixG1) MulVector(v Vector) VectorG1 {
 	out := make(VectorG1, m.Rows())
 	for i := range out {
 		out[i] = new(bn256.G1).ScalarBaseMult(big.NewInt(0))
 		for k := 0; k < m.Cols(); k++ {
-			mik := new(bn256.G1).Set(m[i][k])
-			vk := new(big.Int).Set(v[k])
-			if v[k].Sign() == -1 {
-				vk.Neg(vk)
-				mik.Neg(mik)
-			}
-			tmp := new(bn256.G1).ScalarMult(mik, vk)
+			tmp := new(bn256.G1).ScalarMult(m[i][k], internal.ModPos(v[k], bn256.Order))
 			out[i].Add(tmp, out[i])
 		}
 	}
@@ -92,7 +92,22 @@ func (m MatrixG2) Cols() int {
 	return 0
 }
 
-// MulScalar multiplies matrix m by a scalar s
+// Add sums matrices m and other componentwise (also m * other in
+// multiplicative notation). Both matrices are expected to have the
+// same dimensions. It returns the result in a new MatrixG2 instance.
+func (m MatrixG2) Add(other MatrixG2) MatrixG2 {
+	sum := make(MatrixG2, len(m))
+	for i := range sum {
+		sum[i] = m[i].Add(other[i])
+	}
+
+	return sum
+}
+
+// MulScalar multiplies matrix m by a scalar s (in additive notation).
+// A negative s is handled by negating the elements of m and multiplying
+// them by |s|, hence the result is the same as for s mod bn256.Order.
+// It returns the result in a new MatrixG2 instance.
 func (m MatrixG2) MulScalar(s *big.Int) MatrixG2 {
 	out := make(MatrixG2, m.Rows())
 	for i := range out {
@@ -104,19 +119,15 @@ func (m MatrixG2) MulScalar(s *big.Int) MatrixG2 {
 
 // MulVector multiplies matrix m by a vector v, i.e if
 // m is t * [bn256.G2] for some matrix t, then the result
-// is (t * v) [bn256.G2]
+// is (t * v) [bn256.G2]. The vector v should have m.Cols()
+// elements. Negative entries of v are handled the same way
+// as in MulScalar. It returns the result in a new VectorG2 instance.
 func (m MatrixG2) MulVector(v Vector) VectorG2 {
 	out := make(VectorG2, m.Rows())
 	for i := range out {
 		out[i] = new(bn256.G2).ScalarBaseMult(big.NewInt(0))
 		for k := 0; k < m.Cols(); k++ {
-			mik := new(bn256.G2).Set(m[i][k])
-			vk := new(big.Int).Set(v[k])
-			if v[k].Sign() == -1 {
-				vk.Neg(vk)
-				mik.Neg(mik)
-			}
-			tmp := new(bn256.G2).ScalarMult(mik, vk)
+			tmp := new(bn256.G2).ScalarMult(m[i][k], internal.ModPos(v[k], bn256.Order))
 			out[i].Add(tmp, out[i])
 		}
 	}
@@ -0,0 +1,305 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/sample"
+	"github.com/stretchr/testify/assert"
+)
+
+func testMatricesG(t *testing.T) (Matrix, Matrix, Vector) {
+	m, err := NewMatrix([]Vector{
+		NewVector([]*big.Int{big.NewInt(1), big.NewInt(-2), big.NewInt(3)}),
+		NewVector([]*big.Int{big.NewInt(-4), big.NewInt(5), big.NewInt(0)}),
+	})
+	if err != nil {
+		t.Fatalf("Error during matrix generation: %v", err)
+	}
+	other, err := NewMatrix([]Vector{
+		NewVector([]*big.Int{big.NewInt(-7), big.NewInt(2), big.NewInt(6)}),
+		NewVector([]*big.Int{big.NewInt(4), big.NewInt(-9), big.NewInt(-1)}),
+	})
+	if err != nil {
+		t.Fatalf("Error during matrix generation: %v", err)
+	}
+	v := NewVector([]*big.Int{big.NewInt(-3), big.NewInt(2), big.NewInt(-5)})
+
+	return m, other, v
+}
+
+// stringsG converts a matrix or a vector of group elements into strings
+// so that equal elements compare equal regardless of their internal
+// (projective) representation.
+func stringsG(x interface{}) []string {
+	out := make([]string, 0)
+	switch x := x.(type) {
+	case MatrixG1:
+		for _, row := range x {
+			out = append(out, stringsG(row)...)
+		}
+	case MatrixG2:
+		for _, row := range x {
+			out = append(out, stringsG(row)...)
+		}
+	case VectorG1:
+		for _, e := range x {
+			out = append(out, e.String())
+		}
+	case VectorG2:
+		for _, e := range x {
+			out = append(out, e.String())
+		}
+	}
+
+	return out
+}
+
+func TestMatrixG1(t *testing.T) {
+	m, other, v := testMatricesG(t)
+	mG1 := m.MulG1()
+
+	sum, err := m.Add(other)
+	if err != nil {
+		t.Fatalf("Error during matrix addition: %v", err)
+	}
+	assert.Equal(t, stringsG(sum.MulG1()), stringsG(mG1.Add(other.MulG1())))
+
+	// a negative scalar gives the same result as its value modulo the group order
+	s := big.NewInt(-11)
+	assert.Equal(t, stringsG(m.MulScalar(s).MulG1()), stringsG(mG1.MulScalar(s)))
+	assert.Equal(t, stringsG(mG1.MulScalar(s)), stringsG(mG1.MulScalar(new(big.Int).Mod(s, bn256.Order))))
+
+	mv, err := m.MulVec(v)
+	if err != nil {
+		t.Fatalf("Error during matrix vector multiplication: %v", err)
+	}
+	assert.Equal(t, stringsG(mv.MulG1()), stringsG(mG1.MulVector(v)))
+	assert.Equal(t, stringsG(mG1.MulVector(v)), stringsG(mG1.MulVector(v.Mod(bn256.Order))))
+}
+
+func TestMatrixG2(t *testing.T) {
+	m, other, v := testMatricesG(t)
+	mG2 := m.MulG2()
+
+	sum, err := m.Add(other)
+	if err != nil {
+		t.Fatalf("Error during matrix addition: %v", err)
+	}
+	assert.Equal(t, stringsG(sum.MulG2()), stringsG(mG2.Add(other.MulG2())))
+
+	// a negative scalar gives the same result as its value modulo the group order
+	s := big.NewInt(-11)
+	assert.Equal(t, stringsG(m.MulScalar(s).MulG2()), stringsG(mG2.MulScalar(s)))
+	assert.Equal(t, stringsG(mG2.MulScalar(s)), stringsG(mG2.MulScalar(new(big.Int).Mod(s, bn256.Order))))
+
+	mv, err := m.MulVec(v)
+	if err != nil {
+		t.Fatalf("Error during matrix vector multiplication: %v", err)
+	}
+	assert.Equal(t, stringsG(mv.MulG2()), stringsG(mG2.MulVector(v)))
+	assert.Equal(t, stringsG(mG2.MulVector(v)), stringsG(mG2.MulVector(v.Mod(bn256.Order))))
+}
+
+// naiveMatMulMatG1 multiplies matrices as MatMulMatG1, but with separate
+// scalar multiplications.
+func naiveMatMulMatG1(m Matrix, other MatrixG1) MatrixG1 {
+	prod := make(MatrixG1, m.Rows())
+	for i := range prod {
+		prod[i] = make(VectorG1, other.Cols())
+		for j := range prod[i] {
+			col := make([]*bn256.G1, other.Rows())
+			for k := range col {
+				col[k] = other[k][j]
+			}
+			prod[i][j] = naiveMultiExpG1(col, m[i])
+		}
+	}
+
+	return prod
+}
+
+func TestMatrix_MatMulMatG(t *testing.T) {
+	sampler := sample.NewUniformRange(new(big.Int).Neg(bn256.Order), bn256.Order)
+	// inner dimensions below and above msmMinTerms and across window sizes
+	for _, n := range []int{1, 3, 4, 17, 40} {
+		m, err := NewRandomMatrix(2, n, sampler)
+		if err != nil {
+			t.Fatalf("Error during matrix generation: %v", err)
+		}
+		// include zero, small and exactly-order scalars
+		m[0][0] = big.NewInt(0)
+		m[1][0] = big.NewInt(-1)
+		if n > 1 {
+			m[0][1] = new(big.Int).Set(bn256.Order)
+		}
+		other, err := NewRandomMatrix(n, 3, sampler)
+		if err != nil {
+			t.Fatalf("Error during matrix generation: %v", err)
+		}
+		otherG1 := other.MulG1()
+		otherG2 := other.MulG2()
+
+		prodG1, err := m.MatMulMatG1(otherG1)
+		if err != nil {
+			t.Fatalf("Error during matrix multiplication: %v", err)
+		}
+		assert.Equal(t, stringsG(naiveMatMulMatG1(m, otherG1)), stringsG(prodG1))
+
+		prodG2, err := m.MatMulMatG2(otherG2)
+		if err != nil {
+			t.Fatalf("Error during matrix multiplication: %v", err)
+		}
+		prod, err := m.Mul(other)
+		if err != nil {
+			t.Fatalf("Error during matrix multiplication: %v", err)
+		}
+		assert.Equal(t, stringsG(prod.Mod(bn256.Order).MulG1()), stringsG(prodG1))
+		assert.Equal(t, stringsG(prod.Mod(bn256.Order).MulG2()), stringsG(prodG2))
+
+		col, prodCol := make(VectorG2, n), make(VectorG2, m.Rows())
+		for k := range col {
+			col[k] = otherG2[k][0]
+		}
+		for i := range prodCol {
+			prodCol[i] = prodG2[i][0]
+		}
+		prodVecG2, err := m.MatMulVecG2(col)
+		if err != nil {
+			t.Fatalf("Error during matrix vector multiplication: %v", err)
+		}
+		assert.Equal(t, stringsG(prodCol), stringsG(prodVecG2))
+	}
+}
+
+func benchmarkMatMulMatG1Input(b *testing.B) (Matrix, MatrixG1) {
+	sampler := sample.NewUniform(bn256.Order)
+	m, err := NewRandomMatrix(50, 50, sampler)
+	if err != nil {
+		b.Fatalf("Error during random generation: %v", err)
+	}
+	other, err := NewRandomMatrix(50, 50, sampler)
+	if err != nil {
+		b.Fatalf("Error during random generation: %v", err)
+	}
+
+	return m, other.MulG1()
+}
+
+func BenchmarkMatrix_MatMulMatG1(b *testing.B) {
+	m, other := benchmarkMatMulMatG1Input(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = m.MatMulMatG1(other)
+	}
+}
+
+func BenchmarkMatrix_MatMulMatG1Naive(b *testing.B) {
+	m, other := benchmarkMatMulMatG1Input(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = naiveMatMulMatG1(m, other)
+	}
+}
+
+func TestMatrixG_MarshalBinary(t *testing.T) {
+	// m includes a zero, hence also the identity elements are encoded
+	m, _, _ := testMatricesG(t)
+	mG1, mG2 := m.MulG1(), m.MulG2()
+
+	mG1Bytes, err := mG1.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error during marshaling: %v", err)
+	}
+	var mG1Dec MatrixG1
+	if err := mG1Dec.UnmarshalBinary(mG1Bytes); err != nil {
+		t.Fatalf("Error during unmarshaling: %v", err)
+	}
+	assert.Equal(t, stringsG(mG1), stringsG(mG1Dec))
+
+	mG2Bytes, err := mG2.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error during marshaling: %v", err)
+	}
+	var mG2Dec MatrixG2
+	if err := mG2Dec.UnmarshalBinary(mG2Bytes); err != nil {
+		t.Fatalf("Error during unmarshaling: %v", err)
+	}
+	assert.Equal(t, stringsG(mG2), stringsG(mG2Dec))
+
+	vG1Bytes, err := mG1[1].MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error during marshaling: %v", err)
+	}
+	var vG1Dec VectorG1
+	if err := vG1Dec.UnmarshalBinary(vG1Bytes); err != nil {
+		t.Fatalf("Error during unmarshaling: %v", err)
+	}
+	assert.Equal(t, stringsG(mG1[1]), stringsG(vG1Dec))
+
+	vG2Bytes, err := mG2[1].MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error during marshaling: %v", err)
+	}
+	var vG2Dec VectorG2
+	if err := vG2Dec.UnmarshalBinary(vG2Bytes); err != nil {
+		t.Fatalf("Error during unmarshaling: %v", err)
+	}
+	assert.Equal(t, stringsG(mG2[1]), stringsG(vG2Dec))
+
+	// truncated data, trailing data and points not on the curve
+	// are rejected
+	assert.Error(t, mG1Dec.UnmarshalBinary(mG1Bytes[:len(mG1Bytes)-1]))
+	assert.Error(t, mG2Dec.UnmarshalBinary(mG2Bytes[:len(mG2Bytes)-1]))
+	assert.Error(t, vG1Dec.UnmarshalBinary(append(vG1Bytes, 0)))
+	assert.Error(t, vG2Dec.UnmarshalBinary(vG2Bytes[:7]))
+	malformed := append([]byte(nil), vG1Bytes...)
+	malformed[len(malformed)-1] ^= 1
+	assert.Error(t, vG1Dec.UnmarshalBinary(malformed))
+
+	_, err = VectorG1{nil}.MarshalBinary()
+	assert.Error(t, err)
+}
+
+func TestVectorG1_PairDot(t *testing.T) {
+	sampler := sample.NewUniformRange(big.NewInt(-100), big.NewInt(100))
+	x, err := NewRandomVector(5, sampler)
+	if err != nil {
+		t.Fatalf("Error during random vector generation: %v", err)
+	}
+	y, err := NewRandomVector(5, sampler)
+	if err != nil {
+		t.Fatalf("Error during random vector generation: %v", err)
+	}
+	xy, err := x.Dot(y)
+	if err != nil {
+		t.Fatalf("Error during inner product calculation: %v", err)
+	}
+
+	res, err := x.MulG1().PairDot(y.MulG2())
+	if err != nil {
+		t.Fatalf("Error during pairing: %v", err)
+	}
+	check := new(bn256.GT).ScalarBaseMult(new(big.Int).Mod(xy, bn256.Order))
+	assert.Equal(t, check.String(), res.String())
+
+	_, err = x.MulG1().PairDot(y[:4].MulG2())
+	assert.Error(t, err)
+}
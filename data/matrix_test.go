@@ -20,6 +20,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/sample"
 	"github.com/stretchr/testify/assert"
 )
@@ -94,6 +95,22 @@ func TestMatrix_DimsMatch(t *testing.T) {
 	assert.False(t, m1.DimsMatch(m4))
 }
 
+func TestMatrix_Equal(t *testing.T) {
+	m := Matrix{
+		Vector{big.NewInt(1), big.NewInt(-2)},
+		Vector{big.NewInt(0), big.NewInt(7)},
+	}
+	assert.True(t, m.Equal(m.Copy()))
+	assert.False(t, m.Equal(m[:1]))
+	assert.False(t, m.Equal(Matrix{m[0], Vector{big.NewInt(0)}}))
+	assert.False(t, m.Equal(m.MulScalar(big.NewInt(2))))
+
+	p := big.NewInt(3)
+	assert.True(t, m.EqualMod(m.Mod(p), p))
+	assert.True(t, m.EqualMod(m.MulScalar(big.NewInt(4)), p))
+	assert.False(t, m.EqualMod(m.MulScalar(big.NewInt(2)), p))
+}
+
 func TestMatrix_CheckDims(t *testing.T) {
 	sampler := sample.NewUniform(big.NewInt(10))
 	m, _ := NewRandomMatrix(2, 2, sampler)
@@ -139,6 +156,37 @@ func TestMatrix_MulScalar(t *testing.T) {
 	assert.Equal(t, m.MulScalar(two), mTimesTwo)
 }
 
+func TestMatrix_Scale(t *testing.T) {
+	m := Matrix{
+		Vector{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
+		Vector{big.NewInt(4), big.NewInt(5), big.NewInt(6)},
+	}
+
+	rows, err := m.ScaleRows(Vector{big.NewInt(2), big.NewInt(-1)})
+	if err != nil {
+		t.Fatalf("Error during scaling rows: %v", err)
+	}
+	assert.True(t, rows.Equal(Matrix{
+		Vector{big.NewInt(2), big.NewInt(4), big.NewInt(6)},
+		Vector{big.NewInt(-4), big.NewInt(-5), big.NewInt(-6)},
+	}))
+
+	cols, err := m.ScaleCols(Vector{big.NewInt(0), big.NewInt(3), big.NewInt(-2)})
+	if err != nil {
+		t.Fatalf("Error during scaling columns: %v", err)
+	}
+	assert.True(t, cols.Equal(Matrix{
+		Vector{big.NewInt(0), big.NewInt(6), big.NewInt(-6)},
+		Vector{big.NewInt(0), big.NewInt(15), big.NewInt(-12)},
+	}))
+	assert.Equal(t, int64(1), m[0][0].Int64(), "original matrix should not change")
+
+	_, err = m.ScaleRows(Vector{big.NewInt(1)})
+	assert.Error(t, err)
+	_, err = m.ScaleCols(Vector{big.NewInt(1), big.NewInt(1)})
+	assert.Error(t, err)
+}
+
 func TestMatrix_MulVec(t *testing.T) {
 	m := Matrix{
 		Vector{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
@@ -155,6 +203,42 @@ func TestMatrix_MulVec(t *testing.T) {
 	assert.Error(t, err, "expected an error to because of dimension mismatch")
 }
 
+func TestMatrix_MulVecMod(t *testing.T) {
+	m := Matrix{
+		Vector{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
+		Vector{big.NewInt(4), big.NewInt(-5), big.NewInt(6)},
+	}
+	v := Vector{big.NewInt(2), big.NewInt(2), big.NewInt(2)}
+	p := big.NewInt(7)
+
+	mvExpected := Vector{big.NewInt(5), big.NewInt(3)}
+	mv, err := m.MulVecMod(v, p)
+	if err != nil {
+		t.Fatalf("Error during matrix vector multiplication: %v", err)
+	}
+	assert.Equal(t, mvExpected, mv, "modular product of matrix and vector does not work correctly")
+
+	// the result agrees with MulVec followed by Mod
+	sampler := sample.NewUniform(bn256.Order)
+	mRand, err := NewRandomMatrix(10, 20, sampler)
+	if err != nil {
+		t.Fatalf("Error during random generation: %v", err)
+	}
+	vRand, err := NewRandomVector(20, sampler)
+	if err != nil {
+		t.Fatalf("Error during random generation: %v", err)
+	}
+	mvCheck, _ := mRand.MulVec(vRand)
+	mv, err = mRand.MulVecMod(vRand, bn256.Order)
+	if err != nil {
+		t.Fatalf("Error during matrix vector multiplication: %v", err)
+	}
+	assert.Equal(t, mvCheck.Mod(bn256.Order), mv)
+
+	_, err = m.MulVecMod(Vector{big.NewInt(1)}, p)
+	assert.Error(t, err, "expected an error to because of dimension mismatch")
+}
+
 func TestMatrix_Mul(t *testing.T) {
 	m1 := Matrix{
 		Vector{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
@@ -291,3 +375,361 @@ func TestMatrix_Tensor(t *testing.T) {
 
 	assert.Equal(t, prodExpected, prod, "tensor product of matrices does not work correctly")
 }
+
+func TestMatrix_TensorDims(t *testing.T) {
+	m1 := Matrix{
+		Vector{big.NewInt(2)},
+		Vector{big.NewInt(-1)},
+	}
+	m2 := Matrix{
+		Vector{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
+		Vector{big.NewInt(4), big.NewInt(5), big.NewInt(6)},
+	}
+
+	prodExpected := Matrix{
+		Vector{big.NewInt(2), big.NewInt(4), big.NewInt(6)},
+		Vector{big.NewInt(8), big.NewInt(10), big.NewInt(12)},
+		Vector{big.NewInt(-1), big.NewInt(-2), big.NewInt(-3)},
+		Vector{big.NewInt(-4), big.NewInt(-5), big.NewInt(-6)},
+	}
+	prod := m1.Tensor(m2)
+
+	assert.True(t, prod.CheckDims(4, 3))
+	assert.Equal(t, prodExpected, prod, "tensor product of matrices does not work correctly")
+}
+
+func TestMatrix_ToVec(t *testing.T) {
+	m := Matrix{
+		Vector{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
+		Vector{big.NewInt(4), big.NewInt(5), big.NewInt(6)},
+	}
+	vecExpected := Vector{big.NewInt(1), big.NewInt(2), big.NewInt(3),
+		big.NewInt(4), big.NewInt(5), big.NewInt(6)}
+
+	assert.Equal(t, vecExpected, m.ToVec())
+}
+
+func TestMatrix_Join(t *testing.T) {
+	m1 := Matrix{
+		Vector{big.NewInt(1), big.NewInt(2)},
+		Vector{big.NewInt(3), big.NewInt(4)},
+	}
+	m2 := Matrix{
+		Vector{big.NewInt(5)},
+		Vector{big.NewInt(6)},
+	}
+	m3 := Matrix{
+		Vector{big.NewInt(7), big.NewInt(8)},
+	}
+
+	colsExpected := Matrix{
+		Vector{big.NewInt(1), big.NewInt(2), big.NewInt(5)},
+		Vector{big.NewInt(3), big.NewInt(4), big.NewInt(6)},
+	}
+	cols, err := m1.JoinCols(m2)
+	if err != nil {
+		t.Fatalf("Error during joining columns: %v", err)
+	}
+	assert.Equal(t, colsExpected, cols)
+
+	rowsExpected := Matrix{
+		Vector{big.NewInt(1), big.NewInt(2)},
+		Vector{big.NewInt(3), big.NewInt(4)},
+		Vector{big.NewInt(7), big.NewInt(8)},
+	}
+	rows, err := m1.JoinRows(m3)
+	if err != nil {
+		t.Fatalf("Error during joining rows: %v", err)
+	}
+	assert.Equal(t, rowsExpected, rows)
+
+	// the result should not share memory with the inputs
+	cols[0][0].SetInt64(10)
+	rows[0][0].SetInt64(10)
+	assert.Equal(t, big.NewInt(1), m1[0][0])
+
+	// dimensions mismatch
+	_, err = m1.JoinCols(m3)
+	assert.Error(t, err)
+	_, err = m1.JoinRows(m2)
+	assert.Error(t, err)
+}
+
+func TestMatrix_Identity(t *testing.T) {
+	idExpected := Matrix{
+		Vector{big.NewInt(1), big.NewInt(0), big.NewInt(0)},
+		Vector{big.NewInt(0), big.NewInt(1), big.NewInt(0)},
+	}
+	assert.Equal(t, idExpected, Identity(2, 3))
+
+	m := Matrix{
+		Vector{big.NewInt(1), big.NewInt(2)},
+		Vector{big.NewInt(3), big.NewInt(4)},
+	}
+	prod, err := Identity(2, 2).Mul(m)
+	if err != nil {
+		t.Fatalf("Error during matrix multiplication: %v", err)
+	}
+	assert.Equal(t, m, prod)
+}
+
+func benchmarkMulVecInput(b *testing.B) (Matrix, Vector) {
+	sampler := sample.NewUniform(bn256.Order)
+	m, err := NewRandomMatrix(200, 200, sampler)
+	if err != nil {
+		b.Fatalf("Error during random generation: %v", err)
+	}
+	v, err := NewRandomVector(200, sampler)
+	if err != nil {
+		b.Fatalf("Error during random generation: %v", err)
+	}
+
+	return m, v
+}
+
+func BenchmarkMatrix_MulVec(b *testing.B) {
+	m, v := benchmarkMulVecInput(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mv, _ := m.MulVec(v)
+		_ = mv.Mod(bn256.Order)
+	}
+}
+
+func BenchmarkMatrix_MulVecMod(b *testing.B) {
+	m, v := benchmarkMulVecInput(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = m.MulVecMod(v, bn256.Order)
+	}
+}
+
+func TestParseMatrix(t *testing.T) {
+	bound := new(big.Int).Exp(big.NewInt(2), big.NewInt(200), nil)
+	sampler := sample.NewUniformRange(new(big.Int).Neg(bound), bound)
+	m, err := NewRandomMatrix(4, 6, sampler)
+	if err != nil {
+		t.Fatalf("Error during random matrix generation: %v", err)
+	}
+
+	parsed, err := ParseMatrix(m.String())
+	if err != nil {
+		t.Fatalf("Error during matrix parsing: %v", err)
+	}
+	assert.Equal(t, m, parsed)
+
+	_, err = ParseMatrix("1 2\n3")
+	assert.Error(t, err)
+	_, err = ParseMatrix("1 2\n3 y")
+	assert.Error(t, err)
+}
+
+func TestMatrix_LUMod(t *testing.T) {
+	p := big.NewInt(1000003)
+	sampler := sample.NewUniform(p)
+	m, err := NewRandomMatrix(6, 6, sampler)
+	if err != nil {
+		t.Fatalf("Error during matrix generation: %v", err)
+	}
+
+	lu, err := m.LUMod(p)
+	if err != nil {
+		t.Fatalf("Error during LU decomposition: %v", err)
+	}
+
+	det, err := m.Determinant()
+	if err != nil {
+		t.Fatalf("Error during computation of determinant: %v", err)
+	}
+	assert.Equal(t, det.Mod(det, p), lu.Det(), "computed determinants are not equal")
+
+	// the decomposition is reused for several right-hand sides
+	for i := 0; i < 3; i++ {
+		xTest, err := NewRandomVector(6, sampler)
+		if err != nil {
+			t.Fatalf("Error during vector generation: %v", err)
+		}
+		v, err := m.MulVecMod(xTest, p)
+		if err != nil {
+			t.Fatalf("Error during matrix-vector multiplication: %v", err)
+		}
+		x, err := lu.Solve(v)
+		if err != nil {
+			t.Fatalf("Error during solving: %v", err)
+		}
+		assert.Equal(t, xTest, x, "solution is not correct")
+	}
+
+	mInv, _, err := m.InverseModGauss(p)
+	if err != nil {
+		t.Fatalf("Error during computation of inverse: %v", err)
+	}
+	assert.Equal(t, mInv, lu.Inverse(), "computed inverses are not equal")
+
+	singular := Matrix{
+		Vector{big.NewInt(1), big.NewInt(2)},
+		Vector{big.NewInt(2), big.NewInt(4)},
+	}
+	_, err = singular.LUMod(p)
+	assert.Error(t, err)
+}
+
+func TestMatrix_IsFullRank(t *testing.T) {
+	p := big.NewInt(7)
+	m, err := ParseMatrix("1 2 3\n2 4 6\n0 1 1")
+	if err != nil {
+		t.Fatalf("Error during matrix parsing: %v", err)
+	}
+	assert.Equal(t, 2, m.RankMod(p))
+	assert.False(t, m.IsFullRank(p))
+
+	// the rows become independent after changing an entry
+	m[1][2] = big.NewInt(5)
+	assert.Equal(t, 3, m.RankMod(p))
+	assert.True(t, m.IsFullRank(p))
+
+	// entries are considered modulo p
+	m[1] = NewVector([]*big.Int{big.NewInt(9), big.NewInt(18), big.NewInt(27)})
+	assert.False(t, m.IsFullRank(p))
+
+	// non-square matrices have full rank if their rows or columns are independent
+	wide, err := ParseMatrix("1 0 3 4\n0 1 5 6")
+	if err != nil {
+		t.Fatalf("Error during matrix parsing: %v", err)
+	}
+	assert.True(t, wide.IsFullRank(p))
+	assert.True(t, wide.Transpose().IsFullRank(p))
+	assert.False(t, Matrix{}.IsFullRank(p))
+	assert.Equal(t, 0, Matrix{}.RankMod(p))
+
+	// random matrices over a large field are invertible
+	// with an overwhelming probability
+	r, err := NewRandomMatrix(5, 5, sample.NewUniform(bn256.Order))
+	if err != nil {
+		t.Fatalf("Error during matrix generation: %v", err)
+	}
+	assert.True(t, r.IsFullRank(bn256.Order))
+}
+
+func TestSolveAndKernel(t *testing.T) {
+	p := big.NewInt(1000003)
+	sampler := sample.NewUniform(p)
+	// a 4 x 6 matrix of rank 3, the last row is the sum of the first two
+	m, err := NewRandomMatrix(4, 6, sampler)
+	if err != nil {
+		t.Fatalf("Error during matrix generation: %v", err)
+	}
+	m[3] = m[0].Add(m[1]).Mod(p)
+	x, err := NewRandomVector(6, sampler)
+	if err != nil {
+		t.Fatalf("Error during vector generation: %v", err)
+	}
+	v, err := m.MulVecMod(x, p)
+	if err != nil {
+		t.Fatalf("Error during matrix vector multiplication: %v", err)
+	}
+
+	particular, kernel, err := SolveAndKernel(m, v, p)
+	if err != nil {
+		t.Fatalf("Error during solving: %v", err)
+	}
+	check, err := m.MulVecMod(particular, p)
+	if err != nil {
+		t.Fatalf("Error during matrix vector multiplication: %v", err)
+	}
+	assert.True(t, v.Equal(check))
+	gauss, err := GaussianEliminationSolver(m, v, p)
+	if err != nil {
+		t.Fatalf("Error during solving: %v", err)
+	}
+	assert.True(t, gauss.Equal(particular))
+
+	// the kernel has dimension cols - rank and its vectors are independent
+	assert.Equal(t, 3, len(kernel))
+	assert.True(t, Matrix(kernel).IsFullRank(p))
+	zero := NewConstantVector(4, big.NewInt(0))
+	for _, k := range kernel {
+		mk, err := m.MulVecMod(k, p)
+		if err != nil {
+			t.Fatalf("Error during matrix vector multiplication: %v", err)
+		}
+		assert.True(t, zero.Equal(mk))
+	}
+
+	// an invertible matrix has a trivial kernel
+	sq, err := ParseMatrix("1 2\n3 4")
+	if err != nil {
+		t.Fatalf("Error during matrix parsing: %v", err)
+	}
+	_, kernel, err = SolveAndKernel(sq, NewVector([]*big.Int{big.NewInt(1), big.NewInt(1)}), p)
+	assert.NoError(t, err)
+	assert.Empty(t, kernel)
+
+	// v outside of the column space of m
+	v[3].Add(v[3], big.NewInt(1))
+	_, _, err = SolveAndKernel(m, v, p)
+	assert.Error(t, err)
+	_, _, err = SolveAndKernel(m, v[:3], p)
+	assert.Error(t, err)
+}
+
+func TestModMatrix(t *testing.T) {
+	p := big.NewInt(7)
+	r := ModMatrix{P: p}
+	sampler := sample.NewUniformRange(big.NewInt(-100), big.NewInt(100))
+	m1, err := NewRandomMatrix(3, 4, sampler)
+	if err != nil {
+		t.Fatalf("Error during random matrix generation: %v", err)
+	}
+	m2, err := NewRandomMatrix(3, 4, sampler)
+	if err != nil {
+		t.Fatalf("Error during random matrix generation: %v", err)
+	}
+	v, err := NewRandomVector(4, sampler)
+	if err != nil {
+		t.Fatalf("Error during random vector generation: %v", err)
+	}
+
+	inRange := func(m Matrix) {
+		for _, row := range m {
+			for _, c := range row {
+				assert.True(t, c.Sign() >= 0 && c.Cmp(p) < 0, "element not reduced")
+			}
+		}
+	}
+
+	sum, err := r.Add(m1, m2)
+	assert.NoError(t, err)
+	sumCheck, _ := m1.Add(m2)
+	assert.True(t, sum.EqualMod(sumCheck, p))
+	inRange(sum)
+
+	diff, err := r.Sub(m1, m2)
+	assert.NoError(t, err)
+	diffCheck, _ := m1.Sub(m2)
+	assert.True(t, diff.EqualMod(diffCheck, p))
+	inRange(diff)
+
+	prod, err := r.Mul(m1, m2.Transpose())
+	assert.NoError(t, err)
+	prodCheck, _ := m1.Mul(m2.Transpose())
+	assert.True(t, prod.EqualMod(prodCheck, p))
+	inRange(prod)
+
+	inRange(r.MulScalar(m1, big.NewInt(-3)))
+
+	mv, err := r.MulVec(m1, v)
+	assert.NoError(t, err)
+	inRange(Matrix{mv})
+
+	dot, err := r.Dot(v, v)
+	assert.NoError(t, err)
+	inRange(Matrix{{dot}})
+
+	_, err = r.Mul(m1, m2)
+	assert.Error(t, err)
+	_, err = r.Add(m1, m2.Transpose())
+	assert.Error(t, err)
+}
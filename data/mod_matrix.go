@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import (
+	"math/big"
+)
+
+// ModMatrix performs operations on matrices and vectors with all
+// the results reduced modulo P, so that the reductions cannot be
+// forgotten when composing operations. For example
+//
+//	ModMatrix{P: p}.Mul(m1, m2)
+//
+// gives the same result as m1.Mul(m2) followed by Mod(p). The results
+// have elements in [0, P).
+type ModMatrix struct {
+	P *big.Int
+}
+
+// Add adds matrices m and other modulo P.
+// Error is returned if m and other have different dimensions.
+func (r ModMatrix) Add(m, other Matrix) (Matrix, error) {
+	res, err := m.Add(other)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Mod(r.P), nil
+}
+
+// Sub subtracts matrix other from m modulo P.
+// Error is returned if m and other have different dimensions.
+func (r ModMatrix) Sub(m, other Matrix) (Matrix, error) {
+	res, err := m.Sub(other)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Mod(r.P), nil
+}
+
+// Mul multiplies matrices m and other modulo P.
+// Error is returned if the number of columns of m differs from
+// the number of rows of other.
+func (r ModMatrix) Mul(m, other Matrix) (Matrix, error) {
+	res, err := m.Mul(other)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Mod(r.P), nil
+}
+
+// MulScalar multiplies elements of matrix m by a scalar x modulo P.
+func (r ModMatrix) MulScalar(m Matrix, x *big.Int) Matrix {
+	return m.MulScalar(x).Mod(r.P)
+}
+
+// MulVec multiplies matrix m and vector v modulo P.
+// Error is returned if the number of columns of m differs from the number
+// of elements of v.
+func (r ModMatrix) MulVec(m Matrix, v Vector) (Vector, error) {
+	return m.MulVecMod(v, r.P)
+}
+
+// Dot calculates the inner product of vectors v and other modulo P.
+// Error is returned if the vectors have different lengths.
+func (r ModMatrix) Dot(v, other Vector) (*big.Int, error) {
+	return v.DotMod(other, r.P)
+}
@@ -0,0 +1,221 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package data
+
+import (
+	"math/big"
+
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/internal"
+)
+
+// msmMinTerms is the number of terms from which a multi-scalar
+// multiplication is computed with the bucket method. Below it the
+// sum of separate scalar multiplications is faster.
+const msmMinTerms = 4
+
+// msmWindow returns the width (in bits) of the windows in which
+// the scalars of an n-term multi-scalar multiplication are split.
+func msmWindow(n int) uint {
+	c := uint(2)
+	for n >= 1<<(c+2) {
+		c++
+	}
+
+	return c
+}
+
+// msmDigits reduces the scalars modulo the order of the group, hence
+// negative scalars are handled without negating the points, and
+// returns them together with the maximal bit length among them.
+func msmDigits(scalars []*big.Int) ([]*big.Int, int) {
+	ks := make([]*big.Int, len(scalars))
+	maxBits := 0
+	for i, s := range scalars {
+		ks[i] = internal.ModPos(s, bn256.Order)
+		if ks[i].BitLen() > maxBits {
+			maxBits = ks[i].BitLen()
+		}
+	}
+
+	return ks, maxBits
+}
+
+// msmDigit returns the w-th c-bit digit of k.
+func msmDigit(k *big.Int, w int, c uint) int {
+	d := 0
+	for b := int(c) - 1; b >= 0; b-- {
+		d = d<<1 | int(k.Bit(w*int(c)+b))
+	}
+
+	return d
+}
+
+// multiExpG1 returns the sum of scalars[i] * points[i]. For more than
+// a few terms it uses the bucket (Pippenger) method, which replaces
+// most of the doublings of separate scalar multiplications with
+// additions shared among all the terms.
+//
+// Note that bn256 doubles incorrectly when the target aliases the
+// operands, hence sums are always stored in new elements.
+func multiExpG1(points []*bn256.G1, scalars []*big.Int) *bn256.G1 {
+	if len(points) < msmMinTerms {
+		return naiveMultiExpG1(points, scalars)
+	}
+
+	ks, maxBits := msmDigits(scalars)
+	c := msmWindow(len(points))
+	buckets := make([]*bn256.G1, 1<<c)
+	res := new(bn256.G1).ScalarBaseMult(big.NewInt(0))
+	for w := (maxBits+int(c)-1)/int(c) - 1; w >= 0; w-- {
+		for i := uint(0); i < c; i++ {
+			res = new(bn256.G1).Add(res, res)
+		}
+
+		for d := range buckets {
+			buckets[d] = nil
+		}
+		for i, k := range ks {
+			d := msmDigit(k, w, c)
+			if d == 0 {
+				continue
+			}
+			if buckets[d] == nil {
+				buckets[d] = points[i]
+			} else {
+				buckets[d] = new(bn256.G1).Add(buckets[d], points[i])
+			}
+		}
+
+		// running sums give sum_d d * buckets[d]
+		var running, sum *bn256.G1
+		for d := len(buckets) - 1; d > 0; d-- {
+			if buckets[d] != nil {
+				if running == nil {
+					running = buckets[d]
+				} else {
+					running = new(bn256.G1).Add(running, buckets[d])
+				}
+			}
+			if running != nil {
+				if sum == nil {
+					sum = running
+				} else {
+					sum = new(bn256.G1).Add(sum, running)
+				}
+			}
+		}
+		if sum != nil {
+			res = new(bn256.G1).Add(res, sum)
+		}
+	}
+
+	return res
+}
+
+// naiveMultiExpG1 returns the sum of scalars[i] * points[i] computed
+// with separate scalar multiplications.
+func naiveMultiExpG1(points []*bn256.G1, scalars []*big.Int) *bn256.G1 {
+	res := new(bn256.G1).ScalarBaseMult(big.NewInt(0))
+	for k := range points {
+		s := new(big.Int).Set(scalars[k])
+		p := new(bn256.G1).Set(points[k])
+		if s.Sign() == -1 {
+			p.Neg(p)
+			s.Neg(s)
+		}
+		tmp := new(bn256.G1).ScalarMult(p, s)
+		res.Add(tmp, res)
+	}
+
+	return res
+}
+
+// multiExpG2 returns the sum of scalars[i] * points[i]. It works
+// as multiExpG1.
+func multiExpG2(points []*bn256.G2, scalars []*big.Int) *bn256.G2 {
+	if len(points) < msmMinTerms {
+		return naiveMultiExpG2(points, scalars)
+	}
+
+	ks, maxBits := msmDigits(scalars)
+	c := msmWindow(len(points))
+	buckets := make([]*bn256.G2, 1<<c)
+	res := new(bn256.G2).ScalarBaseMult(big.NewInt(0))
+	for w := (maxBits+int(c)-1)/int(c) - 1; w >= 0; w-- {
+		for i := uint(0); i < c; i++ {
+			res = new(bn256.G2).Add(res, res)
+		}
+
+		for d := range buckets {
+			buckets[d] = nil
+		}
+		for i, k := range ks {
+			d := msmDigit(k, w, c)
+			if d == 0 {
+				continue
+			}
+			if buckets[d] == nil {
+				buckets[d] = points[i]
+			} else {
+				buckets[d] = new(bn256.G2).Add(buckets[d], points[i])
+			}
+		}
+
+		// running sums give sum_d d * buckets[d]
+		var running, sum *bn256.G2
+		for d := len(buckets) - 1; d > 0; d-- {
+			if buckets[d] != nil {
+				if running == nil {
+					running = buckets[d]
+				} else {
+					running = new(bn256.G2).Add(running, buckets[d])
+				}
+			}
+			if running != nil {
+				if sum == nil {
+					sum = running
+				} else {
+					sum = new(bn256.G2).Add(sum, running)
+				}
+			}
+		}
+		if sum != nil {
+			res = new(bn256.G2).Add(res, sum)
+		}
+	}
+
+	return res
+}
+
+// naiveMultiExpG2 returns the sum of scalars[i] * points[i] computed
+// with separate scalar multiplications.
+func naiveMultiExpG2(points []*bn256.G2, scalars []*big.Int) *bn256.G2 {
+	res := new(bn256.G2).ScalarBaseMult(big.NewInt(0))
+	for k := range points {
+		s := new(big.Int).Set(scalars[k])
+		p := new(bn256.G2).Set(points[k])
+		if s.Sign() == -1 {
+			p.Neg(p)
+			s.Neg(s)
+		}
+		tmp := new(bn256.G2).ScalarMult(p, s)
+		res.Add(tmp, res)
+	}
+
+	return res
+}
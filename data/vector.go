@@ -17,10 +17,13 @@
 package data
 
 import (
+	"encoding/binary"
 	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/internal"
 	"github.com/fentec-project/gofe/sample"
 	"golang.org/x/crypto/salsa20"
 )
@@ -119,6 +122,67 @@ func (v Vector) Copy() Vector {
 	return newVec
 }
 
+// Pad returns a new vector of length n that starts with the entries
+// of v and is extended by zeros. It returns an error if v is longer
+// than n.
+func (v Vector) Pad(n int) (Vector, error) {
+	if n < len(v) {
+		return nil, fmt.Errorf("vector of length %d cannot be padded to length %d", len(v), n)
+	}
+	res := make(Vector, n)
+	for i := range res {
+		if i < len(v) {
+			res[i] = new(big.Int).Set(v[i])
+		} else {
+			res[i] = big.NewInt(0)
+		}
+	}
+
+	return res, nil
+}
+
+// Truncate returns a new vector holding the first n entries of v.
+// It returns an error if v is shorter than n.
+func (v Vector) Truncate(n int) (Vector, error) {
+	if n < 0 || n > len(v) {
+		return nil, fmt.Errorf("vector of length %d cannot be truncated to length %d", len(v), n)
+	}
+
+	return v[:n].Copy(), nil
+}
+
+// Equal checks whether vectors v and other are of the same length
+// and have equal entries.
+func (v Vector) Equal(other Vector) bool {
+	if len(v) != len(other) {
+		return false
+	}
+	for i, c := range v {
+		if c.Cmp(other[i]) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// EqualMod checks whether vectors v and other are of the same length
+// and have entries that are equal modulo p.
+func (v Vector) EqualMod(other Vector, p *big.Int) bool {
+	if len(v) != len(other) {
+		return false
+	}
+	diff := new(big.Int)
+	for i, c := range v {
+		diff.Sub(c, other[i])
+		if diff.Mod(diff, p).Sign() != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
 // MulScalar multiplies vector v by a given scalar x.
 // The result is returned in a new Vector.
 func (v Vector) MulScalar(x *big.Int) Vector {
@@ -142,15 +206,19 @@ func (v Vector) Mod(modulo *big.Int) Vector {
 	return NewVector(newCoords)
 }
 
+// ErrBoundExceeded is returned when the coordinates of a vector or
+// a matrix are greater than the bound.
+var ErrBoundExceeded = fmt.Errorf("all coordinates of a vector should not be greater than bound")
+
 // CheckBound checks whether the absolute values of all vector elements
 // are strictly smaller than the provided bound.
-// It returns error if at least one element's absolute value is >= bound.
+// It returns ErrBoundExceeded if at least one element's absolute value is >= bound.
 func (v Vector) CheckBound(bound *big.Int) error {
 	abs := new(big.Int)
 	for _, c := range v {
 		abs.Abs(c)
 		if abs.Cmp(bound) > 0 {
-			return fmt.Errorf("all coordinates of a vector should not be greater than bound")
+			return ErrBoundExceeded
 		}
 	}
 
@@ -180,6 +248,21 @@ func (v Vector) Neg() Vector {
 	return neg
 }
 
+// ModInverse returns a vector of modular inverses of elements of v
+// modulo p. The result is returned in a new Vector.
+// It returns an error if some element is not invertible modulo p.
+func (v Vector) ModInverse(p *big.Int) (Vector, error) {
+	inv := make(Vector, len(v))
+	for i, c := range v {
+		inv[i] = new(big.Int).ModInverse(c, p)
+		if inv[i] == nil {
+			return nil, fmt.Errorf("element %d of the vector is not invertible", i)
+		}
+	}
+
+	return inv, nil
+}
+
 // Add adds vectors v and other.
 // The result is returned in a new Vector.
 func (v Vector) Add(other Vector) Vector {
@@ -219,6 +302,54 @@ func (v Vector) Dot(other Vector) (*big.Int, error) {
 	return prod, nil
 }
 
+// DotMod calculates the dot product (inner product) of vectors v and
+// other modulo p. Unlike Dot followed by Mod, it does not allocate a new
+// integer for each of the products, and it reduces the sum modulo p after
+// every dotModReduceEvery multiply-adds, so that the intermediate values
+// stay bounded no matter how long the vectors are, while reducing after
+// each of them would cost a division per element. The result is in [0, p).
+// It returns an error if vectors have different numbers of elements.
+func (v Vector) DotMod(other Vector, p *big.Int) (*big.Int, error) {
+	if len(v) != len(other) {
+		return nil, fmt.Errorf("vectors should be of same length")
+	}
+
+	prod := new(big.Int)
+	quo := new(big.Int)
+	res := new(big.Int)
+	for i, c := range v {
+		prod.Mul(c, other[i])
+		res.Add(res, prod)
+		if (i+1)%dotModReduceEvery == 0 {
+			quo.QuoRem(res, p, res)
+		}
+	}
+
+	return res.Mod(res, p), nil
+}
+
+// dotModReduceEvery is the number of multiply-adds after which DotMod
+// reduces the accumulated sum modulo p.
+const dotModReduceEvery = 16
+
+// Sum returns the sum of the elements of vector v.
+func (v Vector) Sum() *big.Int {
+	sum := new(big.Int)
+	for _, c := range v {
+		sum.Add(sum, c)
+	}
+
+	return sum
+}
+
+// SumMod returns the sum of the elements of vector v modulo p, i.e.
+// an element of [0, p).
+func (v Vector) SumMod(p *big.Int) *big.Int {
+	sum := v.Sum()
+
+	return sum.Mod(sum, p)
+}
+
 // MulAsPolyInRing multiplies vectors v and other as polynomials
 // in the ring of polynomials R = Z[x]/((x^n)+1), where n is length of
 // the vectors. Note that the input vector [1, 2, 3] represents a
@@ -261,11 +392,7 @@ func (v Vector) MulAsPolyInRing(other Vector) (Vector, error) {
 func (v Vector) MulG1() VectorG1 {
 	prod := make(VectorG1, len(v))
 	for i := range prod {
-		vi := new(big.Int).Abs(v[i])
-		prod[i] = new(bn256.G1).ScalarBaseMult(vi)
-		if v[i].Sign() == -1 {
-			prod[i].Neg(prod[i])
-		}
+		prod[i] = new(bn256.G1).ScalarBaseMult(internal.ModPos(v[i], bn256.Order))
 	}
 
 	return prod
@@ -275,17 +402,9 @@ func (v Vector) MulG1() VectorG1 {
 // and returns the result (v[0] * g1[0], ... , v[n-1] * g1[n-1]) in a
 // VectorG1 instance.
 func (v Vector) MulVecG1(g1 VectorG1) VectorG1 {
-	zero := big.NewInt(0)
-
 	prod := make(VectorG1, len(v))
 	for i := range prod {
-		vi := new(big.Int).Set(v[i])
-		g1i := new(bn256.G1).Set(g1[i])
-		if vi.Cmp(zero) == -1 {
-			g1i.Neg(g1i)
-			vi.Neg(vi)
-		}
-		prod[i] = new(bn256.G1).ScalarMult(g1i, vi)
+		prod[i] = new(bn256.G1).ScalarMult(g1[i], internal.ModPos(v[i], bn256.Order))
 	}
 
 	return prod
@@ -297,11 +416,7 @@ func (v Vector) MulVecG1(g1 VectorG1) VectorG1 {
 func (v Vector) MulG2() VectorG2 {
 	prod := make(VectorG2, len(v))
 	for i := range prod {
-		vi := new(big.Int).Abs(v[i])
-		prod[i] = new(bn256.G2).ScalarBaseMult(vi)
-		if v[i].Sign() == -1 {
-			prod[i].Neg(prod[i])
-		}
+		prod[i] = new(bn256.G2).ScalarBaseMult(internal.ModPos(v[i], bn256.Order))
 	}
 
 	return prod
@@ -311,22 +426,43 @@ func (v Vector) MulG2() VectorG2 {
 // and returns the result (v[0] * g2[0], ... , v[n-1] * g2[n-1]) in a
 // VectorG2 instance.
 func (v Vector) MulVecG2(g2 VectorG2) VectorG2 {
-	zero := big.NewInt(0)
-
 	prod := make(VectorG2, len(v))
 	for i := range prod {
-		vi := new(big.Int).Set(v[i])
-		g2i := new(bn256.G2).Set(g2[i])
-		if vi.Cmp(zero) == -1 {
-			g2i.Neg(g2i)
-			vi.Neg(vi)
-		}
-		prod[i] = new(bn256.G2).ScalarMult(g2i, vi)
+		prod[i] = new(bn256.G2).ScalarMult(g2[i], internal.ModPos(v[i], bn256.Order))
 	}
 
 	return prod
 }
 
+// CanonicalBytes returns an unambiguous encoding of the vector that is
+// suitable as an input to a hash function. The encoding starts with the
+// number of elements, followed by each element given by a sign byte
+// (1 for negative values, 0 otherwise), the length of its absolute value
+// in bytes and the big-endian bytes of the absolute value. All the
+// lengths are encoded as 8 byte big-endian integers. Nil elements are
+// encoded as zeros.
+func (v Vector) CanonicalBytes() []byte {
+	lenBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(lenBytes, uint64(len(v)))
+	res := append([]byte{}, lenBytes...)
+	for _, e := range v {
+		var abs []byte
+		sign := byte(0)
+		if e != nil {
+			abs = e.Bytes()
+			if e.Sign() < 0 {
+				sign = 1
+			}
+		}
+		binary.BigEndian.PutUint64(lenBytes, uint64(len(abs)))
+		res = append(res, sign)
+		res = append(res, lenBytes...)
+		res = append(res, abs...)
+	}
+
+	return res
+}
+
 // String produces a string representation of a vector.
 func (v Vector) String() string {
 	vStr := ""
@@ -336,6 +472,23 @@ func (v Vector) String() string {
 	return vStr
 }
 
+// ParseVector parses a vector from its string representation given
+// by String, i.e. from decimal integers separated by white space.
+// It returns an error if some element is not a valid integer.
+func ParseVector(s string) (Vector, error) {
+	fields := strings.Fields(s)
+	v := make(Vector, len(fields))
+	for i, f := range fields {
+		vi, ok := new(big.Int).SetString(f, 10)
+		if !ok {
+			return nil, fmt.Errorf("element %d of the vector is not an integer: %s", i, f)
+		}
+		v[i] = vi
+	}
+
+	return v, nil
+}
+
 // Tensor creates a tensor product of vectors v and other.
 // The result is returned in a new Vector.
 func (v Vector) Tensor(other Vector) Vector {
@@ -17,9 +17,11 @@
 package data
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/internal"
 )
 
 // VectorG1 wraps a slice of elements from elliptic curve BN256.G1 group.
@@ -59,13 +61,8 @@ func (v VectorG1) Copy() VectorG1 {
 
 // MulScalar multiplies s * v (in additive notation).
 func (v VectorG1) MulScalar(s *big.Int) VectorG1 {
-	sTmp := new(big.Int).Set(s)
+	sTmp := internal.ModPos(s, bn256.Order)
 	out := v.Copy()
-	if s.Sign() == -1 {
-		sTmp.Neg(s)
-		out = out.Neg()
-	}
-
 	for i := range out {
 		out[i].ScalarMult(out[i], sTmp)
 	}
@@ -73,6 +70,24 @@ func (v VectorG1) MulScalar(s *big.Int) VectorG1 {
 	return out
 }
 
+// PairDot computes the sum of pairings e(v_i, other_i) over all the
+// elements of v and other (also their product in multiplicative
+// notation), i.e. e(g1, g2)^<x, y> if v and other are the encodings
+// of vectors x and y. It returns an error if the vectors are not of
+// the same length.
+func (v VectorG1) PairDot(other VectorG2) (*bn256.GT, error) {
+	if len(v) != len(other) {
+		return nil, fmt.Errorf("vectors should be of same length")
+	}
+
+	sum := new(bn256.GT).ScalarBaseMult(big.NewInt(0))
+	for i := range v {
+		sum = new(bn256.GT).Add(bn256.Pair(v[i], other[i]), sum)
+	}
+
+	return sum, nil
+}
+
 // VectorG2 wraps a slice of elements from elliptic curve BN256.G2 group.
 type VectorG2 []*bn256.G2
 
@@ -110,13 +125,8 @@ func (v VectorG2) Copy() VectorG2 {
 
 // MulScalar multiplies s * v (in additive notation).
 func (v VectorG2) MulScalar(s *big.Int) VectorG2 {
-	sTmp := new(big.Int).Set(s)
+	sTmp := internal.ModPos(s, bn256.Order)
 	out := v.Copy()
-	if s.Sign() == -1 {
-		sTmp.Neg(s)
-		out = out.Neg()
-	}
-
 	for i := range out {
 		out[i].ScalarMult(out[i], sTmp)
 	}
@@ -20,6 +20,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/sample"
 	"github.com/stretchr/testify/assert"
 )
@@ -87,3 +88,179 @@ func TestVecor_Tensor(t *testing.T) {
 
 	assert.Equal(t, prodExpected, prod, "tensor product of vectors does not work correctly")
 }
+
+func TestVector_Neg(t *testing.T) {
+	v := Vector{big.NewInt(1), big.NewInt(-2), big.NewInt(0)}
+	neg := v.Neg()
+
+	assert.Equal(t, Vector{big.NewInt(-1), big.NewInt(2), big.NewInt(0)}, neg)
+	assert.Equal(t, big.NewInt(1), v[0], "original vector should not change")
+}
+
+func TestVector_Equal(t *testing.T) {
+	v := Vector{big.NewInt(1), big.NewInt(-2), big.NewInt(0)}
+	assert.True(t, v.Equal(Vector{big.NewInt(1), big.NewInt(-2), new(big.Int)}))
+	assert.False(t, v.Equal(Vector{big.NewInt(1), big.NewInt(-2), big.NewInt(5)}))
+	assert.False(t, v.Equal(v[:2]))
+
+	p := big.NewInt(5)
+	assert.True(t, v.EqualMod(Vector{big.NewInt(6), big.NewInt(3), big.NewInt(-5)}, p))
+	assert.False(t, v.EqualMod(Vector{big.NewInt(6), big.NewInt(3), big.NewInt(1)}, p))
+	assert.False(t, v.EqualMod(v[:2], p))
+}
+
+func TestVector_PadTruncate(t *testing.T) {
+	v := Vector{big.NewInt(1), big.NewInt(-2)}
+
+	padded, err := v.Pad(4)
+	if err != nil {
+		t.Fatalf("Error during padding: %v", err)
+	}
+	assert.True(t, padded.Equal(Vector{big.NewInt(1), big.NewInt(-2), big.NewInt(0), big.NewInt(0)}))
+	padded[0].SetInt64(5)
+	assert.Equal(t, int64(1), v[0].Int64(), "original vector should not change")
+
+	truncated, err := padded.Truncate(1)
+	if err != nil {
+		t.Fatalf("Error during truncation: %v", err)
+	}
+	assert.True(t, truncated.Equal(Vector{big.NewInt(5)}))
+
+	_, err = v.Pad(1)
+	assert.Error(t, err)
+	_, err = v.Truncate(3)
+	assert.Error(t, err)
+}
+
+func TestVector_ModInverse(t *testing.T) {
+	p := big.NewInt(7)
+	v := Vector{big.NewInt(2), big.NewInt(3), big.NewInt(-1)}
+	inv, err := v.ModInverse(p)
+	if err != nil {
+		t.Fatalf("Error during computation of inverses: %v", err)
+	}
+	assert.Equal(t, Vector{big.NewInt(4), big.NewInt(5), big.NewInt(6)}, inv)
+
+	vZero := Vector{big.NewInt(2), big.NewInt(14)}
+	_, err = vZero.ModInverse(p)
+	assert.Error(t, err)
+}
+
+func TestVector_CanonicalBytes(t *testing.T) {
+	vecs := []Vector{
+		{big.NewInt(1), big.NewInt(23)},
+		{big.NewInt(12), big.NewInt(3)},
+		{big.NewInt(123)},
+		{big.NewInt(-1), big.NewInt(23)},
+		{big.NewInt(1), big.NewInt(-23)},
+		{big.NewInt(1), big.NewInt(23), big.NewInt(0)},
+		{big.NewInt(0)},
+		{big.NewInt(256)},
+		{},
+	}
+
+	// distinct vectors should have distinct encodings
+	seen := make(map[string]int)
+	for i, v := range vecs {
+		enc := string(v.CanonicalBytes())
+		if j, ok := seen[enc]; ok {
+			t.Fatalf("vectors %v and %v have the same encoding", vecs[j], v)
+		}
+		seen[enc] = i
+	}
+
+	// equal vectors should have equal encodings
+	v := Vector{big.NewInt(-7), new(big.Int).Lsh(big.NewInt(1), 200)}
+	assert.Equal(t, v.CanonicalBytes(), v.Copy().CanonicalBytes())
+}
+
+func TestParseVector(t *testing.T) {
+	bound := new(big.Int).Exp(big.NewInt(2), big.NewInt(200), nil)
+	sampler := sample.NewUniformRange(new(big.Int).Neg(bound), bound)
+	v, err := NewRandomVector(20, sampler)
+	if err != nil {
+		t.Fatalf("Error during random vector generation: %v", err)
+	}
+
+	parsed, err := ParseVector(v.String())
+	if err != nil {
+		t.Fatalf("Error during vector parsing: %v", err)
+	}
+	assert.Equal(t, v, parsed)
+
+	parsed, err = ParseVector(Vector{}.String())
+	assert.NoError(t, err)
+	assert.Empty(t, parsed)
+
+	_, err = ParseVector("1 2 x")
+	assert.Error(t, err)
+}
+
+func TestVector_DotMod(t *testing.T) {
+	bound := new(big.Int).Exp(big.NewInt(2), big.NewInt(300), nil)
+	sampler := sample.NewUniformRange(new(big.Int).Neg(bound), bound)
+	v, err := NewRandomVector(50, sampler)
+	if err != nil {
+		t.Fatalf("Error during random vector generation: %v", err)
+	}
+	w, err := NewRandomVector(50, sampler)
+	if err != nil {
+		t.Fatalf("Error during random vector generation: %v", err)
+	}
+
+	dot, err := v.Dot(w)
+	if err != nil {
+		t.Fatalf("Error during inner product calculation: %v", err)
+	}
+	dotMod, err := v.DotMod(w, bn256.Order)
+	if err != nil {
+		t.Fatalf("Error during inner product calculation: %v", err)
+	}
+	assert.Equal(t, dot.Mod(dot, bn256.Order), dotMod)
+
+	_, err = v.DotMod(w[1:], bn256.Order)
+	assert.Error(t, err)
+}
+
+func benchmarkDotInput(b *testing.B) (Vector, Vector) {
+	sampler := sample.NewUniform(bn256.Order)
+	v, err := NewRandomVector(1000, sampler)
+	if err != nil {
+		b.Fatalf("Error during random generation: %v", err)
+	}
+	w, err := NewRandomVector(1000, sampler)
+	if err != nil {
+		b.Fatalf("Error during random generation: %v", err)
+	}
+
+	return v, w
+}
+
+func BenchmarkVector_Dot(b *testing.B) {
+	v, w := benchmarkDotInput(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dot, _ := v.Dot(w)
+		_ = dot.Mod(dot, bn256.Order)
+	}
+}
+
+func BenchmarkVector_DotMod(b *testing.B) {
+	v, w := benchmarkDotInput(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = v.DotMod(w, bn256.Order)
+	}
+}
+
+func TestVector_Sum(t *testing.T) {
+	v := Vector{big.NewInt(3), big.NewInt(-8), big.NewInt(4)}
+	assert.Equal(t, big.NewInt(-1), v.Sum())
+	assert.Equal(t, big.NewInt(6), v.SumMod(big.NewInt(7)))
+	assert.Equal(t, big.NewInt(0), Vector{}.Sum())
+
+	m := Matrix{v, Vector{big.NewInt(1), big.NewInt(2), big.NewInt(3)}}
+	assert.Equal(t, Vector{big.NewInt(-1), big.NewInt(6)}, m.SumRows())
+}
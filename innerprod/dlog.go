@@ -0,0 +1,31 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package innerprod
+
+import "github.com/fentec-project/gofe/internal/dlog"
+
+// SetDlogPrecompCache enables or disables the process-wide cache of
+// the tables used to compute discrete logarithms in the BN256 group
+// during decryption. The cache is enabled by default: the steps
+// computed by the first successful decryption are reused by all the
+// following ones, in any of the pairing based schemes of this library
+// (including the quadratic ones), at the cost of holding a table of
+// up to 2^18 steps in memory. Memory sensitive callers can disable it,
+// which releases the cached tables.
+func SetDlogPrecompCache(enabled bool) {
+	dlog.SetPrecompCache(enabled)
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package innerprod
+
+import (
+	"github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/internal"
+	"github.com/fentec-project/gofe/internal/dlog"
+)
+
+// The errors returned by the inner product schemes. They can be
+// checked with errors.Is.
+var (
+	// ErrBoundExceeded is returned when the coordinates of an input
+	// vector are greater than the bound of the scheme.
+	ErrBoundExceeded = data.ErrBoundExceeded
+	// ErrDlogNotFound is returned when the decrypted value could not
+	// be found within the bound of the scheme, e.g. when the key and
+	// the ciphertext do not match.
+	ErrDlogNotFound = dlog.ErrNotFound
+	// ErrMalformedPubKey is returned for a malformed public key.
+	ErrMalformedPubKey = internal.ErrMalformedPubKey
+	// ErrMalformedSecKey is returned for a malformed secret key.
+	ErrMalformedSecKey = internal.ErrMalformedSecKey
+	// ErrMalformedDecKey is returned for a malformed derived key.
+	ErrMalformedDecKey = internal.ErrMalformedDecKey
+	// ErrMalformedCipher is returned for a malformed ciphertext.
+	ErrMalformedCipher = internal.ErrMalformedCipher
+	// ErrMalformedInput is returned for malformed input data.
+	ErrMalformedInput = internal.ErrMalformedInput
+)
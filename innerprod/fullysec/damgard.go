@@ -17,10 +17,13 @@
 package fullysec
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"math/big"
 
 	"github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/innerprod"
 	"github.com/fentec-project/gofe/internal"
 	"github.com/fentec-project/gofe/internal/dlog"
 	"github.com/fentec-project/gofe/internal/keygen"
@@ -50,7 +53,8 @@ type DamgardParams struct {
 // "Fully secure functional encryption for inner products,
 // from standard assumptions".
 type Damgard struct {
-	Params *DamgardParams
+	Params     *DamgardParams
+	randReader io.Reader
 }
 
 // NewDamgard configures a new instance of the scheme.
@@ -60,8 +64,9 @@ type Damgard struct {
 //
 // It returns an error in case the scheme could not be properly
 // configured, or if precondition l * bound² is >= order of the cyclic
-// group.
-func NewDamgard(l, modulusLength int, bound *big.Int) (*Damgard, error) {
+// group. Optionally a source of randomness for generating keys and
+// encrypting can be set with innerprod.WithRandomness.
+func NewDamgard(l, modulusLength int, bound *big.Int, opts ...innerprod.Option) (*Damgard, error) {
 	key, err := keygen.NewElGamal(modulusLength)
 	if err != nil {
 		return nil, err
@@ -77,8 +82,8 @@ func NewDamgard(l, modulusLength int, bound *big.Int) (*Damgard, error) {
 	}
 
 	h := new(big.Int)
+	sampler := sample.NewUniformRange(two, key.Q)
 	for {
-		sampler := sample.NewUniformRange(two, key.Q)
 		r, err := sampler.Sample()
 		if err != nil {
 			return nil, err
@@ -107,9 +112,75 @@ func NewDamgard(l, modulusLength int, bound *big.Int) (*Damgard, error) {
 			P:     key.P,
 			Q:     key.Q,
 		},
+		randReader: innerprod.NewOptions(opts...).Rand,
 	}, nil
 }
 
+// damgardSecLevels maps security levels (in bits) to the bit lengths
+// of the modulus that are believed to achieve them, following the
+// recommendations for finite field discrete logarithm groups given
+// in NIST SP 800-57.
+var damgardSecLevels = []struct {
+	secBits, modulusLength int
+}{
+	{80, 1024},
+	{112, 2048},
+	{128, 3072},
+	{192, 7680},
+	{256, 15360},
+}
+
+// DamgardModulusLength returns the minimal bit length of the modulus
+// for which the scheme with input vectors of length l whose coordinates
+// are bounded by bound achieves secBits bits of security and satisfies
+// the precondition 2 * l * bound² < q. It returns an error if secBits
+// is greater than 256.
+func DamgardModulusLength(l int, bound *big.Int, secBits int) (int, error) {
+	modulusLength := 0
+	for _, e := range damgardSecLevels {
+		if e.secBits >= secBits {
+			modulusLength = e.modulusLength
+			break
+		}
+	}
+	if modulusLength == 0 {
+		return 0, fmt.Errorf("security levels above %d bits are not supported",
+			damgardSecLevels[len(damgardSecLevels)-1].secBits)
+	}
+
+	// p = 2q + 1 is a safe prime, so q has one bit less than p
+	bSquared := new(big.Int).Mul(bound, bound)
+	prod := new(big.Int).Mul(big.NewInt(int64(2*l)), bSquared)
+	if minLength := prod.BitLen() + 2; minLength > modulusLength {
+		modulusLength = minLength
+	}
+
+	return modulusLength, nil
+}
+
+// NewDamgardAuto configures a new instance of the scheme, choosing
+// the minimal bit length of the modulus by DamgardModulusLength. It
+// accepts the length of input vectors l, a bound by which coordinates
+// of input vectors are bounded, and the targeted security level in
+// bits. Besides the scheme it returns the chosen modulus length.
+//
+// It returns an error in case the scheme could not be properly
+// configured. Optionally a source of randomness for generating keys
+// and encrypting can be set with innerprod.WithRandomness.
+func NewDamgardAuto(l int, bound *big.Int, secBits int, opts ...innerprod.Option) (*Damgard, int, error) {
+	modulusLength, err := DamgardModulusLength(l, bound, secBits)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	damgard, err := NewDamgard(l, modulusLength, bound, opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return damgard, modulusLength, nil
+}
+
 // NewDamgardPrecomp configures a new instance of the scheme based on
 // precomputed prime numbers and generators.
 // It accepts the length of input vectors l, the bit length of the
@@ -121,8 +192,9 @@ func NewDamgard(l, modulusLength int, bound *big.Int) (*Damgard, error) {
 //
 // It returns an error in case the scheme could not be properly
 // configured, or if precondition l * bound² is >= order of the cyclic
-// group.
-func NewDamgardPrecomp(l, modulusLength int, bound *big.Int) (*Damgard, error) {
+// group. Optionally a source of randomness for generating keys and
+// encrypting can be set with innerprod.WithRandomness.
+func NewDamgardPrecomp(l, modulusLength int, bound *big.Int, opts ...innerprod.Option) (*Damgard, error) {
 	one := big.NewInt(1)
 	two := big.NewInt(2)
 	g := new(big.Int)
@@ -175,15 +247,19 @@ func NewDamgardPrecomp(l, modulusLength int, bound *big.Int) (*Damgard, error) {
 			P:     p,
 			Q:     q,
 		},
+		randReader: innerprod.NewOptions(opts...).Rand,
 	}, nil
 }
 
 // NewDamgardFromParams takes configuration parameters of an existing
 // Damgard scheme instance, and reconstructs the scheme with same configuration
-// parameters. It returns a new Damgard instance.
-func NewDamgardFromParams(params *DamgardParams) *Damgard {
+// parameters. It returns a new Damgard instance. Optionally a source of
+// randomness for generating keys and encrypting can be set with
+// innerprod.WithRandomness.
+func NewDamgardFromParams(params *DamgardParams, opts ...innerprod.Option) *Damgard {
 	return &Damgard{
-		Params: params,
+		Params:     params,
+		randReader: innerprod.NewOptions(opts...).Rand,
 	}
 }
 
@@ -193,6 +269,43 @@ type DamgardSecKey struct {
 	T data.Vector
 }
 
+// damgardSecKeyJSON is the JSON form of DamgardSecKey
+// with all the values written as decimal strings.
+type damgardSecKeyJSON struct {
+	S []string `json:"s"`
+	T []string `json:"t"`
+}
+
+// MarshalJSON encodes the secret key as a JSON object
+// with the values written as decimal strings.
+func (k DamgardSecKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(damgardSecKeyJSON{S: vecToDecimal(k.S), T: vecToDecimal(k.T)})
+}
+
+// UnmarshalJSON decodes the secret key from the form given by
+// MarshalJSON. It returns an error if a value is not a decimal
+// integer or if the lengths of the parts do not match.
+func (k *DamgardSecKey) UnmarshalJSON(b []byte) error {
+	var kJSON damgardSecKeyJSON
+	if err := json.Unmarshal(b, &kJSON); err != nil {
+		return err
+	}
+	if len(kJSON.S) != len(kJSON.T) {
+		return fmt.Errorf("parts of the secret key have different lengths")
+	}
+	s, err := vecFromDecimal(kJSON.S)
+	if err != nil {
+		return err
+	}
+	t, err := vecFromDecimal(kJSON.T)
+	if err != nil {
+		return err
+	}
+	k.S, k.T = s, t
+
+	return nil
+}
+
 // GenerateMasterKeys generates a master secret key and master
 // public key for the scheme. It returns an error in case master keys
 // could not be generated.
@@ -202,7 +315,7 @@ func (d *Damgard) GenerateMasterKeys() (*DamgardSecKey, data.Vector, error) {
 	mskT := make(data.Vector, d.Params.L)
 
 	masterPubKey := make([]*big.Int, d.Params.L)
-	sampler := sample.NewUniformRange(big.NewInt(2), d.Params.Q)
+	sampler := sample.NewUniformRangeWithReader(big.NewInt(2), d.Params.Q, d.randReader)
 
 	for i := 0; i < d.Params.L; i++ {
 		s, err := sampler.Sample()
@@ -232,6 +345,37 @@ type DamgardDerivedKey struct {
 	Key2 *big.Int
 }
 
+// damgardDerivedKeyJSON is the JSON form of DamgardDerivedKey
+// with both values written as decimal strings.
+type damgardDerivedKeyJSON struct {
+	Key1 string `json:"key1"`
+	Key2 string `json:"key2"`
+}
+
+// MarshalJSON encodes the derived key as a JSON object
+// with the values written as decimal strings.
+func (k DamgardDerivedKey) MarshalJSON() ([]byte, error) {
+	v := vecToDecimal(data.Vector{k.Key1, k.Key2})
+	return json.Marshal(damgardDerivedKeyJSON{Key1: v[0], Key2: v[1]})
+}
+
+// UnmarshalJSON decodes the derived key from the form given by
+// MarshalJSON. It returns an error if a value is not a decimal
+// integer.
+func (k *DamgardDerivedKey) UnmarshalJSON(b []byte) error {
+	var kJSON damgardDerivedKeyJSON
+	if err := json.Unmarshal(b, &kJSON); err != nil {
+		return err
+	}
+	v, err := vecFromDecimal([]string{kJSON.Key1, kJSON.Key2})
+	if err != nil {
+		return err
+	}
+	k.Key1, k.Key2 = v[0], v[1]
+
+	return nil
+}
+
 // DeriveKey takes master secret key and input vector y, and returns the
 // functional encryption key. In case the key could not be derived, it
 // returns an error.
@@ -240,19 +384,16 @@ func (d *Damgard) DeriveKey(masterSecKey *DamgardSecKey, y data.Vector) (*Damgar
 		return nil, err
 	}
 
-	key1, err := masterSecKey.S.Dot(y)
+	k1, err := masterSecKey.S.DotMod(y, d.Params.Q)
 	if err != nil {
 		return nil, err
 	}
 
-	key2, err := masterSecKey.T.Dot(y)
+	k2, err := masterSecKey.T.DotMod(y, d.Params.Q)
 	if err != nil {
 		return nil, err
 	}
 
-	k1 := new(big.Int).Mod(key1, d.Params.Q)
-	k2 := new(big.Int).Mod(key2, d.Params.Q)
-
 	return &DamgardDerivedKey{Key1: k1, Key2: k2}, nil
 }
 
@@ -263,7 +404,7 @@ func (d *Damgard) Encrypt(x, masterPubKey data.Vector) (data.Vector, error) {
 		return nil, err
 	}
 
-	sampler := sample.NewUniformRange(big.NewInt(2), d.Params.Q)
+	sampler := sample.NewUniformRangeWithReader(big.NewInt(2), d.Params.Q, d.randReader)
 	r, err := sampler.Sample()
 	if err != nil {
 		return nil, err
@@ -297,6 +438,72 @@ func (d *Damgard) Decrypt(cipher data.Vector, key *DamgardDerivedKey, y data.Vec
 		return nil, err
 	}
 
+	r, err := d.decryptToGroup(cipher, key, y)
+	if err != nil {
+		return nil, err
+	}
+
+	bSquared := new(big.Int).Exp(d.Params.Bound, big.NewInt(2), big.NewInt(0))
+	bound := new(big.Int).Mul(big.NewInt(int64(d.Params.L)), bSquared)
+
+	calc, err := dlog.NewCalc().InZp(d.Params.P, d.Params.Q)
+	if err != nil {
+		return nil, err
+	}
+	calc = calc.WithNeg()
+
+	res, err := calc.WithBound(bound).BabyStepGiantStep(r, d.Params.G)
+	return res, err
+}
+
+// DecryptSum accepts a slice of ciphertexts of vectors x_1,..., x_m, each
+// possibly encrypted with a different master public key, the functional
+// encryption keys for y derived from the corresponding master secret keys,
+// and a plaintext vector y. It returns the sum of inner products
+// <x_1, y> + ... + <x_m, y>. Each ciphertext is only decrypted to a group
+// element; the elements are multiplied together and a single discrete
+// logarithm is computed for the summed bound.
+// If decryption failed, error is returned.
+func (d *Damgard) DecryptSum(ciphers []data.Vector, keys []*DamgardDerivedKey, y data.Vector) (*big.Int, error) {
+	if len(ciphers) == 0 {
+		return nil, fmt.Errorf("at least one ciphertext should be given")
+	}
+	if len(ciphers) != len(keys) {
+		return nil, fmt.Errorf("the number of ciphertexts and keys should be the same")
+	}
+	if err := y.CheckBound(d.Params.Bound); err != nil {
+		return nil, err
+	}
+
+	r := big.NewInt(1)
+	for i, cipher := range ciphers {
+		ri, err := d.decryptToGroup(cipher, keys[i], y)
+		if err != nil {
+			return nil, err
+		}
+		r.Mul(r, ri)
+		r.Mod(r, d.Params.P)
+	}
+
+	bSquared := new(big.Int).Exp(d.Params.Bound, big.NewInt(2), big.NewInt(0))
+	bound := new(big.Int).Mul(big.NewInt(int64(d.Params.L*len(ciphers))), bSquared)
+
+	calc, err := dlog.NewCalc().InZp(d.Params.P, d.Params.Q)
+	if err != nil {
+		return nil, err
+	}
+	calc = calc.WithNeg()
+
+	return calc.WithBound(bound).BabyStepGiantStep(r, d.Params.G)
+}
+
+// decryptToGroup accepts the encrypted vector, functional encryption key,
+// and a plaintext vector y. It returns g^<x, y> in Z_p.
+func (d *Damgard) decryptToGroup(cipher data.Vector, key *DamgardDerivedKey, y data.Vector) (*big.Int, error) {
+	if len(cipher) != len(y)+2 {
+		return nil, fmt.Errorf("the length of the ciphertext does not match the length of y")
+	}
+
 	num := big.NewInt(1)
 	for i, ct := range cipher[2:] {
 		t1 := internal.ModExp(ct, y[i], d.Params.P)
@@ -308,17 +515,86 @@ func (d *Damgard) Decrypt(cipher data.Vector, key *DamgardDerivedKey, y data.Vec
 
 	denom := new(big.Int).Mod(new(big.Int).Mul(t1, t2), d.Params.P)
 	denomInv := new(big.Int).ModInverse(denom, d.Params.P)
-	r := new(big.Int).Mod(new(big.Int).Mul(num, denomInv), d.Params.P)
 
-	bSquared := new(big.Int).Exp(d.Params.Bound, big.NewInt(2), big.NewInt(0))
-	bound := new(big.Int).Mul(big.NewInt(int64(d.Params.L)), bSquared)
+	return new(big.Int).Mod(new(big.Int).Mul(num, denomInv), d.Params.P), nil
+}
 
-	calc, err := dlog.NewCalc().InZp(d.Params.P, d.Params.Q)
+// KAT generates a known-answer test of the scheme for vectors x and y.
+// The master keys, the functional encryption key for y and the
+// encryption of x are derived deterministically from seed, using the
+// parameters of d, so the test can be reproduced and checked against
+// other implementations. An error is returned if any of the steps
+// fails, or if the decryption does not give <x, y>.
+func (d *Damgard) KAT(seed []byte, x, y data.Vector) (*innerprod.KAT, error) {
+	det := NewDamgardFromParams(d.Params, innerprod.WithSeed(seed))
+	masterSecKey, masterPubKey, err := det.GenerateMasterKeys()
 	if err != nil {
 		return nil, err
 	}
-	calc = calc.WithNeg()
+	key, err := det.DeriveKey(masterSecKey, y)
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := det.Encrypt(x, masterPubKey)
+	if err != nil {
+		return nil, err
+	}
+	xy, err := det.Decrypt(cipher, key, y)
+	if err != nil {
+		return nil, err
+	}
+	kat := &innerprod.KAT{Scheme: "fullysec.Damgard", Seed: seed, Params: d.Params,
+		MasterSecKey: masterSecKey, MasterPubKey: masterPubKey,
+		X: x, Y: y, FEKey: key, Cipher: cipher, XY: xy}
+	if err := kat.Verify(); err != nil {
+		return nil, err
+	}
 
-	res, err := calc.WithBound(bound).BabyStepGiantStep(r, d.Params.G)
-	return res, err
+	return kat, nil
+}
+
+// MarshalDamgardCipher encodes a ciphertext of the Damgard scheme
+// as a JSON array of decimal strings.
+func MarshalDamgardCipher(cipher data.Vector) ([]byte, error) {
+	return json.Marshal(vecToDecimal(cipher))
+}
+
+// UnmarshalDamgardCipher decodes a ciphertext of the Damgard scheme
+// from the form given by MarshalDamgardCipher. It returns an error
+// if a value is not a decimal integer.
+func UnmarshalDamgardCipher(b []byte) (data.Vector, error) {
+	var cJSON []string
+	if err := json.Unmarshal(b, &cJSON); err != nil {
+		return nil, err
+	}
+
+	return vecFromDecimal(cJSON)
+}
+
+// vecToDecimal writes the elements of v as decimal strings.
+func vecToDecimal(v data.Vector) []string {
+	res := make([]string, len(v))
+	for i, e := range v {
+		if e == nil {
+			continue
+		}
+		res[i] = e.String()
+	}
+
+	return res
+}
+
+// vecFromDecimal reads a vector from its elements written
+// as decimal strings.
+func vecFromDecimal(s []string) (data.Vector, error) {
+	res := make(data.Vector, len(s))
+	for i, e := range s {
+		x, ok := new(big.Int).SetString(e, 10)
+		if !ok {
+			return nil, fmt.Errorf("element %d is not a decimal integer", i)
+		}
+		res[i] = x
+	}
+
+	return res, nil
 }
@@ -17,11 +17,12 @@
 package fullysec
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/big"
+	"sync"
 
 	"github.com/fentec-project/gofe/data"
-	"github.com/fentec-project/gofe/internal"
 	"github.com/fentec-project/gofe/internal/dlog"
 	"github.com/fentec-project/gofe/sample"
 )
@@ -129,7 +130,7 @@ func NewDamgardMultiPrecomp(numClients, l, modulusLength int, bound *big.Int) (*
 func NewDamgardMultiClientFromParams(bound *big.Int, params *DamgardParams) *DamgardMultiClient {
 	return &DamgardMultiClient{
 		Bound:   bound,
-		Damgard: &Damgard{params},
+		Damgard: NewDamgardFromParams(params),
 	}
 }
 
@@ -142,12 +143,21 @@ func NewDamgardMultiFromParams(numClients int, bound *big.Int, params *DamgardPa
 	return &DamgardMulti{
 		NumClients: numClients,
 		Bound:      bound,
-		Damgard:    &Damgard{params},
+		Damgard:    NewDamgardFromParams(params),
 	}
 }
 
 // DamgardMultiSecKeys is a struct containing keys and one time pads for all the clients in
-// the Damgard multi input scheme.
+// the Damgard multi input scheme. Msk holds the master secret keys of the clients
+// in the underlying Damgard scheme and is needed only by the central authority
+// for deriving keys. Mpk holds the public keys of the clients, which are not
+// secret by themselves. Otp holds the one-time pads of the clients, which
+// are secret: the i-th client needs its one-time pad to encrypt, but anyone
+// knowing it could, given a derived key, decrypt the inner product of the
+// client's input alone instead of only the sum over all the clients, and
+// could encrypt in the name of the client. Hence the scheme is a secret-key
+// scheme and there is no encryption with public values only. A client's
+// public key and one-time pad can be bundled with ClientEncKey.
 type DamgardMultiSecKeys struct {
 	Msk []*DamgardSecKey
 	Mpk data.Matrix
@@ -162,6 +172,7 @@ func (dm *DamgardMulti) GenerateMasterKeys() (*DamgardMultiSecKeys, error) {
 	multiMpk := make([]data.Vector, dm.NumClients)
 	multiOtp := make([]data.Vector, dm.NumClients)
 
+	otpSampler := sample.NewUniform(dm.Params.Q)
 	for i := 0; i < dm.NumClients; i++ {
 		msk, mpk, err := dm.Damgard.GenerateMasterKeys()
 		if err != nil {
@@ -170,7 +181,7 @@ func (dm *DamgardMulti) GenerateMasterKeys() (*DamgardMultiSecKeys, error) {
 		multiMsk[i] = msk
 		multiMpk[i] = mpk
 
-		otp, err := data.NewRandomVector(dm.Params.L, sample.NewUniform(dm.Params.Q))
+		otp, err := data.NewRandomVector(dm.Params.L, otpSampler)
 		if err != nil {
 			return nil, fmt.Errorf("error in random vector generation")
 		}
@@ -200,12 +211,116 @@ func (e *DamgardMultiClient) Encrypt(x data.Vector, pubKey, otp data.Vector) (da
 	return e.Damgard.Encrypt(xAddOtp, pubKey)
 }
 
+// DamgardMultiClientEncKey is the encryption key of a single client
+// of the DamgardMulti scheme. It bundles the client's public key of
+// the underlying Damgard scheme with its one-time pad, which is secret,
+// see DamgardMultiSecKeys. The fields are not exported so that the
+// one-time pad is not used or leaked by mistake; the key can be
+// transferred to the client in the JSON form.
+type DamgardMultiClientEncKey struct {
+	pubKey data.Vector
+	otp    data.Vector
+}
+
+// damgardMultiClientEncKeyJSON is the JSON form of DamgardMultiClientEncKey.
+type damgardMultiClientEncKeyJSON struct {
+	PubKey []string `json:"pubKey"`
+	Otp    []string `json:"otp"`
+}
+
+// ClientEncKey returns the encryption key of the i-th client. It
+// returns an error if there is no client with index i.
+func (k *DamgardMultiSecKeys) ClientEncKey(i int) (*DamgardMultiClientEncKey, error) {
+	if i < 0 || i >= len(k.Mpk) || i >= len(k.Otp) {
+		return nil, fmt.Errorf("client index %d out of range", i)
+	}
+
+	return &DamgardMultiClientEncKey{
+		pubKey: k.Mpk[i].Copy(),
+		otp:    k.Otp[i].Copy(),
+	}, nil
+}
+
+// MarshalJSON encodes the encryption key of a client. The result
+// contains the one-time pad and must be kept secret.
+func (k *DamgardMultiClientEncKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(damgardMultiClientEncKeyJSON{
+		PubKey: vecToDecimal(k.pubKey),
+		Otp:    vecToDecimal(k.otp),
+	})
+}
+
+// UnmarshalJSON decodes the encryption key of a client from the form
+// given by MarshalJSON. It returns an error if the lengths of the public
+// key and the one-time pad do not match.
+func (k *DamgardMultiClientEncKey) UnmarshalJSON(b []byte) error {
+	var kJSON damgardMultiClientEncKeyJSON
+	if err := json.Unmarshal(b, &kJSON); err != nil {
+		return err
+	}
+	pubKey, err := vecFromDecimal(kJSON.PubKey)
+	if err != nil {
+		return err
+	}
+	otp, err := vecFromDecimal(kJSON.Otp)
+	if err != nil {
+		return err
+	}
+	if len(pubKey) != len(otp) {
+		return fmt.Errorf("the lengths of the public key and the one-time pad do not match")
+	}
+	k.pubKey = pubKey
+	k.otp = otp
+
+	return nil
+}
+
+// EncryptWithKey works as Encrypt, but takes the public key and the
+// one-time pad from the client's encryption key.
+func (e *DamgardMultiClient) EncryptWithKey(x data.Vector, key *DamgardMultiClientEncKey) (data.Vector, error) {
+	if len(key.pubKey) != e.Params.L || len(key.otp) != e.Params.L {
+		return nil, fmt.Errorf("the encryption key does not match the parameters of the scheme")
+	}
+
+	return e.Encrypt(x, key.pubKey, key.otp)
+}
+
 // DamgardMultiDerivedKey is a functional encryption key for DamgardMulti scheme.
 type DamgardMultiDerivedKey struct {
 	Keys []*DamgardDerivedKey
 	Z    *big.Int // Σ <u_i, y_i> where u_i is OTP key for i-th client
 }
 
+// NumClients returns the number of clients whose keys are included
+// in the functional encryption key, i.e. the number of ciphertexts
+// it can decrypt.
+func (k *DamgardMultiDerivedKey) NumClients() int {
+	return len(k.Keys)
+}
+
+// Validate checks that the functional encryption key includes a key
+// for each of numClients clients. If some of the keys are missing,
+// the indices of the corresponding clients are listed in the error.
+func (k *DamgardMultiDerivedKey) Validate(numClients int) error {
+	if k.Z == nil {
+		return fmt.Errorf("the key does not include the one-time pad part")
+	}
+	if len(k.Keys) != numClients {
+		return fmt.Errorf("the key covers %d clients instead of %d", len(k.Keys), numClients)
+	}
+	missing := make([]int, 0)
+	for i, key := range k.Keys {
+		if key == nil || key.Key1 == nil || key.Key2 == nil {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the key is missing the keys of clients %v", missing)
+	}
+
+	return nil
+}
+
 // DeriveKey takes master secret key and a matrix y comprised
 // of input vectors, and returns the functional encryption key.
 // In case the key could not be derived, it returns an error.
@@ -235,34 +350,43 @@ func (dm *DamgardMulti) DeriveKey(secKey *DamgardMultiSecKeys, y data.Matrix) (*
 // Decrypt accepts an array of ciphers, i.e. an array of encrypted vectors,
 // functional encryption key, and a matrix y describing the inner-product.
 // It returns the sum of inner products Σ_i <x_i, y_i>.
-// If decryption failed, error is returned.
+// If decryption failed, error is returned. In particular, an error is
+// returned if a ciphertext, a row of y or a key of some client is missing.
 func (dm *DamgardMulti) Decrypt(cipher []data.Vector, key *DamgardMultiDerivedKey, y data.Matrix) (*big.Int, error) {
 	if err := y.CheckBound(dm.Bound); err != nil {
 		return nil, err
 	}
+	if len(cipher) != dm.NumClients {
+		return nil, fmt.Errorf("got %d ciphertexts, but the scheme has %d clients", len(cipher), dm.NumClients)
+	}
+	if y.Rows() != dm.NumClients {
+		return nil, fmt.Errorf("y has %d rows, but the scheme has %d clients", y.Rows(), dm.NumClients)
+	}
+	if err := key.Validate(dm.NumClients); err != nil {
+		return nil, err
+	}
 
 	r := big.NewInt(1)
 	for k := 0; k < dm.NumClients; k++ {
-		num := big.NewInt(1)
-		for i, ct := range cipher[k][2:] {
-			t1 := internal.ModExp(ct, y[k][i], dm.Params.P)
-			num = num.Mod(new(big.Int).Mul(num, t1), dm.Params.P)
+		rk, err := dm.decryptToGroup(cipher[k], key.Keys[k], y[k])
+		if err != nil {
+			return nil, err
 		}
-
-		t1 := new(big.Int).Exp(cipher[k][0], key.Keys[k].Key1, dm.Params.P)
-		t2 := new(big.Int).Exp(cipher[k][1], key.Keys[k].Key2, dm.Params.P)
-
-		denom := new(big.Int).Mod(new(big.Int).Mul(t1, t2), dm.Params.P)
-		denomInv := new(big.Int).ModInverse(denom, dm.Params.P)
-		r.Mul(r, denomInv)
-		r.Mul(r, num)
+		r.Mul(r, rk)
 		r.Mod(r, dm.Params.P)
 	}
 
+	return dm.decryptFinalize(r, key)
+}
+
+// decryptFinalize removes the one-time pad mask Z from the product r
+// of the clients' contributions and computes the discrete logarithm
+// to obtain the sum of inner products.
+func (dm *DamgardMulti) decryptFinalize(r *big.Int, key *DamgardMultiDerivedKey) (*big.Int, error) {
 	zExp := new(big.Int).Exp(dm.Params.G, key.Z, dm.Params.P)
 	zExpInv := new(big.Int).ModInverse(zExp, dm.Params.P)
 
-	r.Mul(r, zExpInv)
+	r = new(big.Int).Mul(r, zExpInv)
 	r.Mod(r, dm.Params.P)
 
 	calc, err := dlog.NewCalc().InZp(dm.Params.P, dm.Params.Q)
@@ -277,3 +401,108 @@ func (dm *DamgardMulti) Decrypt(cipher []data.Vector, key *DamgardMultiDerivedKe
 
 	return res, err
 }
+
+// DamgardMultiAggregator collects the ciphertexts of the clients of
+// the DamgardMulti scheme one by one, as they arrive, and folds each
+// of them into a running product. Hence the ciphertexts need not be
+// kept in memory until all of them are available. It is safe for
+// concurrent use.
+type DamgardMultiAggregator struct {
+	dm    *DamgardMulti
+	key   *DamgardMultiDerivedKey
+	y     data.Matrix
+	r     *big.Int
+	added []bool
+	count int
+	mu    sync.Mutex
+}
+
+// NewAggregator accepts a functional encryption key and a matrix y
+// describing the inner-product and returns an aggregator to which
+// the ciphertexts of the clients can be added incrementally.
+// It returns an error if y is not bounded by the bound of the scheme
+// or does not match the number of clients.
+func (dm *DamgardMulti) NewAggregator(key *DamgardMultiDerivedKey, y data.Matrix) (*DamgardMultiAggregator, error) {
+	if err := y.CheckBound(dm.Bound); err != nil {
+		return nil, err
+	}
+	if len(y) != dm.NumClients {
+		return nil, fmt.Errorf("y should have a row for each client")
+	}
+	if err := key.Validate(dm.NumClients); err != nil {
+		return nil, err
+	}
+
+	return &DamgardMultiAggregator{
+		dm:    dm,
+		key:   key,
+		y:     y,
+		r:     big.NewInt(1),
+		added: make([]bool, dm.NumClients),
+	}, nil
+}
+
+// Add accepts the index of a client and its ciphertext and folds the
+// ciphertext into the aggregate. It returns an error if the index is
+// out of range, if the client's ciphertext was already added or if
+// the ciphertext is malformed.
+func (a *DamgardMultiAggregator) Add(clientIdx int, cipher data.Vector) error {
+	if clientIdx < 0 || clientIdx >= a.dm.NumClients {
+		return fmt.Errorf("client index %d out of range", clientIdx)
+	}
+
+	a.mu.Lock()
+	added := a.added[clientIdx]
+	a.mu.Unlock()
+	if added {
+		return fmt.Errorf("ciphertext of client %d already added", clientIdx)
+	}
+
+	rk, err := a.dm.decryptToGroup(cipher, a.key.Keys[clientIdx], a.y[clientIdx])
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.added[clientIdx] {
+		return fmt.Errorf("ciphertext of client %d already added", clientIdx)
+	}
+	a.r.Mul(a.r, rk)
+	a.r.Mod(a.r, a.dm.Params.P)
+	a.added[clientIdx] = true
+	a.count++
+
+	return nil
+}
+
+// Missing returns the indices of the clients whose ciphertexts
+// have not been added yet.
+func (a *DamgardMultiAggregator) Missing() []int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	missing := make([]int, 0, a.dm.NumClients-a.count)
+	for i, ok := range a.added {
+		if !ok {
+			missing = append(missing, i)
+		}
+	}
+
+	return missing
+}
+
+// Decrypt returns the sum of inner products Σ_i <x_i, y_i> of the
+// added ciphertexts. It returns an error if the ciphertexts of some
+// clients are still missing or if the decryption failed.
+func (a *DamgardMultiAggregator) Decrypt() (*big.Int, error) {
+	a.mu.Lock()
+	if a.count != a.dm.NumClients {
+		a.mu.Unlock()
+		return nil, fmt.Errorf("ciphertexts of %d clients are missing", a.dm.NumClients-a.count)
+	}
+	r := new(big.Int).Set(a.r)
+	a.mu.Unlock()
+
+	return a.dm.decryptFinalize(r, a.key)
+}
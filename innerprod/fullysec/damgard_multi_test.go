@@ -17,6 +17,7 @@
 package fullysec_test
 
 import (
+	"encoding/json"
 	"math/big"
 	"testing"
 
@@ -117,3 +118,205 @@ func TestFullySec_DamgardMultiDDH(t *testing.T) {
 		})
 	}
 }
+
+func TestFullySec_DamgardMultiAggregator(t *testing.T) {
+	numClients := 4
+	l := 3
+	bound := big.NewInt(1000)
+	sampler := sample.NewUniformRange(new(big.Int).Add(new(big.Int).Neg(bound), big.NewInt(1)), bound)
+
+	damgardMulti, err := fullysec.NewDamgardMultiPrecomp(numClients, l, 2048, bound)
+	if err != nil {
+		t.Fatalf("Failed to initialize multi input inner product: %v", err)
+	}
+	client := fullysec.NewDamgardMultiClientFromParams(bound, damgardMulti.Params)
+
+	secKeys, err := damgardMulti.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Error during keys generation: %v", err)
+	}
+	y, err := data.NewRandomMatrix(numClients, l, sampler)
+	if err != nil {
+		t.Fatalf("Error during matrix generation: %v", err)
+	}
+	x, err := data.NewRandomMatrix(numClients, l, sampler)
+	if err != nil {
+		t.Fatalf("Error during matrix generation: %v", err)
+	}
+	derivedKey, err := damgardMulti.DeriveKey(secKeys, y)
+	if err != nil {
+		t.Fatalf("Error during key derivation: %v", err)
+	}
+
+	agg, err := damgardMulti.NewAggregator(derivedKey, y)
+	if err != nil {
+		t.Fatalf("Error during aggregator creation: %v", err)
+	}
+
+	// ciphertexts arrive in an arbitrary order
+	for _, i := range []int{2, 0, 3} {
+		c, err := client.Encrypt(x[i], secKeys.Mpk[i], secKeys.Otp[i])
+		if err != nil {
+			t.Fatalf("Error during encryption: %v", err)
+		}
+		if err := agg.Add(i, c); err != nil {
+			t.Fatalf("Error during adding a ciphertext: %v", err)
+		}
+	}
+	assert.Equal(t, []int{1}, agg.Missing())
+	_, err = agg.Decrypt()
+	assert.Error(t, err, "decryption should fail with a missing client")
+
+	c, err := client.Encrypt(x[1], secKeys.Mpk[1], secKeys.Otp[1])
+	if err != nil {
+		t.Fatalf("Error during encryption: %v", err)
+	}
+	assert.Error(t, agg.Add(numClients, c), "out of range index should be rejected")
+	assert.Error(t, agg.Add(1, c[:l]), "malformed ciphertext should be rejected")
+	if err := agg.Add(1, c); err != nil {
+		t.Fatalf("Error during adding a ciphertext: %v", err)
+	}
+	assert.Error(t, agg.Add(1, c), "duplicate ciphertext should be rejected")
+	assert.Empty(t, agg.Missing())
+
+	xy, err := agg.Decrypt()
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+	xyCheck, err := x.Dot(y)
+	if err != nil {
+		t.Fatalf("Error during inner product calculation: %v", err)
+	}
+	assert.Equal(t, xyCheck.Cmp(xy), 0, "obtained incorrect inner product")
+}
+
+func TestFullySec_DamgardMultiClientEncKey(t *testing.T) {
+	numClients := 3
+	l := 4
+	bound := big.NewInt(1000)
+	sampler := sample.NewUniformRange(new(big.Int).Add(new(big.Int).Neg(bound), big.NewInt(1)), bound)
+
+	damgardMulti, err := fullysec.NewDamgardMultiPrecomp(numClients, l, 2048, bound)
+	if err != nil {
+		t.Fatalf("Failed to initialize multi input inner product: %v", err)
+	}
+	secKeys, err := damgardMulti.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Error during keys generation: %v", err)
+	}
+
+	x, err := data.NewRandomMatrix(numClients, l, sampler)
+	if err != nil {
+		t.Fatalf("Error during matrix generation: %v", err)
+	}
+	y, err := data.NewRandomMatrix(numClients, l, sampler)
+	if err != nil {
+		t.Fatalf("Error during matrix generation: %v", err)
+	}
+
+	ciphertexts := make([]data.Vector, numClients)
+	for i := 0; i < numClients; i++ {
+		// the central authority sends the encryption key to the client
+		key, err := secKeys.ClientEncKey(i)
+		if err != nil {
+			t.Fatalf("Error during getting the client key: %v", err)
+		}
+		keyJSON, err := json.Marshal(key)
+		if err != nil {
+			t.Fatalf("Error during key marshalling: %v", err)
+		}
+		var clientKey fullysec.DamgardMultiClientEncKey
+		if err := json.Unmarshal(keyJSON, &clientKey); err != nil {
+			t.Fatalf("Error during key unmarshalling: %v", err)
+		}
+
+		client := fullysec.NewDamgardMultiClientFromParams(bound, damgardMulti.Params)
+		ciphertexts[i], err = client.EncryptWithKey(x[i], &clientKey)
+		if err != nil {
+			t.Fatalf("Error during encryption: %v", err)
+		}
+	}
+
+	derivedKey, err := damgardMulti.DeriveKey(secKeys, y)
+	if err != nil {
+		t.Fatalf("Error during key derivation: %v", err)
+	}
+	xy, err := damgardMulti.Decrypt(ciphertexts, derivedKey, y)
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+	xyCheck, err := x.Dot(y)
+	if err != nil {
+		t.Fatalf("Error during inner product calculation: %v", err)
+	}
+	assert.Equal(t, xyCheck.Cmp(xy), 0, "obtained incorrect inner product")
+
+	_, err = secKeys.ClientEncKey(numClients)
+	assert.Error(t, err)
+	var badKey fullysec.DamgardMultiClientEncKey
+	err = json.Unmarshal([]byte(`{"pubKey":["1","2"],"otp":["1"]}`), &badKey)
+	assert.Error(t, err)
+}
+
+func TestFullySec_DamgardMultiDecryptDims(t *testing.T) {
+	numClients := 3
+	l := 2
+	bound := big.NewInt(1000)
+	sampler := sample.NewUniformRange(new(big.Int).Add(new(big.Int).Neg(bound), big.NewInt(1)), bound)
+
+	damgardMulti, err := fullysec.NewDamgardMultiPrecomp(numClients, l, 2048, bound)
+	if err != nil {
+		t.Fatalf("Failed to initialize multi input inner product: %v", err)
+	}
+	secKeys, err := damgardMulti.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Error during keys generation: %v", err)
+	}
+	x, err := data.NewRandomMatrix(numClients, l, sampler)
+	if err != nil {
+		t.Fatalf("Error during matrix generation: %v", err)
+	}
+	y, err := data.NewRandomMatrix(numClients, l, sampler)
+	if err != nil {
+		t.Fatalf("Error during matrix generation: %v", err)
+	}
+
+	client := fullysec.NewDamgardMultiClientFromParams(bound, damgardMulti.Params)
+	ciphertexts := make([]data.Vector, numClients)
+	for i := 0; i < numClients; i++ {
+		ciphertexts[i], err = client.Encrypt(x[i], secKeys.Mpk[i], secKeys.Otp[i])
+		if err != nil {
+			t.Fatalf("Error during encryption: %v", err)
+		}
+	}
+	derivedKey, err := damgardMulti.DeriveKey(secKeys, y)
+	if err != nil {
+		t.Fatalf("Error during key derivation: %v", err)
+	}
+	assert.Equal(t, numClients, derivedKey.NumClients())
+	assert.NoError(t, derivedKey.Validate(numClients))
+
+	// a missing ciphertext, row of y or key is reported
+	// instead of causing a panic
+	_, err = damgardMulti.Decrypt(ciphertexts[:numClients-1], derivedKey, y)
+	assert.Error(t, err)
+	_, err = damgardMulti.Decrypt(ciphertexts, derivedKey, y[:numClients-1])
+	assert.Error(t, err)
+
+	partialKey := &fullysec.DamgardMultiDerivedKey{Keys: append(derivedKey.Keys[:1:1], nil, derivedKey.Keys[2]), Z: derivedKey.Z}
+	err = partialKey.Validate(numClients)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "[1]")
+	_, err = damgardMulti.Decrypt(ciphertexts, partialKey, y)
+	assert.Error(t, err)
+
+	xy, err := damgardMulti.Decrypt(ciphertexts, derivedKey, y)
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+	xyCheck, err := x.Dot(y)
+	if err != nil {
+		t.Fatalf("Error during inner product calculation: %v", err)
+	}
+	assert.Equal(t, xyCheck.Cmp(xy), 0, "obtained incorrect inner product")
+}
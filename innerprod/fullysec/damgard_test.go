@@ -17,10 +17,15 @@
 package fullysec_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"math/big"
+	"math/rand"
 	"testing"
 
 	"github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/innerprod"
 	"github.com/fentec-project/gofe/innerprod/fullysec"
 	"github.com/fentec-project/gofe/sample"
 	"github.com/stretchr/testify/assert"
@@ -105,3 +110,303 @@ func TestFullySec_DamgardDDH(t *testing.T) {
 		})
 	}
 }
+
+// damgardSumInstance creates m ciphertexts of random vectors, each
+// under an independent master key, together with the corresponding
+// functional keys for a random vector y.
+func damgardSumInstance(damgard *fullysec.Damgard, m int) ([]data.Vector, []*fullysec.DamgardDerivedKey, data.Vector, *big.Int, error) {
+	bound := damgard.Params.Bound
+	sampler := sample.NewUniformRange(new(big.Int).Add(new(big.Int).Neg(bound), big.NewInt(1)), bound)
+	y, err := data.NewRandomVector(damgard.Params.L, sampler)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	ciphers := make([]data.Vector, m)
+	keys := make([]*fullysec.DamgardDerivedKey, m)
+	sum := big.NewInt(0)
+	for i := 0; i < m; i++ {
+		masterSecKey, masterPubKey, err := damgard.GenerateMasterKeys()
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		keys[i], err = damgard.DeriveKey(masterSecKey, y)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		x, err := data.NewRandomVector(damgard.Params.L, sampler)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		ciphers[i], err = damgard.Encrypt(x, masterPubKey)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		xy, _ := x.Dot(y)
+		sum.Add(sum, xy)
+	}
+
+	return ciphers, keys, y, sum, nil
+}
+
+func TestFullySec_DamgardDecryptSum(t *testing.T) {
+	damgard, err := fullysec.NewDamgardPrecomp(8, 1024, big.NewInt(1024))
+	if err != nil {
+		t.Fatalf("Error during scheme creation: %v", err)
+	}
+
+	ciphers, keys, y, sumCheck, err := damgardSumInstance(damgard, 5)
+	if err != nil {
+		t.Fatalf("Error during instance creation: %v", err)
+	}
+
+	sum, err := damgard.DecryptSum(ciphers, keys, y)
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+	assert.Equal(t, sumCheck.Cmp(sum), 0, "obtained incorrect sum of inner products")
+
+	_, err = damgard.DecryptSum(ciphers, keys[1:], y)
+	assert.Error(t, err)
+}
+
+func BenchmarkDamgard_DecryptSum(b *testing.B) {
+	damgard, err := fullysec.NewDamgardPrecomp(8, 1024, big.NewInt(1024))
+	if err != nil {
+		b.Fatalf("Error during scheme creation: %v", err)
+	}
+	ciphers, keys, y, _, err := damgardSumInstance(damgard, 10)
+	if err != nil {
+		b.Fatalf("Error during instance creation: %v", err)
+	}
+
+	b.Run("DecryptSum", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := damgard.DecryptSum(ciphers, keys, y)
+			if err != nil {
+				b.Fatalf("Error during decryption: %v", err)
+			}
+		}
+	})
+
+	b.Run("Decrypt", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for j := range ciphers {
+				_, err := damgard.Decrypt(ciphers[j], keys[j], y)
+				if err != nil {
+					b.Fatalf("Error during decryption: %v", err)
+				}
+			}
+		}
+	})
+}
+
+func TestFullySec_DamgardJSON(t *testing.T) {
+	l := 5
+	bound := big.NewInt(1024)
+	sampler := sample.NewUniformRange(new(big.Int).Neg(bound), bound)
+
+	damgard, err := fullysec.NewDamgard(l, 512, bound)
+	if err != nil {
+		t.Fatalf("Error during simple inner product creation: %v", err)
+	}
+	masterSecKey, masterPubKey, err := damgard.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Error during master key generation: %v", err)
+	}
+	x, err := data.NewRandomVector(l, sampler)
+	if err != nil {
+		t.Fatalf("Error during random generation: %v", err)
+	}
+	y, err := data.NewRandomVector(l, sampler)
+	if err != nil {
+		t.Fatalf("Error during random generation: %v", err)
+	}
+	xyCheck, err := x.Dot(y)
+	if err != nil {
+		t.Fatalf("Error during inner product calculation: %v", err)
+	}
+	ciphertext, err := damgard.Encrypt(x, masterPubKey)
+	if err != nil {
+		t.Fatalf("Error during encryption: %v", err)
+	}
+
+	// serialize the master secret key and the ciphertext, and derive
+	// a key from the reconstructed master secret key
+	secKeyJSON, err := json.Marshal(masterSecKey)
+	if err != nil {
+		t.Fatalf("Error during serialization: %v", err)
+	}
+	cipherJSON, err := fullysec.MarshalDamgardCipher(ciphertext)
+	if err != nil {
+		t.Fatalf("Error during serialization: %v", err)
+	}
+	var secKey fullysec.DamgardSecKey
+	if err = json.Unmarshal(secKeyJSON, &secKey); err != nil {
+		t.Fatalf("Error during deserialization: %v", err)
+	}
+	assert.Equal(t, masterSecKey, &secKey)
+	cipher, err := fullysec.UnmarshalDamgardCipher(cipherJSON)
+	if err != nil {
+		t.Fatalf("Error during deserialization: %v", err)
+	}
+
+	key, err := damgard.DeriveKey(&secKey, y)
+	if err != nil {
+		t.Fatalf("Error during key derivation: %v", err)
+	}
+
+	// serialize the derived key
+	keyJSON, err := json.Marshal(key)
+	if err != nil {
+		t.Fatalf("Error during serialization: %v", err)
+	}
+	var derivedKey fullysec.DamgardDerivedKey
+	if err = json.Unmarshal(keyJSON, &derivedKey); err != nil {
+		t.Fatalf("Error during deserialization: %v", err)
+	}
+
+	xy, err := damgard.Decrypt(cipher, &derivedKey, y)
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+	assert.Equal(t, 0, xy.Cmp(xyCheck), "obtained incorrect inner product")
+
+	_, err = fullysec.UnmarshalDamgardCipher([]byte(`["12", "0x1f"]`))
+	assert.Error(t, err)
+	err = json.Unmarshal([]byte(`{"s": ["1", "2"], "t": ["3"]}`), &secKey)
+	assert.Error(t, err)
+}
+
+func TestFullySec_DamgardWithRandomness(t *testing.T) {
+	l := 3
+	bound := big.NewInt(1024)
+	damgard, err := fullysec.NewDamgardPrecomp(l, 1024, bound)
+	if err != nil {
+		t.Fatalf("Error during simple inner product creation: %v", err)
+	}
+	x := data.Vector{big.NewInt(1), big.NewInt(-2), big.NewInt(3)}
+
+	// two instances with equally seeded sources of randomness
+	// should produce the same keys and ciphertexts
+	ciphers := make([]data.Vector, 2)
+	for i := range ciphers {
+		d := fullysec.NewDamgardFromParams(damgard.Params, innerprod.WithRandomness(rand.New(rand.NewSource(42))))
+		_, masterPubKey, err := d.GenerateMasterKeys()
+		if err != nil {
+			t.Fatalf("Error during master key generation: %v", err)
+		}
+		ciphers[i], err = d.Encrypt(x, masterPubKey)
+		if err != nil {
+			t.Fatalf("Error during encryption: %v", err)
+		}
+	}
+	assert.Equal(t, ciphers[0], ciphers[1])
+
+	// while the default source of randomness should not
+	_, masterPubKey, err := damgard.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Error during master key generation: %v", err)
+	}
+	cipher1, err := damgard.Encrypt(x, masterPubKey)
+	if err != nil {
+		t.Fatalf("Error during encryption: %v", err)
+	}
+	cipher2, err := damgard.Encrypt(x, masterPubKey)
+	if err != nil {
+		t.Fatalf("Error during encryption: %v", err)
+	}
+	assert.NotEqual(t, cipher1, cipher2)
+}
+
+func TestFullySec_DamgardAuto(t *testing.T) {
+	modulusLength, err := fullysec.DamgardModulusLength(100, big.NewInt(1000), 112)
+	assert.NoError(t, err)
+	assert.Equal(t, 2048, modulusLength)
+
+	// the bound dominates the security level
+	bound := new(big.Int).Exp(big.NewInt(2), big.NewInt(1100), nil)
+	modulusLength, err = fullysec.DamgardModulusLength(8, bound, 112)
+	assert.NoError(t, err)
+	assert.Equal(t, 2207, modulusLength)
+
+	_, err = fullysec.DamgardModulusLength(8, big.NewInt(10), 300)
+	assert.Error(t, err)
+
+	l := 5
+	damgard, modulusLength, err := fullysec.NewDamgardAuto(l, big.NewInt(100), 80)
+	if err != nil {
+		t.Fatalf("Error during simple inner product creation: %v", err)
+	}
+	assert.Equal(t, 1024, modulusLength)
+	assert.Equal(t, 1024, damgard.Params.P.BitLen())
+}
+
+func TestFullySec_DamgardErrors(t *testing.T) {
+	l := 4
+	bound := big.NewInt(100)
+	damgard, err := fullysec.NewDamgardPrecomp(l, 1024, bound)
+	if err != nil {
+		t.Fatalf("Error during scheme creation: %v", err)
+	}
+	masterSecKey, masterPubKey, err := damgard.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Error during master key generation: %v", err)
+	}
+	otherSecKey, _, err := damgard.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Error during master key generation: %v", err)
+	}
+
+	tooBig := data.NewConstantVector(l, big.NewInt(1000))
+	_, err = damgard.DeriveKey(masterSecKey, tooBig)
+	assert.True(t, errors.Is(err, innerprod.ErrBoundExceeded))
+	_, err = damgard.Encrypt(tooBig, masterPubKey)
+	assert.True(t, errors.Is(err, innerprod.ErrBoundExceeded))
+
+	// a key derived from another master key does not decrypt
+	y := data.NewConstantVector(l, big.NewInt(1))
+	key, err := damgard.DeriveKey(otherSecKey, y)
+	if err != nil {
+		t.Fatalf("Error during key derivation: %v", err)
+	}
+	cipher, err := damgard.Encrypt(y, masterPubKey)
+	if err != nil {
+		t.Fatalf("Error during encryption: %v", err)
+	}
+	_, err = damgard.Decrypt(cipher, key, y)
+	assert.True(t, errors.Is(err, innerprod.ErrDlogNotFound))
+}
+
+func TestFullySec_DamgardKAT(t *testing.T) {
+	damgard, err := fullysec.NewDamgardPrecomp(3, 1024, big.NewInt(1024))
+	if err != nil {
+		t.Fatalf("Error during simple inner product creation: %v", err)
+	}
+	x := data.Vector{big.NewInt(1), big.NewInt(-2), big.NewInt(3)}
+	y := data.Vector{big.NewInt(4), big.NewInt(5), big.NewInt(-6)}
+
+	export := func(seed string) string {
+		kat, err := damgard.KAT([]byte(seed), x, y)
+		if err != nil {
+			t.Fatalf("Error during known-answer test generation: %v", err)
+		}
+		assert.Equal(t, big.NewInt(-24), kat.XY)
+		var buf bytes.Buffer
+		if err := kat.WriteJSON(&buf); err != nil {
+			t.Fatalf("Error during known-answer test export: %v", err)
+		}
+		return buf.String()
+	}
+
+	assert.Equal(t, export("seed"), export("seed"))
+	assert.NotEqual(t, export("seed"), export("another seed"))
+
+	// a known-answer test with a wrong result is rejected
+	kat, err := damgard.KAT([]byte("seed"), x, y)
+	if err != nil {
+		t.Fatalf("Error during known-answer test generation: %v", err)
+	}
+	kat.XY = big.NewInt(24)
+	assert.Error(t, kat.Verify())
+}
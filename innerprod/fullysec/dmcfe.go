@@ -196,9 +196,8 @@ func DMCFEDecrypt(ciphers []*bn256.G1, keyShares []data.VectorG2, y data.Vector,
 	t1.Neg(t1)
 	s.Add(s, t1)
 
-	g1gen := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
-	g2gen := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
-	g := bn256.Pair(g1gen, g2gen)
+	// e(g1, g2) is the generator of GT, no need to compute the pairing
+	g := new(bn256.GT).ScalarBaseMult(big.NewInt(1))
 
 	dec, err := dlog.NewCalc().InBN256().WithNeg().WithBound(bound).BabyStepGiantStep(s, g)
 
@@ -17,6 +17,7 @@
 package fullysec
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/fentec-project/bn256"
@@ -129,18 +130,32 @@ func (f FHMultiIPE) GenerateKeys() (*FHMultiIPESecKey, *bn256.GT, error) {
 	return &FHMultiIPESecKey{BHat: BHat, BStarHat: BStarHat}, gTMu, nil
 }
 
+// maxRandomOBAttempts bounds the number of random matrices sampled
+// by randomOB before giving up.
+const maxRandomOBAttempts = 8
+
 // randomOB is a helping function that samples a random l x l matrix B
-// and calculates BStar = mu * (B^-1)^T
+// and calculates BStar = mu * (B^-1)^T. A random matrix is singular only
+// with a negligible probability, but if this happens it is resampled,
+// at most maxRandomOBAttempts times.
 func randomOB(l int, mu *big.Int) (data.Matrix, data.Matrix, error) {
 	sampler := sample.NewUniform(bn256.Order)
-	B, err := data.NewRandomMatrix(l, l, sampler)
-	if err != nil {
-		return nil, nil, err
+	var B, BStar data.Matrix
+	var err error
+	for i := 0; i < maxRandomOBAttempts; i++ {
+		B, err = data.NewRandomMatrix(l, l, sampler)
+		if err != nil {
+			return nil, nil, err
+		}
+		// the inverse fails exactly when B is not of full rank, so
+		// there is no need to check the rank separately
+		BStar, _, err = B.InverseModGauss(bn256.Order)
+		if err == nil {
+			break
+		}
 	}
-
-	BStar, _, err := B.InverseModGauss(bn256.Order)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, fmt.Errorf("failed to sample an invertible matrix in %d attempts", maxRandomOBAttempts)
 	}
 	BStar = BStar.Transpose()
 	BStar = BStar.MulScalar(mu)
@@ -153,8 +168,26 @@ func randomOB(l int, mu *big.Int) (data.Matrix, data.Matrix, error) {
 // master secret key, and returns the functional encryption key. That is
 // a key that for encrypted x_1,...,x_m allows to calculate the sum of
 // inner products <x_1,y_1> + ... + <x_m, y_m>. In case the key could not
-// be derived, it returns an error.
+// be derived, e.g. if the dimensions of y or secKey do not fit the scheme,
+// it returns an error.
 func (f FHMultiIPE) DeriveKey(y data.Matrix, secKey *FHMultiIPESecKey) (data.MatrixG2, error) {
+	if len(y) != f.Params.NumClients {
+		return nil, fmt.Errorf("y should have %d rows", f.Params.NumClients)
+	}
+	for i := range y {
+		if len(y[i]) != f.Params.VecLen {
+			return nil, fmt.Errorf("rows of y should have length %d", f.Params.VecLen)
+		}
+	}
+	if secKey == nil || len(secKey.BStarHat) != f.Params.NumClients {
+		return nil, fmt.Errorf("secret key should include a matrix for each of %d clients", f.Params.NumClients)
+	}
+	for i := range secKey.BStarHat {
+		if err := f.checkPartKey(secKey.BStarHat[i], f.Params.VecLen+f.Params.SecLevel); err != nil {
+			return nil, err
+		}
+	}
+
 	sampler := sample.NewUniform(bn256.Order)
 	gamma, err := data.NewRandomMatrix(f.Params.SecLevel, f.Params.NumClients, sampler)
 	if err != nil {
@@ -191,8 +224,16 @@ func (f FHMultiIPE) DeriveKey(y data.Matrix, secKey *FHMultiIPESecKey) (data.Mat
 }
 
 // Encrypt encrypts input vector x with the provided part of the master secret key.
-// It returns a ciphertext vector. If encryption failed, error is returned.
+// It returns a ciphertext vector. If encryption failed, e.g. if the dimensions
+// of x or partSecKey do not fit the scheme, error is returned.
 func (f FHMultiIPE) Encrypt(x data.Vector, partSecKey data.Matrix) (data.VectorG1, error) {
+	if len(x) != f.Params.VecLen {
+		return nil, fmt.Errorf("x should have length %d", f.Params.VecLen)
+	}
+	if err := f.checkPartKey(partSecKey, f.Params.VecLen+f.Params.SecLevel+1); err != nil {
+		return nil, err
+	}
+
 	sampler := sample.NewUniform(bn256.Order)
 	phi, err := data.NewRandomVector(f.Params.SecLevel, sampler)
 	if err != nil {
@@ -217,23 +258,148 @@ func (f FHMultiIPE) Encrypt(x data.Vector, partSecKey data.Matrix) (data.VectorG
 	return keyVec.MulG1(), nil
 }
 
+// CombineCiphers assembles the ciphertexts of the clients, given as a map
+// from the index of a client to its ciphertext, into the matrix expected
+// by Decrypt, whose i-th row is the ciphertext of the i-th client. It
+// returns an error if a ciphertext of some client is missing, if an index
+// does not belong to a client, or if a ciphertext is of a wrong length.
+func (f *FHMultiIPE) CombineCiphers(parts map[int]data.VectorG1) (data.MatrixG1, error) {
+	rowLen := 2*f.Params.VecLen + 2*f.Params.SecLevel + 1
+	for i := range parts {
+		if i < 0 || i >= f.Params.NumClients {
+			return nil, fmt.Errorf("ciphertext of an unknown client %d", i)
+		}
+	}
+
+	cipher := make(data.MatrixG1, f.Params.NumClients)
+	for i := range cipher {
+		c, ok := parts[i]
+		if !ok {
+			return nil, fmt.Errorf("ciphertext of client %d is missing", i)
+		}
+		if len(c) != rowLen {
+			return nil, fmt.Errorf("ciphertext of client %d should have length %d", i, rowLen)
+		}
+		cipher[i] = c
+	}
+
+	return cipher, nil
+}
+
 // Decrypt accepts the ciphertext as a matrix whose rows are encryptions of vectors
 // x_1,...,x_m and a functional encryption key corresponding to vectors y_1,...,y_m.
 // It returns the sum of inner products <x_1,y_1> + ... + <x_m, y_m>. If decryption
-// failed, an error is returned.
+// failed, e.g. if the dimensions of cipher or key do not fit the scheme, an error
+// is returned.
 func (f *FHMultiIPE) Decrypt(cipher data.MatrixG1, key data.MatrixG2, pubKey *bn256.GT) (*big.Int, error) {
+	sum, err := f.DecryptToGT(cipher, key)
+	if err != nil {
+		return nil, err
+	}
+
+	dec, err := dlog.NewCalc().InBN256().WithNeg().WithBound(f.bound()).BabyStepGiantStep(sum, pubKey)
+
+	return dec, err
+}
+
+// DecryptToGT accepts the ciphertext as a matrix whose rows are encryptions of
+// vectors x_1,...,x_m and a functional encryption key corresponding to vectors
+// y_1,...,y_m. It returns pubKey^(<x_1,y_1> + ... + <x_m, y_m>), i.e. the value
+// from which Decrypt obtains the result by computing a discrete logarithm.
+// It returns an error if the dimensions of cipher or key do not fit the scheme.
+func (f *FHMultiIPE) DecryptToGT(cipher data.MatrixG1, key data.MatrixG2) (*bn256.GT, error) {
+	rowLen := 2*f.Params.VecLen + 2*f.Params.SecLevel + 1
+	if len(cipher) != f.Params.NumClients || len(key) != f.Params.NumClients {
+		return nil, fmt.Errorf("ciphertext and key should have %d rows", f.Params.NumClients)
+	}
+	for i := 0; i < f.Params.NumClients; i++ {
+		if len(cipher[i]) != rowLen || len(key[i]) != rowLen {
+			return nil, fmt.Errorf("rows of ciphertext and key should have length %d", rowLen)
+		}
+	}
+
 	sum := new(bn256.GT).ScalarBaseMult(big.NewInt(0))
 	for i := 0; i < f.Params.NumClients; i++ {
-		for j := 0; j < 2*f.Params.VecLen+2*f.Params.SecLevel+1; j++ {
-			paired := bn256.Pair(cipher[i][j], key[i][j])
-			sum.Add(paired, sum)
+		paired, err := cipher[i].PairDot(key[i])
+		if err != nil {
+			return nil, err
 		}
+		sum = new(bn256.GT).Add(paired, sum)
 	}
 
-	boundXY := new(big.Int).Mul(f.Params.BoundX, f.Params.BoundY)
-	bound := new(big.Int).Mul(big.NewInt(int64(f.Params.NumClients*f.Params.VecLen)), boundXY)
+	return sum, nil
+}
 
-	dec, err := dlog.NewCalc().InBN256().WithNeg().WithBound(bound).BabyStepGiantStep(sum, pubKey)
+// CompareToThreshold compares the decrypted sum of inner products
+// <x_1,y_1> + ... + <x_m, y_m> with the given threshold. It returns -1, 0
+// or 1 if the result is smaller than, equal to or greater than the threshold,
+// respectively. Equality is checked directly in GT. Since the result lies in
+// [-B, B] for the bound B of the scheme, the difference d = result - threshold
+// lies in [-B-threshold, B-threshold], so that thresholds outside of [-B, B]
+// need no discrete logarithm at all. Otherwise only the shorter of the
+// intervals (0, B-threshold] and [-B-threshold, 0) is searched for d, and if
+// d is not found there it lies in the other one. Hence the comparison costs
+// at most half of the search done by Decrypt, and less the closer threshold
+// is to B or -B.
+func (f *FHMultiIPE) CompareToThreshold(cipher data.MatrixG1, key data.MatrixG2, pubKey *bn256.GT, threshold *big.Int) (int, error) {
+	sum, err := f.DecryptToGT(cipher, key)
+	if err != nil {
+		return 0, err
+	}
 
-	return dec, err
+	thresholdAbs := new(big.Int).Abs(threshold)
+	pubKeyToThreshold := new(bn256.GT).ScalarMult(pubKey, thresholdAbs)
+	if threshold.Sign() < 0 {
+		pubKeyToThreshold.Neg(pubKeyToThreshold)
+	}
+	if sum.String() == pubKeyToThreshold.String() {
+		return 0, nil
+	}
+
+	// d lies in [-negBound, posBound]
+	posBound := new(big.Int).Sub(f.bound(), threshold)
+	negBound := new(big.Int).Add(f.bound(), threshold)
+	if posBound.Sign() <= 0 {
+		return -1, nil
+	}
+	if negBound.Sign() <= 0 {
+		return 1, nil
+	}
+
+	diff := new(bn256.GT).Add(sum, new(bn256.GT).Neg(pubKeyToThreshold))
+	sign := 1
+	searchBound := posBound
+	if negBound.Cmp(posBound) < 0 {
+		diff.Neg(diff)
+		sign = -1
+		searchBound = negBound
+	}
+	if _, err := dlog.NewCalc().InBN256().WithBound(searchBound).BabyStepGiantStep(diff, pubKey); err != nil {
+		return -sign, nil
+	}
+
+	return sign, nil
+}
+
+// checkPartKey checks that a part of the master secret key belonging
+// to a single client is a matrix with the given number of rows of
+// length 2*VecLen+2*SecLevel+1.
+func (f *FHMultiIPE) checkPartKey(partKey data.Matrix, rows int) error {
+	rowLen := 2*f.Params.VecLen + 2*f.Params.SecLevel + 1
+	if len(partKey) != rows {
+		return fmt.Errorf("part of the secret key should have %d rows", rows)
+	}
+	for _, row := range partKey {
+		if len(row) != rowLen {
+			return fmt.Errorf("rows of the secret key should have length %d", rowLen)
+		}
+	}
+
+	return nil
+}
+
+// bound returns the bound on the absolute value of the decrypted result.
+func (f *FHMultiIPE) bound() *big.Int {
+	boundXY := new(big.Int).Mul(f.Params.BoundX, f.Params.BoundY)
+	return new(big.Int).Mul(big.NewInt(int64(f.Params.NumClients*f.Params.VecLen)), boundXY)
 }
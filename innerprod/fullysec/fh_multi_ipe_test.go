@@ -20,6 +20,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/data"
 	"github.com/fentec-project/gofe/innerprod/fullysec"
 	"github.com/fentec-project/gofe/sample"
@@ -96,3 +97,88 @@ func TestFH_Multi_IPE(t *testing.T) {
 	}
 	assert.Equal(t, xy.Cmp(xyCheck), 0, "obtained incorrect inner product")
 }
+
+func TestFH_Multi_IPE_DecryptToGT(t *testing.T) {
+	secLevel := 1
+	vecLen := 3
+	numClient := 2
+	bound := big.NewInt(10)
+	fhmulti := fullysec.NewFHMultiIPE(secLevel, numClient, vecLen, bound, bound)
+	masterSecKey, pubKey, err := fhmulti.GenerateKeys()
+	if err != nil {
+		t.Fatalf("Error during keys generation: %v", err)
+	}
+
+	x := data.Matrix{
+		data.Vector{big.NewInt(1), big.NewInt(-2), big.NewInt(3)},
+		data.Vector{big.NewInt(4), big.NewInt(0), big.NewInt(5)},
+	}
+	y := data.Matrix{
+		data.Vector{big.NewInt(2), big.NewInt(1), big.NewInt(1)},
+		data.Vector{big.NewInt(1), big.NewInt(7), big.NewInt(-1)},
+	}
+	// <x_1,y_1> + <x_2,y_2> = 3 + (-1) = 2
+	result := big.NewInt(2)
+
+	cipher := make(data.MatrixG1, numClient)
+	for i := 0; i < numClient; i++ {
+		cipher[i], err = fhmulti.Encrypt(x[i], masterSecKey.BHat[i])
+		if err != nil {
+			t.Fatalf("Error during encryption: %v", err)
+		}
+	}
+	key, err := fhmulti.DeriveKey(y, masterSecKey)
+	if err != nil {
+		t.Fatalf("Error during key derivation: %v", err)
+	}
+
+	// ciphertexts of the clients can be combined in any order
+	combined, err := fhmulti.CombineCiphers(map[int]data.VectorG1{1: cipher[1], 0: cipher[0]})
+	if err != nil {
+		t.Fatalf("Error during combining ciphertexts: %v", err)
+	}
+	assert.Equal(t, cipher, combined)
+	_, err = fhmulti.CombineCiphers(map[int]data.VectorG1{0: cipher[0]})
+	assert.Error(t, err)
+	_, err = fhmulti.CombineCiphers(map[int]data.VectorG1{0: cipher[0], 1: cipher[1][1:]})
+	assert.Error(t, err)
+	_, err = fhmulti.CombineCiphers(map[int]data.VectorG1{0: cipher[0], 1: cipher[1], 2: cipher[1]})
+	assert.Error(t, err)
+
+	resGT, err := fhmulti.DecryptToGT(cipher, key)
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+	assert.Equal(t, new(bn256.GT).ScalarMult(pubKey, result).String(), resGT.String())
+
+	// the bound of the scheme is 2*3*10*10 = 600, thresholds beyond it
+	// are decided without a discrete logarithm
+	for threshold, expected := range map[int64]int{2: 0, 1: 1, -5: 1, 3: -1, 100: -1, 599: -1,
+		-599: 1, 600: -1, -600: 1, 1000: -1, -1000: 1} {
+		cmp, err := fhmulti.CompareToThreshold(cipher, key, pubKey, big.NewInt(threshold))
+		if err != nil {
+			t.Fatalf("Error during comparison with %d: %v", threshold, err)
+		}
+		assert.Equal(t, expected, cmp, "wrong comparison with threshold %d", threshold)
+	}
+
+	// malformed inputs are rejected
+	_, err = fhmulti.DecryptToGT(cipher[:1], key)
+	assert.Error(t, err)
+	_, err = fhmulti.DecryptToGT(cipher, key[:1])
+	assert.Error(t, err)
+	_, err = fhmulti.Decrypt(data.MatrixG1{cipher[0], cipher[1][1:]}, key, pubKey)
+	assert.Error(t, err)
+	_, err = fhmulti.Encrypt(x[0][1:], masterSecKey.BHat[0])
+	assert.Error(t, err)
+	_, err = fhmulti.Encrypt(x[0], masterSecKey.BHat[0][1:])
+	assert.Error(t, err)
+	_, err = fhmulti.Encrypt(x[0], masterSecKey.BStarHat[0])
+	assert.Error(t, err)
+	_, err = fhmulti.DeriveKey(y[:1], masterSecKey)
+	assert.Error(t, err)
+	_, err = fhmulti.DeriveKey(data.Matrix{y[0], y[1][1:]}, masterSecKey)
+	assert.Error(t, err)
+	_, err = fhmulti.DeriveKey(y, &fullysec.FHMultiIPESecKey{BHat: masterSecKey.BHat, BStarHat: masterSecKey.BHat})
+	assert.Error(t, err)
+}
@@ -19,6 +19,8 @@ package fullysec
 import (
 	"fmt"
 	"math/big"
+	"runtime"
+	"sync"
 
 	"crypto/rand"
 
@@ -80,12 +82,15 @@ func NewFHIPEFromParams(params *FHIPEParams) *FHIPE {
 	}
 }
 
-// FHIPESecKey is a secret key for FHIPE scheme.
+// FHIPESecKey is a secret key for FHIPE scheme. Det caches the
+// determinant of B, so that it is not recomputed for each derived
+// key; if it is nil, it is computed from B when needed.
 type FHIPESecKey struct {
 	G1    *bn256.G1
 	G2    *bn256.G2
 	B     data.Matrix
 	BStar data.Matrix
+	Det   *big.Int
 }
 
 // GenerateMasterKey generates a master secret key for the scheme.
@@ -114,7 +119,7 @@ func (d *FHIPE) GenerateMasterKey() (*FHIPESecKey, error) {
 	bStar = bStar.MulScalar(det)
 	bStar = bStar.Mod(bn256.Order)
 
-	return &FHIPESecKey{G1: g1, G2: g2, B: b, BStar: bStar}, nil
+	return &FHIPESecKey{G1: g1, G2: g2, B: b, BStar: bStar, Det: det}, nil
 }
 
 // FHIPEDerivedKey is a functional encryption key for FHIPE scheme.
@@ -143,15 +148,18 @@ func (d *FHIPE) DeriveKey(y data.Vector, masterKey *FHIPESecKey) (*FHIPEDerivedK
 		return nil, err
 	}
 
-	det, err := masterKey.B.DeterminantGauss(bn256.Order)
-	if err != nil {
-		return nil, err
+	det := masterKey.Det
+	if det == nil {
+		det, err = masterKey.B.DeterminantGauss(bn256.Order)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	k1 := new(bn256.G1).ScalarMult(masterKey.G1, det)
 	k1.ScalarMult(k1, alpha)
 
-	alphaBY, err := masterKey.B.MulVec(y)
+	alphaBY, err := masterKey.B.MulVecMod(y, bn256.Order)
 	if err != nil {
 		return nil, err
 	}
@@ -193,7 +201,7 @@ func (d *FHIPE) Encrypt(x data.Vector, masterKey *FHIPESecKey) (*FHIPECipher, er
 
 	c1 := new(bn256.G2).ScalarMult(masterKey.G2, beta)
 
-	betaBStarX, err := masterKey.BStar.MulVec(x)
+	betaBStarX, err := masterKey.BStar.MulVecMod(x, bn256.Order)
 	if err != nil {
 		return nil, err
 	}
@@ -212,23 +220,153 @@ func (d *FHIPE) Encrypt(x data.Vector, masterKey *FHIPESecKey) (*FHIPECipher, er
 // It returns the inner product of x and y. If decryption failed,
 // an error is returned.
 func (d *FHIPE) Decrypt(cipher *FHIPECipher, key *FHIPEDerivedKey) (*big.Int, error) {
+	d2, d1, err := d.DecryptToGT(cipher, key)
+	if err != nil {
+		return nil, err
+	}
+
+	dec, err := d.calc().BabyStepGiantStep(d2, d1)
+	return dec, err
+}
+
+// DecryptToGT accepts the ciphertext and functional encryption key.
+// It returns the values d2 and d1 of GT such that d2 = d1^<x,y>, i.e.
+// the values from which Decrypt obtains the result by computing a
+// discrete logarithm. Note that the base d1 is randomized by both
+// the key and the ciphertext. It returns an error if the dimensions of
+// cipher or key do not fit the scheme.
+func (d *FHIPE) DecryptToGT(cipher *FHIPECipher, key *FHIPEDerivedKey) (*bn256.GT, *bn256.GT, error) {
 	if len(cipher.C2) != d.Params.L || len(key.K2) != d.Params.L {
-		return nil, fmt.Errorf("key or cipher length error")
+		return nil, nil, fmt.Errorf("key or cipher length error")
 	}
 
-	d1 := bn256.Pair(key.K1, cipher.C1)
+	d1, d2, err := d.pair(cipher, key)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	d2 := new(bn256.GT).ScalarBaseMult(big.NewInt(0))
-	for i := 0; i < d.Params.L; i++ {
-		pairedI := bn256.Pair(key.K2[i], cipher.C2[i])
-		d2 = new(bn256.GT).Add(pairedI, d2)
+	return d2, d1, nil
+}
+
+// pair computes d1 = e(K1, C1) and d2 = e(K2, C2), where d2 = d1^<x,y>.
+func (d *FHIPE) pair(cipher *FHIPECipher, key *FHIPEDerivedKey) (*bn256.GT, *bn256.GT, error) {
+	d1 := bn256.Pair(key.K1, cipher.C1)
+	d2, err := key.K2.PairDot(cipher.C2)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	return d1, d2, nil
+}
+
+// calc returns a calculator of discrete logarithms bounded by the
+// largest possible absolute value of the inner product.
+func (d *FHIPE) calc() *dlog.CalcBN256 {
 	// calculate the upper bound of the result needed for the
 	// discrete logarithm computation
 	boundXY := new(big.Int).Mul(d.Params.BoundX, d.Params.BoundY)
 	bound := new(big.Int).Mul(big.NewInt(int64(d.Params.L)), boundXY)
 
-	dec, err := dlog.NewCalc().InBN256().WithNeg().WithBound(bound).BabyStepGiantStep(d2, d1)
-	return dec, err
+	return dlog.NewCalc().InBN256().WithNeg().WithBound(bound)
+}
+
+// FHIPEDecryptor decrypts many ciphertexts of the same FHIPE scheme.
+// It configures the discrete logarithm calculator once and shares it
+// among all the decryptions.
+type FHIPEDecryptor struct {
+	fhipe *FHIPE
+	calc  *dlog.CalcBN256
+}
+
+// NewDecryptor returns a decryptor for the ciphertexts of the scheme.
+// Note that the base e(K1, C1) of the discrete logarithm is randomized
+// by both the key and the ciphertext, hence the baby steps computed for
+// one ciphertext cannot be reused for another. The decryptor thus only
+// shares the setup of the calculator, while DecryptBatch gains its speed
+// by decrypting the ciphertexts concurrently.
+func (d *FHIPE) NewDecryptor() *FHIPEDecryptor {
+	return &FHIPEDecryptor{fhipe: d, calc: d.calc()}
+}
+
+// Decrypt accepts the ciphertext and functional encryption key.
+// It returns the inner product of x and y. If decryption failed,
+// an error is returned.
+func (dec *FHIPEDecryptor) Decrypt(cipher *FHIPECipher, key *FHIPEDerivedKey) (*big.Int, error) {
+	d2, d1, err := dec.fhipe.DecryptToGT(cipher, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return dec.calc.BabyStepGiantStep(d2, d1)
+}
+
+// DecryptBatch decrypts all the ciphertexts with the functional
+// encryption key. The decryptions are split among a pool of workers.
+// It returns the inner products in the order of the ciphertexts. If
+// some decryption failed, an error is returned.
+func (dec *FHIPEDecryptor) DecryptBatch(ciphers []*FHIPECipher, key *FHIPEDerivedKey) ([]*big.Int, error) {
+	res := make([]*big.Int, len(ciphers))
+	if len(ciphers) == 0 {
+		return res, nil
+	}
+
+	jobs := make(chan int, len(ciphers))
+	errChan := make(chan error, len(ciphers))
+	var wg sync.WaitGroup
+	workers := runtime.NumCPU()
+	if workers > len(ciphers) {
+		workers = len(ciphers)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				xy, err := dec.Decrypt(ciphers[i], key)
+				if err != nil {
+					errChan <- fmt.Errorf("ciphertext %d: %w", i, err)
+					continue
+				}
+				res[i] = xy
+			}
+		}()
+	}
+	for i := range ciphers {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	close(errChan)
+	if err, ok := <-errChan; ok {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ArgMaxDecrypt decrypts all the ciphertexts with the functional
+// encryption key and returns the index of the ciphertext with the
+// greatest inner product together with the inner product. If more
+// ciphertexts attain the maximum, the smallest index is returned.
+// Note that all the inner products are still computed, so the
+// decryptor learns them as well. It returns an error if no
+// ciphertexts are given or some decryption failed.
+func (d *FHIPE) ArgMaxDecrypt(ciphers []*FHIPECipher, key *FHIPEDerivedKey) (int, *big.Int, error) {
+	if len(ciphers) == 0 {
+		return 0, nil, fmt.Errorf("at least one ciphertext should be given")
+	}
+
+	res, err := d.NewDecryptor().DecryptBatch(ciphers, key)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	argMax := 0
+	for i, xy := range res {
+		if xy.Cmp(res[argMax]) > 0 {
+			argMax = i
+		}
+	}
+
+	return argMax, res[argMax], nil
 }
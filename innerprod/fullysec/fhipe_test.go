@@ -20,6 +20,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/data"
 	"github.com/fentec-project/gofe/innerprod/fullysec"
 	"github.com/fentec-project/gofe/sample"
@@ -85,3 +86,192 @@ func TestFHIPE(t *testing.T) {
 	}
 	assert.Equal(t, xy.Cmp(xyCheck), 0, "obtained incorrect inner product")
 }
+
+func TestFHIPE_DecryptBatch(t *testing.T) {
+	l := 5
+	bound := big.NewInt(64)
+	numCiphers := 8
+
+	fhipe, err := fullysec.NewFHIPE(l, bound, bound)
+	if err != nil {
+		t.Fatalf("Error during scheme creation: %v", err)
+	}
+	masterSecKey, err := fhipe.GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("Error during master key generation: %v", err)
+	}
+
+	sampler := sample.NewUniformRange(new(big.Int).Add(new(big.Int).Neg(bound), big.NewInt(1)), bound)
+	y, err := data.NewRandomVector(l, sampler)
+	if err != nil {
+		t.Fatalf("Error during random vector generation: %v", err)
+	}
+	key, err := fhipe.DeriveKey(y, masterSecKey)
+	if err != nil {
+		t.Fatalf("Error during key derivation: %v", err)
+	}
+
+	ciphers := make([]*fullysec.FHIPECipher, numCiphers)
+	xyCheck := make([]*big.Int, numCiphers)
+	for i := range ciphers {
+		x, err := data.NewRandomVector(l, sampler)
+		if err != nil {
+			t.Fatalf("Error during random vector generation: %v", err)
+		}
+		ciphers[i], err = fhipe.Encrypt(x, masterSecKey)
+		if err != nil {
+			t.Fatalf("Error during encryption: %v", err)
+		}
+		xyCheck[i], err = x.Dot(y)
+		if err != nil {
+			t.Fatalf("Error during inner product calculation")
+		}
+	}
+
+	decryptor := fullysec.NewFHIPEFromParams(fhipe.Params).NewDecryptor()
+	xy, err := decryptor.DecryptBatch(ciphers, key)
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+	for i := range xy {
+		assert.Equal(t, xyCheck[i].Cmp(xy[i]), 0, "obtained incorrect inner product")
+	}
+
+	// a malformed ciphertext makes the batch fail
+	ciphers[3] = &fullysec.FHIPECipher{C1: ciphers[3].C1, C2: ciphers[3].C2[1:]}
+	_, err = decryptor.DecryptBatch(ciphers, key)
+	assert.Error(t, err)
+}
+
+func TestFHIPE_DecryptToGT(t *testing.T) {
+	l := 5
+	bound := big.NewInt(100)
+	fhipe, err := fullysec.NewFHIPE(l, bound, bound)
+	if err != nil {
+		t.Fatalf("Error during scheme creation: %v", err)
+	}
+	masterSecKey, err := fhipe.GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("Error during master key generation: %v", err)
+	}
+
+	x := data.NewVector([]*big.Int{big.NewInt(1), big.NewInt(-2), big.NewInt(3), big.NewInt(0), big.NewInt(5)})
+	y := data.NewVector([]*big.Int{big.NewInt(4), big.NewInt(1), big.NewInt(-1), big.NewInt(7), big.NewInt(2)})
+	ciphertext, err := fhipe.Encrypt(x, masterSecKey)
+	if err != nil {
+		t.Fatalf("Error during encryption: %v", err)
+	}
+	key, err := fhipe.DeriveKey(y, masterSecKey)
+	if err != nil {
+		t.Fatalf("Error during key derivation: %v", err)
+	}
+
+	// <x, y> = 9, hence the result equals the base to the power of 9
+	res, base, err := fhipe.DecryptToGT(ciphertext, key)
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+	assert.Equal(t, new(bn256.GT).ScalarMult(base, big.NewInt(9)).String(), res.String())
+
+	_, _, err = fhipe.DecryptToGT(&fullysec.FHIPECipher{C1: ciphertext.C1, C2: ciphertext.C2[1:]}, key)
+	assert.Error(t, err)
+}
+
+func TestFHIPE_ArgMaxDecrypt(t *testing.T) {
+	l := 3
+	bound := big.NewInt(10)
+	fhipe, err := fullysec.NewFHIPE(l, bound, bound)
+	if err != nil {
+		t.Fatalf("Error during scheme creation: %v", err)
+	}
+	masterSecKey, err := fhipe.GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("Error during master key generation: %v", err)
+	}
+
+	y := data.NewVector([]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(-1)})
+	key, err := fhipe.DeriveKey(y, masterSecKey)
+	if err != nil {
+		t.Fatalf("Error during key derivation: %v", err)
+	}
+
+	// the inner products with y are -3, 7, 2 and 7
+	candidates := []data.Vector{
+		data.NewVector([]*big.Int{big.NewInt(-1), big.NewInt(0), big.NewInt(2)}),
+		data.NewVector([]*big.Int{big.NewInt(3), big.NewInt(2), big.NewInt(0)}),
+		data.NewVector([]*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(0)}),
+		data.NewVector([]*big.Int{big.NewInt(1), big.NewInt(4), big.NewInt(2)}),
+	}
+	ciphers := make([]*fullysec.FHIPECipher, len(candidates))
+	for i, x := range candidates {
+		ciphers[i], err = fhipe.Encrypt(x, masterSecKey)
+		if err != nil {
+			t.Fatalf("Error during encryption: %v", err)
+		}
+	}
+
+	i, xy, err := fhipe.ArgMaxDecrypt(ciphers, key)
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+	assert.Equal(t, 1, i)
+	assert.Equal(t, int64(7), xy.Int64())
+
+	_, _, err = fhipe.ArgMaxDecrypt(nil, key)
+	assert.Error(t, err)
+}
+
+func BenchmarkFHIPE_DecryptBatch(b *testing.B) {
+	l := 5
+	bound := big.NewInt(64)
+	numCiphers := 100
+
+	fhipe, err := fullysec.NewFHIPE(l, bound, bound)
+	if err != nil {
+		b.Fatalf("Error during scheme creation: %v", err)
+	}
+	masterSecKey, err := fhipe.GenerateMasterKey()
+	if err != nil {
+		b.Fatalf("Error during master key generation: %v", err)
+	}
+	sampler := sample.NewUniformRange(new(big.Int).Add(new(big.Int).Neg(bound), big.NewInt(1)), bound)
+	y, err := data.NewRandomVector(l, sampler)
+	if err != nil {
+		b.Fatalf("Error during random vector generation: %v", err)
+	}
+	key, err := fhipe.DeriveKey(y, masterSecKey)
+	if err != nil {
+		b.Fatalf("Error during key derivation: %v", err)
+	}
+	ciphers := make([]*fullysec.FHIPECipher, numCiphers)
+	for i := range ciphers {
+		x, err := data.NewRandomVector(l, sampler)
+		if err != nil {
+			b.Fatalf("Error during random vector generation: %v", err)
+		}
+		ciphers[i], err = fhipe.Encrypt(x, masterSecKey)
+		if err != nil {
+			b.Fatalf("Error during encryption: %v", err)
+		}
+	}
+
+	b.Run("DecryptBatch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, err := fhipe.NewDecryptor().DecryptBatch(ciphers, key)
+			if err != nil {
+				b.Fatalf("Error during decryption: %v", err)
+			}
+		}
+	})
+
+	b.Run("Decrypt", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for j := range ciphers {
+				_, err := fhipe.Decrypt(ciphers[j], key)
+				if err != nil {
+					b.Fatalf("Error during decryption: %v", err)
+				}
+			}
+		}
+	})
+}
@@ -18,10 +18,12 @@ package fullysec
 
 import (
 	"crypto/rand"
+	"io"
 	"math"
 	"math/big"
 
 	"github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/innerprod"
 	gofe "github.com/fentec-project/gofe/internal"
 	"github.com/fentec-project/gofe/sample"
 	"github.com/pkg/errors"
@@ -67,7 +69,8 @@ type LWEParams struct {
 // "Fully secure functional encryption for inner products,
 // from standard assumptions".
 type LWE struct {
-	Params *LWEParams
+	Params     *LWEParams
+	randReader io.Reader
 }
 
 // NewLWE configures a new instance of the scheme.
@@ -83,8 +86,9 @@ type LWE struct {
 // evaluation exists yet in the literature.
 //
 // It returns an error in case public parameters of the scheme could
-// not be generated.
-func NewLWE(l, n int, boundX, boundY *big.Int) (*LWE, error) {
+// not be generated. Optionally a source of randomness for generating
+// keys and encrypting can be set with innerprod.WithRandomness.
+func NewLWE(l, n int, boundX, boundY *big.Int, opts ...innerprod.Option) (*LWE, error) {
 	// K = 2 * l * boundX * boundY
 	K := new(big.Int).Mul(boundX, boundY)
 	K.Mul(K, big.NewInt(int64(l*2)))
@@ -205,9 +209,22 @@ func NewLWE(l, n int, boundX, boundY *big.Int) (*LWE, error) {
 			LSigma2: lSigma2,
 			A:       randMat,
 		},
+		randReader: innerprod.NewOptions(opts...).Rand,
 	}, nil
 }
 
+// NewLWEFromParams takes configuration parameters of an existing
+// LWE scheme instance, and reconstructs the scheme with same configuration
+// parameters. It returns a new LWE instance. Optionally a source of
+// randomness for generating keys and encrypting can be set with
+// innerprod.WithRandomness.
+func NewLWEFromParams(params *LWEParams, opts ...innerprod.Option) *LWE {
+	return &LWE{
+		Params:     params,
+		randReader: innerprod.NewOptions(opts...).Rand,
+	}
+}
+
 // GenerateSecretKey generates a secret key for the scheme.
 // The secret key is a matrix with dimensions l*m.
 //
@@ -215,8 +232,8 @@ func NewLWE(l, n int, boundX, boundY *big.Int) (*LWE, error) {
 func (s *LWE) GenerateSecretKey() (data.Matrix, error) {
 	var val *big.Int
 
-	sampler1 := sample.NewNormalDoubleConstant(s.Params.LSigma1)
-	sampler2 := sample.NewNormalDoubleConstant(s.Params.LSigma2)
+	sampler1 := sample.NewNormalDoubleConstantWithReader(s.Params.LSigma1, s.randReader)
+	sampler2 := sample.NewNormalDoubleConstantWithReader(s.Params.LSigma2, s.randReader)
 
 	Z := make(data.Matrix, s.Params.L)
 	halfRows := Z.Rows() / 2
@@ -291,13 +308,13 @@ func (s *LWE) Encrypt(x data.Vector, U data.Matrix) (data.Vector, error) {
 	}
 
 	// Create a random vector
-	r, err := data.NewRandomVector(s.Params.N, sample.NewUniform(s.Params.Q))
+	r, err := data.NewRandomVector(s.Params.N, sample.NewUniformWithReader(s.Params.Q, s.randReader))
 	if err != nil {
 		return nil, errors.Wrap(err, "error in encrypt")
 	}
 
 	// calculate the standard distribution and sample vectors e0, e1
-	sampler := sample.NewNormalDoubleConstant(s.Params.LSigmaQ)
+	sampler := sample.NewNormalDoubleConstantWithReader(s.Params.LSigmaQ, s.randReader)
 
 	e0, err0 := data.NewRandomVector(s.Params.M, sampler)
 	e1, err1 := data.NewRandomVector(s.Params.L, sampler)
@@ -360,3 +377,41 @@ func (s *LWE) Decrypt(cipher, zY, y data.Vector) (*big.Int, error) {
 
 	return mu, nil
 }
+
+// KAT generates a known-answer test of the scheme for vectors x and y.
+// The keys and the encryption of x are derived deterministically from
+// seed, using the parameters of s, so the test can be reproduced and
+// checked against other implementations. An error is returned if any
+// of the steps fails, or if the decryption does not give <x, y>.
+func (s *LWE) KAT(seed []byte, x, y data.Vector) (*innerprod.KAT, error) {
+	det := NewLWEFromParams(s.Params, innerprod.WithSeed(seed))
+	Z, err := det.GenerateSecretKey()
+	if err != nil {
+		return nil, err
+	}
+	U, err := det.GeneratePublicKey(Z)
+	if err != nil {
+		return nil, err
+	}
+	key, err := det.DeriveKey(y, Z)
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := det.Encrypt(x, U)
+	if err != nil {
+		return nil, err
+	}
+	xy, err := det.Decrypt(cipher, key, y)
+	if err != nil {
+		return nil, err
+	}
+
+	kat := &innerprod.KAT{Scheme: "fullysec.LWE", Seed: seed, Params: s.Params,
+		MasterSecKey: Z, MasterPubKey: U,
+		X: x, Y: y, FEKey: key, Cipher: cipher, XY: xy}
+	if err := kat.Verify(); err != nil {
+		return nil, err
+	}
+
+	return kat, nil
+}
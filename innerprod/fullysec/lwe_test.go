@@ -17,6 +17,8 @@
 package fullysec_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"math/big"
 	"testing"
 
@@ -90,3 +92,99 @@ func testVectorData(len int, boundX, boundY *big.Int) (data.Vector, data.Vector,
 
 	return x, y, xy
 }
+
+func TestFullySec_LWEFromParams(t *testing.T) {
+	l := 4
+	n := 64
+	boundX := big.NewInt(1000)
+	boundY := big.NewInt(1000)
+	x, y, xy := testVectorData(l, boundX, boundY)
+
+	fsLWE, err := fullysec.NewLWE(l, n, boundX, boundY)
+	if err != nil {
+		t.Fatalf("Error during scheme creation: %v", err)
+	}
+	Z, err := fsLWE.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("Error during secret key generation: %v", err)
+	}
+	U, err := fsLWE.GeneratePublicKey(Z)
+	if err != nil {
+		t.Fatalf("Error during public key generation: %v", err)
+	}
+
+	// persist the parameters and reconstruct the scheme from them
+	paramsJSON, err := json.Marshal(fsLWE.Params)
+	if err != nil {
+		t.Fatalf("Error during parameters marshaling: %v", err)
+	}
+	params := new(fullysec.LWEParams)
+	if err := json.Unmarshal(paramsJSON, params); err != nil {
+		t.Fatalf("Error during parameters unmarshaling: %v", err)
+	}
+	rebuilt := fullysec.NewLWEFromParams(params)
+
+	// keys generated before the reconstruction work with the new
+	// instance and the other way around
+	cipher, err := rebuilt.Encrypt(x, U)
+	if err != nil {
+		t.Fatalf("Error during encryption: %v", err)
+	}
+	zY, err := fsLWE.DeriveKey(y, Z)
+	if err != nil {
+		t.Fatalf("Error during key derivation: %v", err)
+	}
+	xyDecrypted, err := rebuilt.Decrypt(cipher, zY, y)
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+	assert.Equal(t, xy.Cmp(xyDecrypted), 0, "obtained incorrect inner product")
+
+	Z2, err := rebuilt.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("Error during secret key generation: %v", err)
+	}
+	U2, err := rebuilt.GeneratePublicKey(Z2)
+	if err != nil {
+		t.Fatalf("Error during public key generation: %v", err)
+	}
+	cipher, err = fsLWE.Encrypt(x, U2)
+	if err != nil {
+		t.Fatalf("Error during encryption: %v", err)
+	}
+	zY, err = rebuilt.DeriveKey(y, Z2)
+	if err != nil {
+		t.Fatalf("Error during key derivation: %v", err)
+	}
+	xyDecrypted, err = fsLWE.Decrypt(cipher, zY, y)
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+	assert.Equal(t, xy.Cmp(xyDecrypted), 0, "obtained incorrect inner product")
+}
+
+func TestFullySec_LWEKAT(t *testing.T) {
+	scheme, err := fullysec.NewLWE(3, 32, big.NewInt(10), big.NewInt(10))
+	if err != nil {
+		t.Fatalf("Error during scheme creation: %v", err)
+	}
+	x := data.Vector{big.NewInt(1), big.NewInt(-2), big.NewInt(3)}
+	y := data.Vector{big.NewInt(4), big.NewInt(5), big.NewInt(-6)}
+
+	export := func(seed string) string {
+		kat, err := scheme.KAT([]byte(seed), x, y)
+		if err != nil {
+			t.Fatalf("Error during known-answer test generation: %v", err)
+		}
+		assert.Equal(t, big.NewInt(-24), kat.XY)
+		assert.NoError(t, kat.Verify())
+		var buf bytes.Buffer
+		if err := kat.WriteJSON(&buf); err != nil {
+			t.Fatalf("Error during known-answer test export: %v", err)
+		}
+		return buf.String()
+	}
+
+	assert.Equal(t, export("seed"), export("seed"))
+	assert.NotEqual(t, export("seed"), export("another seed"))
+}
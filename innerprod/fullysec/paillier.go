@@ -19,9 +19,11 @@ package fullysec
 import (
 	"crypto/rand"
 	"fmt"
+	"io"
 	"math/big"
 
 	"github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/innerprod"
 	"github.com/fentec-project/gofe/internal"
 	"github.com/fentec-project/gofe/internal/keygen"
 	"github.com/fentec-project/gofe/sample"
@@ -45,7 +47,8 @@ type PaillierParams struct {
 // "Fully secure functional encryption for inner products,
 // from standard assumptions".
 type Paillier struct {
-	Params *PaillierParams
+	Params     *PaillierParams
+	randReader io.Reader
 }
 
 // NewPaillier configures a new instance of the scheme.
@@ -60,8 +63,9 @@ type Paillier struct {
 //
 // It returns an error in the case the scheme could not be properly
 // configured, or if the precondition boundX, boundY < (n / l)^(1/2)
-// is not satisfied.
-func NewPaillier(l, lambda, bitLen int, boundX, boundY *big.Int) (*Paillier, error) {
+// is not satisfied. Optionally a source of randomness for generating
+// keys and encrypting can be set with innerprod.WithRandomness.
+func NewPaillier(l, lambda, bitLen int, boundX, boundY *big.Int, opts ...innerprod.Option) (*Paillier, error) {
 	// generate two safe primes
 	p, err := keygen.GetSafePrime(bitLen)
 	if err != nil {
@@ -138,15 +142,19 @@ func NewPaillier(l, lambda, bitLen int, boundX, boundY *big.Int) (*Paillier, err
 			Lambda:  lambda,
 			G:       g,
 		},
+		randReader: innerprod.NewOptions(opts...).Rand,
 	}, nil
 }
 
 // NewPaillierFromParams takes configuration parameters of an existing
 // Paillier scheme instance, and reconstructs the scheme with same configuration
-// parameters. It returns a new Paillier instance.
-func NewPaillierFromParams(params *PaillierParams) *Paillier {
+// parameters. It returns a new Paillier instance. Optionally a source of
+// randomness for generating keys and encrypting can be set with
+// innerprod.WithRandomness.
+func NewPaillierFromParams(params *PaillierParams, opts ...innerprod.Option) *Paillier {
 	return &Paillier{
-		Params: params,
+		Params:     params,
+		randReader: innerprod.NewOptions(opts...).Rand,
 	}
 }
 
@@ -155,7 +163,7 @@ func NewPaillierFromParams(params *PaillierParams) *Paillier {
 // could not be generated.
 func (s *Paillier) GenerateMasterKeys() (data.Vector, data.Vector, error) {
 	// sampler for sampling a secret key
-	sampler := sample.NewNormalDoubleConstant(s.Params.LSigma)
+	sampler := sample.NewNormalDoubleConstantWithReader(s.Params.LSigma, s.randReader)
 
 	// generate a secret key
 	secKey, err := data.NewRandomVector(s.Params.L, sampler)
@@ -195,7 +203,7 @@ func (s *Paillier) Encrypt(x, masterPubKey data.Vector) (data.Vector, error) {
 
 	// generate a randomness for the encryption
 	nOver4 := new(big.Int).Quo(s.Params.N, big.NewInt(4))
-	r, err := rand.Int(rand.Reader, nOver4)
+	r, err := sample.NewUniformWithReader(nOver4, s.randReader).Sample()
 	if err != nil {
 		return nil, err
 	}
@@ -237,10 +245,51 @@ func (s *Paillier) Decrypt(cipher data.Vector, key *big.Int, y data.Vector) (*bi
 		cX.Mod(cX, s.Params.NSquare)
 	}
 
+	return s.decode(cX), nil
+}
+
+// DecryptVector accepts the encrypted vector and the master secret key
+// and returns the whole encrypted vector x, not only an inner product.
+// Note that this requires the master secret key, a functional
+// encryption key does not suffice; it is meant for the authority,
+// e.g. for debugging or auditing.
+//
+// It returns an error if the ciphertext or the key are malformed,
+// or if the decrypted vector violates the configured bound.
+func (s *Paillier) DecryptVector(cipher data.Vector, masterSecKey data.Vector) (data.Vector, error) {
+	if len(cipher) != s.Params.L+1 {
+		return nil, fmt.Errorf("ciphertext should be of length l + 1")
+	}
+	if len(masterSecKey) != s.Params.L {
+		return nil, fmt.Errorf("master secret key should be of length l")
+	}
+
+	x := make(data.Vector, s.Params.L)
+	for i, ct := range cipher[1:] {
+		// x_i is decrypted from c_i * c_0^(-s_i) = 1 + x_i * n in Z_n^2
+		sNeg := new(big.Int).Neg(masterSecKey[i])
+		cX := internal.ModExp(cipher[0], sNeg, s.Params.NSquare)
+		cX.Mul(cX, ct)
+		cX.Mod(cX, s.Params.NSquare)
+		x[i] = s.decode(cX)
+	}
+
+	if s.Params.BoundX != nil {
+		if err := x.CheckBound(s.Params.BoundX); err != nil {
+			return nil, err
+		}
+	}
+
+	return x, nil
+}
+
+// decode returns the value v for which cX = 1 + v * n in Z_n^2,
+// where v is taken from the interval (-n/2, n/2].
+func (s *Paillier) decode(cX *big.Int) *big.Int {
 	// decryption is calculated as (cX-1 mod n^2)/n
-	cX.Sub(cX, big.NewInt(1))
-	cX.Mod(cX, s.Params.NSquare)
-	ret := new(big.Int).Quo(cX, s.Params.N)
+	t := new(big.Int).Sub(cX, big.NewInt(1))
+	t.Mod(t, s.Params.NSquare)
+	ret := t.Quo(t, s.Params.N)
 	// if the return value is negative this is seen as the above ret being
 	// greater than n/2; in this case ret = ret - n
 	nHalf := new(big.Int).Quo(s.Params.N, big.NewInt(2))
@@ -248,5 +297,66 @@ func (s *Paillier) Decrypt(cipher data.Vector, key *big.Int, y data.Vector) (*bi
 		ret.Sub(ret, s.Params.N)
 	}
 
-	return ret, nil
+	return ret
+}
+
+// KAT generates a known-answer test of the scheme for vectors x and y.
+// The master keys and the encryption of x are derived deterministically
+// from seed, using the parameters of s, so the test can be reproduced
+// and checked against other implementations. An error is returned if
+// any of the steps fails, or if the decryption does not give <x, y>.
+func (s *Paillier) KAT(seed []byte, x, y data.Vector) (*innerprod.KAT, error) {
+	det := NewPaillierFromParams(s.Params, innerprod.WithSeed(seed))
+	masterSecKey, masterPubKey, err := det.GenerateMasterKeys()
+	if err != nil {
+		return nil, err
+	}
+	key, err := det.DeriveKey(masterSecKey, y)
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := det.Encrypt(x, masterPubKey)
+	if err != nil {
+		return nil, err
+	}
+	xy, err := det.Decrypt(cipher, key, y)
+	if err != nil {
+		return nil, err
+	}
+	kat := &innerprod.KAT{Scheme: "fullysec.Paillier", Seed: seed, Params: s.Params,
+		MasterSecKey: masterSecKey, MasterPubKey: masterPubKey,
+		X: x, Y: y, FEKey: key, Cipher: cipher, XY: xy}
+	if err := kat.Verify(); err != nil {
+		return nil, err
+	}
+
+	return kat, nil
+}
+
+// Add accepts ciphertexts c1 and c2 of vectors x1 and x2, and
+// returns a ciphertext of x1 + x2. The ciphertexts are multiplied
+// component-wise in Z_n^2; in particular c_0 = g^(r1 + r2), hence the
+// result decrypts with the same functional encryption keys as c1 and c2.
+// Note that the coordinates of x1 + x2 might exceed BoundX, so the
+// decryption is correct as long as the resulting inner product is
+// smaller than n/2 in absolute value.
+//
+// It returns an error if the ciphertexts are malformed.
+func (s *Paillier) Add(c1, c2 data.Vector) (data.Vector, error) {
+	if len(c1) != s.Params.L+1 || len(c2) != s.Params.L+1 {
+		return nil, fmt.Errorf("ciphertexts should be of length l + 1")
+	}
+
+	sum := make(data.Vector, s.Params.L+1)
+	for i := range sum {
+		for _, c := range []*big.Int{c1[i], c2[i]} {
+			if c.Sign() <= 0 || c.Cmp(s.Params.NSquare) >= 0 {
+				return nil, fmt.Errorf("ciphertext elements should be in Z_n^2*")
+			}
+		}
+		sum[i] = new(big.Int).Mul(c1[i], c2[i])
+		sum[i].Mod(sum[i], s.Params.NSquare)
+	}
+
+	return sum, nil
 }
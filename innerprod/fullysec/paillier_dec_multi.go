@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fullysec
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/sample"
+)
+
+// PaillierDecMultiClient represents a client in a decentralized
+// multi client variant of the underlying multi input (paillier_multi)
+// scheme. It follows the same decentralization as DamgardDecMultiClient,
+// based on Abdalla, Benhamouda, Kohlweiss, and Waldner:
+// "Decentralizing Inner-Product Functional Encryption".
+// The participants in the scheme are clients without a central authority.
+// Instead of receiving one time pads from the central authority, the
+// clients derive shares that sum to 0 from the public keys of each
+// other, so that client i can encrypt vector x_i. The scheme allows the
+// clients to interactively generate a key_Y, depending on a matrix Y
+// with rows y_i, so that given key_y and the ciphertext the decryptor
+// can compute value Σ_i <x_i, y_i> (sum of dot products).
+type PaillierDecMultiClient struct {
+	// index of the client
+	Idx int
+	*PaillierMulti
+	ClientPubKey *big.Int
+	ClientSecKey *big.Int
+	Share        data.Matrix
+}
+
+// NewPaillierDecMultiClient configures a new client in the decentralized
+// scheme based on a underlying PaillierMulti scheme.
+// It accepts the identification of the client (an integer from [0, numClients))
+// and the underlying PaillierMulti scheme (which contains all the shared
+// parameters). The public key of the client is an element of the subgroup
+// of Z_N^2* generated by the generator of the Paillier scheme.
+//
+// It returns an error in case the scheme cannot be properly initialized.
+func NewPaillierDecMultiClient(idx int, paillierMulti *PaillierMulti) (*PaillierDecMultiClient, error) {
+	nOver4 := new(big.Int).Quo(paillierMulti.Params.N, big.NewInt(4))
+	sec, err := sample.NewUniform(nOver4).Sample()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate random value")
+	}
+	pub := new(big.Int).Exp(paillierMulti.Params.G, sec, paillierMulti.Params.NSquare)
+
+	return &PaillierDecMultiClient{
+		Idx:           idx,
+		PaillierMulti: paillierMulti,
+		ClientPubKey:  pub,
+		ClientSecKey:  sec,
+	}, nil
+}
+
+// SetShare sets a shared key for client c, based on the public keys of all the
+// clients involved in the scheme. It assumes that Idx of a client indicates
+// which is the corresponding public key in pubKeys. Shared keys are such that
+// each client has a random key but all the shared keys sum to 0.
+func (c *PaillierDecMultiClient) SetShare(pubKeys []*big.Int) error {
+	c.Share = data.NewConstantMatrix(c.NumClients, c.Params.L, big.NewInt(0))
+	var add data.Matrix
+	var err error
+	for k := 0; k < len(pubKeys); k++ {
+		if k == c.Idx {
+			continue
+		}
+		sharedNum := new(big.Int).Exp(pubKeys[k], c.ClientSecKey, c.Params.NSquare)
+		sharedKey := sha256.Sum256([]byte(sharedNum.String()))
+
+		add, err = data.NewRandomDetMatrix(c.NumClients, c.Params.L, c.Params.NSquare, &sharedKey)
+		if err != nil {
+			return err
+		}
+
+		if k < c.Idx {
+			c.Share, err = c.Share.Add(add)
+			if err != nil {
+				return err
+			}
+		} else {
+			c.Share, err = c.Share.Sub(add)
+			if err != nil {
+				return err
+			}
+		}
+		c.Share = c.Share.Mod(c.Params.NSquare)
+	}
+
+	return nil
+}
+
+// PaillierDecMultiSecKey is a secret key that each client has.
+type PaillierDecMultiSecKey struct {
+	sk     data.Vector
+	pk     data.Vector
+	OtpKey data.Vector
+}
+
+// GenerateKeys generates the secret key for each client.
+//
+// It returns an error in case master keys could not be generated.
+func (c *PaillierDecMultiClient) GenerateKeys() (*PaillierDecMultiSecKey, error) {
+	masterSecretKey, masterPublicKey, err := c.Paillier.GenerateMasterKeys()
+	if err != nil {
+		return nil, fmt.Errorf("error in master key generation")
+	}
+
+	otpVector, err := data.NewRandomVector(c.Params.L,
+		sample.NewUniform(c.Params.NSquare))
+	if err != nil {
+		return nil, fmt.Errorf("error in random vector generation")
+	}
+
+	return &PaillierDecMultiSecKey{sk: masterSecretKey,
+		pk:     masterPublicKey,
+		OtpKey: otpVector}, nil
+}
+
+// Encrypt generates a ciphertext from the input vector x
+// with the provided secret key.
+// If encryption failed, error is returned.
+func (c *PaillierDecMultiClient) Encrypt(x data.Vector, key *PaillierDecMultiSecKey) (data.Vector, error) {
+	if c.BoundX != nil {
+		if err := x.CheckBound(c.BoundX); err != nil {
+			return nil, err
+		}
+	}
+
+	xAddOtp := x.Add(key.OtpKey)
+	xAddOtp = xAddOtp.Mod(c.Params.NSquare)
+
+	return c.Paillier.Encrypt(xAddOtp, key.pk)
+}
+
+// PaillierDecMultiDerivedKeyPart is functional encryption key for
+// decentralized Paillier scheme.
+type PaillierDecMultiDerivedKeyPart struct {
+	KeyPart    *big.Int
+	OTPKeyPart *big.Int
+}
+
+// DeriveKeyShare is run by a client. It takes a secret key and
+// a matrix y comprised of input vectors, and returns a part of
+// the functional encryption key.
+// In case the key could not be derived, it returns an error.
+func (c *PaillierDecMultiClient) DeriveKeyShare(secKey *PaillierDecMultiSecKey, y data.Matrix) (*PaillierDecMultiDerivedKeyPart, error) {
+	if c.BoundY != nil {
+		if err := y.CheckBound(c.BoundY); err != nil {
+			return nil, err
+		}
+	}
+	if len(y) != c.NumClients {
+		return nil, fmt.Errorf("the number of rows of y does not match the number of clients")
+	}
+
+	yPart := y[c.Idx]
+	z1, err := secKey.OtpKey.Dot(yPart)
+	if err != nil {
+		return nil, err
+	}
+
+	z2, err := c.Share.Dot(y)
+	if err != nil {
+		return nil, err
+	}
+
+	zPart := new(big.Int).Add(z1, z2)
+	zPart.Mod(zPart, c.Params.NSquare)
+	key, err := c.Paillier.DeriveKey(secKey.sk, yPart)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaillierDecMultiDerivedKeyPart{key, zPart}, nil
+}
+
+// PaillierDecMultiDec represents a decryptor for the decentralized variant of the
+// underlying multi input Paillier scheme.
+type PaillierDecMultiDec struct {
+	*PaillierMulti
+}
+
+// NewPaillierDecMultiDec takes the underlying PaillierMulti and instantiates a
+// new PaillierDecMultiDec struct.
+func NewPaillierDecMultiDec(paillierMulti *PaillierMulti) *PaillierDecMultiDec {
+	return &PaillierDecMultiDec{
+		PaillierMulti: NewPaillierMultiFromParams(paillierMulti.NumClients, paillierMulti.BoundX,
+			paillierMulti.BoundY, paillierMulti.Params),
+	}
+}
+
+// Decrypt accepts an array of ciphertexts comprised of encrypted vectors,
+// an array of partial functional encryption keys, and a matrix y representing
+// the inner-product vectors. It returns the sum of inner products.
+// If decryption failed, an error is returned.
+func (dc *PaillierDecMultiDec) Decrypt(cipher []data.Vector, partKeys []*PaillierDecMultiDerivedKeyPart, y data.Matrix) (*big.Int, error) {
+	if len(cipher) != len(partKeys) {
+		return nil, fmt.Errorf("the number of keys does not match the number of ciphertexts")
+	}
+	if len(cipher) != dc.NumClients {
+		return nil, fmt.Errorf("the number of ciphertexts does not match the number of clients")
+	}
+
+	keys := make([]*big.Int, len(partKeys))
+	z := big.NewInt(0)
+	for i := 0; i < len(partKeys); i++ {
+		z.Add(z, partKeys[i].OTPKeyPart)
+		keys[i] = partKeys[i].KeyPart
+	}
+	z.Mod(z, dc.Params.NSquare)
+	key := &PaillierMultiDerivedKey{Keys: keys,
+		Z: z,
+	}
+
+	return dc.PaillierMulti.Decrypt(cipher, key, y)
+}
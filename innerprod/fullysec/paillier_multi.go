@@ -100,7 +100,7 @@ func NewPaillierMultiClientFromParams(params *PaillierParams, boundX, boundY *bi
 	return &PaillierMultiClient{
 		BoundY:   boundY,
 		BoundX:   boundX,
-		Paillier: &Paillier{params},
+		Paillier: NewPaillierFromParams(params),
 	}
 }
 
@@ -114,7 +114,7 @@ func NewPaillierMultiFromParams(numClients int, boundX, boundY *big.Int, params
 		NumClients: numClients,
 		BoundX:     boundX,
 		BoundY:     boundY,
-		Paillier:   &Paillier{params},
+		Paillier:   NewPaillierFromParams(params),
 	}
 }
 
@@ -133,6 +133,7 @@ func (dm *PaillierMulti) GenerateMasterKeys() (*PaillierMultiSecKeys, error) {
 	multiMpk := make([]data.Vector, dm.NumClients)
 	multiOtp := make([]data.Vector, dm.NumClients)
 
+	otpSampler := sample.NewUniform(dm.Params.NSquare)
 	for i := 0; i < dm.NumClients; i++ {
 		msk, mpk, err := dm.Paillier.GenerateMasterKeys()
 		if err != nil {
@@ -141,7 +142,7 @@ func (dm *PaillierMulti) GenerateMasterKeys() (*PaillierMultiSecKeys, error) {
 		multiMsk[i] = msk
 		multiMpk[i] = mpk
 
-		otp, err := data.NewRandomVector(dm.Params.L, sample.NewUniform(dm.Params.NSquare))
+		otp, err := data.NewRandomVector(dm.Params.L, otpSampler)
 		if err != nil {
 			return nil, fmt.Errorf("error in random vector generation")
 		}
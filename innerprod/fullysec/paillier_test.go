@@ -17,6 +17,7 @@
 package fullysec_test
 
 import (
+	"bytes"
 	"math/big"
 	"testing"
 
@@ -80,3 +81,128 @@ func TestFullySec_Paillier(t *testing.T) {
 	}
 	assert.Equal(t, xy.Cmp(xyCheck), 0, "Original and decrypted values should match")
 }
+
+func TestFullySec_PaillierAdd(t *testing.T) {
+	l := 10
+	bound := big.NewInt(100000)
+	sampler := sample.NewUniformRange(new(big.Int).Neg(bound), bound)
+
+	paillier, err := fullysec.NewPaillier(l, 128, 512, bound, bound)
+	if err != nil {
+		t.Fatalf("Error during simple inner product creation: %v", err)
+	}
+
+	masterSecKey, masterPubKey, err := paillier.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Error during master key generation: %v", err)
+	}
+
+	y, err := data.NewRandomVector(l, sampler)
+	if err != nil {
+		t.Fatalf("Error during random generation: %v", err)
+	}
+	key, err := paillier.DeriveKey(masterSecKey, y)
+	if err != nil {
+		t.Fatalf("Error during key derivation: %v", err)
+	}
+
+	x1, err := data.NewRandomVector(l, sampler)
+	if err != nil {
+		t.Fatalf("Error during random generation: %v", err)
+	}
+	x2, err := data.NewRandomVector(l, sampler)
+	if err != nil {
+		t.Fatalf("Error during random generation: %v", err)
+	}
+
+	c1, err := paillier.Encrypt(x1, masterPubKey)
+	if err != nil {
+		t.Fatalf("Error during encryption: %v", err)
+	}
+	c2, err := paillier.Encrypt(x2, masterPubKey)
+	if err != nil {
+		t.Fatalf("Error during encryption: %v", err)
+	}
+
+	sum, err := paillier.Add(c1, c2)
+	if err != nil {
+		t.Fatalf("Error during ciphertext addition: %v", err)
+	}
+
+	xy, err := paillier.Decrypt(sum, key, y)
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+
+	x1y, _ := x1.Dot(y)
+	x2y, _ := x2.Dot(y)
+	xyCheck, _ := x1.Add(x2).Dot(y)
+	assert.Equal(t, 0, xy.Cmp(xyCheck), "decrypted sum should equal <x1+x2, y>")
+	assert.Equal(t, 0, xy.Cmp(new(big.Int).Add(x1y, x2y)), "decrypted sum should equal <x1, y> + <x2, y>")
+
+	_, err = paillier.Add(c1, c2[1:])
+	assert.Error(t, err)
+}
+
+func TestFullySec_PaillierDecryptVector(t *testing.T) {
+	l := 10
+	bound := big.NewInt(100000)
+	sampler := sample.NewUniformRange(new(big.Int).Neg(bound), bound)
+
+	paillier, err := fullysec.NewPaillier(l, 128, 512, bound, bound)
+	if err != nil {
+		t.Fatalf("Error during simple inner product creation: %v", err)
+	}
+
+	masterSecKey, masterPubKey, err := paillier.GenerateMasterKeys()
+	if err != nil {
+		t.Fatalf("Error during master key generation: %v", err)
+	}
+
+	x, err := data.NewRandomVector(l, sampler)
+	if err != nil {
+		t.Fatalf("Error during random generation: %v", err)
+	}
+
+	ciphertext, err := paillier.Encrypt(x, masterPubKey)
+	if err != nil {
+		t.Fatalf("Error during encryption: %v", err)
+	}
+
+	xDec, err := paillier.DecryptVector(ciphertext, masterSecKey)
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+	assert.True(t, x.Equal(xDec), "Original and decrypted vectors should match")
+
+	_, err = paillier.DecryptVector(ciphertext[1:], masterSecKey)
+	assert.Error(t, err)
+	_, err = paillier.DecryptVector(ciphertext, masterSecKey[1:])
+	assert.Error(t, err)
+}
+
+func TestFullySec_PaillierKAT(t *testing.T) {
+	scheme, err := fullysec.NewPaillier(3, 128, 512, big.NewInt(10), big.NewInt(10))
+	if err != nil {
+		t.Fatalf("Error during scheme creation: %v", err)
+	}
+	x := data.Vector{big.NewInt(1), big.NewInt(-2), big.NewInt(3)}
+	y := data.Vector{big.NewInt(4), big.NewInt(5), big.NewInt(-6)}
+
+	export := func(seed string) string {
+		kat, err := scheme.KAT([]byte(seed), x, y)
+		if err != nil {
+			t.Fatalf("Error during known-answer test generation: %v", err)
+		}
+		assert.Equal(t, big.NewInt(-24), kat.XY)
+		assert.NoError(t, kat.Verify())
+		var buf bytes.Buffer
+		if err := kat.WriteJSON(&buf); err != nil {
+			t.Fatalf("Error during known-answer test export: %v", err)
+		}
+		return buf.String()
+	}
+
+	assert.Equal(t, export("seed"), export("seed"))
+	assert.NotEqual(t, export("seed"), export("another seed"))
+}
@@ -291,13 +291,8 @@ func (d *PartFHIPE) PartDecrypt(cipher data.VectorG1, feKey data.VectorG2) (*bn2
 	if len(cipher) != d.Params.L+4 || len(feKey) != d.Params.L+4 {
 		return nil, fmt.Errorf("the length of FE key or ciphertext does not match the dimension of the scheme")
 	}
-	dec := new(bn256.GT).ScalarBaseMult(big.NewInt(0))
-	for i := 0; i < d.Params.L+4; i++ {
-		pairedI := bn256.Pair(cipher[i], feKey[i])
-		dec = new(bn256.GT).Add(pairedI, dec)
-	}
 
-	return dec, nil
+	return cipher.PairDot(feKey)
 }
 
 // Decrypt accepts the encrypted vector and functional encryption key.
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package innerprod
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/fentec-project/gofe/data"
+)
+
+// KAT holds a known-answer test of an inner product scheme: the
+// parameters of the scheme, the keys and the ciphertext obtained
+// deterministically from Seed, and the expected result of the
+// decryption. The fields holding keys are of the types used by the
+// scheme, so that they are written in the same form as when the keys
+// are marshaled on their own.
+//
+// Known-answer tests can be generated with the KAT methods of the
+// fullysec Damgard, Paillier and LWE schemes and of the simple LWE
+// scheme. The other schemes of the library (the remaining inner product
+// schemes, among them FHIPE, FHMultiIPE and DMCFE, the quadratic schemes
+// and the ABE schemes) do not support seeded randomness and are not
+// covered.
+type KAT struct {
+	Scheme       string      `json:"scheme"`
+	Seed         []byte      `json:"seed"`
+	Params       interface{} `json:"params"`
+	MasterSecKey interface{} `json:"masterSecKey"`
+	MasterPubKey interface{} `json:"masterPubKey"`
+	X            data.Vector `json:"x"`
+	Y            data.Vector `json:"y"`
+	FEKey        interface{} `json:"feKey"`
+	Cipher       data.Vector `json:"cipher"`
+	XY           *big.Int    `json:"xy"`
+}
+
+// WriteJSON writes the known-answer test to w as indented JSON.
+func (k *KAT) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(k)
+}
+
+// Verify checks that the expected result of the decryption XY is the
+// inner product of X and Y.
+func (k *KAT) Verify() error {
+	xy, err := k.X.Dot(k.Y)
+	if err != nil {
+		return err
+	}
+	if k.XY == nil || xy.Cmp(k.XY) != 0 {
+		return fmt.Errorf("decryption does not give the inner product")
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package innerprod
+
+import (
+	"crypto/rand"
+	"io"
+
+	"github.com/fentec-project/gofe/sample"
+)
+
+// Options holds optional settings of an inner product scheme.
+type Options struct {
+	// Rand is the source of randomness used by the scheme when
+	// generating keys and encrypting.
+	Rand io.Reader
+}
+
+// Option is a functional option that can be passed to the
+// constructors of inner product schemes.
+type Option func(*Options)
+
+// WithRandomness sets the source of randomness used by the scheme
+// when generating keys and encrypting. By default crypto/rand is used.
+// A deterministic reader should only be used for testing, since it
+// makes the keys and ciphertexts predictable.
+func WithRandomness(r io.Reader) Option {
+	return func(o *Options) {
+		o.Rand = r
+	}
+}
+
+// WithSeed makes the key generation and encryption of the scheme
+// deterministic, by using a pseudo-random source of randomness
+// determined by seed, see sample.NewSeededReader. It is meant only
+// for producing reproducible known-answer tests.
+func WithSeed(seed []byte) Option {
+	return WithRandomness(sample.NewSeededReader(seed))
+}
+
+// NewOptions returns the options with the given functional options
+// applied on top of the default ones.
+func NewOptions(opts ...Option) *Options {
+	o := &Options{Rand: rand.Reader}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Rand == nil {
+		o.Rand = rand.Reader
+	}
+
+	return o
+}
@@ -178,11 +178,7 @@ func (d *DDH) DeriveKey(masterSecKey, y data.Vector) (*big.Int, error) {
 		return nil, err
 	}
 
-	key, err := masterSecKey.Dot(y)
-	if err != nil {
-		return nil, err
-	}
-	return new(big.Int).Mod(key, d.Params.Q), nil
+	return masterSecKey.DotMod(y, d.Params.Q)
 }
 
 // Encrypt encrypts input vector x with the provided master public key.
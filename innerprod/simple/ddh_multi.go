@@ -127,6 +127,7 @@ func (dm *DDHMulti) GenerateMasterKeys() (data.Matrix, *DDHMultiSecKey, error) {
 	mpkVecs := make([]data.Vector, dm.Slots)
 	otpVecs := make([]data.Vector, dm.Slots)
 
+	otpSampler := sample.NewUniform(dm.Params.Bound)
 	for i := 0; i < dm.Slots; i++ {
 		masterSecretKey, masterPublicKey, err := dm.DDH.GenerateMasterKeys()
 
@@ -136,7 +137,7 @@ func (dm *DDHMulti) GenerateMasterKeys() (data.Matrix, *DDHMultiSecKey, error) {
 		mskVecs[i] = masterSecretKey
 		mpkVecs[i] = masterPublicKey
 
-		otpVector, err := data.NewRandomVector(dm.Params.L, sample.NewUniform(dm.Params.Bound))
+		otpVector, err := data.NewRandomVector(dm.Params.L, otpSampler)
 		if err != nil {
 			return nil, nil, fmt.Errorf("error in random vector generation")
 		}
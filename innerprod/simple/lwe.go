@@ -24,8 +24,10 @@ import (
 	"math/bits"
 
 	"fmt"
+	"io"
 
 	"github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/innerprod"
 	gofe "github.com/fentec-project/gofe/internal"
 	"github.com/fentec-project/gofe/sample"
 	"github.com/pkg/errors"
@@ -56,7 +58,8 @@ type LWEParams struct {
 // Abdalla, Bourse, De Caro, and Pointchev:
 // "Simple Functional Encryption Schemes for Inner Products".
 type LWE struct {
-	Params *LWEParams
+	Params     *LWEParams
+	randReader io.Reader
 }
 
 // NewLWE configures a new instance of the scheme.
@@ -72,13 +75,75 @@ type LWE struct {
 // known attacks has been performed. Unfortunately, no such (theoretical)
 // evaluation exists yet in the literature.
 //
-// It returns an error in case public parameters of the scheme could
-// not be generated.
-func NewLWE(l int, boundX, boundY *big.Int, n int) (*LWE, error) {
+// It returns an error in case l, n or the bounds are not positive or
+// public parameters of the scheme could not be generated. Optionally
+// a source of randomness for generating keys and encrypting can be set
+// with innerprod.WithRandomness.
+func NewLWE(l int, boundX, boundY *big.Int, n int, opts ...innerprod.Option) (*LWE, error) {
+	return newLWE(l, boundX, boundY, n, randomPrime, opts...)
+}
+
+// NewLWEDeterministic configures a new instance of the scheme as
+// NewLWE, but instead of searching for random primes, the moduli p and
+// q are the smallest primes of the bit lengths required by the bounds,
+// hence the parameters (n, m, p, q) are the same on every call with
+// the same inputs. It does not choose n for a security level: as noted
+// for NewLWE, no concrete security evaluation of this scheme exists in
+// the literature, so there are no vetted (n, m, q) sets to offer as
+// presets and n must still be chosen by the caller.
+//
+// Sampling the public matrix A still dominates the running time for
+// large n. To avoid it, store Params of an instance and reconstruct
+// the scheme with NewLWEFromParams.
+func NewLWEDeterministic(l int, boundX, boundY *big.Int, n int, opts ...innerprod.Option) (*LWE, error) {
+	return newLWE(l, boundX, boundY, n, firstPrime, opts...)
+}
+
+// NewLWEFromParams takes configuration parameters of an existing
+// LWE scheme instance, and reconstructs the scheme with same configuration
+// parameters. It returns a new LWE instance. Optionally a source of
+// randomness for generating keys and encrypting can be set with
+// innerprod.WithRandomness.
+func NewLWEFromParams(params *LWEParams, opts ...innerprod.Option) *LWE {
+	return &LWE{
+		Params:     params,
+		randReader: innerprod.NewOptions(opts...).Rand,
+	}
+}
+
+// randomPrime returns a random prime of the given bit length.
+func randomPrime(bits int) (*big.Int, error) {
+	return rand.Prime(rand.Reader, bits)
+}
+
+// firstPrime returns the smallest prime of the given bit length.
+func firstPrime(bits int) (*big.Int, error) {
+	if bits < 2 {
+		return nil, fmt.Errorf("prime size must be at least 2 bits")
+	}
+	p := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	for !p.ProbablyPrime(20) {
+		p.Add(p, big.NewInt(1))
+	}
+
+	return p, nil
+}
+
+// newLWE configures a new instance of the scheme as described in
+// NewLWE, obtaining the moduli p and q from the function prime.
+func newLWE(l int, boundX, boundY *big.Int, n int, prime func(bits int) (*big.Int, error),
+	opts ...innerprod.Option) (*LWE, error) {
+	if l <= 0 || n <= 0 {
+		return nil, fmt.Errorf("l and n should be positive")
+	}
+	if boundX == nil || boundY == nil || boundX.Sign() <= 0 || boundY.Sign() <= 0 {
+		return nil, fmt.Errorf("bounds should be positive")
+	}
+
 	// generate parameters
 	// p > boundX * boundY * l * 2
 	nBitsP := boundX.BitLen() + boundY.BitLen() + bits.Len(uint(l)) + 2
-	p, err := rand.Prime(rand.Reader, nBitsP)
+	p, err := prime(nBitsP)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot generate public parameters")
 	}
@@ -96,7 +161,7 @@ func NewLWE(l int, boundX, boundY *big.Int, n int) (*LWE, error) {
 	x.Mul(x, xSqrt)
 	xI, _ := x.Int(nil)
 	nBitsQ := xI.BitLen() + 1
-	q, err := rand.Prime(rand.Reader, nBitsQ)
+	q, err := prime(nBitsQ)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot generate public parameters")
 	}
@@ -143,6 +208,7 @@ func NewLWE(l int, boundX, boundY *big.Int, n int) (*LWE, error) {
 			SigmaQ: sigmaQ,
 			LSigma: lSigma,
 		},
+		randReader: innerprod.NewOptions(opts...).Rand,
 	}, nil
 }
 
@@ -152,7 +218,7 @@ func NewLWE(l int, boundX, boundY *big.Int, n int) (*LWE, error) {
 //
 // In case secret key could not be generated, it returns an error.
 func (s *LWE) GenerateSecretKey() (data.Matrix, error) {
-	return data.NewRandomMatrix(s.Params.N, s.Params.L, sample.NewUniform(s.Params.Q))
+	return data.NewRandomMatrix(s.Params.N, s.Params.L, sample.NewUniformWithReader(s.Params.Q, s.randReader))
 }
 
 // GeneratePublicKey accepts a secret key SK, standard deviation sigma.
@@ -166,7 +232,7 @@ func (s *LWE) GeneratePublicKey(SK data.Matrix) (data.Matrix, error) {
 	}
 
 	// Initialize and fill noise matrix E with m*l samples
-	sampler := sample.NewNormalDoubleConstant(s.Params.LSigma)
+	sampler := sample.NewNormalDoubleConstantWithReader(s.Params.LSigma, s.randReader)
 
 	E, err := data.NewRandomMatrix(s.Params.M, s.Params.L, sampler)
 	if err != nil {
@@ -223,7 +289,7 @@ func (s *LWE) Encrypt(x data.Vector, PK data.Matrix) (data.Vector, error) {
 	}
 
 	// Create a random vector comprised of m 0s and 1s
-	r, err := data.NewRandomVector(s.Params.M, sample.NewBit())
+	r, err := data.NewRandomVector(s.Params.M, sample.NewBitWithReader(s.randReader))
 	if err != nil {
 		return nil, errors.Wrap(err, "error in encrypt")
 	}
@@ -307,3 +373,41 @@ func (s *LWE) Decrypt(ct, skY, y data.Vector) (*big.Int, error) {
 	d.Div(d, s.Params.Q)
 	return d, nil
 }
+
+// KAT generates a known-answer test of the scheme for vectors x and y.
+// The keys and the encryption of x are derived deterministically from
+// seed, using the parameters of s, so the test can be reproduced and
+// checked against other implementations. An error is returned if any
+// of the steps fails, or if the decryption does not give <x, y>.
+func (s *LWE) KAT(seed []byte, x, y data.Vector) (*innerprod.KAT, error) {
+	det := NewLWEFromParams(s.Params, innerprod.WithSeed(seed))
+	SK, err := det.GenerateSecretKey()
+	if err != nil {
+		return nil, err
+	}
+	PK, err := det.GeneratePublicKey(SK)
+	if err != nil {
+		return nil, err
+	}
+	key, err := det.DeriveKey(y, SK)
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := det.Encrypt(x, PK)
+	if err != nil {
+		return nil, err
+	}
+	xy, err := det.Decrypt(cipher, key, y)
+	if err != nil {
+		return nil, err
+	}
+
+	kat := &innerprod.KAT{Scheme: "simple.LWE", Seed: seed, Params: s.Params,
+		MasterSecKey: SK, MasterPubKey: PK,
+		X: x, Y: y, FEKey: key, Cipher: cipher, XY: xy}
+	if err := kat.Verify(); err != nil {
+		return nil, err
+	}
+
+	return kat, nil
+}
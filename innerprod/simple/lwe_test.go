@@ -17,10 +17,13 @@
 package simple_test
 
 import (
+	"bytes"
 	"math/big"
+	"math/rand"
 	"testing"
 
 	"github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/innerprod"
 	"github.com/fentec-project/gofe/innerprod/simple"
 	"github.com/fentec-project/gofe/sample"
 	"github.com/stretchr/testify/assert"
@@ -83,3 +86,118 @@ func testVectorData(len int, boundX, boundY *big.Int) (data.Vector, data.Vector,
 
 	return x, y, xy
 }
+
+func TestSimple_LWEWithRandomness(t *testing.T) {
+	l := 4
+	n := 128
+	b := big.NewInt(10000)
+	x, y, xy := testVectorData(l, b, b)
+
+	// public parameters are generated with crypto/rand, while keys and
+	// ciphertexts are generated with the given source of randomness
+	src := rand.New(rand.NewSource(42))
+	simpleLWE, err := simple.NewLWE(l, b, b, n, innerprod.WithRandomness(src))
+	assert.NoError(t, err)
+
+	// reseeding the source should reproduce the keys and ciphertexts
+	ciphers := make([]data.Vector, 2)
+	for i := range ciphers {
+		src.Seed(42)
+		SK, err := simpleLWE.GenerateSecretKey()
+		assert.NoError(t, err)
+		PK, err := simpleLWE.GeneratePublicKey(SK)
+		assert.NoError(t, err)
+		ciphers[i], err = simpleLWE.Encrypt(x, PK)
+		assert.NoError(t, err)
+
+		skY, err := simpleLWE.DeriveKey(y, SK)
+		assert.NoError(t, err)
+		xyDecrypted, err := simpleLWE.Decrypt(ciphers[i], skY, y)
+		assert.NoError(t, err)
+		assert.Equal(t, xy.Cmp(xyDecrypted), 0, "obtained incorrect inner product")
+	}
+	assert.Equal(t, ciphers[0], ciphers[1])
+}
+
+func TestSimple_NewLWEChecks(t *testing.T) {
+	b := big.NewInt(10)
+	_, err := simple.NewLWE(0, b, b, 128)
+	assert.Error(t, err)
+	_, err = simple.NewLWE(4, b, b, 0)
+	assert.Error(t, err)
+	_, err = simple.NewLWE(4, big.NewInt(0), b, 128)
+	assert.Error(t, err)
+	_, err = simple.NewLWE(4, b, nil, 128)
+	assert.Error(t, err)
+	_, err = simple.NewLWEDeterministic(4, b, b, 0)
+	assert.Error(t, err)
+}
+
+func TestSimple_LWEDeterministic(t *testing.T) {
+	l := 2
+	b := big.NewInt(100)
+	x, y, xy := testVectorData(l, b, b)
+
+	simpleLWE, err := simple.NewLWEDeterministic(l, b, b, 256)
+	if err != nil {
+		t.Fatalf("Error during scheme creation: %v", err)
+	}
+	assert.Equal(t, 256, simpleLWE.Params.N)
+	// the moduli are the smallest primes of their bit lengths
+	for _, m := range []*big.Int{simpleLWE.Params.P, simpleLWE.Params.Q} {
+		assert.True(t, m.ProbablyPrime(20))
+		for c := new(big.Int).Lsh(big.NewInt(1), uint(m.BitLen()-1)); c.Cmp(m) < 0; c.Add(c, big.NewInt(1)) {
+			assert.False(t, c.ProbablyPrime(20))
+		}
+	}
+
+	// a scheme reconstructed from the parameters interoperates with the original
+	rebuilt := simple.NewLWEFromParams(simpleLWE.Params)
+	SK, err := simpleLWE.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("Error during secret key generation: %v", err)
+	}
+	PK, err := simpleLWE.GeneratePublicKey(SK)
+	if err != nil {
+		t.Fatalf("Error during public key generation: %v", err)
+	}
+	cipher, err := rebuilt.Encrypt(x, PK)
+	if err != nil {
+		t.Fatalf("Error during encryption: %v", err)
+	}
+	skY, err := rebuilt.DeriveKey(y, SK)
+	if err != nil {
+		t.Fatalf("Error during key derivation: %v", err)
+	}
+	xyDecrypted, err := simpleLWE.Decrypt(cipher, skY, y)
+	if err != nil {
+		t.Fatalf("Error during decryption: %v", err)
+	}
+	assert.Equal(t, xy.Cmp(xyDecrypted), 0, "obtained incorrect inner product")
+}
+
+func TestSimple_LWEKAT(t *testing.T) {
+	scheme, err := simple.NewLWE(3, big.NewInt(10), big.NewInt(10), 32)
+	if err != nil {
+		t.Fatalf("Error during scheme creation: %v", err)
+	}
+	x := data.Vector{big.NewInt(1), big.NewInt(-2), big.NewInt(3)}
+	y := data.Vector{big.NewInt(4), big.NewInt(5), big.NewInt(-6)}
+
+	export := func(seed string) string {
+		kat, err := scheme.KAT([]byte(seed), x, y)
+		if err != nil {
+			t.Fatalf("Error during known-answer test generation: %v", err)
+		}
+		assert.Equal(t, big.NewInt(-24), kat.XY)
+		assert.NoError(t, kat.Verify())
+		var buf bytes.Buffer
+		if err := kat.WriteJSON(&buf); err != nil {
+			t.Fatalf("Error during known-answer test export: %v", err)
+		}
+		return buf.String()
+	}
+
+	assert.Equal(t, export("seed"), export("seed"))
+	assert.NotEqual(t, export("seed"), export("another seed"))
+}
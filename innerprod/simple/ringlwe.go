@@ -193,6 +193,44 @@ func (s *RingLWE) GeneratePublicKey(SK data.Matrix) (data.Matrix, error) {
 	return PK, nil
 }
 
+// VerifyKeyPair checks if the public key PK corresponds to the secret
+// key SK, i.e. if PK = a * SK + E for a matrix E with small entries,
+// as generated by GeneratePublicKey. Since the entries of E are sampled
+// from a discrete Gaussian distribution with standard deviation Sigma1,
+// each entry of E is accepted if its absolute value is at most
+// 20 * Sigma1, which fails for genuine keys only with a negligible
+// probability. In case of malformed keys it returns an error.
+func (s *RingLWE) VerifyKeyPair(SK, PK data.Matrix) (bool, error) {
+	if !SK.CheckDims(s.Params.L, s.Params.N) {
+		return false, gofe.ErrMalformedSecKey
+	}
+	if !PK.CheckDims(s.Params.L, s.Params.N) {
+		return false, gofe.ErrMalformedPubKey
+	}
+
+	boundF := new(big.Float).Mul(s.Params.Sigma1, big.NewFloat(20))
+	bound, _ := boundF.Int(nil)
+	halfQ := new(big.Int).Div(s.Params.Q, big.NewInt(2))
+	for i := 0; i < s.Params.L; i++ {
+		// calculate E_i = (PK_i - a * SK_i) % q with entries in (-q/2, q/2]
+		aSkI, err := SK[i].MulAsPolyInRing(s.Params.A)
+		if err != nil {
+			return false, err
+		}
+		eI := PK[i].Sub(aSkI).Mod(s.Params.Q)
+		for _, e := range eI {
+			if e.Cmp(halfQ) == 1 {
+				e.Sub(e, s.Params.Q)
+			}
+			if e.CmpAbs(bound) == 1 {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
 // DeriveKey accepts input vector y and master secret key SK, and derives a
 // functional encryption key.
 // In case of malformed secret key or input vector that violates the
@@ -306,19 +344,9 @@ func (s *RingLWE) Encrypt(X data.Matrix, PK data.Matrix) (*RingLWECipher, error)
 // If decryption failed (for instance with input data that violates the
 // configured bound or malformed ciphertext or keys), error is returned.
 func (s *RingLWE) Decrypt(CT *RingLWECipher, skY, y data.Vector) (data.Vector, error) {
-	if err := y.CheckBound(s.Params.BoundY); err != nil {
+	if err := s.checkDecryptInput(CT, skY, y); err != nil {
 		return nil, err
 	}
-	if len(skY) != s.Params.N {
-		return nil, gofe.ErrMalformedDecKey
-	}
-	if len(y) != s.Params.L {
-		return nil, gofe.ErrMalformedInput
-	}
-
-	if !CT.Ct0.CheckDims(s.Params.L, s.Params.N) || len(CT.Ct1) != s.Params.N {
-		return nil, gofe.ErrMalformedCipher
-	}
 	CT0 := CT.Ct0
 	ct1 := CT.Ct1
 
@@ -336,15 +364,143 @@ func (s *RingLWE) Decrypt(CT *RingLWECipher, skY, y data.Vector) (data.Vector, e
 	halfQ := new(big.Int).Div(s.Params.Q, big.NewInt(2))
 
 	d = d.Apply(func(x *big.Int) *big.Int {
-		if x.Cmp(halfQ) == 1 {
-			x.Sub(x, s.Params.Q)
-		}
-		x.Mul(x, s.Params.P)
-		x.Add(x, halfQ)
-		x.Div(x, s.Params.Q)
-
-		return x
+		return s.round(x, halfQ)
 	})
 
 	return d[:CT.K], nil
 }
+
+// checkDecryptInput checks that the ciphertext, the derived key and
+// the inner-product vector match the parameters of the scheme.
+func (s *RingLWE) checkDecryptInput(CT *RingLWECipher, skY, y data.Vector) error {
+	if err := y.CheckBound(s.Params.BoundY); err != nil {
+		return err
+	}
+	if len(skY) != s.Params.N {
+		return gofe.ErrMalformedDecKey
+	}
+	if len(y) != s.Params.L {
+		return gofe.ErrMalformedInput
+	}
+	if !CT.Ct0.CheckDims(s.Params.L, s.Params.N) || len(CT.Ct1) != s.Params.N {
+		return gofe.ErrMalformedCipher
+	}
+
+	return nil
+}
+
+// round maps a coefficient x in [0, Q) of the decrypted polynomial
+// to the closest value in Z_P, i.e. it computes round(x * P / Q),
+// with x taken in (-Q/2, Q/2]. It modifies and returns x.
+func (s *RingLWE) round(x, halfQ *big.Int) *big.Int {
+	if x.Cmp(halfQ) == 1 {
+		x.Sub(x, s.Params.Q)
+	}
+	x.Mul(x, s.Params.P)
+	x.Add(x, halfQ)
+	x.Div(x, s.Params.Q)
+
+	return x
+}
+
+// DecryptCoords works as Decrypt, but computes only the inner products
+// of y with the columns of the encrypted matrix given by indices, in the
+// given order. Only the needed coefficients of the product of ring
+// elements are computed, hence decrypting a few coordinates of a wide
+// ciphertext is considerably faster than decrypting all of them. The
+// results are the same as the corresponding coordinates returned by
+// Decrypt. An error is returned if some index is not smaller than the
+// number of encrypted columns, or in case of malformed input.
+func (s *RingLWE) DecryptCoords(CT *RingLWECipher, skY, y data.Vector, indices []int) (data.Vector, error) {
+	if err := s.checkDecryptInput(CT, skY, y); err != nil {
+		return nil, err
+	}
+	for _, i := range indices {
+		if i < 0 || i >= CT.K {
+			return nil, gofe.ErrMalformedInput
+		}
+	}
+
+	n := s.Params.N
+	halfQ := new(big.Int).Div(s.Params.Q, big.NewInt(2))
+	prod := new(big.Int)
+	res := make(data.Vector, len(indices))
+	for k, i := range indices {
+		// i-th coordinate of CT0^T * y
+		d := big.NewInt(0)
+		for j := 0; j < s.Params.L; j++ {
+			prod.Mul(CT.Ct0[j][i], y[j])
+			d.Add(d, prod)
+		}
+
+		// subtract the i-th coefficient of ct1 * skY in the ring
+		// Z[x]/(x^n + 1), see data.Vector.MulAsPolyInRing
+		for j := 0; j <= i; j++ {
+			prod.Mul(CT.Ct1[i-j], skY[j])
+			d.Sub(d, prod)
+		}
+		for j := i + 1; j < n; j++ {
+			prod.Mul(CT.Ct1[n+i-j], skY[j])
+			d.Add(d, prod)
+		}
+
+		d.Mod(d, s.Params.Q)
+		res[k] = s.round(d, halfQ)
+	}
+
+	return res, nil
+}
+
+// EncryptMatrix encrypts matrix X with an arbitrary number of columns
+// using public key PK. X is split into ceil(X.Cols() / N) chunks of at
+// most N columns, which are encrypted separately with Encrypt. It returns
+// the ciphertexts of the chunks in order. In case of malformed input or
+// public key an error is returned.
+func (s *RingLWE) EncryptMatrix(X data.Matrix, PK data.Matrix) ([]*RingLWECipher, error) {
+	if X.Rows() != s.Params.L || X.Cols() == 0 {
+		return nil, gofe.ErrMalformedInput
+	}
+
+	numChunks := (X.Cols() + s.Params.N - 1) / s.Params.N
+	res := make([]*RingLWECipher, numChunks)
+	for c := 0; c < numChunks; c++ {
+		end := (c + 1) * s.Params.N
+		if end > X.Cols() {
+			end = X.Cols()
+		}
+		chunk := make(data.Matrix, X.Rows())
+		for i, row := range X {
+			chunk[i] = row[c*s.Params.N : end]
+		}
+
+		ct, err := s.Encrypt(chunk, PK)
+		if err != nil {
+			return nil, err
+		}
+		res[c] = ct
+	}
+
+	return res, nil
+}
+
+// DecryptMatrix accepts the ciphertexts produced by EncryptMatrix, secret
+// key skY and plaintext vector y. It decrypts every chunk with Decrypt and
+// joins the results into the vector of inner products of y with all the
+// columns of the encrypted matrix. If decryption of some chunk failed,
+// an error is returned.
+func (s *RingLWE) DecryptMatrix(CTs []*RingLWECipher, skY, y data.Vector) (data.Vector, error) {
+	if len(CTs) == 0 {
+		return nil, gofe.ErrMalformedCipher
+	}
+
+	res := make(data.Vector, 0, len(CTs)*s.Params.N)
+	for _, ct := range CTs {
+		d, err := s.Decrypt(ct, skY, y)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, d...)
+	}
+
+	return res, nil
+}
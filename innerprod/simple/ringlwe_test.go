@@ -84,3 +84,109 @@ func TestSimple_RingLWE(t *testing.T) {
 		assert.Equal(t, xy[i].Cmp(xyDecrypted[i]), 0, "obtained incorrect inner product")
 	}
 }
+
+func TestRingLWE_VerifyKeyPair(t *testing.T) {
+	ringLWE, err := simple.NewRingLWE(75, 5, big.NewInt(2), big.NewInt(2))
+	assert.NoError(t, err)
+
+	SK1, err := ringLWE.GenerateSecretKey()
+	assert.NoError(t, err)
+	PK1, err := ringLWE.GeneratePublicKey(SK1)
+	assert.NoError(t, err)
+	SK2, err := ringLWE.GenerateSecretKey()
+	assert.NoError(t, err)
+
+	// a genuine pair verifies
+	ok, err := ringLWE.VerifyKeyPair(SK1, PK1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// a mismatched pair does not verify
+	ok, err = ringLWE.VerifyKeyPair(SK2, PK1)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// malformed keys return an error
+	_, err = ringLWE.VerifyKeyPair(data.Matrix{}, PK1)
+	assert.Error(t, err)
+	_, err = ringLWE.VerifyKeyPair(SK1, data.Matrix{})
+	assert.Error(t, err)
+}
+
+func TestRingLWE_EncryptMatrix(t *testing.T) {
+	l := 10
+	bx := big.NewInt(2)
+	by := big.NewInt(2)
+	ringLWE, err := simple.NewRingLWE(75, l, bx, by)
+	assert.NoError(t, err)
+
+	// the matrix is wider than a single ciphertext allows
+	sampler := sample.NewUniformRange(new(big.Int).Neg(bx), bx)
+	y, _ := data.NewRandomVector(l, sampler)
+	dimX := 2*ringLWE.Params.N + 3
+	X, _ := data.NewRandomMatrix(l, dimX, sampler)
+	xy, _ := X.Transpose().MulVec(y)
+
+	SK, err := ringLWE.GenerateSecretKey()
+	assert.NoError(t, err)
+	PK, err := ringLWE.GeneratePublicKey(SK)
+	assert.NoError(t, err)
+	skY, err := ringLWE.DeriveKey(y, SK)
+	assert.NoError(t, err)
+
+	_, err = ringLWE.Encrypt(X, PK)
+	assert.Error(t, err)
+	ciphers, err := ringLWE.EncryptMatrix(X, PK)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(ciphers))
+
+	xyDecrypted, err := ringLWE.DecryptMatrix(ciphers, skY, y)
+	assert.NoError(t, err)
+	assert.Equal(t, dimX, len(xyDecrypted))
+	for i := 0; i < dimX; i++ {
+		assert.Equal(t, xy[i].Cmp(xyDecrypted[i]), 0, "obtained incorrect inner product")
+	}
+
+	_, err = ringLWE.EncryptMatrix(data.Matrix{}, PK)
+	assert.Error(t, err)
+	_, err = ringLWE.DecryptMatrix(nil, skY, y)
+	assert.Error(t, err)
+}
+
+func TestRingLWE_DecryptCoords(t *testing.T) {
+	l := 10
+	bx := big.NewInt(2)
+	by := big.NewInt(2)
+	ringLWE, err := simple.NewRingLWE(75, l, bx, by)
+	assert.NoError(t, err)
+
+	sampler := sample.NewUniformRange(new(big.Int).Neg(bx), bx)
+	y, _ := data.NewRandomVector(l, sampler)
+	dimX := ringLWE.Params.N - 5
+	X, _ := data.NewRandomMatrix(l, dimX, sampler)
+
+	SK, err := ringLWE.GenerateSecretKey()
+	assert.NoError(t, err)
+	PK, err := ringLWE.GeneratePublicKey(SK)
+	assert.NoError(t, err)
+	skY, err := ringLWE.DeriveKey(y, SK)
+	assert.NoError(t, err)
+	CT, err := ringLWE.Encrypt(X, PK)
+	assert.NoError(t, err)
+
+	xyDecrypted, err := ringLWE.Decrypt(CT, skY, y)
+	assert.NoError(t, err)
+
+	indices := []int{dimX - 1, 0, 7, 0}
+	coords, err := ringLWE.DecryptCoords(CT, skY, y, indices)
+	assert.NoError(t, err)
+	assert.Equal(t, len(indices), len(coords))
+	for k, i := range indices {
+		assert.Equal(t, xyDecrypted[i].Cmp(coords[k]), 0, "obtained incorrect inner product")
+	}
+
+	_, err = ringLWE.DecryptCoords(CT, skY, y, []int{dimX})
+	assert.Error(t, err)
+	_, err = ringLWE.DecryptCoords(CT, skY, y, []int{-1})
+	assert.Error(t, err)
+}
@@ -17,7 +17,6 @@
 package dlog
 
 import (
-	"fmt"
 	"math/big"
 
 	"github.com/fentec-project/bn256"
@@ -35,7 +34,7 @@ func bruteForce(h, g, p, bound *big.Int) (*big.Int, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("failed to find discrete logarithm within bound")
+	return nil, ErrNotFound
 }
 
 // Simply brute-forces all possible options to compute dlog in BN256 GT group.
@@ -51,5 +50,5 @@ func bruteForceBN256(h, g *bn256.GT, bound *big.Int) (*big.Int, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("failed to find discrete logarithm within bound")
+	return nil, ErrNotFound
 }
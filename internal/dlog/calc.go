@@ -17,9 +17,15 @@
 package dlog
 
 import (
+	"bytes"
 	"crypto/sha1"
+	"encoding/binary"
+	"encoding/gob"
 	"fmt"
+	"io"
 	"math/big"
+	"strconv"
+	"sync"
 
 	"github.com/fentec-project/bn256"
 )
@@ -29,8 +35,15 @@ import (
 // exhaustive computation for practical purposes.
 // If Calc is configured to use a boundary value > MaxBound,
 // it will be automatically adjusted to MaxBound.
+// MaxBound is only the default, which is copied to every calculator
+// when it is created; to use a different limit for a single
+// calculator use WithMaxBound instead of changing MaxBound.
 var MaxBound = new(big.Int).Exp(big.NewInt(2), big.NewInt(48), nil)
 
+// ErrNotFound is returned when the discrete logarithm could not
+// be found within the bound of the calculator.
+var ErrNotFound = fmt.Errorf("failed to find the discrete logarithm within bound")
+
 // Calc represents a discrete logarithm calculator.
 type Calc struct{}
 
@@ -42,10 +55,11 @@ func NewCalc() *Calc {
 // CalcZp represents a calculator for discrete logarithms
 // that operates in the Zp group of integers modulo prime p.
 type CalcZp struct {
-	p     *big.Int
-	bound *big.Int
-	m     *big.Int
-	neg   bool
+	p        *big.Int
+	bound    *big.Int
+	maxBound *big.Int
+	m        *big.Int
+	neg      bool
 }
 
 // InZp builds parameters needed to calculate a discrete
@@ -70,37 +84,63 @@ func (*Calc) InZp(p, order *big.Int) (*CalcZp, error) {
 	m.Add(m, one)
 
 	return &CalcZp{
-		p:     p,
-		bound: bound,
-		m:     m,
-		neg:   false,
+		p:        p,
+		bound:    bound,
+		maxBound: new(big.Int).Set(MaxBound),
+		m:        m,
+		neg:      false,
 	}, nil
 }
 
 // WithBound sets a bound for the calculator of the discrete logarithm.
+// The bound is ignored if it is not smaller than the maximal bound
+// of the calculator (see WithMaxBound).
 func (c *CalcZp) WithBound(bound *big.Int) *CalcZp {
-	if bound != nil && bound.Cmp(MaxBound) < 0 && bound.Sign() > 0 {
+	if bound != nil && bound.Cmp(c.maxBound) < 0 && bound.Sign() > 0 {
 		m := new(big.Int).Sqrt(bound)
 		m.Add(m, big.NewInt(1))
 
 		return &CalcZp{
-			bound: bound,
-			m:     m,
-			p:     c.p,
-			neg:   c.neg,
+			bound:    bound,
+			maxBound: c.maxBound,
+			m:        m,
+			p:        c.p,
+			neg:      c.neg,
 		}
 	}
 	return c
 }
 
+// WithMaxBound returns a calculator that accepts bounds (see WithBound)
+// smaller than maxBound instead of the package default MaxBound. It
+// allows calculators that are used concurrently to have different
+// limits. The current bound is kept, unless it exceeds maxBound.
+func (c *CalcZp) WithMaxBound(maxBound *big.Int) *CalcZp {
+	ret := &CalcZp{
+		bound:    c.bound,
+		maxBound: new(big.Int).Set(maxBound),
+		m:        c.m,
+		p:        c.p,
+		neg:      c.neg,
+	}
+	if c.bound.Cmp(maxBound) > 0 {
+		ret.bound = ret.maxBound
+		ret.m = new(big.Int).Sqrt(maxBound)
+		ret.m.Add(ret.m, big.NewInt(1))
+	}
+
+	return ret
+}
+
 // WithNeg sets that the result should be searched also among
 // negative integers.
 func (c *CalcZp) WithNeg() *CalcZp {
 	return &CalcZp{
-		bound: c.bound,
-		m:     c.m,
-		p:     c.p,
-		neg:   true,
+		bound:    c.bound,
+		maxBound: c.maxBound,
+		m:        c.m,
+		p:        c.p,
+		neg:      true,
 	}
 }
 
@@ -177,7 +217,7 @@ func (c *CalcZp) runBabyStepGiantStep(h, g *big.Int, retChan chan *big.Int, errC
 		x = new(big.Int).Mod(new(big.Int).Mul(x, z), c.p)
 	}
 	retChan <- nil
-	errChan <- fmt.Errorf("failed to find the discrete logarithm within bound " + c.bound.String())
+	errChan <- fmt.Errorf("%w %s", ErrNotFound, c.bound.String())
 }
 
 // runBabyStepGiantStepIterative implements the baby-step giant-step method to
@@ -235,13 +275,14 @@ func (c *CalcZp) runBabyStepGiantStepIterative(h, g *big.Int, retChan chan *big.
 	}
 
 	retChan <- nil
-	errChan <- fmt.Errorf("failed to find the discrete logarithm within bound")
+	errChan <- ErrNotFound
 }
 
 // CalcBN256 represents a calculator for discrete logarithms
 // that operates in the BN256 group.
 type CalcBN256 struct {
 	bound   *big.Int
+	maxBound *big.Int
 	m       *big.Int
 	Precomp map[string]*big.Int
 	precompMaxBits int
@@ -251,23 +292,28 @@ type CalcBN256 struct {
 // InBN256 builds parameters needed to calculate a discrete
 // logarithm in a pairing BN256 group.
 func (*Calc) InBN256() *CalcBN256 {
-	m := new(big.Int).Sqrt(MaxBound)
+	maxBound := new(big.Int).Set(MaxBound)
+	m := new(big.Int).Sqrt(maxBound)
 	m.Add(m, big.NewInt(1))
 	return &CalcBN256{
-		bound: MaxBound,
+		bound: maxBound,
+		maxBound: maxBound,
 		m:     m,
 		neg:   false,
 	}
 }
 
 // WithBound sets a bound for the calculator of the discrete logarithm.
+// The bound is ignored if it is not smaller than the maximal bound
+// of the calculator (see WithMaxBound).
 func (c *CalcBN256) WithBound(bound *big.Int) *CalcBN256 {
-	if bound != nil && bound.Cmp(MaxBound) < 0 {
+	if bound != nil && bound.Cmp(c.maxBound) < 0 {
 		m := new(big.Int).Sqrt(bound)
 		m.Add(m, big.NewInt(1))
 
 		return &CalcBN256{
 			bound:   bound,
+			maxBound: c.maxBound,
 			m:       m,
 			Precomp: c.Precomp,
 			precompMaxBits: c.precompMaxBits,
@@ -282,6 +328,7 @@ func (c *CalcBN256) WithBound(bound *big.Int) *CalcBN256 {
 func (c *CalcBN256) WithNeg() *CalcBN256 {
 	return &CalcBN256{
 		bound:   c.bound,
+		maxBound: c.maxBound,
 		m:       c.m,
 		Precomp: c.Precomp,
 		precompMaxBits: c.precompMaxBits,
@@ -289,14 +336,151 @@ func (c *CalcBN256) WithNeg() *CalcBN256 {
 	}
 }
 
+// WithMaxBound returns a calculator that accepts bounds (see WithBound)
+// smaller than maxBound instead of the package default MaxBound, and
+// uses maxBound in BabyStepGiantStepAdaptive. It allows calculators
+// that are used concurrently to have different limits. The current
+// bound is kept, unless it exceeds maxBound.
+func (c *CalcBN256) WithMaxBound(maxBound *big.Int) *CalcBN256 {
+	ret := &CalcBN256{
+		bound:   c.bound,
+		maxBound: new(big.Int).Set(maxBound),
+		m:       c.m,
+		Precomp: c.Precomp,
+		precompMaxBits: c.precompMaxBits,
+		neg:     c.neg,
+	}
+	if c.bound.Cmp(maxBound) > 0 {
+		ret.bound = ret.maxBound
+		ret.m = new(big.Int).Sqrt(maxBound)
+		ret.m.Add(ret.m, big.NewInt(1))
+	}
+
+	return ret
+}
+
 // Precompute precomputes small steps for the discrete logarithm
-// search. The resulting precomputation table is of size 2^maxBits.
+// search. The resulting precomputation table is of size 2^maxBits,
+// or larger if a larger table is held by the precomputation cache
+// (see SetPrecompCache). c.Precomp is never shared with the cache
+// or other calculators, so it can be modified by the caller.
 func (c *CalcBN256) Precompute(maxBits int) error {
 	if maxBits < 2 {
 		return fmt.Errorf("maxBits should be at least 1")
 	}
 	g := new(bn256.GT).ScalarBaseMult(big.NewInt(1))
 
+	if t, ok := lookupTable(g, maxBits); ok {
+		c.Precomp, c.precompMaxBits = copyTable(t.table), t.maxBits
+		return nil
+	}
+	c.Precomp, c.precompMaxBits = precomputeTable(g, maxBits), maxBits
+	if cacheable(maxBits) {
+		storeTable(g, copyTable(c.Precomp), maxBits)
+	}
+	return nil
+}
+
+// precompCacheMaxBits is the size (in bits) of the largest table that
+// is put in the precomputation cache. It limits the memory held by the
+// cache for the lifetime of the process to a few tens of megabytes.
+const precompCacheMaxBits = 18
+
+// precompCache holds precomputation tables shared by all the CalcBN256
+// instances of the process, keyed by the marshaled generator of BN256.GT
+// they were computed for. The tables in the cache are never modified.
+var precompCache = struct {
+	sync.Mutex
+	enabled bool
+	tables  map[string]cachedPrecomp
+}{enabled: true, tables: make(map[string]cachedPrecomp)}
+
+// cachedPrecomp is a precomputation table holding the small steps g^i
+// for at least all i < 2^maxBits.
+type cachedPrecomp struct {
+	table   map[string]*big.Int
+	maxBits int
+}
+
+// SetPrecompCache enables or disables the precomputation cache shared
+// by all the CalcBN256 instances. The cache is enabled by default:
+// the baby steps of the generator of BN256.GT computed by Precompute or
+// by a successful BabyStepGiantStep are kept and reused by all the
+// following computations in the process, which speeds up repeated
+// decryptions at the cost of holding a table of up to 2^18 steps in
+// memory. Memory sensitive callers can disable the cache, which also
+// releases the tables held by it.
+func SetPrecompCache(enabled bool) {
+	precompCache.Lock()
+	defer precompCache.Unlock()
+	precompCache.enabled = enabled
+	if !enabled {
+		precompCache.tables = make(map[string]cachedPrecomp)
+	}
+}
+
+// lookupTable returns the cached precomputation table for g if it
+// covers at least maxBits bits. The returned table must not be modified.
+func lookupTable(g *bn256.GT, maxBits int) (cachedPrecomp, bool) {
+	precompCache.Lock()
+	t, ok := precompCache.tables[string(g.Marshal())]
+	precompCache.Unlock()
+
+	return t, ok && t.maxBits >= maxBits
+}
+
+// cachedTable returns a precomputation table for g covering at least
+// maxBits bits together with its size, either from the cache or by
+// computing it and storing it in the cache. The returned table must
+// not be modified.
+func cachedTable(g *bn256.GT, maxBits int) (map[string]*big.Int, int) {
+	if t, ok := lookupTable(g, maxBits); ok {
+		return t.table, t.maxBits
+	}
+
+	table := precomputeTable(g, maxBits)
+	storeTable(g, table, maxBits)
+
+	return table, maxBits
+}
+
+// cacheable reports whether a table covering maxBits bits would be
+// put in the cache.
+func cacheable(maxBits int) bool {
+	precompCache.Lock()
+	defer precompCache.Unlock()
+
+	return precompCache.enabled && maxBits <= precompCacheMaxBits
+}
+
+// copyTable returns a copy of the precomputation table t.
+func copyTable(t map[string]*big.Int) map[string]*big.Int {
+	ret := make(map[string]*big.Int, len(t))
+	for k, v := range t {
+		ret[k] = v
+	}
+
+	return ret
+}
+
+// storeTable puts the table for g in the cache if the cache is enabled,
+// the table is not larger than precompCacheMaxBits and it is larger
+// than the one already held. The table must not be modified afterwards.
+func storeTable(g *bn256.GT, table map[string]*big.Int, maxBits int) {
+	key := string(g.Marshal())
+	precompCache.Lock()
+	defer precompCache.Unlock()
+	if !precompCache.enabled || maxBits > precompCacheMaxBits {
+		return
+	}
+	if t, ok := precompCache.tables[key]; !ok || t.maxBits < maxBits {
+		precompCache.tables[key] = cachedPrecomp{table: table, maxBits: maxBits}
+	}
+}
+
+// precomputeTable computes the small steps g^i for all i with at most
+// maxBits bits, indexed by a truncated hash of g^i.
+func precomputeTable(g *bn256.GT, maxBits int) map[string]*big.Int {
 	one := big.NewInt(1)
 	sh := sha1.New()
 	// big.Int cannot be a key, thus we use a stringified bytes representation of the integer
@@ -310,8 +494,62 @@ func (c *CalcBN256) Precompute(maxBits int) error {
 		x = new(bn256.GT).Add(x, g)
 	}
 
-	c.Precomp = T
-	c.precompMaxBits = maxBits
+	return T
+}
+
+// precompTable is the form in which a precomputation table of
+// CalcBN256 is saved. Generator holds the marshaled generator of
+// BN256.GT for which the table was computed.
+type precompTable struct {
+	Generator []byte
+	MaxBits   int
+	Table     map[string]*big.Int
+}
+
+// SavePrecomp writes the precomputation table of the calculator,
+// together with the generator it was computed for, to w. The table
+// can be reloaded with LoadPrecomp to avoid recomputing it. It returns
+// an error if no table was precomputed yet.
+func (c *CalcBN256) SavePrecomp(w io.Writer) error {
+	if c.Precomp == nil {
+		return fmt.Errorf("no precomputation table to save")
+	}
+	g := new(bn256.GT).ScalarBaseMult(big.NewInt(1))
+
+	return gob.NewEncoder(w).Encode(precompTable{
+		Generator: g.Marshal(),
+		MaxBits:   c.precompMaxBits,
+		Table:     c.Precomp,
+	})
+}
+
+// LoadPrecomp reads a precomputation table written by SavePrecomp
+// from r and sets it as the precomputation table of the calculator.
+// It returns an error if the table was computed for a different
+// generator or is otherwise malformed.
+func (c *CalcBN256) LoadPrecomp(r io.Reader) error {
+	var t precompTable
+	if err := gob.NewDecoder(r).Decode(&t); err != nil {
+		return err
+	}
+
+	g := new(bn256.GT).ScalarBaseMult(big.NewInt(1))
+	if !bytes.Equal(t.Generator, g.Marshal()) {
+		return fmt.Errorf("precomputation table was computed for a different generator")
+	}
+	if t.MaxBits < 2 || len(t.Table) == 0 {
+		return fmt.Errorf("precomputation table is not of the proper form")
+	}
+
+	// check that the table indeed holds the powers of the generator
+	sh := sha1.New()
+	sh.Write([]byte(g.String()))
+	if e, ok := t.Table[string(sh.Sum(nil)[:10])]; !ok || e.Cmp(big.NewInt(1)) != 0 {
+		return fmt.Errorf("precomputation table does not match the generator")
+	}
+
+	c.Precomp = t.Table
+	c.precompMaxBits = t.MaxBits
 	return nil
 }
 
@@ -319,7 +557,7 @@ func (c *CalcBN256) Precompute(maxBits int) error {
 // compute the discrete logarithm in the BN256.GT group.
 //
 // It searches for a solution <= bound. If bound argument is nil,
-// the bound is automatically set to the hard coded MaxBound.
+// the bound is automatically set to the maximal bound of the calculator.
 //
 // The function returns x, where h = g^x in BN256.GT group where operations
 // are written as multiplications. If the solution was not found
@@ -346,7 +584,7 @@ func (c *CalcBN256) BabyStepGiantStepStd(h, g *bn256.GT) (*big.Int, error) {
 		x.Add(x, z)
 	}
 
-	return nil, fmt.Errorf("failed to find discrete logarithm within bound")
+	return nil, ErrNotFound
 }
 
 // BabyStepGiantStep uses the baby-step giant-step method to
@@ -394,6 +632,80 @@ func (c *CalcBN256) BabyStepGiantStep(h, g *bn256.GT) (*big.Int, error) {
 	return ret, nil
 }
 
+// BabyStepGiantStepAdaptive computes the discrete logarithm in the
+// BN256.GT group with the baby-step giant-step method, without
+// committing to a bound up front. It ignores the bound of the
+// calculator: the search starts within [0, 2^8], or within
+// [-2^8, 2^8] if c.neg is set to true, and the bound is doubled after
+// each unsuccessful round up to the maximal bound of the calculator
+// (MaxBound by default, see WithMaxBound). The table of baby steps is
+// kept between the rounds and only extended with the steps the larger
+// bound needs. Hence a search for a result x takes O(sqrt(|x|)) time
+// and memory, no matter how large the maximal bound is. It is meant
+// for the cases when the size of the result cannot be predicted. If
+// the solution was not found within the maximal bound, it returns an
+// error.
+func (c *CalcBN256) BabyStepGiantStepAdaptive(h, g *bn256.GT) (*big.Int, error) {
+	one := big.NewInt(1)
+	hInv := new(bn256.GT).Neg(h)
+
+	// T holds the baby steps g^i for all i < steps, x is g^steps
+	T := make(map[string]*big.Int)
+	x := bn256.GetGTOne()
+	steps := big.NewInt(0)
+
+	bound := new(big.Int).Lsh(one, adaptiveStartBits)
+	for {
+		if bound.Cmp(c.maxBound) > 0 {
+			bound.Set(c.maxBound)
+		}
+
+		// extend the table of baby steps to m = sqrt(bound) + 1 steps
+		m := new(big.Int).Sqrt(bound)
+		m.Add(m, one)
+		for ; steps.Cmp(m) < 0; steps.Add(steps, one) {
+			T[string(x.Marshal())] = new(big.Int).Set(steps)
+			x = new(bn256.GT).Add(x, g)
+		}
+
+		// giant steps of size m
+		z := new(bn256.GT).Neg(x)
+		if ret, ok := giantSteps(h, z, T, m, bound); ok {
+			return ret, nil
+		}
+		if c.neg {
+			if ret, ok := giantSteps(hInv, z, T, m, bound); ok {
+				return ret.Neg(ret), nil
+			}
+		}
+
+		if bound.Cmp(c.maxBound) >= 0 {
+			return nil, ErrNotFound
+		}
+		bound.Lsh(bound, 1)
+	}
+}
+
+// adaptiveStartBits is the size (in bits) of the bound of the first
+// round of BabyStepGiantStepAdaptive.
+const adaptiveStartBits = 8
+
+// giantSteps searches for x within [0, bound] such that h = g^x, given
+// the baby steps T holding g^i for all i < m and z = g^-m. It reports
+// whether x was found.
+func giantSteps(h, z *bn256.GT, T map[string]*big.Int, m, bound *big.Int) (*big.Int, bool) {
+	y := new(bn256.GT).Set(h)
+	for j := big.NewInt(0); j.Cmp(bound) <= 0; j.Add(j, m) {
+		if e, ok := T[string(y.Marshal())]; ok {
+			ret := new(big.Int).Add(j, e)
+			return ret, ret.Cmp(bound) <= 0
+		}
+		y.Add(y, z)
+	}
+
+	return nil, false
+}
+
 // runBabyStepGiantStepIterative implements the baby-step giant-step method to
 // compute the discrete logarithm in the BN256.GT group. It is meant to be run
 // as a goroutine.
@@ -408,12 +720,23 @@ func (c *CalcBN256) runBabyStepGiantStepIterative(h, g *bn256.GT, retChan chan *
 	two := big.NewInt(2)
 
 
-	var startBits int
-	if c.Precomp == nil {
-		_ = c.Precompute(2)
+	// the precomputed table holds small steps of the generator of
+	// BN256.GT, for any other base a small table is computed for g;
+	// without a table of its own the calculator starts from the shared
+	// cache, which also receives the steps computed in the search below
+	isGen := g.String() == new(bn256.GT).ScalarBaseMult(big.NewInt(1)).String()
+	precomp, startBits := c.Precomp, c.precompMaxBits
+	if !isGen {
+		startBits = 2
+		precomp = precomputeTable(g, startBits)
+	} else if precomp == nil {
+		precomp, startBits = cachedTable(g, 2)
+	}
+	// a table computed for a larger bound should not make the giant
+	// steps larger than needed for this search
+	if startBits > c.m.BitLen() {
+		startBits = c.m.BitLen()
 	}
-
-	startBits = c.precompMaxBits
 
 	// prepare values for the loop
 	y := new(bn256.GT).Set(h)
@@ -433,7 +756,7 @@ func (c *CalcBN256) runBabyStepGiantStepIterative(h, g *bn256.GT, retChan chan *
 			return
 		default:
 			sh.Write([]byte(y.String()))
-			e, ok := c.Precomp[string(sh.Sum(nil)[:10])]
+			e, ok := precomp[string(sh.Sum(nil)[:10])]
 			sh.Reset()
 			if ok {
 				retChan <- new(big.Int).Add(j, e)
@@ -447,10 +770,20 @@ func (c *CalcBN256) runBabyStepGiantStepIterative(h, g *bn256.GT, retChan chan *
 	x := new(bn256.GT).ScalarMult(g, new(big.Int).Exp(big.NewInt(2), big.NewInt(int64(startBits)), nil))
 
 	T := make(map[string]*big.Int)
-	for k,v := range c.Precomp {
+	for k,v := range precomp {
 		T[k] = v
 	}
 
+	// T holds the small steps g^k for all k < 2^coveredBits; for the
+	// generator of BN256.GT it is put in the cache when the search
+	// succeeds, so that a failed search does not pin a large table
+	coveredBits := startBits
+	storeSteps := func() {
+		if isGen && coveredBits > startBits {
+			storeTable(g, T, coveredBits)
+		}
+	}
+
 	bits := int64(c.m.BitLen())
 	for i := int64(startBits); i < bits; i++ {
 		select {
@@ -476,6 +809,7 @@ func (c *CalcBN256) runBabyStepGiantStepIterative(h, g *bn256.GT, retChan chan *
 					x = new(bn256.GT).Add(x, g)
 				}
 			}
+			coveredBits = giantStep.BitLen() - 1
 			// make giant steps and search for the solution
 			bound.Exp(giantStep, two, nil)
 			for ; j.Cmp(bound) < 0; j.Add(j, giantStep) {
@@ -487,6 +821,7 @@ func (c *CalcBN256) runBabyStepGiantStepIterative(h, g *bn256.GT, retChan chan *
 					e, ok := T[string(sh.Sum(nil)[:10])]
 					sh.Reset()
 					if ok {
+						storeSteps()
 						retChan <- new(big.Int).Add(j, e)
 						errChan <- nil
 						return
@@ -498,5 +833,110 @@ func (c *CalcBN256) runBabyStepGiantStepIterative(h, g *bn256.GT, retChan chan *
 		}
 	}
 	retChan <- nil
-	errChan <- fmt.Errorf("failed to find the discrete logarithm within bound")
+	errChan <- ErrNotFound
+}
+
+// kangarooAttempts is the number of independent pairs of kangaroos
+// PollardRho runs before it gives up.
+const kangarooAttempts = 8
+
+// PollardRho computes the discrete logarithm in the BN256.GT group
+// using the kangaroo (lambda) variant of Pollard's rho method, which
+// is suited for a bounded interval, instead of a cycle detection over
+// the whole group: a tame kangaroo starting at g^bound jumps ahead and
+// sets a trap where it stops, and a wild kangaroo starting at h follows
+// the same pseudo-random jumps until it lands on the trap or passes it.
+// In contrast to BabyStepGiantStep it needs only a constant amount of
+// memory, while its expected running time is still O(sqrt(bound)).
+//
+// It searches for x within [0, bound], or within [-bound, bound] if
+// c.neg is set to true, such that h = g^x in BN256.GT group where
+// operations are written as multiplications. The method is
+// probabilistic; if a walk fails it is repeated with a different
+// pseudo-random jump function. If the solution was not found it
+// returns an error.
+func (c *CalcBN256) PollardRho(h, g *bn256.GT) (*big.Int, error) {
+	// if negative results are allowed, search for x + bound
+	// within [0, 2 * bound]
+	bound := new(big.Int).Set(c.bound)
+	hShift := new(bn256.GT).Set(h)
+	if c.neg {
+		hShift.Add(hShift, new(bn256.GT).ScalarMult(g, c.bound))
+		bound.Lsh(bound, 1)
+	}
+
+	for i := 0; i < kangarooAttempts; i++ {
+		ret, ok := kangarooWalk(hShift, g, bound, i)
+		if !ok {
+			continue
+		}
+		if c.neg {
+			ret.Sub(ret, c.bound)
+		}
+
+		return ret, nil
+	}
+
+	return nil, ErrNotFound
+}
+
+// kangarooWalk runs a single tame and a single wild kangaroo
+// searching for x within [0, bound] such that h = g^x in BN256.GT. The
+// jumps of both kangaroos are powers of two chosen by hashing the
+// current position together with salt. It returns false if the wild
+// kangaroo passed the tame one without landing on its trap.
+func kangarooWalk(h, g *bn256.GT, bound *big.Int, salt int) (*big.Int, bool) {
+	// choose the jumps so that their mean is about sqrt(bound) / 2
+	sqrtBound := new(big.Int).Sqrt(bound)
+	halfSqrt := new(big.Int).Rsh(sqrtBound, 1)
+	k := 1
+	for mean := big.NewInt(1); mean.Cmp(halfSqrt) < 0; k++ {
+		mean.Lsh(big.NewInt(1), uint(k+1))
+		mean.Sub(mean, big.NewInt(1))
+		mean.Div(mean, big.NewInt(int64(k+1)))
+	}
+	jumps := make([]*big.Int, k)
+	gToJumps := make([]*bn256.GT, k)
+	for i := range jumps {
+		jumps[i] = new(big.Int).Lsh(big.NewInt(1), uint(i))
+		gToJumps[i] = new(bn256.GT).ScalarMult(g, jumps[i])
+	}
+
+	sh := sha1.New()
+	saltStr := strconv.Itoa(salt)
+	jumpIndex := func(x *bn256.GT) int {
+		sh.Reset()
+		sh.Write([]byte(saltStr))
+		sh.Write(x.Marshal())
+		return int(binary.BigEndian.Uint64(sh.Sum(nil)[:8]) % uint64(k))
+	}
+
+	// the tame kangaroo starts at g^bound and sets a trap after
+	// 2 * sqrt(bound) jumps
+	steps := new(big.Int).Lsh(sqrtBound, 1)
+	steps.Add(steps, big.NewInt(1))
+	tame := new(bn256.GT).ScalarMult(g, bound)
+	tameDist := big.NewInt(0)
+	for i := big.NewInt(0); i.Cmp(steps) < 0; i.Add(i, big.NewInt(1)) {
+		j := jumpIndex(tame)
+		tame.Add(tame, gToJumps[j])
+		tameDist.Add(tameDist, jumps[j])
+	}
+	trap := tame.Marshal()
+
+	// the wild kangaroo starts at h and jumps until it either
+	// falls into the trap or passes it
+	wild := new(bn256.GT).Set(h)
+	wildDist := big.NewInt(0)
+	maxDist := new(big.Int).Add(bound, tameDist)
+	for wildDist.Cmp(maxDist) <= 0 {
+		if bytes.Equal(wild.Marshal(), trap) {
+			return maxDist.Sub(maxDist, wildDist), true
+		}
+		j := jumpIndex(wild)
+		wild.Add(wild, gToJumps[j])
+		wildDist.Add(wildDist, jumps[j])
+	}
+
+	return nil, false
 }
@@ -17,6 +17,8 @@
 package dlog
 
 import (
+	"bytes"
+	"encoding/gob"
 	"math/big"
 	"testing"
 
@@ -107,3 +109,248 @@ func TestCalcBN256_BabyStepGiantStep(t *testing.T) {
 	}
 	assert.Equal(t, xCheck.Cmp(x), 0, "BabyStepGiantStep in BN256 returns wrong dlog")
 }
+
+func TestCalcBN256_BabyStepGiantStepBase(t *testing.T) {
+	// small results must be found also when the base differs
+	// from the generator the precomputed table was built for
+	g := new(bn256.GT).ScalarBaseMult(big.NewInt(12345))
+	calc := NewCalc().InBN256().WithBound(big.NewInt(1000)).WithNeg()
+	for _, xCheck := range []int64{0, 1, 3, -2, 17, -999} {
+		h := new(bn256.GT).ScalarMult(g, new(big.Int).Abs(big.NewInt(xCheck)))
+		if xCheck < 0 {
+			h.Neg(h)
+		}
+		x, err := calc.BabyStepGiantStep(h, g)
+		if err != nil {
+			t.Fatalf("Error in baby step - giant step algorithm for %d: %v", xCheck, err)
+		}
+		assert.Equal(t, big.NewInt(xCheck).Cmp(x), 0, "BabyStepGiantStep in BN256 returns wrong dlog")
+	}
+}
+
+func TestCalcBN256_PollardRho(t *testing.T) {
+	bound := big.NewInt(100000)
+	sampler := sample.NewUniformRange(new(big.Int).Neg(bound), bound)
+	g := new(bn256.GT).ScalarBaseMult(big.NewInt(1))
+	calc := NewCalc().InBN256().WithBound(bound).WithNeg()
+
+	xs := []*big.Int{big.NewInt(0), new(big.Int).Set(bound), new(big.Int).Neg(bound)}
+	for i := 0; i < 3; i++ {
+		x, err := sampler.Sample()
+		if err != nil {
+			t.Fatalf("error when generating random number: %v", err)
+		}
+		xs = append(xs, x)
+	}
+
+	for _, xCheck := range xs {
+		h := new(bn256.GT).ScalarMult(g, new(big.Int).Abs(xCheck))
+		if xCheck.Sign() == -1 {
+			h.Neg(h)
+		}
+
+		x, err := calc.PollardRho(h, g)
+		if err != nil {
+			t.Fatalf("Error in Pollard rho algorithm: %v", err)
+		}
+		assert.Equal(t, xCheck.Cmp(x), 0, "PollardRho in BN256 returns wrong dlog")
+
+		xBSGS, err := calc.BabyStepGiantStep(h, g)
+		if err != nil {
+			t.Fatalf("Error in baby step - giant step algorithm: %v", err)
+		}
+		assert.Equal(t, xBSGS.Cmp(x), 0, "PollardRho and BabyStepGiantStep results differ")
+	}
+
+	// a value out of the bound should not be found
+	h := new(bn256.GT).ScalarMult(g, new(big.Int).Mul(bound, big.NewInt(1000)))
+	_, err := NewCalc().InBN256().WithBound(big.NewInt(100)).PollardRho(h, g)
+	assert.Error(t, err)
+}
+
+func TestCalcBN256_SavePrecomp(t *testing.T) {
+	calc := NewCalc().InBN256().WithBound(big.NewInt(1000000)).WithNeg()
+	err := calc.SavePrecomp(new(bytes.Buffer))
+	assert.Error(t, err)
+
+	err = calc.Precompute(10)
+	if err != nil {
+		t.Fatalf("error when precomputing: %v", err)
+	}
+
+	var buf bytes.Buffer
+	err = calc.SavePrecomp(&buf)
+	if err != nil {
+		t.Fatalf("error when saving the precomputation table: %v", err)
+	}
+	saved := buf.Bytes()
+
+	calcLoaded := NewCalc().InBN256().WithBound(big.NewInt(1000000)).WithNeg()
+	err = calcLoaded.LoadPrecomp(bytes.NewReader(saved))
+	if err != nil {
+		t.Fatalf("error when loading the precomputation table: %v", err)
+	}
+	assert.Equal(t, calc.Precomp, calcLoaded.Precomp)
+
+	xCheck := big.NewInt(-123456)
+	g := new(bn256.GT).ScalarBaseMult(big.NewInt(1))
+	h := new(bn256.GT).ScalarMult(g, new(big.Int).Neg(xCheck))
+	h.Neg(h)
+	x, err := calcLoaded.BabyStepGiantStep(h, g)
+	if err != nil {
+		t.Fatalf("Error in baby step - giant step algorithm: %v", err)
+	}
+	assert.Equal(t, xCheck.Cmp(x), 0, "BabyStepGiantStep with a loaded table returns wrong dlog")
+
+	// a table for a different generator should be rejected
+	var bufWrong bytes.Buffer
+	gWrong := new(bn256.GT).ScalarBaseMult(big.NewInt(2))
+	err = gob.NewEncoder(&bufWrong).Encode(precompTable{
+		Generator: gWrong.Marshal(),
+		MaxBits:   10,
+		Table:     calc.Precomp,
+	})
+	if err != nil {
+		t.Fatalf("error when encoding the table: %v", err)
+	}
+	err = NewCalc().InBN256().LoadPrecomp(&bufWrong)
+	assert.Error(t, err)
+}
+
+func TestCalcBN256_BabyStepGiantStepAdaptive(t *testing.T) {
+	g := new(bn256.GT).ScalarBaseMult(big.NewInt(1))
+	calc := NewCalc().InBN256().WithBound(big.NewInt(100)).WithNeg()
+	for _, xCheck := range []int64{0, 7, -30, 1 << 21, -(3 << 20)} {
+		h := new(bn256.GT).ScalarMult(g, new(big.Int).Abs(big.NewInt(xCheck)))
+		if xCheck < 0 {
+			h.Neg(h)
+		}
+		x, err := calc.BabyStepGiantStepAdaptive(h, g)
+		if err != nil {
+			t.Fatalf("Error in adaptive baby step - giant step algorithm for %d: %v", xCheck, err)
+		}
+		assert.Equal(t, big.NewInt(xCheck).Cmp(x), 0, "BabyStepGiantStepAdaptive in BN256 returns wrong dlog")
+	}
+
+	// the bound of the calculator is still respected by BabyStepGiantStep
+	h := new(bn256.GT).ScalarMult(g, big.NewInt(1<<21))
+	_, err := calc.BabyStepGiantStep(h, g)
+	assert.Error(t, err)
+
+	// a small result under a large maximal bound is found in the first
+	// rounds, also for a base that is not the generator
+	base := new(bn256.GT).ScalarMult(g, big.NewInt(12345))
+	calcLarge := NewCalc().InBN256().WithNeg().WithMaxBound(new(big.Int).Lsh(big.NewInt(1), 100))
+	for _, xCheck := range []int64{3, -200, 1000} {
+		h := new(bn256.GT).ScalarMult(base, new(big.Int).Abs(big.NewInt(xCheck)))
+		if xCheck < 0 {
+			h.Neg(h)
+		}
+		x, err := calcLarge.BabyStepGiantStepAdaptive(h, base)
+		if err != nil {
+			t.Fatalf("Error in adaptive baby step - giant step algorithm for %d: %v", xCheck, err)
+		}
+		assert.Equal(t, big.NewInt(xCheck).Cmp(x), 0, "BabyStepGiantStepAdaptive in BN256 returns wrong dlog")
+	}
+}
+
+func TestCalcBN256_PrecompCache(t *testing.T) {
+	// the cache is enabled by default; disabling and enabling it again
+	// starts the test from an empty cache
+	assert.True(t, precompCache.enabled)
+	defer SetPrecompCache(true)
+	SetPrecompCache(false)
+	SetPrecompCache(true)
+
+	// a failed search does not put its steps in the cache
+	g := new(bn256.GT).ScalarBaseMult(big.NewInt(1))
+	hFar := new(bn256.GT).ScalarMult(g, big.NewInt(5000))
+	_, err := NewCalc().InBN256().WithBound(big.NewInt(100)).BabyStepGiantStep(hFar, g)
+	assert.Error(t, err)
+	_, maxBits := cachedTable(g, 2)
+	assert.Equal(t, 2, maxBits)
+
+	bound := big.NewInt(1 << 20)
+	for _, xCheck := range []int64{700000, -5, 1 << 19, 0, -(1 << 20)} {
+		h := new(bn256.GT).ScalarMult(g, new(big.Int).Abs(big.NewInt(xCheck)))
+		if xCheck < 0 {
+			h.Neg(h)
+		}
+		x, err := NewCalc().InBN256().WithBound(bound).WithNeg().BabyStepGiantStep(h, g)
+		if err != nil {
+			t.Fatalf("Error in baby step - giant step algorithm for %d: %v", xCheck, err)
+		}
+		assert.Equal(t, big.NewInt(xCheck).Cmp(x), 0, "BabyStepGiantStep in BN256 returns wrong dlog")
+	}
+
+	// the first search warmed the cache, so new calculators start
+	// with a larger table
+	table, maxBits := cachedTable(g, 2)
+	assert.True(t, maxBits > 2)
+	assert.True(t, len(table) >= 1<<maxBits)
+	calc := NewCalc().InBN256()
+	err = calc.Precompute(4)
+	if err != nil {
+		t.Fatalf("error when precomputing: %v", err)
+	}
+	assert.Equal(t, maxBits, calc.precompMaxBits)
+
+	// the table of the calculator is a copy of the cached one
+	for k := range calc.Precomp {
+		delete(calc.Precomp, k)
+	}
+	table, _ = cachedTable(g, 2)
+	assert.True(t, len(table) >= 1<<maxBits)
+
+	// values beyond the bound are still not found with a warm cache
+	_, err = NewCalc().InBN256().WithBound(big.NewInt(100)).BabyStepGiantStep(hFar, g)
+	assert.Error(t, err)
+
+	// tables larger than the cap are not cached
+	storeTable(g, map[string]*big.Int{}, precompCacheMaxBits+1)
+	_, maxBits = cachedTable(g, 2)
+	assert.True(t, maxBits <= precompCacheMaxBits)
+
+	// with the cache disabled tables are not shared
+	SetPrecompCache(false)
+	_, maxBits = cachedTable(g, 2)
+	assert.Equal(t, 2, maxBits)
+}
+
+func TestCalcBN256_WithMaxBound(t *testing.T) {
+	g := new(bn256.GT).ScalarBaseMult(big.NewInt(1))
+	h := new(bn256.GT).ScalarMult(g, big.NewInt(5000))
+
+	// bounds above the maximal bound of the calculator are ignored
+	calc := NewCalc().InBN256().WithMaxBound(big.NewInt(1000))
+	_, err := calc.WithBound(big.NewInt(10000)).BabyStepGiantStep(h, g)
+	assert.Error(t, err)
+	_, err = calc.BabyStepGiantStepAdaptive(h, g)
+	assert.Error(t, err)
+
+	// other calculators and the package default are not affected
+	assert.Equal(t, new(big.Int).Exp(big.NewInt(2), big.NewInt(48), nil), MaxBound)
+	x, err := NewCalc().InBN256().WithBound(big.NewInt(10000)).BabyStepGiantStep(h, g)
+	if err != nil {
+		t.Fatalf("Error in baby step - giant step algorithm: %v", err)
+	}
+	assert.Equal(t, int64(5000), x.Int64())
+}
+
+func TestCalcZp_WithMaxBound(t *testing.T) {
+	p := big.NewInt(1000003)
+	g := big.NewInt(2)
+	h := new(big.Int).Exp(g, big.NewInt(5000), p)
+
+	calc, err := NewCalc().InZp(p, nil)
+	if err != nil {
+		t.Fatalf("Error in creation of the calculator: %v", err)
+	}
+	_, err = calc.WithMaxBound(big.NewInt(1000)).WithBound(big.NewInt(10000)).BabyStepGiantStep(h, g)
+	assert.Error(t, err)
+	x, err := calc.WithBound(big.NewInt(10000)).BabyStepGiantStep(h, g)
+	if err != nil {
+		t.Fatalf("Error in baby step - giant step algorithm: %v", err)
+	}
+	assert.Equal(t, int64(5000), x.Int64())
+}
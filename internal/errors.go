@@ -36,3 +36,7 @@ var ErrMalformedCipher = fmt.Errorf("ciphertext %s", malformedStr)
 
 // ErrMalformedInput is an error for input data.
 var ErrMalformedInput = fmt.Errorf("input data %s", malformedStr)
+
+// ErrNotQuadraticResidue is returned when a square root of a value
+// that is not a quadratic residue is requested.
+var ErrNotQuadraticResidue = fmt.Errorf("value is not a quadratic residue")
@@ -0,0 +1,30 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal
+
+import "math/big"
+
+// ModPos returns the canonical representative of x modulo p, i.e. the
+// unique value in [0, p) congruent to x, even if x < 0. The result is
+// a new big.Int, so x is left unchanged. The modulus p must be positive.
+//
+// It is meant to be used where a possibly negative value has to be
+// mapped to Z_p, e.g. when a signed scalar is used as an exponent in
+// a group of order p.
+func ModPos(x, p *big.Int) *big.Int {
+	return new(big.Int).Mod(x, p)
+}
@@ -0,0 +1,36 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/fentec-project/gofe/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModPos(t *testing.T) {
+	p := big.NewInt(7)
+	for x, want := range map[int64]int64{-15: 6, -7: 0, -1: 6, 0: 0, 3: 3, 7: 0, 16: 2} {
+		xBig := big.NewInt(x)
+		res := internal.ModPos(xBig, p)
+		assert.Equal(t, want, res.Int64(), "ModPos(%d, 7)", x)
+		// x is left unchanged
+		assert.Equal(t, x, xBig.Int64())
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ModSqrt returns a square root of a modulo an odd prime p, i.e. a
+// value r in [0, p) such that r^2 = a (mod p); the other square root is
+// p - r. It is computed with the Tonelli-Shanks algorithm. Unlike
+// big.Int.ModSqrt, which gives an unspecified result when p is not
+// prime, ModSqrt returns an error if p is not an odd prime (checked
+// probabilistically) and ErrNotQuadraticResidue if a has no square root
+// modulo p.
+func ModSqrt(a, p *big.Int) (*big.Int, error) {
+	if p.Cmp(big.NewInt(2)) <= 0 || p.Bit(0) == 0 || !p.ProbablyPrime(20) {
+		return nil, fmt.Errorf("modulus %v is not an odd prime", p)
+	}
+	a = new(big.Int).Mod(a, p)
+	if a.Sign() == 0 {
+		return a, nil
+	}
+	if big.Jacobi(a, p) != 1 {
+		return nil, ErrNotQuadraticResidue
+	}
+
+	// p - 1 = q * 2^s with q odd
+	pMinusOne := new(big.Int).Sub(p, big.NewInt(1))
+	s := pMinusOne.TrailingZeroBits()
+	q := new(big.Int).Rsh(pMinusOne, s)
+
+	// r = a^((q + 1) / 2) is a root of a * t for t = a^q, which is
+	// iteratively corrected until t = 1
+	exp := new(big.Int).Add(q, big.NewInt(1))
+	exp.Rsh(exp, 1)
+	r := new(big.Int).Exp(a, exp, p)
+	if s == 1 {
+		return r, nil
+	}
+	t := new(big.Int).Exp(a, q, p)
+
+	// c is a generator of the 2-Sylow subgroup, obtained from
+	// a quadratic non-residue z
+	z := big.NewInt(2)
+	for big.Jacobi(z, p) != -1 {
+		z.Add(z, big.NewInt(1))
+	}
+	c := new(big.Int).Exp(z, q, p)
+
+	one := big.NewInt(1)
+	m := s
+	tPow := new(big.Int)
+	for t.Cmp(one) != 0 {
+		// find the least i such that t^(2^i) = 1
+		i := uint(0)
+		tPow.Set(t)
+		for tPow.Cmp(one) != 0 {
+			tPow.Mul(tPow, tPow).Mod(tPow, p)
+			i++
+		}
+		if i == m {
+			return nil, ErrNotQuadraticResidue
+		}
+
+		b := new(big.Int).Exp(c, new(big.Int).Lsh(one, m-i-1), p)
+		m = i
+		c.Mul(b, b).Mod(c, p)
+		t.Mul(t, c).Mod(t, p)
+		r.Mul(r, b).Mod(r, p)
+	}
+
+	return r, nil
+}
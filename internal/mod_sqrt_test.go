@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/internal"
+	"github.com/fentec-project/gofe/sample"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModSqrt(t *testing.T) {
+	// 23 is a safe prime, 17 - 1 and 97 - 1 are divisible by high
+	// powers of 2
+	for _, p := range []int64{3, 17, 23, 97} {
+		pBig := big.NewInt(p)
+		squares := make(map[int64]bool)
+		for x := int64(0); x < p; x++ {
+			squares[x*x%p] = true
+		}
+		for a := int64(0); a < p; a++ {
+			r, err := internal.ModSqrt(big.NewInt(a), pBig)
+			if !squares[a] {
+				assert.Equal(t, internal.ErrNotQuadraticResidue, err)
+				continue
+			}
+			if err != nil {
+				t.Fatalf("Error during square root computation: %v", err)
+			}
+			assert.Equal(t, a, new(big.Int).Exp(r, big.NewInt(2), pBig).Int64())
+		}
+	}
+
+	// residues and non-residues modulo the order of bn256
+	p := bn256.Order
+	x, err := sample.NewUniform(p).Sample()
+	if err != nil {
+		t.Fatalf("Error during random value generation: %v", err)
+	}
+	a := new(big.Int).Exp(x, big.NewInt(2), p)
+	r, err := internal.ModSqrt(a, p)
+	if err != nil {
+		t.Fatalf("Error during square root computation: %v", err)
+	}
+	assert.Equal(t, a, new(big.Int).Exp(r, big.NewInt(2), p))
+
+	nonResidue := big.NewInt(2)
+	for big.Jacobi(nonResidue, p) != -1 {
+		nonResidue.Add(nonResidue, big.NewInt(1))
+	}
+	_, err = internal.ModSqrt(new(big.Int).Mul(a, nonResidue), p)
+	assert.Equal(t, internal.ErrNotQuadraticResidue, err)
+
+	// the modulus has to be an odd prime
+	for _, m := range []int64{2, 15, 16} {
+		_, err = internal.ModSqrt(big.NewInt(4), big.NewInt(m))
+		assert.Error(t, err)
+	}
+}
@@ -214,14 +214,21 @@ type QuadCipher struct {
 // Encrypt encrypts input vectors x and y with the given
 // public key. It returns the appropriate ciphertext.
 // If the ciphertext could not be generated, it returns an error.
+// An error wrapping data.ErrBoundExceeded and naming the offending
+// coordinate is returned if x or y are not bounded by the bound of
+// the scheme, since otherwise x^T * F * y could not be decrypted.
+// Note that the vector encrypted with the underlying PartFHIPE scheme
+// is masked with randomness and reduced modulo the order of the group,
+// hence it needs no bound; the decryption only depends on the bounds
+// of x, y and F.
 func (q *Quad) Encrypt(x, y data.Vector, pubKey *QuadPubKey) (*QuadCipher, error) {
 	if len(x) != q.Params.N || len(y) != q.Params.M {
 		return nil, fmt.Errorf("dimensions of vectors are incorrect")
 	}
-	if err := x.CheckBound(q.Params.Bound); err != nil {
+	if err := checkBound("x", x, q.Params.Bound); err != nil {
 		return nil, err
 	}
-	if err := y.CheckBound(q.Params.Bound); err != nil {
+	if err := checkBound("y", y, q.Params.Bound); err != nil {
 		return nil, err
 	}
 
@@ -257,11 +264,15 @@ func (q *Quad) Encrypt(x, y data.Vector, pubKey *QuadPubKey) (*QuadCipher, error
 }
 
 // DeriveKey derives the functional encryption key for the scheme.
-// It returns an error if the key could not be derived.
+// It returns an error if the key could not be derived, or if the
+// elements of F are not bounded by the bound of the scheme.
 func (q *Quad) DeriveKey(secKey *QuadSecKey, F data.Matrix) (data.VectorG2, error) {
 	if F.Rows() != q.Params.N || F.Cols() != q.Params.M {
 		return nil, fmt.Errorf("dimensions of the given matrix are incorrect")
 	}
+	if err := checkMatrixBound("F", F, q.Params.Bound); err != nil {
+		return nil, err
+	}
 
 	UtF, err := secKey.U.Transpose().Mul(F)
 	if err != nil {
@@ -283,9 +294,73 @@ func (q *Quad) DeriveKey(secKey *QuadSecKey, F data.Matrix) (data.VectorG2, erro
 	return feKey, err
 }
 
+// DeriveKeySparse works as DeriveKey, but it is meant for matrices F
+// with mostly zero elements, e.g. when only a few cross terms of x
+// and y are selected. Instead of multiplying dense matrices, it
+// accumulates the products U^T * F and F * V only over the nonzero
+// elements of F, while the underlying key is derived in the same way.
+func (q *Quad) DeriveKeySparse(secKey *QuadSecKey, F data.Matrix) (data.VectorG2, error) {
+	if F.Rows() != q.Params.N || F.Cols() != q.Params.M {
+		return nil, fmt.Errorf("dimensions of the given matrix are incorrect")
+	}
+	if err := checkMatrixBound("F", F, q.Params.Bound); err != nil {
+		return nil, err
+	}
+
+	UtF := data.NewConstantMatrix(secKey.U.Cols(), q.Params.M, big.NewInt(0))
+	FV := data.NewConstantMatrix(q.Params.N, secKey.V.Cols(), big.NewInt(0))
+	prod := new(big.Int)
+	for i, row := range F {
+		if len(row) != q.Params.M {
+			return nil, fmt.Errorf("dimensions of the given matrix are incorrect")
+		}
+		for j, f := range row {
+			if f.Sign() == 0 {
+				continue
+			}
+			for k := range UtF {
+				prod.Mul(secKey.U[i][k], f)
+				UtF[k][j].Add(UtF[k][j], prod)
+			}
+			for k := range FV[i] {
+				prod.Mul(f, secKey.V[j][k])
+				FV[i][k].Add(FV[i][k], prod)
+			}
+		}
+	}
+	UtF = UtF.Mod(bn256.Order)
+	FV = FV.Mod(bn256.Order)
+
+	yIPE := append(UtF.ToVec(), FV.ToVec()...)
+
+	return q.Params.PartFHIPE.DeriveKey(yIPE, secKey.SecIPE)
+}
+
 // Decrypt decrypts the ciphertext c with the derived functional
 // encryption key key in order to obtain function x^T * F * y.
 func (q *Quad) Decrypt(c *QuadCipher, feKey data.VectorG2, F data.Matrix) (*big.Int, error) {
+	if err := checkMatrixBound("F", F, q.Params.Bound); err != nil {
+		return nil, err
+	}
+	dec, err := q.DecryptToGT(c, feKey, F)
+	if err != nil {
+		return nil, err
+	}
+
+	// get upper bounds
+	b3 := new(big.Int).Exp(q.Params.Bound, big.NewInt(3), nil)
+	b := new(big.Int).Mul(b3, big.NewInt(int64(q.Params.N*q.Params.M)))
+	calc := dlog.NewCalc().InBN256().WithBound(b).WithNeg()
+
+	res, err := calc.BabyStepGiantStep(dec, new(bn256.GT).ScalarBaseMult(big.NewInt(1)))
+
+	return res, err
+}
+
+// DecryptToGT works as Decrypt, but it returns e(g1, g2)^(x^T * F * y),
+// where e(g1, g2) is the generator of GT, i.e. the value from which
+// Decrypt obtains the result by computing a discrete logarithm.
+func (q *Quad) DecryptToGT(c *QuadCipher, feKey data.VectorG2, F data.Matrix) (*bn256.GT, error) {
 	if len(feKey) != q.Params.PartFHIPE.Params.L+4 {
 		return nil, fmt.Errorf("dimensions of the given FE key are incorrect")
 	}
@@ -303,20 +378,35 @@ func (q *Quad) Decrypt(c *QuadCipher, feKey data.VectorG2, F data.Matrix) (*big.
 		return nil, err
 	}
 
-	dec := new(bn256.GT).ScalarBaseMult(big.NewInt(0))
-	for i := 0; i < q.Params.N; i++ {
-		pairedI := bn256.Pair(c.Cx[i], FCy[i])
-		dec = new(bn256.GT).Add(pairedI, dec)
+	dec, err := c.Cx.PairDot(FCy)
+	if err != nil {
+		return nil, err
 	}
 	d = new(bn256.GT).Neg(d)
 	dec = new(bn256.GT).Add(dec, d)
 
-	// get upper bounds
-	b3 := new(big.Int).Exp(q.Params.Bound, big.NewInt(3), nil)
-	b := new(big.Int).Mul(b3, big.NewInt(int64(q.Params.N*q.Params.M)))
-	calc := dlog.NewCalc().InBN256().WithBound(b).WithNeg()
+	return dec, nil
+}
 
-	res, err := calc.BabyStepGiantStep(dec, new(bn256.GT).ScalarBaseMult(big.NewInt(1)))
+// checkBound checks that the coordinates of vector v, named name in
+// the error, are bounded by bound in absolute value.
+func checkBound(name string, v data.Vector, bound *big.Int) error {
+	for i, c := range v {
+		if new(big.Int).Abs(c).Cmp(bound) > 0 {
+			return fmt.Errorf("%s[%d] = %v exceeds the bound %v: %w", name, i, c, bound, data.ErrBoundExceeded)
+		}
+	}
 
-	return res, err
+	return nil
+}
+
+// checkMatrixBound works as checkBound, but for a matrix m.
+func checkMatrixBound(name string, m data.Matrix, bound *big.Int) error {
+	for i, row := range m {
+		if err := checkBound(fmt.Sprintf("%s[%d]", name, i), row, bound); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -17,9 +17,11 @@
 package quadratic_test
 
 import (
+	"errors"
 	"math/big"
 	"testing"
 
+	"github.com/fentec-project/bn256"
 	"github.com/fentec-project/gofe/data"
 	"github.com/fentec-project/gofe/quadratic"
 	"github.com/fentec-project/gofe/sample"
@@ -85,3 +87,125 @@ func TestQuad(t *testing.T) {
 	}
 	assert.Equal(t, check, dec, "Decryption wrong")
 }
+
+func TestQuad_DecryptToGT(t *testing.T) {
+	n := 3
+	m := 2
+	q, err := quadratic.NewQuad(n, m, big.NewInt(10))
+	if err != nil {
+		t.Fatalf("error when creating scheme: %v", err)
+	}
+	pubKey, secKey, err := q.GenerateKeys()
+	if err != nil {
+		t.Fatalf("error when generating keys: %v", err)
+	}
+
+	x := data.NewVector([]*big.Int{big.NewInt(1), big.NewInt(-2), big.NewInt(3)})
+	y := data.NewVector([]*big.Int{big.NewInt(4), big.NewInt(5)})
+	f, err := data.NewMatrix([]data.Vector{
+		data.NewVector([]*big.Int{big.NewInt(1), big.NewInt(0)}),
+		data.NewVector([]*big.Int{big.NewInt(2), big.NewInt(-1)}),
+		data.NewVector([]*big.Int{big.NewInt(0), big.NewInt(3)}),
+	})
+	if err != nil {
+		t.Fatalf("error when creating matrix: %v", err)
+	}
+
+	c, err := q.Encrypt(x, y, pubKey)
+	if err != nil {
+		t.Fatalf("error when encrypting: %v", err)
+	}
+	feKey, err := q.DeriveKey(secKey, f)
+	if err != nil {
+		t.Fatalf("error when deriving key: %v", err)
+	}
+
+	// x^T * f * y = 4 - 6 + 45 = 43, which can be checked
+	// without computing a discrete logarithm
+	dec, err := q.DecryptToGT(c, feKey, f)
+	if err != nil {
+		t.Fatalf("error when decrypting: %v", err)
+	}
+	assert.Equal(t, new(bn256.GT).ScalarBaseMult(big.NewInt(43)).String(), dec.String())
+}
+
+func TestQuad_Bound(t *testing.T) {
+	n, m := 3, 2
+	bound := big.NewInt(10)
+	q, err := quadratic.NewQuad(n, m, bound)
+	if err != nil {
+		t.Fatalf("error when creating scheme: %v", err)
+	}
+	pubKey, secKey, err := q.GenerateKeys()
+	if err != nil {
+		t.Fatalf("error when generating keys: %v", err)
+	}
+
+	x := data.Vector{big.NewInt(1), big.NewInt(-11), big.NewInt(3)}
+	y := data.Vector{big.NewInt(4), big.NewInt(5)}
+	_, err = q.Encrypt(x, y, pubKey)
+	assert.True(t, errors.Is(err, data.ErrBoundExceeded))
+	assert.Contains(t, err.Error(), "x[1]")
+
+	x[1].SetInt64(-10)
+	y[0].SetInt64(100)
+	_, err = q.Encrypt(x, y, pubKey)
+	assert.True(t, errors.Is(err, data.ErrBoundExceeded))
+	assert.Contains(t, err.Error(), "y[0]")
+
+	F := data.NewConstantMatrix(n, m, big.NewInt(1))
+	F[2][1].SetInt64(11)
+	_, err = q.DeriveKey(secKey, F)
+	assert.True(t, errors.Is(err, data.ErrBoundExceeded))
+	assert.Contains(t, err.Error(), "F[2][1]")
+}
+
+func TestQuad_DeriveKeySparse(t *testing.T) {
+	n, m := 6, 4
+	bound := big.NewInt(100)
+	q, err := quadratic.NewQuad(n, m, bound)
+	if err != nil {
+		t.Fatalf("error when creating scheme: %v", err)
+	}
+	pubKey, secKey, err := q.GenerateKeys()
+	if err != nil {
+		t.Fatalf("error when generating keys: %v", err)
+	}
+
+	boundNeg := new(big.Int).Add(new(big.Int).Neg(bound), big.NewInt(1))
+	sampler := sample.NewUniformRange(boundNeg, bound)
+	x, err := data.NewRandomVector(n, sampler)
+	if err != nil {
+		t.Fatalf("error when generating random vector: %v", err)
+	}
+	y, err := data.NewRandomVector(m, sampler)
+	if err != nil {
+		t.Fatalf("error when generating random vector: %v", err)
+	}
+	c, err := q.Encrypt(x, y, pubKey)
+	if err != nil {
+		t.Fatalf("error when encrypting: %v", err)
+	}
+
+	// a matrix selecting only a few cross terms
+	f := data.NewConstantMatrix(n, m, big.NewInt(0))
+	f[0][3].SetInt64(7)
+	f[2][1].SetInt64(-5)
+	f[5][0].SetInt64(1)
+	feKey, err := q.DeriveKeySparse(secKey, f)
+	if err != nil {
+		t.Fatalf("error when deriving key: %v", err)
+	}
+	dec, err := q.Decrypt(c, feKey, f)
+	if err != nil {
+		t.Fatalf("error when decrypting: %v", err)
+	}
+	check, err := f.MulXMatY(x, y)
+	if err != nil {
+		t.Fatalf("error when computing x*F*y: %v", err)
+	}
+	assert.Equal(t, check, dec, "Decryption wrong")
+
+	_, err = q.DeriveKeySparse(secKey, data.NewConstantMatrix(n, m+1, big.NewInt(0)))
+	assert.Error(t, err)
+}
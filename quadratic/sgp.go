@@ -215,9 +215,8 @@ func (q *SGP) Decrypt(c *SGPCipher, key *bn256.G2, F data.Matrix) (*big.Int, err
 		}
 	}
 
-	g1gen := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
-	g2gen := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
-	g := bn256.Pair(g1gen, g2gen)
+	// e(g1, g2) is the generator of GT, no need to compute the pairing
+	g := new(bn256.GT).ScalarBaseMult(big.NewInt(1))
 
 	// b: b = n^2 * b^3
 	b3 := new(big.Int).Exp(q.Bound, big.NewInt(3), nil)
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sample
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// CenteredBinomial samples random values from the centered binomial
+// distribution with parameter eta. Each value is computed as
+// sum_{i=1}^eta (a_i - b_i) for random bits a_i, b_i, hence it lies in
+// [-eta, eta] and has mean 0 and variance eta/2. It is the noise
+// distribution used by several lattice based schemes (e.g. Kyber) in
+// place of the discrete Gaussian distribution.
+type CenteredBinomial struct {
+	eta    int
+	reader io.Reader
+}
+
+// NewCenteredBinomial returns an instance of CenteredBinomial sampler
+// with parameter eta.
+func NewCenteredBinomial(eta int) *CenteredBinomial {
+	return &CenteredBinomial{eta: eta}
+}
+
+// NewCenteredBinomialWithReader returns an instance of CenteredBinomial
+// sampler that reads randomness from r instead of crypto/rand.
+func NewCenteredBinomialWithReader(eta int, r io.Reader) *CenteredBinomial {
+	return &CenteredBinomial{eta: eta, reader: r}
+}
+
+// Sample samples a value from the centered binomial distribution.
+// It returns an error if eta is not positive or the randomness
+// could not be read.
+func (c *CenteredBinomial) Sample() (*big.Int, error) {
+	if c.eta < 1 {
+		return nil, fmt.Errorf("parameter eta should be positive")
+	}
+
+	// the first eta bits are a_i and the next eta bits are b_i
+	randBytes := make([]byte, (2*c.eta+7)/8)
+	_, err := io.ReadFull(readerOrDefault(c.reader), randBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	res := 0
+	for i := 0; i < 2*c.eta; i += 8 {
+		b := randBytes[i/8]
+		for j := 0; j < 8 && i+j < 2*c.eta; j++ {
+			bit := int(b>>uint(j)) & 1
+			if i+j < c.eta {
+				res += bit
+			} else {
+				res -= bit
+			}
+		}
+	}
+
+	return big.NewInt(int64(res)), nil
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sample_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/fentec-project/gofe/sample"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCenteredBinomial(t *testing.T) {
+	var tests = []struct {
+		eta    int
+		expect paramBounds
+	}{
+		{
+			eta: 2,
+			expect: paramBounds{
+				meanLow:  -0.02,
+				meanHigh: 0.02,
+				varLow:   0.97,
+				varHigh:  1.03,
+			},
+		},
+		{
+			eta: 5,
+			expect: paramBounds{
+				meanLow:  -0.03,
+				meanHigh: 0.03,
+				varLow:   2.43,
+				varHigh:  2.57,
+			},
+		},
+		{
+			eta: 12,
+			expect: paramBounds{
+				meanLow:  -0.04,
+				meanHigh: 0.04,
+				varLow:   5.85,
+				varHigh:  6.15,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s := sample.NewCenteredBinomial(test.eta)
+		testNormalSampler(t, s, test.expect)
+
+		bound := big.NewInt(int64(test.eta))
+		for i := 0; i < 1000; i++ {
+			x, err := s.Sample()
+			if err != nil {
+				t.Fatalf("Error during sampling: %v", err)
+			}
+			assert.True(t, new(big.Int).Abs(x).Cmp(bound) <= 0, "sampled value out of range")
+		}
+	}
+
+	_, err := sample.NewCenteredBinomial(0).Sample()
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sample
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+)
+
+// DiscreteLaplace samples random values from the discrete Laplace
+// (two-sided geometric) distribution, centered on 0. In particular each
+// value x from Z is sampled with probability proportional to
+// exp(-|x|/scale). The magnitude of a value is sampled from the
+// geometric distribution by inverting its cumulative distribution
+// function in float64 arithmetic, and its sign by a random bit.
+// Note that the implementation is not constant time, and it is
+// meant for experiments rather than as a noise for cryptographic
+// schemes.
+type DiscreteLaplace struct {
+	logQ   float64 // -1/scale, the logarithm of the ratio of the geometric distribution
+	reader io.Reader
+}
+
+// NewDiscreteLaplace returns an instance of DiscreteLaplace sampler
+// with the given scale.
+func NewDiscreteLaplace(scale *big.Float) *DiscreteLaplace {
+	return NewDiscreteLaplaceWithReader(scale, nil)
+}
+
+// NewDiscreteLaplaceWithReader returns an instance of DiscreteLaplace
+// sampler that reads randomness from r instead of crypto/rand.
+func NewDiscreteLaplaceWithReader(scale *big.Float, r io.Reader) *DiscreteLaplace {
+	s, _ := scale.Float64()
+	return &DiscreteLaplace{logQ: -1 / s, reader: r}
+}
+
+// Sample samples a value from the discrete Laplace distribution.
+// It returns an error if the scale is not positive or the randomness
+// could not be read.
+func (c *DiscreteLaplace) Sample() (*big.Int, error) {
+	if !(c.logQ < 0) || math.IsInf(c.logQ, -1) {
+		return nil, fmt.Errorf("scale should be positive")
+	}
+
+	randBytes := make([]byte, 8)
+	for {
+		_, err := io.ReadFull(readerOrDefault(c.reader), randBytes)
+		if err != nil {
+			return nil, err
+		}
+		r := binary.LittleEndian.Uint64(randBytes)
+		// the lowest bit determines the sign, the highest 53 bits
+		// give u uniform in (0, 1]
+		neg := r&1 == 1
+		u := float64((r>>11)+1) / (1 << 53)
+
+		// the magnitude is geometric: P(k) = (1 - q) * q^k
+		k := math.Floor(math.Log(u) / c.logQ)
+		// zero would be sampled twice as often as it should be,
+		// hence negative zeros are rejected
+		if neg && k == 0 {
+			continue
+		}
+
+		x := new(big.Int)
+		new(big.Float).SetFloat64(k).Int(x)
+		if neg {
+			x.Neg(x)
+		}
+
+		return x, nil
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sample_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/sample"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscreteLaplace(t *testing.T) {
+	var tests = []struct {
+		scale  float64
+		expect paramBounds
+	}{
+		{
+			// variance is 2q/(1-q)^2 for q = exp(-1/scale)
+			scale: 1,
+			expect: paramBounds{
+				meanLow:  -0.02,
+				meanHigh: 0.02,
+				varLow:   1.78,
+				varHigh:  1.9,
+			},
+		},
+		{
+			scale: 3,
+			expect: paramBounds{
+				meanLow:  -0.07,
+				meanHigh: 0.07,
+				varLow:   17.1,
+				varHigh:  18.6,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		testNormalSampler(t, sample.NewDiscreteLaplace(big.NewFloat(test.scale)), test.expect)
+	}
+
+	// the sampler can be used to fill a vector of noise
+	v, err := data.NewRandomVector(100, sample.NewDiscreteLaplace(big.NewFloat(2)))
+	assert.NoError(t, err)
+	assert.Len(t, v, 100)
+
+	_, err = sample.NewDiscreteLaplace(big.NewFloat(0)).Sample()
+	assert.Error(t, err)
+	_, err = sample.NewDiscreteLaplace(big.NewFloat(-1)).Sample()
+	assert.Error(t, err)
+}
@@ -19,6 +19,7 @@ package sample
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"io"
 	"math"
 	"math/big"
 )
@@ -82,6 +83,11 @@ var cmpMask = uint64(1) << 61
 // it is argued that such a sampling achieves a relative error at most
 // 2^{-45} with the chosen parameters.
 func Bernoulli(t *big.Int, lSquareInv *big.Float) (bool, error) {
+	return bernoulli(t, lSquareInv, rand.Reader)
+}
+
+// bernoulli works as Bernoulli, reading randomness from r.
+func bernoulli(t *big.Int, lSquareInv *big.Float, r io.Reader) (bool, error) {
 	aBig := new(big.Float).SetInt(t)
 	aBig.Mul(aBig, lSquareInv)
 	a, _ := aBig.Float64()
@@ -99,7 +105,7 @@ func Bernoulli(t *big.Int, lSquareInv *big.Float) (bool, error) {
 	powOfAExponent := (math.Float64bits(powOfZ) >> mantissaPrecision) - uint64(negFloorA)
 
 	randBytes := make([]byte, 16)
-	_, err := rand.Read(randBytes)
+	_, err := io.ReadFull(r, randBytes)
 	if err != nil {
 		return false, err
 	}
@@ -17,8 +17,8 @@
 package sample
 
 import (
-	"crypto/rand"
 	"encoding/binary"
+	"io"
 	"math/big"
 )
 
@@ -45,7 +45,7 @@ var SigmaCDT, _ = new(big.Float).SetString("0.84932180028801904272150283410")
 // NormalCDT samples random values from the discrete Normal (Gaussian)
 // probability distribution, limited to non-negative values (half-Gaussian).
 // In particular each value x from Z^+ is sampled with probability proportional to
-// exp(-x^2/sigma^2) where sigma = sqrt(1/2ln(2)).
+// exp(-x^2/(2sigma^2)) = 2^(-x^2) where sigma = sqrt(1/2ln(2)).
 // The implementation is based on paper:
 // "FACCT: FAst, Compact, and Constant-Time Discrete Gaussian
 // Sampler over Integers" by R. K. Zhao, R. Steinfeld, and A. Sakzad
@@ -54,6 +54,7 @@ var SigmaCDT, _ = new(big.Float).SetString("0.84932180028801904272150283410")
 // 2^{-46} with the chosen parameters.
 type NormalCDT struct {
 	*normal
+	reader io.Reader
 }
 
 // NewNormalCDT returns an instance of NormalCDT sampler.
@@ -62,11 +63,17 @@ func NewNormalCDT() *NormalCDT {
 	return s
 }
 
+// NewNormalCDTWithReader returns an instance of NormalCDT sampler
+// that reads randomness from r instead of crypto/rand.
+func NewNormalCDTWithReader(r io.Reader) *NormalCDT {
+	return &NormalCDT{reader: r}
+}
+
 // Sample samples discrete non-negative values with Gaussian
 // distribution.
 func (c *NormalCDT) Sample() (*big.Int, error) {
 	randBytes := make([]byte, 16)
-	_, err := rand.Read(randBytes)
+	_, err := io.ReadFull(readerOrDefault(c.reader), randBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -17,9 +17,11 @@
 package sample_test
 
 import (
+	"math"
 	"testing"
 
 	"github.com/fentec-project/gofe/sample"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNormalCDT(t *testing.T) {
@@ -48,3 +50,29 @@ func TestNormalCDT(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalCDT_Distribution(t *testing.T) {
+	// each value x >= 0 should be sampled with probability
+	// proportional to 2^(-x^2)
+	sampler := sample.NewNormalCDT()
+	n := 200000
+	counts := make(map[int64]int)
+	for i := 0; i < n; i++ {
+		x, err := sampler.Sample()
+		if err != nil {
+			t.Fatalf("Error during sampling: %v", err)
+		}
+		assert.True(t, x.Sign() >= 0, "sampled value is negative")
+		counts[x.Int64()]++
+	}
+
+	total := 0.0
+	for x := 0; x < 10; x++ {
+		total += math.Exp2(-float64(x * x))
+	}
+	for x := int64(0); x < 4; x++ {
+		p := math.Exp2(-float64(x*x)) / total
+		freq := float64(counts[x]) / float64(n)
+		assert.InDelta(t, p, freq, 0.005, "wrong frequency of %d", x)
+	}
+}
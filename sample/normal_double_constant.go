@@ -18,6 +18,9 @@ package sample
 
 import (
 	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"math"
 	"math/big"
 )
 
@@ -35,6 +38,13 @@ import (
 // see https://eprint.iacr.org/2018/1234.pdf.
 // See the above paper for the argumentation of the choice of
 // parameters and proof of precision and security.
+//
+// For small l (at most 64), the sampler instead precomputes a
+// cumulative distribution table of the whole distribution and samples
+// by scanning all of its entries, which is also constant time and
+// considerably faster than the rejection sampling above. The table
+// approximates the probabilities with a relative error of about 2^-52
+// and an absolute error of at most 2^-63.
 type NormalDoubleConstant struct {
 	*normal
 	// NormalCDT sampler used in the first part
@@ -44,12 +54,28 @@ type NormalDoubleConstant struct {
 	// precomputed values for faster sampling
 	lSquareInv *big.Float
 	twiceL     *big.Int
+	reader     io.Reader
+	// cumulative distribution table used for small l, and the
+	// largest absolute value that can be sampled from it
+	table []uint64
+	tail  int64
 }
 
+// cdtMaxL is the largest l for which NormalDoubleConstant samples
+// from a precomputed cumulative distribution table.
+const cdtMaxL = 64
+
 // NewNormalDoubleConstant returns an instance of NormalDoubleConstant
 // sampler. It assumes mean = 0. Parameter l needs to be given, such
 // that sigma = l * sqrt(1/2ln(2)).
 func NewNormalDoubleConstant(l *big.Int) *NormalDoubleConstant {
+	return NewNormalDoubleConstantWithReader(l, rand.Reader)
+}
+
+// NewNormalDoubleConstantWithReader returns an instance of
+// NormalDoubleConstant sampler that reads randomness from r
+// instead of crypto/rand.
+func NewNormalDoubleConstantWithReader(l *big.Int, r io.Reader) *NormalDoubleConstant {
 	lSquare := new(big.Float).SetInt(l)
 	lSquare.Mul(lSquare, lSquare)
 	lSquareInv := new(big.Float).Quo(big.NewFloat(1), lSquare)
@@ -58,18 +84,81 @@ func NewNormalDoubleConstant(l *big.Int) *NormalDoubleConstant {
 
 	s := &NormalDoubleConstant{
 		normal:     &normal{},
-		samplerCDT: NewNormalCDT(),
+		samplerCDT: NewNormalCDTWithReader(r),
 		l:          new(big.Int).Set(l),
 		lSquareInv: lSquareInv,
 		twiceL:     twiceL,
+		reader:     r,
+	}
+	if l.Sign() > 0 && l.Cmp(big.NewInt(cdtMaxL)) <= 0 {
+		s.table, s.tail = newCDTTable(l.Int64())
 	}
 
 	return s
 }
 
+// newCDTTable precomputes a cumulative distribution table of the
+// discrete Gaussian distribution centered on 0 with sigma =
+// l * sqrt(1/2ln(2)), i.e. each value x is sampled with probability
+// proportional to exp(-x^2/(2sigma^2)) = 2^(-x^2/l^2). The i-th entry
+// of the table is the probability of sampling a value smaller than or
+// equal to -tail + i, scaled to 63 bits. The values with absolute
+// value greater than tail = 9 * l have probability smaller than 2^-80
+// and are omitted. Each probability is computed from the nearer end
+// of the distribution, so that small probabilities keep their precision.
+func newCDTTable(l int64) ([]uint64, int64) {
+	tail := 9 * l
+	lSquare := float64(l * l)
+
+	// tailSum[x] is the sum of the weights of the values from x to tail
+	tailSum := make([]float64, tail+2)
+	for x := tail; x >= 0; x-- {
+		tailSum[x] = tailSum[x+1] + math.Exp2(-float64(x*x)/lSquare)
+	}
+	total := 2*tailSum[0] - 1
+
+	table := make([]uint64, 2*tail)
+	for i := range table {
+		v := int64(i) - tail
+		if v < 0 {
+			// the probability of the values from -tail to v
+			table[i] = uint64(math.Ldexp(tailSum[-v]/total, 63))
+		} else {
+			// one minus the probability of the values from v+1 to tail
+			table[i] = (uint64(1) << 63) - uint64(math.Ldexp(tailSum[v+1]/total, 63))
+		}
+	}
+
+	return table, tail
+}
+
+// sampleTable samples a value using the precomputed cumulative
+// distribution table. All the entries of the table are compared to a
+// uniformly random 63 bit value, so the time needed does not depend on
+// the sampled value.
+func (s *NormalDoubleConstant) sampleTable() (*big.Int, error) {
+	randBytes := make([]byte, 8)
+	if _, err := io.ReadFull(readerOrDefault(s.reader), randBytes); err != nil {
+		return nil, err
+	}
+	r := binary.LittleEndian.Uint64(randBytes) & cdtLowMask
+
+	// the highest bit of c - 1 - r is set iff r >= c
+	x := uint64(0)
+	for _, c := range s.table {
+		x += (c - 1 - r) >> 63
+	}
+
+	return big.NewInt(int64(x) - s.tail), nil
+}
+
 // Sample samples according to discrete Gauss distribution using
 // NormalDoubleConstant and second sampling.
 func (s *NormalDoubleConstant) Sample() (*big.Int, error) {
+	if s.table != nil {
+		return s.sampleTable()
+	}
+
 	// prepare values
 	var sign int64
 	var check bool
@@ -84,7 +173,7 @@ func (s *NormalDoubleConstant) Sample() (*big.Int, error) {
 			return nil, err
 		}
 		// sample uniformly from an interval
-		y, err := rand.Int(rand.Reader, s.twiceL)
+		y, err := rand.Int(readerOrDefault(s.reader), s.twiceL)
 		if err != nil {
 			return nil, err
 		}
@@ -104,7 +193,7 @@ func (s *NormalDoubleConstant) Sample() (*big.Int, error) {
 		// zeroCheck == 1 if and only if sign == 1 and res.Sign() == 0
 		zeroCheck := int64(res.Sign()) + sign
 		// sample from Bernoulli to decide if accept
-		check, err = Bernoulli(checkVal, s.lSquareInv)
+		check, err = bernoulli(checkVal, s.lSquareInv, readerOrDefault(s.reader))
 		if err != nil {
 			return nil, err
 		}
@@ -17,10 +17,12 @@
 package sample_test
 
 import (
+	"math"
 	"math/big"
 	"testing"
 
 	"github.com/fentec-project/gofe/sample"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNormalDoubleConstant(t *testing.T) {
@@ -74,3 +76,43 @@ func TestNormalDoubleConstant(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalDoubleConstant_SmallSigma(t *testing.T) {
+	// for small l the values are sampled from a table; each value x
+	// should be sampled with probability proportional to 2^(-x^2/l^2)
+	l := int64(2)
+	sampler := sample.NewNormalDoubleConstant(big.NewInt(l))
+	n := 200000
+	counts := make(map[int64]int)
+	for i := 0; i < n; i++ {
+		x, err := sampler.Sample()
+		if err != nil {
+			t.Fatalf("Error during sampling: %v", err)
+		}
+		counts[x.Int64()]++
+	}
+
+	total := 0.0
+	for x := -10 * l; x <= 10*l; x++ {
+		total += math.Exp2(-float64(x*x) / float64(l*l))
+	}
+	for x := int64(-3); x <= 3; x++ {
+		p := math.Exp2(-float64(x*x)/float64(l*l)) / total
+		freq := float64(counts[x]) / float64(n)
+		assert.InDelta(t, p, freq, 0.01, "wrong frequency of %d", x)
+	}
+}
+
+func BenchmarkNormalDoubleConstant_Small(b *testing.B) {
+	sampler := sample.NewNormalDoubleConstant(big.NewInt(10))
+	for i := 0; i < b.N; i++ {
+		_, _ = sampler.Sample()
+	}
+}
+
+func BenchmarkNormalDoubleConstant_Large(b *testing.B) {
+	sampler := sample.NewNormalDoubleConstant(big.NewInt(1000))
+	for i := 0; i < b.N; i++ {
+		_, _ = sampler.Sample()
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sample
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"io"
+)
+
+// zeroReader is an endless source of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+
+	return len(p), nil
+}
+
+// NewSeededReader returns a deterministic source of pseudo-random
+// bytes determined by seed. The bytes are the AES-256 keystream in
+// the CTR mode, with the key derived from seed by SHA-256 and an all
+// zero initialization vector, hence the stream can be reproduced by
+// other implementations.
+//
+// Passing the reader to the samplers (or to a scheme with
+// innerprod.WithRandomness) makes the sampled values reproducible,
+// which is useful for known-answer tests. It should never be used
+// for generating real keys or ciphertexts, since everything derived
+// from it is predictable from the seed.
+func NewSeededReader(seed []byte) io.Reader {
+	key := sha256.Sum256(seed)
+	// the key is of a valid length, hence no error is possible
+	block, _ := aes.NewCipher(key[:])
+	stream := cipher.NewCTR(block, make([]byte, aes.BlockSize))
+
+	return &cipher.StreamReader{S: stream, R: zeroReader{}}
+}
@@ -18,35 +18,85 @@ package sample
 
 import (
 	"crypto/rand"
+	"fmt"
+	"io"
 	"math/big"
 )
 
 // UniformRange samples random values from the interval [min, max).
+// The length of the interval and the number of random bytes needed
+// to sample from it are computed once when the sampler is created.
+//
+// A UniformRange instance is not modified by sampling, hence a single
+// instance can be shared across goroutines as long as its reader is
+// safe for concurrent use, which is the case for the default crypto/rand
+// reader. Callers are thus encouraged to create the sampler once and
+// reuse it instead of creating a new one for each sampled value.
 type UniformRange struct {
-	min *big.Int
-	max *big.Int
+	min     *big.Int
+	max     *big.Int
+	width   *big.Int // max - min
+	byteLen int      // number of random bytes read per sampling attempt
+	topMask byte     // mask applied to the most significant random byte
+	reader  io.Reader
 }
 
 // NewUniformRange returns an instance of the UniformRange sampler.
 // It accepts lower and upper bounds on the sampled values.
 func NewUniformRange(min, max *big.Int) *UniformRange {
+	return NewUniformRangeWithReader(min, max, rand.Reader)
+}
+
+// NewUniformRangeWithReader returns an instance of the UniformRange
+// sampler that reads randomness from r instead of crypto/rand.
+// It accepts lower and upper bounds on the sampled values.
+func NewUniformRangeWithReader(min, max *big.Int, r io.Reader) *UniformRange {
+	width := new(big.Int).Sub(max, min)
+
+	// the same way as in crypto/rand.Int, the random bytes are
+	// masked to the bit length of width - 1
+	bitLen := 0
+	if width.Sign() > 0 {
+		bitLen = new(big.Int).Sub(width, big.NewInt(1)).BitLen()
+	}
+	topBits := uint(bitLen % 8)
+	if topBits == 0 {
+		topBits = 8
+	}
+
 	return &UniformRange{
-		min: min,
-		max: max,
+		min:     new(big.Int).Set(min),
+		max:     new(big.Int).Set(max),
+		width:   width,
+		byteLen: (bitLen + 7) / 8,
+		topMask: byte(int(1<<topBits) - 1),
+		reader:  r,
 	}
 }
 
 // Sample samples random values from the interval [min, max).
+// It returns an error if max is not greater than min.
 func (u *UniformRange) Sample() (*big.Int, error) {
-	maxMinusMin := new(big.Int).Sub(u.max, u.min)
-	res, err := rand.Int(rand.Reader, maxMinusMin)
-	if err != nil {
-		return nil, err
+	if u.width.Sign() <= 0 {
+		return nil, fmt.Errorf("upper bound should be greater than lower bound")
 	}
 
-	res.Add(res, u.min)
+	res := new(big.Int)
+	if u.byteLen > 0 {
+		b := make([]byte, u.byteLen)
+		for {
+			if _, err := io.ReadFull(readerOrDefault(u.reader), b); err != nil {
+				return nil, err
+			}
+			b[0] &= u.topMask
+			res.SetBytes(b)
+			if res.Cmp(u.width) < 0 {
+				break
+			}
+		}
+	}
 
-	return res, err
+	return res.Add(res, u.min), nil
 }
 
 // Uniform samples random values from the interval [0, max).
@@ -60,9 +110,32 @@ func NewUniform(max *big.Int) *UniformRange {
 	return NewUniformRange(big.NewInt(0), max)
 }
 
+// NewUniformWithReader returns an instance of the Uniform sampler
+// that reads randomness from r instead of crypto/rand.
+// It accepts an upper bound on the sampled values.
+func NewUniformWithReader(max *big.Int, r io.Reader) *UniformRange {
+	return NewUniformRangeWithReader(big.NewInt(0), max, r)
+}
+
+// NewUniformNonZero returns an instance of the Uniform sampler
+// that samples random values from the interval [1, max), e.g. the
+// nonzero elements of Z_max. It is meant for the randomness whose
+// value zero would be degenerate, such as the blinding scalars in
+// encryption.
+func NewUniformNonZero(max *big.Int) *UniformRange {
+	return NewUniformRange(big.NewInt(1), max)
+}
+
+// NewUniformNonZeroWithReader returns an instance of the Uniform
+// sampler that samples random values from the interval [1, max)
+// and reads randomness from r instead of crypto/rand.
+func NewUniformNonZeroWithReader(max *big.Int, r io.Reader) *UniformRange {
+	return NewUniformRangeWithReader(big.NewInt(1), max, r)
+}
+
 // Sample samples random values from the interval [0, max).
 func (u *Uniform) Sample() (*big.Int, error) {
-	return rand.Int(rand.Reader, u.max)
+	return rand.Int(readerOrDefault(u.reader), u.max)
 }
 
 // Bit samples a single random bit (value 0 or 1).
@@ -74,3 +147,66 @@ type Bit struct {
 func NewBit() *UniformRange {
 	return NewUniform(big.NewInt(2))
 }
+
+// NewBitWithReader returns an instance of Bit sampler that
+// reads randomness from r instead of crypto/rand.
+func NewBitWithReader(r io.Reader) *UniformRange {
+	return NewUniformWithReader(big.NewInt(2), r)
+}
+
+// UniformInvertible samples random values from the interval
+// [1, modulus) that are coprime with modulus, i.e. values that
+// are invertible modulo modulus.
+type UniformInvertible struct {
+	modulus *big.Int
+	reader  io.Reader
+}
+
+// NewUniformInvertible returns an instance of the UniformInvertible
+// sampler. It accepts the modulus with which the sampled values are
+// coprime.
+func NewUniformInvertible(modulus *big.Int) *UniformInvertible {
+	return NewUniformInvertibleWithReader(modulus, rand.Reader)
+}
+
+// NewUniformInvertibleWithReader returns an instance of the
+// UniformInvertible sampler that reads randomness from r instead
+// of crypto/rand. It accepts the modulus with which the sampled
+// values are coprime.
+func NewUniformInvertibleWithReader(modulus *big.Int, r io.Reader) *UniformInvertible {
+	return &UniformInvertible{
+		modulus: modulus,
+		reader:  r,
+	}
+}
+
+// Sample samples random values from the interval [1, modulus) that
+// are coprime with modulus. Values that are not invertible are
+// rejected and sampled again. It returns an error if modulus is
+// smaller than 2.
+func (u *UniformInvertible) Sample() (*big.Int, error) {
+	one := big.NewInt(1)
+	if u.modulus.Cmp(one) <= 0 {
+		return nil, fmt.Errorf("modulus should be at least 2")
+	}
+
+	gcd := new(big.Int)
+	for {
+		res, err := rand.Int(readerOrDefault(u.reader), u.modulus)
+		if err != nil {
+			return nil, err
+		}
+		if res.Sign() != 0 && gcd.GCD(nil, nil, res, u.modulus).Cmp(one) == 0 {
+			return res, nil
+		}
+	}
+}
+
+// readerOrDefault returns r, or crypto/rand reader if r is nil.
+func readerOrDefault(r io.Reader) io.Reader {
+	if r == nil {
+		return rand.Reader
+	}
+
+	return r
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2018 XLAB d.o.o
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sample_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/fentec-project/bn256"
+	"github.com/fentec-project/gofe/data"
+	"github.com/fentec-project/gofe/sample"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUniformInvertible(t *testing.T) {
+	// 36 = 2^2 * 3^2, so only a third of the values are invertible
+	modulus := big.NewInt(36)
+	sampler := sample.NewUniformInvertible(modulus)
+
+	v, err := data.NewRandomVector(1000, sampler)
+	if err != nil {
+		t.Fatalf("Error during random vector generation: %v", err)
+	}
+	for _, x := range v {
+		assert.True(t, x.Sign() > 0 && x.Cmp(modulus) < 0, "sampled value out of range")
+		assert.NotNil(t, new(big.Int).ModInverse(x, modulus), "sampled value is not invertible")
+	}
+
+	_, err = sample.NewUniformInvertible(big.NewInt(1)).Sample()
+	assert.Error(t, err)
+}
+
+func TestUniformNonZero(t *testing.T) {
+	// with a small modulus 0 would be sampled many times
+	// by a uniform sampler
+	modulus := big.NewInt(5)
+	sampler := sample.NewUniformNonZero(modulus)
+
+	counts := make([]int, 5)
+	v, err := data.NewRandomVector(5000, sampler)
+	if err != nil {
+		t.Fatalf("Error during random vector generation: %v", err)
+	}
+	for _, x := range v {
+		assert.True(t, x.Sign() > 0 && x.Cmp(modulus) < 0, "sampled value out of range")
+		counts[x.Int64()]++
+	}
+	assert.Equal(t, 0, counts[0], "zero should never be sampled")
+	for i := 1; i < 5; i++ {
+		// each value is expected 1250 times
+		assert.True(t, counts[i] > 1000 && counts[i] < 1500, "sampled values are not uniform")
+	}
+
+	_, err = sample.NewUniformNonZero(big.NewInt(1)).Sample()
+	assert.Error(t, err)
+}
+
+func TestUniformRange(t *testing.T) {
+	min := big.NewInt(-1000)
+	max := big.NewInt(3000)
+	sampler := sample.NewUniformRange(min, max)
+
+	// a single sampler is shared across goroutines
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				x, err := sampler.Sample()
+				if err != nil {
+					errs <- err
+					return
+				}
+				if x.Cmp(min) < 0 || x.Cmp(max) >= 0 {
+					errs <- fmt.Errorf("sampled value %v out of range", x)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Error during sampling: %v", err)
+	}
+
+	// the sampler consumes randomness the same way as crypto/rand.Int
+	seed := make([]byte, 4096)
+	for i := range seed {
+		seed[i] = byte(i * 7)
+	}
+	width := new(big.Int).Sub(max, min)
+	s := sample.NewUniformRangeWithReader(min, max, bytes.NewReader(seed))
+	r := bytes.NewReader(seed)
+	for i := 0; i < 100; i++ {
+		x, err := s.Sample()
+		if err != nil {
+			t.Fatalf("Error during sampling: %v", err)
+		}
+		y, err := rand.Int(r, width)
+		if err != nil {
+			t.Fatalf("Error during sampling: %v", err)
+		}
+		assert.Equal(t, y.Add(y, min), x)
+	}
+
+	x, err := sample.NewUniformRange(big.NewInt(5), big.NewInt(6)).Sample()
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(5), x)
+
+	_, err = sample.NewUniformRange(big.NewInt(5), big.NewInt(5)).Sample()
+	assert.Error(t, err)
+}
+
+func BenchmarkUniformRange_PerCall(b *testing.B) {
+	q := bn256.Order
+	for i := 0; i < b.N; i++ {
+		_, _ = sample.NewUniformRange(big.NewInt(1), q).Sample()
+	}
+}
+
+func BenchmarkUniformRange_Reused(b *testing.B) {
+	sampler := sample.NewUniformRange(big.NewInt(1), bn256.Order)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = sampler.Sample()
+	}
+}
+
+func TestNewSeededReader(t *testing.T) {
+	read := func(seed string) []byte {
+		buf := make([]byte, 100)
+		if _, err := io.ReadFull(sample.NewSeededReader([]byte(seed)), buf); err != nil {
+			t.Fatalf("Error during reading: %v", err)
+		}
+		return buf
+	}
+
+	assert.Equal(t, read("seed"), read("seed"))
+	assert.NotEqual(t, read("seed"), read("another seed"))
+}